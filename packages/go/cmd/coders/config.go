@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jayphen/coders/internal/config"
+)
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage configuration",
+		Long:  `Manage coders configuration files.`,
+	}
+
+	cmd.AddCommand(newConfigShowCmd())
+	cmd.AddCommand(newConfigInitCmd())
+	cmd.AddCommand(newConfigPathCmd())
+
+	return cmd
+}
+
+func newConfigShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show",
+		Short: "Show current configuration",
+		Long:  `Display the current configuration values from all sources.`,
+		RunE:  runConfigShow,
+	}
+}
+
+func newConfigInitCmd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Create example configuration file",
+		Long: `Create an example configuration file at ~/.config/coders/config.yaml.
+
+The generated file contains all available options with their default values.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigInit(force)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing config file")
+
+	return cmd
+}
+
+func newConfigPathCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "path",
+		Short: "Show configuration file paths",
+		Long:  `Display the paths where configuration files are searched.`,
+		RunE:  runConfigPath,
+	}
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Get()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	fmt.Println("Current configuration:")
+	fmt.Println()
+	fmt.Printf("  default_tool:       %s\n", cfg.DefaultTool)
+	fmt.Printf("  heartbeat_interval: %s\n", cfg.HeartbeatInterval)
+	fmt.Printf("  redis_url:          %s\n", cfg.RedisURL)
+	fmt.Printf("  dashboard_port:     %d\n", cfg.DashboardPort)
+	fmt.Printf("  default_model:      %s\n", valueOrDefault(cfg.DefaultModel, "(not set)"))
+	fmt.Printf("  default_heartbeat:  %t\n", cfg.DefaultHeartbeat)
+	fmt.Printf("  multiplexer:        %s\n", cfg.Multiplexer)
+	fmt.Printf("  tmux_socket:        %s\n", valueOrDefault(cfg.TmuxSocket, "(default server)"))
+	fmt.Println()
+	fmt.Println("  Ollama:")
+	fmt.Printf("    base_url:   %s\n", valueOrDefault(cfg.Ollama.BaseURL, "(not set)"))
+	fmt.Printf("    auth_token: %s\n", maskSecret(cfg.Ollama.AuthToken))
+	fmt.Printf("    api_key:    %s\n", maskSecret(cfg.Ollama.APIKey))
+	fmt.Println()
+	fmt.Println("  Sessions:")
+	fmt.Printf("    kill_children_on_parent_exit: %t\n", cfg.Sessions.KillChildrenOnParentExit)
+	fmt.Printf("    notify_parent_on_promise:     %t\n", cfg.Sessions.NotifyParentOnPromise)
+	fmt.Printf("    restart_context_lines:        %d\n", cfg.Sessions.RestartContextLines)
+	fmt.Println()
+	fmt.Println("  Transcripts:")
+	fmt.Printf("    enabled:     %t\n", cfg.Transcripts.Enabled)
+	fmt.Printf("    max_size:    %d MB\n", cfg.Transcripts.MaxSize)
+	fmt.Printf("    max_backups: %d\n", cfg.Transcripts.MaxBackups)
+	fmt.Println()
+	fmt.Println("  Crash Recovery:")
+	fmt.Printf("    base_delay:              %s\n", cfg.CrashRecovery.BaseDelay)
+	fmt.Printf("    max_delay:               %s\n", cfg.CrashRecovery.MaxDelay)
+	fmt.Printf("    rate_limit_delay:        %s\n", cfg.CrashRecovery.RateLimitDelay)
+	fmt.Printf("    circuit_breaker_crashes: %d\n", cfg.CrashRecovery.CircuitBreakerCrashes)
+	fmt.Printf("    circuit_breaker_window:  %s\n", cfg.CrashRecovery.CircuitBreakerWindow)
+	fmt.Println()
+	fmt.Println("  Remediation:")
+	fmt.Printf("    enabled: %t\n", cfg.Remediation.Enabled)
+	fmt.Printf("    grace:   %s\n", cfg.Remediation.Grace)
+	for _, rule := range cfg.Remediation.Rules {
+		fmt.Printf("    - %s after %s: %s\n", rule.Status, rule.After, rule.Action)
+	}
+	fmt.Println()
+	fmt.Println("  Sandbox:")
+	fmt.Printf("    default:           %t\n", cfg.Sandbox.Default)
+	fmt.Printf("    backend:           %s\n", cfg.Sandbox.Backend)
+	fmt.Printf("    network:           %t\n", cfg.Sandbox.Network)
+	fmt.Printf("    allow_multiplexer: %t\n", cfg.Sandbox.AllowMultiplexer)
+	fmt.Printf("    read_write:        %s\n", valueOrDefault(strings.Join(cfg.Sandbox.ReadWrite, ", "), "(none)"))
+	fmt.Printf("    read_only:         %s\n", valueOrDefault(strings.Join(cfg.Sandbox.ReadOnly, ", "), "(none)"))
+	fmt.Printf("    hide:              %s\n", strings.Join(cfg.Sandbox.Hide, ", "))
+	fmt.Println()
+	fmt.Printf("  Task sources: %s\n", valueOrDefault(strings.Join(cfg.TaskSources, "; "), "(none)"))
+	fmt.Println("  Models:")
+	tools := make([]string, 0, len(cfg.Models))
+	for tool := range cfg.Models {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	for _, tool := range tools {
+		fmt.Printf("    %-9s %s\n", tool+":", strings.Join(cfg.Models[tool], ", "))
+	}
+	templates := make([]string, 0, len(cfg.SpawnTemplates))
+	for _, t := range cfg.SpawnTemplates {
+		templates = append(templates, t.Name)
+	}
+	fmt.Printf("  Spawn templates: %s\n", valueOrDefault(strings.Join(templates, ", "), "(none)"))
+	fmt.Println()
+	fmt.Println("  Budget:")
+	fmt.Printf("    daily:  %s\n", formatBudgetCap(cfg.Budget.Daily))
+	fmt.Printf("    weekly: %s\n", formatBudgetCap(cfg.Budget.Weekly))
+	fmt.Printf("    action: %s\n", cfg.Budget.Action)
+	fmt.Println()
+	fmt.Println("  Routing:")
+	for i, rule := range cfg.Routing.Rules {
+		target := rule.Tool
+		if rule.Model != "" {
+			target += " (" + rule.Model + ")"
+		}
+		fmt.Printf("    rule %d:        %s -> %s\n", i+1, rule.Match, target)
+	}
+	fmt.Printf("    fallback:      %s\n", valueOrDefault(strings.Join(cfg.Routing.Fallback, " -> "), "(none)"))
+	fmt.Printf("    session limit: %.0f%% (avoid for %s)\n", cfg.Routing.SessionLimit, cfg.Routing.SessionReset)
+	fmt.Printf("    weekly limit:  %.0f%% (avoid for %s)\n", cfg.Routing.WeeklyLimit, cfg.Routing.WeeklyReset)
+	projects := make([]string, 0, len(cfg.Routing.Projects))
+	for dir := range cfg.Routing.Projects {
+		projects = append(projects, dir)
+	}
+	sort.Strings(projects)
+	for _, dir := range projects {
+		fmt.Printf("    %s: %s\n", dir, strings.Join(cfg.Routing.Projects[dir], " -> "))
+	}
+	fmt.Println()
+	fmt.Println("  Notifications:")
+	channelNames := make([]string, 0, len(cfg.Notifications.Channels))
+	for name := range cfg.Notifications.Channels {
+		channelNames = append(channelNames, name)
+	}
+	sort.Strings(channelNames)
+	for _, name := range channelNames {
+		fmt.Printf("    channel %s: %s\n", name, cfg.Notifications.Channels[name].Type)
+	}
+	for _, rule := range cfg.Notifications.Rules {
+		fmt.Printf("    - %s -> %s\n", strings.Join(rule.Events, ","), strings.Join(rule.Channels, ","))
+	}
+	if cfg.Notifications.QuietHours.Start != "" {
+		fmt.Printf("    quiet_hours: %s-%s\n", cfg.Notifications.QuietHours.Start, cfg.Notifications.QuietHours.End)
+	}
+	fmt.Printf("    dedup_window: %s\n", cfg.Notifications.DedupWindow)
+
+	return nil
+}
+
+func runConfigInit(force bool) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	configPath := filepath.Join(homeDir, ".config", "coders", "config.yaml")
+
+	// Check if file exists
+	if _, err := os.Stat(configPath); err == nil && !force {
+		return fmt.Errorf("config file already exists at %s (use --force to overwrite)", configPath)
+	}
+
+	if err := config.WriteExample(configPath); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	fmt.Printf("Created config file at: %s\n", configPath)
+	fmt.Println()
+	fmt.Println("Edit this file to customize your settings.")
+	fmt.Println("Run 'coders config show' to see current values.")
+
+	return nil
+}
+
+func runConfigPath(cmd *cobra.Command, args []string) error {
+	fmt.Println("Configuration file search paths (in priority order):")
+	fmt.Println()
+
+	paths := config.ConfigPaths()
+	for i, p := range paths {
+		exists := "not found"
+		if _, err := os.Stat(p); err == nil {
+			exists = "found"
+		}
+		fmt.Printf("  %d. %s (%s)\n", i+1, p, exists)
+	}
+
+	fmt.Println()
+	fmt.Println("Environment variables can override file settings.")
+	fmt.Println("Supported env vars:")
+	fmt.Println("  CODERS_DEFAULT_TOOL")
+	fmt.Println("  CODERS_HEARTBEAT_INTERVAL")
+	fmt.Println("  CODERS_REDIS_URL (or REDIS_URL)")
+	fmt.Println("  CODERS_DASHBOARD_PORT")
+	fmt.Println("  CODERS_DEFAULT_MODEL")
+	fmt.Println("  CODERS_DEFAULT_HEARTBEAT")
+	fmt.Println("  CODERS_OLLAMA_BASE_URL")
+	fmt.Println("  CODERS_OLLAMA_AUTH_TOKEN")
+	fmt.Println("  CODERS_OLLAMA_API_KEY")
+	fmt.Println("  CODERS_KILL_CHILDREN_ON_PARENT_EXIT")
+	fmt.Println("  CODERS_NOTIFY_PARENT_ON_PROMISE")
+	fmt.Println("  CODERS_REMEDIATION_ENABLED")
+	fmt.Println("  CODERS_NOTIFY_WEBHOOK_URL")
+	fmt.Println("  CODERS_NOTIFY_SLACK_URL")
+
+	return nil
+}
+
+func valueOrDefault(val, def string) string {
+	if val == "" {
+		return def
+	}
+	return val
+}
+
+func maskSecret(val string) string {
+	if val == "" {
+		return "(not set)"
+	}
+	if len(val) <= 8 {
+		return "***"
+	}
+	return val[:4] + "..." + val[len(val)-4:]
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/redis"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+// maxRestartDiffBytes caps the git diff included in a restart prompt.
+const maxRestartDiffBytes = 8000
+
+// newConversationID returns an ID for tools that let the caller name the
+// conversation at spawn time. Empty for tools that don't.
+func newConversationID(tool string) string {
+	if tool != "claude" {
+		return ""
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	// RFC 4122 version 4 UUID
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// conversationArgs returns the CLI arguments that start (or, with resume,
+// continue) a named conversation.
+func conversationArgs(tool, conversationID string, resume bool) string {
+	if conversationID == "" {
+		return ""
+	}
+	switch tool {
+	case "claude":
+		if resume {
+			return " --resume " + shellEscape(conversationID)
+		}
+		return " --session-id " + shellEscape(conversationID)
+	}
+	return ""
+}
+
+// canResumeConversation reports whether a restart can reopen the session's
+// previous conversation instead of starting from a fresh prompt.
+func canResumeConversation(state *types.SessionState) bool {
+	return conversationArgs(state.Tool, state.ConversationID, true) != ""
+}
+
+// gitHead returns the current commit in dir, or "" outside a git repo.
+func gitHead(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// restartContext is what a restarted session is told about its previous run.
+type restartContext struct {
+	Reason     string
+	Transcript string              // Last lines of pane output before the crash
+	DiffStat   string              // git diff --stat since spawn
+	Diff       string              // git diff since spawn, truncated
+	Blocked    *types.CoderPromise // Blocked promise published by the session, if any
+}
+
+// gatherRestartContext collects the previous run's output, changes and
+// blockers. Must be called before the old session is killed.
+func gatherRestartContext(redisClient *redis.Client, state *types.SessionState, reason string, lines int) restartContext {
+	rc := restartContext{Reason: reason}
+
+	if lines > 0 {
+		if out, err := mux.CapturePane(state.SessionID, mux.CaptureOptions{Lines: lines}); err == nil {
+			rc.Transcript = strings.TrimSpace(out)
+		}
+	}
+
+	if state.SpawnCommit != "" {
+		if out, err := exec.Command("git", "-C", state.Cwd, "diff", "--stat", state.SpawnCommit).Output(); err == nil {
+			rc.DiffStat = strings.TrimRight(string(out), "\n")
+		}
+		if out, err := exec.Command("git", "-C", state.Cwd, "diff", state.SpawnCommit).Output(); err == nil {
+			rc.Diff = truncateDiff(string(out), maxRestartDiffBytes)
+		}
+	}
+
+	if redisClient != nil {
+		if promise, err := redisClient.GetPromise(state.SessionID); err == nil && promise != nil && promise.Status == types.PromiseBlocked {
+			rc.Blocked = promise
+		}
+	}
+
+	return rc
+}
+
+// truncateDiff cuts a diff to at most max bytes on a line boundary.
+func truncateDiff(diff string, max int) string {
+	diff = strings.TrimRight(diff, "\n")
+	if len(diff) <= max {
+		return diff
+	}
+	cut := diff[:max]
+	if i := strings.LastIndexByte(cut, '\n'); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + fmt.Sprintf("\n... (diff truncated, %d more bytes)", len(diff)-len(cut))
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Jayphen/coders/internal/types"
+)
+
+func TestConversationArgs(t *testing.T) {
+	id := newConversationID("claude")
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Fatalf("newConversationID(claude) = %q, want a v4 UUID", id)
+	}
+	if got := newConversationID("codex"); got != "" {
+		t.Errorf("newConversationID(codex) = %q, want empty", got)
+	}
+
+	if got := conversationArgs("claude", id, false); got != " --session-id '"+id+"'" {
+		t.Errorf("spawn args = %q", got)
+	}
+	if got := conversationArgs("claude", id, true); got != " --resume '"+id+"'" {
+		t.Errorf("resume args = %q", got)
+	}
+	if got := conversationArgs("gemini", id, true); got != "" {
+		t.Errorf("gemini resume args = %q, want empty", got)
+	}
+
+	if !canResumeConversation(&types.SessionState{Tool: "claude", ConversationID: id}) {
+		t.Error("claude session with a conversation ID should be resumable")
+	}
+	if canResumeConversation(&types.SessionState{Tool: "claude"}) {
+		t.Error("session without a conversation ID should not be resumable")
+	}
+}
+
+func TestBuildRestartPrompt(t *testing.T) {
+	rc := restartContext{
+		Reason:     "tmux session no longer exists",
+		Transcript: "Running tests...",
+		DiffStat:   " auth.go | 4 ++--",
+		Diff:       "+func login() {}",
+		Blocked:    &types.CoderPromise{Summary: "Need credentials", Blockers: []string{"API key"}},
+	}
+
+	resumed := buildRestartPrompt("claude", "Fix login", 2, rc, true)
+	if !strings.Contains(resumed, "restart #2") || strings.Contains(resumed, "TASK:") {
+		t.Errorf("resumed prompt should be a short note, got:\n%s", resumed)
+	}
+
+	fresh := buildRestartPrompt("claude", "Fix login", 1, rc, false)
+	for _, want := range []string{"TASK: Fix login", "tmux session no longer exists", "Need credentials", "API key", "auth.go", "+func login() {}", "Running tests...", "/coders:promise"} {
+		if !strings.Contains(fresh, want) {
+			t.Errorf("restart prompt missing %q:\n%s", want, fresh)
+		}
+	}
+
+	bare := buildRestartPrompt("codex", "Fix login", 1, restartContext{}, false)
+	if !strings.Contains(bare, "Check git status") || !strings.Contains(bare, "coders promise") {
+		t.Errorf("prompt without context should fall back to git status hint:\n%s", bare)
+	}
+}
+
+func TestTruncateDiff(t *testing.T) {
+	diff := strings.Repeat("+line\n", 100)
+	got := truncateDiff(diff, 50)
+	if !strings.Contains(got, "diff truncated") {
+		t.Errorf("expected truncation marker, got %q", got)
+	}
+	if kept := strings.SplitN(got, "\n...", 2)[0]; !strings.HasSuffix(kept, "+line") {
+		t.Errorf("truncation should happen on a line boundary: %q", got)
+	}
+	if got := truncateDiff("+a\n", 50); got != "+a" {
+		t.Errorf("short diff changed: %q", got)
+	}
+}
+
+func TestGatherRestartContextDiff(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	git("init")
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", ".")
+	git("commit", "-m", "initial")
+
+	head := gitHead(dir)
+	if head == "" {
+		t.Fatal("gitHead returned empty for a repo")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rc := gatherRestartContext(nil, &types.SessionState{SessionID: "coder-missing", Cwd: dir, SpawnCommit: head}, "crashed", 0)
+	if !strings.Contains(rc.DiffStat, "main.go") || !strings.Contains(rc.Diff, "+func main() {}") {
+		t.Errorf("expected diff since spawn, got stat %q diff %q", rc.DiffStat, rc.Diff)
+	}
+}
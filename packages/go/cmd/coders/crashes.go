@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+
+	"github.com/Jayphen/coders/internal/forensics"
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/tui"
+)
+
+func newCrashesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "crashes",
+		Short: "Browse crash forensics bundles",
+		Long: `Browse the forensics bundles saved by the crash watcher.
+
+When a session spawned with --restart-on-crash crashes, the crash watcher
+saves its full scrollback, process tree, relevant environment (secrets
+redacted), git status and diff, session state and recent heartbeats before
+restarting it. Bundles are stored under the state directory
+(~/.local/state/coders/crashes by default).`,
+	}
+
+	cmd.AddCommand(newCrashesListCmd())
+	cmd.AddCommand(newCrashesShowCmd())
+
+	return cmd
+}
+
+func newCrashesListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list [session]",
+		Short: "List crash bundles",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sessionID := ""
+			if len(args) == 1 {
+				sessionID = crashSessionID(args[0])
+			}
+			return runCrashesList(sessionID)
+		},
+	}
+}
+
+func newCrashesShowCmd() *cobra.Command {
+	var index, lines int
+	var full bool
+
+	cmd := &cobra.Command{
+		Use:   "show <session>",
+		Short: "Show a crash bundle",
+		Long: `Show a crash bundle for a session. Defaults to the most recent crash;
+use --index to pick an older one (0 is the newest).`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCrashesShow(crashSessionID(args[0]), index, lines, full)
+		},
+	}
+
+	cmd.Flags().IntVar(&index, "index", 0, "Which crash to show, newest first")
+	cmd.Flags().IntVarP(&lines, "lines", "n", 40, "Scrollback lines to show")
+	cmd.Flags().BoolVar(&full, "full", false, "Show the full scrollback and git diff")
+
+	return cmd
+}
+
+// crashSessionID accepts a session name with or without the coder- prefix.
+func crashSessionID(name string) string {
+	if strings.HasPrefix(name, mux.SessionPrefix) {
+		return name
+	}
+	if bundles, _ := forensics.List(name); len(bundles) > 0 {
+		return name
+	}
+	return mux.SessionPrefix + name
+}
+
+func runCrashesList(sessionID string) error {
+	bundles, err := forensics.List(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to list crash bundles: %w", err)
+	}
+	if len(bundles) == 0 {
+		fmt.Println("No crash bundles found")
+		return nil
+	}
+
+	header := fmt.Sprintf("%-20s %-28s %-13s %-5s %s", "TIME", "SESSION", "CLASS", "EXIT", "REASON")
+	fmt.Println(lipgloss.NewStyle().Bold(true).Foreground(tui.ColorGray).Render(header))
+	fmt.Println(strings.Repeat("-", 90))
+
+	for _, b := range bundles {
+		name := strings.TrimPrefix(b.SessionID, mux.SessionPrefix)
+		if len(name) > 26 {
+			name = name[:23] + "..."
+		}
+		class := b.Class
+		if class == "" {
+			class = "-"
+		}
+		reason := b.Reason
+		if len(reason) > 40 {
+			reason = reason[:37] + "..."
+		}
+		fmt.Printf("%-20s %-28s %-13s %-5s %s\n",
+			b.CrashedAt.Local().Format("2006-01-02 15:04:05"), name, class, formatExitCode(b.ExitCode), reason)
+	}
+	return nil
+}
+
+func runCrashesShow(sessionID string, index, lines int, full bool) error {
+	bundles, err := forensics.List(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to list crash bundles: %w", err)
+	}
+	if len(bundles) == 0 {
+		return fmt.Errorf("no crash bundles for %s", sessionID)
+	}
+	if index < 0 || index >= len(bundles) {
+		return fmt.Errorf("index %d out of range (%d bundles)", index, len(bundles))
+	}
+
+	b, err := forensics.Load(bundles[index].Dir)
+	if err != nil {
+		return fmt.Errorf("failed to load crash bundle: %w", err)
+	}
+
+	section := lipgloss.NewStyle().Bold(true).Foreground(tui.ColorCyan)
+
+	fmt.Printf("\033[31m💥 Crash: %s\033[0m\n", b.SessionID)
+	fmt.Printf("   Time:   %s (%s ago)\n", b.CrashedAt.Local().Format("2006-01-02 15:04:05"), formatDuration(time.Since(b.CrashedAt)))
+	fmt.Printf("   Class:  %s\n", valueOrDefault(b.Class, "-"))
+	fmt.Printf("   Reason: %s\n", b.Reason)
+	fmt.Printf("   Exit:   %s\n", formatExitCode(b.ExitCode))
+	if b.State != nil {
+		fmt.Printf("   Tool:   %s\n", b.State.Tool)
+		if b.State.Task != "" {
+			fmt.Printf("   Task:   %s\n", b.State.Task)
+		}
+		fmt.Printf("   Restarts: %d/%d\n", b.State.RestartCount, b.State.MaxRestarts)
+	}
+	if b.Cwd != "" {
+		fmt.Printf("   Directory: %s\n", b.Cwd)
+	}
+
+	if len(b.Panes) > 0 || b.ProcessTree != "" {
+		fmt.Println()
+		fmt.Println(section.Render("Processes"))
+		for _, p := range b.Panes {
+			status := "alive"
+			if p.Dead {
+				status = "dead, status " + valueOrDefault(p.DeadStatus, "?")
+			}
+			fmt.Printf("   pane %s pid %d (%s) running %s\n", p.ID, p.PID, status, p.Command)
+		}
+		printIndented(b.ProcessTree)
+	}
+
+	if len(b.Heartbeats) > 0 {
+		fmt.Println()
+		fmt.Println(section.Render("Recent heartbeats"))
+		shown := b.Heartbeats
+		if len(shown) > 5 && !full {
+			shown = shown[:5]
+		}
+		for _, hb := range shown {
+			fmt.Printf("   %s %-8s %s\n", time.UnixMilli(hb.Timestamp).Local().Format("15:04:05"), hb.Status, hb.LastActivity)
+		}
+	}
+
+	if len(b.Events) > 0 {
+		fmt.Println()
+		fmt.Println(section.Render("Session events"))
+		for _, e := range b.Events {
+			fmt.Printf("   %s %s %s: %s\n", time.UnixMilli(e.Timestamp).Local().Format("15:04:05"), e.Type, e.Action, e.Message)
+		}
+	}
+
+	if len(b.Env) > 0 {
+		fmt.Println()
+		fmt.Println(section.Render("Environment"))
+		keys := make([]string, 0, len(b.Env))
+		for k := range b.Env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("   %s=%s\n", k, b.Env[k])
+		}
+	}
+
+	if b.GitStatus != "" {
+		fmt.Println()
+		fmt.Println(section.Render("Git status"))
+		printIndented(b.GitStatus)
+	}
+	if full && b.GitDiff != "" {
+		fmt.Println()
+		fmt.Println(section.Render("Git diff"))
+		fmt.Println(b.GitDiff)
+	}
+
+	if b.Scrollback != "" {
+		fmt.Println()
+		scrollback := strings.TrimRight(b.Scrollback, "\n")
+		all := strings.Split(scrollback, "\n")
+		if !full && lines > 0 && len(all) > lines {
+			fmt.Println(section.Render(fmt.Sprintf("Scrollback (last %d of %d lines)", lines, len(all))))
+			all = all[len(all)-lines:]
+		} else {
+			fmt.Println(section.Render("Scrollback"))
+		}
+		fmt.Println(strings.Join(all, "\n"))
+	}
+
+	fmt.Printf("\n\033[33m💡 Bundle: %s\033[0m\n", b.Dir)
+	return nil
+}
+
+// formatExitCode formats an optional exit status.
+func formatExitCode(code *int) string {
+	if code == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d", *code)
+}
+
+// printIndented prints multi-line text indented under a section heading.
+func printIndented(text string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if line != "" {
+			fmt.Printf("   %s\n", line)
+		}
+	}
+}
@@ -0,0 +1,613 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/crash"
+	"github.com/Jayphen/coders/internal/forensics"
+	"github.com/Jayphen/coders/internal/logging"
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/notify"
+	"github.com/Jayphen/coders/internal/proc"
+	"github.com/Jayphen/coders/internal/redis"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+var (
+	crashWatcherSessionID string
+)
+
+func newCrashWatcherCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    "crash-watcher",
+		Short:  "Monitor a session for crashes and restart if needed",
+		Hidden: true, // Internal command, started by spawn
+		Long: `Run a background crash watcher that monitors a tmux session.
+If the session crashes or the CLI process dies unexpectedly, it will
+automatically restart the session. Claude sessions resume their previous
+conversation; other tools are restarted with the task plus the last pane
+output, the git diff since spawn and any blocked promise.
+
+This is typically started automatically by 'coders spawn' when --restart-on-crash is enabled.`,
+		RunE: runCrashWatcher,
+	}
+
+	cmd.Flags().StringVar(&crashWatcherSessionID, "session", "", "Session ID to watch")
+
+	return cmd
+}
+
+func runCrashWatcher(cmd *cobra.Command, args []string) error {
+	log := logging.WithCommand("crash-watcher")
+
+	sessionID := crashWatcherSessionID
+	if sessionID == "" {
+		sessionID = os.Getenv("CODERS_SESSION_ID")
+	}
+	if sessionID == "" {
+		log.Error("session ID required")
+		return fmt.Errorf("session ID required (use --session or CODERS_SESSION_ID env)")
+	}
+
+	log = log.WithSessionID(sessionID)
+
+	// Connect to Redis
+	redisClient, err := redis.NewClient()
+	if err != nil {
+		log.WithError(err).Error("failed to connect to Redis")
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	defer redisClient.Close()
+
+	// Get session state from Redis
+	ctx := context.Background()
+	state, err := redisClient.GetSessionState(ctx, sessionID)
+	if err != nil {
+		log.WithError(err).Error("failed to get session state")
+		return fmt.Errorf("failed to get session state: %w", err)
+	}
+	if state == nil {
+		log.Error("no session state found")
+		return fmt.Errorf("no session state found for %s", sessionID)
+	}
+
+	log.WithFields(map[string]interface{}{
+		"max_restarts":     state.MaxRestarts,
+		"current_restarts": state.RestartCount,
+	}).Info("crash watcher started")
+	fmt.Printf("[CrashWatcher] Started for session: %s\n", sessionID)
+	fmt.Printf("[CrashWatcher] Max restarts: %d, Current restarts: %d\n", state.MaxRestarts, state.RestartCount)
+
+	// Set up signal handling
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	// Check interval
+	checkInterval := 5 * time.Second
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	// Consecutive failures counter for debouncing
+	consecutiveFailures := 0
+	failureThreshold := 2 // Require 2 consecutive failures before restart
+
+	policy := crash.DefaultPolicy()
+	if cfg, err := config.Get(); err == nil {
+		policy = crash.PolicyFromConfig(cfg.CrashRecovery)
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			result := classifySession(redisClient, sessionID)
+			if result.Class == crash.ClassCompleted {
+				log.Info("tool exited after publishing a promise, stopping crash watcher")
+				fmt.Printf("[CrashWatcher] Session completed, exiting\n")
+				return nil
+			}
+			if !result.Crashed() {
+				// Reset failure counter on successful check
+				if consecutiveFailures > 0 {
+					fmt.Printf("[CrashWatcher] Session recovered, resetting failure counter\n")
+				}
+				consecutiveFailures = 0
+				continue
+			}
+
+			consecutiveFailures++
+			log.WithFields(map[string]interface{}{
+				"consecutive_failures": consecutiveFailures,
+				"threshold":            failureThreshold,
+				"class":                string(result.Class),
+				"reason":               result.Reason,
+			}).Warn("session appears crashed")
+			fmt.Printf("[CrashWatcher] Session appears crashed (check %d/%d): %s\n",
+				consecutiveFailures, failureThreshold, result.Reason)
+
+			if consecutiveFailures < failureThreshold {
+				continue
+			}
+
+			log.WithFields(map[string]interface{}{
+				"class":  string(result.Class),
+				"reason": result.Reason,
+			}).Error("session confirmed crashed")
+			fmt.Printf("[CrashWatcher] Session confirmed crashed (%s): %s\n", result.Class, result.Reason)
+
+			// A deliberate 'coders kill' removes the session state; don't resurrect it.
+			// Otherwise reread it, as restarts made by 'coders restart' or
+			// remediation count towards the restart budget too.
+			if current, err := redisClient.GetSessionState(ctx, sessionID); err == nil {
+				if current == nil {
+					log.Info("session state removed, session was killed deliberately")
+					fmt.Printf("[CrashWatcher] Session was killed, exiting\n")
+					return nil
+				}
+				state = current
+			}
+
+			// Save the evidence before restartSession or cleanup tears the pane down
+			bundleDir := saveCrashBundle(ctx, redisClient, state, result)
+
+			now := time.Now()
+			decision := policy.Decide(result.Class, state.RestartCount, recentCrashTimes(ctx, redisClient, sessionID, now), now)
+			if decision.Restart && state.RestartCount >= state.MaxRestarts {
+				decision = crash.Decision{Reason: fmt.Sprintf("max restarts (%d) reached", state.MaxRestarts)}
+			}
+
+			// Record crash event
+			crashEvent := &types.CrashEvent{
+				SessionID:      sessionID,
+				Timestamp:      now.UnixMilli(),
+				Reason:         result.Reason,
+				Class:          string(result.Class),
+				ExitCode:       result.ExitCode,
+				WillRestart:    decision.Restart,
+				RestartDelayMs: decision.Delay.Milliseconds(),
+				Bundle:         bundleDir,
+			}
+			if err := redisClient.RecordCrashEvent(ctx, crashEvent); err != nil {
+				fmt.Printf("[CrashWatcher] Failed to record crash event: %v\n", err)
+			}
+
+			name := strings.TrimPrefix(sessionID, mux.SessionPrefix)
+			if !decision.Restart {
+				emitSessionEvent(ctx, redisClient, notify.Event{
+					Type:      notify.EventMaxRestarts,
+					Title:     fmt.Sprintf("Coder gave up: %s", name),
+					Message:   fmt.Sprintf("%s: %s; not restarting (%s)", result.Class, result.Reason, decision.Reason),
+					SessionID: sessionID,
+					Fields:    map[string]string{"class": string(result.Class)},
+				})
+
+				log.WithField("reason", decision.Reason).Warn("not restarting")
+				fmt.Printf("[CrashWatcher] Not restarting: %s\n", decision.Reason)
+				if cfg, err := config.Get(); err == nil && cfg.Sessions.KillChildrenOnParentExit {
+					for _, child := range killChildSessions(ctx, redisClient, sessionID) {
+						fmt.Printf("[CrashWatcher] Killed child session: %s\n", child)
+					}
+				}
+				// Clean up session state
+				if err := redisClient.DeleteSessionState(ctx, sessionID); err != nil {
+					log.WithError(err).Warn("failed to delete session state")
+					fmt.Printf("[CrashWatcher] Failed to delete session state: %v\n", err)
+				}
+				return nil
+			}
+
+			emitSessionEvent(ctx, redisClient, notify.Event{
+				Type:      notify.EventCrash,
+				Title:     fmt.Sprintf("Coder crashed: %s", name),
+				Message:   fmt.Sprintf("%s: %s; restarting %d/%d in %s", result.Class, result.Reason, state.RestartCount+1, state.MaxRestarts, formatDuration(decision.Delay)),
+				SessionID: sessionID,
+				Fields:    map[string]string{"class": string(result.Class)},
+			})
+
+			// Back off before restarting
+			log.WithFields(map[string]interface{}{
+				"restart_attempt": state.RestartCount + 1,
+				"max_restarts":    state.MaxRestarts,
+				"delay":           decision.Delay.String(),
+			}).Info("attempting restart")
+			fmt.Printf("[CrashWatcher] Attempting restart %d/%d in %s...\n",
+				state.RestartCount+1, state.MaxRestarts, formatDuration(decision.Delay))
+
+			select {
+			case <-time.After(decision.Delay):
+			case sig := <-sigChan:
+				fmt.Printf("\n[CrashWatcher] Received %v, shutting down...\n", sig)
+				return nil
+			}
+
+			// The session may have been killed while we were waiting
+			if current, err := redisClient.GetSessionState(ctx, sessionID); err == nil && current == nil {
+				log.Info("session state removed during backoff")
+				fmt.Printf("[CrashWatcher] Session was killed, exiting\n")
+				return nil
+			}
+
+			if err := restartSession(redisClient, state, result.Reason); err != nil {
+				log.WithError(err).Error("failed to restart session")
+				fmt.Printf("[CrashWatcher] Failed to restart session: %v\n", err)
+				return err
+			}
+
+			log.Info("session restarted successfully")
+			fmt.Printf("[CrashWatcher] Session restarted successfully\n")
+			consecutiveFailures = 0
+
+			// Refresh state from Redis (restart count updated)
+			state, err = redisClient.GetSessionState(ctx, sessionID)
+			if err != nil || state == nil {
+				fmt.Printf("[CrashWatcher] Failed to refresh session state, exiting\n")
+				return nil
+			}
+
+		case sig := <-sigChan:
+			fmt.Printf("\n[CrashWatcher] Received %v, shutting down...\n", sig)
+			return nil
+		}
+	}
+}
+
+// classifySession gathers evidence about a session and classifies it.
+// Pane output is only used to explain an exit, never to detect one.
+func classifySession(redisClient *redis.Client, sessionID string) crash.Result {
+	ev := crash.Evidence{SessionExists: mux.SessionExists(sessionID)}
+	if !ev.SessionExists {
+		return crash.Classify(ev)
+	}
+
+	ev.ToolRunning = toolRunning(sessionID)
+	if ev.ToolRunning {
+		return crash.Classify(ev)
+	}
+
+	ev.ExitCode = crash.ReadExitCode(sessionID)
+	if out, err := mux.CapturePane(sessionID, mux.CaptureOptions{Lines: 30}); err == nil {
+		ev.Output = out
+	}
+	if promise, err := redisClient.GetPromise(sessionID); err == nil && promise != nil {
+		ev.HasPromise = true
+	}
+	return crash.Classify(ev)
+}
+
+// toolRunning reports whether the command a session was started with is
+// still running. Spawned panes run "sh -c '<tool>; <record exit>; exec $SHELL'",
+// so the tool is running while the pane's wrapper shell has a live child, or
+// while the pane runs something other than a shell.
+func toolRunning(sessionID string) bool {
+	if crash.ReadExitCode(sessionID) != nil {
+		return false
+	}
+	pids, err := mux.PanePIDs(sessionID)
+	if err != nil || len(pids) == 0 {
+		return false
+	}
+	table, err := proc.Snapshot()
+	if err != nil {
+		return false
+	}
+
+	for _, pid := range pids {
+		p, ok := table.Get(pid)
+		if !ok || !p.Alive() {
+			continue
+		}
+		if !p.IsShell() {
+			return true
+		}
+		// Exited children linger as zombies until reaped; they don't count
+		for _, child := range table.Children(pid) {
+			if child.Alive() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// saveCrashBundle writes a forensics bundle for a crash and returns its
+// directory, or "" if it couldn't be saved.
+func saveCrashBundle(ctx context.Context, redisClient *redis.Client, state *types.SessionState, result crash.Result) string {
+	log := logging.WithCommand("crash-watcher").WithSessionID(state.SessionID)
+
+	bundle := forensics.Collect(state.SessionID, state.Cwd, state.SpawnCommit)
+	bundle.Class = string(result.Class)
+	bundle.Reason = result.Reason
+	bundle.ExitCode = result.ExitCode
+	bundle.State = state
+	bundle.Heartbeats, _ = redisClient.GetHeartbeatHistory(ctx, state.SessionID)
+	bundle.Events, _ = redisClient.GetEvents(ctx, state.SessionID)
+	if checks, err := redisClient.GetHealthChecks(ctx); err == nil {
+		bundle.HealthCheck = checks[state.SessionID]
+	}
+
+	dir, err := forensics.Save(bundle)
+	if err != nil {
+		log.WithError(err).Warn("failed to save crash bundle")
+		fmt.Printf("[CrashWatcher] Failed to save crash bundle: %v\n", err)
+		return ""
+	}
+	log.WithField("bundle", dir).Info("saved crash bundle")
+	fmt.Printf("[CrashWatcher] Saved crash bundle: %s\n", dir)
+	return dir
+}
+
+// recentCrashTimes returns the times of earlier recorded crashes plus now,
+// for the circuit breaker.
+func recentCrashTimes(ctx context.Context, redisClient *redis.Client, sessionID string, now time.Time) []time.Time {
+	times := []time.Time{now}
+	events, err := redisClient.GetCrashEvents(ctx, sessionID)
+	if err != nil {
+		return times
+	}
+	for _, e := range events {
+		times = append(times, time.UnixMilli(e.Timestamp))
+	}
+	return times
+}
+
+// exitStatusCommand returns a shell snippet that records the previous
+// command's exit status for crash classification.
+func exitStatusCommand(sessionID string) string {
+	path := crash.ExitCodePath(sessionID)
+	return fmt.Sprintf("status=$?; mkdir -p %s && echo $status > %s",
+		shellEscape(filepath.Dir(path)), shellEscape(path))
+}
+
+// sessionMeta returns the multiplexer metadata recorded for a session.
+func sessionMeta(state *types.SessionState) types.SessionMeta {
+	return types.SessionMeta{
+		Tool:     state.Tool,
+		Task:     state.Task,
+		Parent:   state.ParentSessionID,
+		Loop:     state.LoopID,
+		TaskID:   state.TaskID,
+		Worktree: state.Worktree,
+	}
+}
+
+// restartSession restarts a crashed session using its stored state.
+// Tools with a resumable conversation reopen it; others get a prompt with
+// the previous run's output, changes and blockers.
+func restartSession(redisClient *redis.Client, state *types.SessionState, reason string) error {
+	ctx := context.Background()
+
+	contextLines := config.DefaultRestartContextLines
+	if cfg, err := config.Get(); err == nil {
+		contextLines = cfg.Sessions.RestartContextLines
+	}
+
+	// Capture what the session was doing before its pane goes away
+	rc := gatherRestartContext(redisClient, state, reason, contextLines)
+	resume := canResumeConversation(state)
+
+	// Mark the restart so the orphan reaper spares the children while the
+	// session is briefly gone
+	state.RestartingAt = time.Now().UnixMilli()
+	if err := redisClient.SetSessionState(ctx, state); err != nil {
+		return fmt.Errorf("failed to update session state: %w", err)
+	}
+
+	// Kill any remaining processes in the old session
+	if mux.SessionExists(state.SessionID) {
+		_ = mux.KillSession(state.SessionID)
+		time.Sleep(500 * time.Millisecond) // Give tmux time to clean up
+	}
+
+	// Get user's shell
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/bash"
+	}
+
+	prompt := buildRestartPrompt(state.Tool, state.Task, state.RestartCount+1, rc, resume)
+
+	// Build the tool command. Gemini takes its prompt as an argument.
+	toolTask := state.Task
+	if state.Tool == "gemini" && state.Task != "" {
+		toolTask = prompt
+	}
+	toolCmd := buildToolCommand(state.Tool, toolTask, state.Model, state.SessionID, state.UseOllama) +
+		conversationArgs(state.Tool, state.ConversationID, resume)
+	if state.Sandbox {
+		var err error
+		if toolCmd, _, err = applySandbox(toolCmd, state.Cwd, state.SandboxNetwork); err != nil {
+			return fmt.Errorf("failed to sandbox session: %w", err)
+		}
+	}
+	toolCmd = applyResourceLimits(state.SessionID, toolCmd, state.Limits)
+
+	// Create prompt file if needed
+	promptFile := ""
+	if (state.Task != "" || resume) && (state.Tool == "claude" || state.Tool == "codex" || state.Tool == "opencode") {
+		promptFile = fmt.Sprintf("/tmp/coders-prompt-%d.txt", time.Now().UnixNano())
+		if err := os.WriteFile(promptFile, []byte(prompt), 0644); err != nil {
+			return fmt.Errorf("failed to write prompt file: %w", err)
+		}
+	}
+
+	// Build full tmux command
+	crash.ClearExitCode(state.SessionID)
+	var fullCmd string
+	if promptFile != "" {
+		fullCmd = fmt.Sprintf("cd %s && %s < %s; %s; exec %s",
+			shellEscape(state.Cwd), toolCmd, promptFile, exitStatusCommand(state.SessionID), shell)
+	} else {
+		fullCmd = fmt.Sprintf("cd %s && %s; %s; exec %s",
+			shellEscape(state.Cwd), toolCmd, exitStatusCommand(state.SessionID), shell)
+	}
+
+	if err := mux.CreateSession(state.SessionID, state.Cwd, fullCmd, sessionMeta(state)); err != nil {
+		return fmt.Errorf("failed to create %s session: %w", mux.Default().Name(), err)
+	}
+
+	// Keep appending to the same transcript across restarts
+	if err := startTranscript(state.SessionID); err != nil {
+		fmt.Printf("[CrashWatcher] Warning: failed to start transcript: %v\n", err)
+	}
+
+	logging.WithCommand("crash-watcher").WithSessionID(state.SessionID).WithFields(map[string]interface{}{
+		"resumed":         resume,
+		"conversation_id": state.ConversationID,
+		"restart":         state.RestartCount + 1,
+	}).Info("session relaunched")
+
+	// Update session state in Redis
+	state.RestartCount++
+	state.LastRestartAt = time.Now().UnixMilli()
+	state.RestartingAt = 0
+	if err := redisClient.SetSessionState(ctx, state); err != nil {
+		return fmt.Errorf("failed to update session state: %w", err)
+	}
+
+	// Wait for CLI to be ready
+	if ready := waitForCLIReady(state.SessionID, state.Tool, 30*time.Second); !ready {
+		fmt.Printf("[CrashWatcher] Warning: timeout waiting for CLI to start\n")
+	}
+
+	// Restart heartbeat if it was enabled
+	if state.HeartbeatEnabled {
+		if err := startHeartbeat(state.SessionID, state.Task, state.ParentSessionID); err != nil {
+			fmt.Printf("[CrashWatcher] Warning: failed to start heartbeat: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// buildRestartPrompt creates a prompt that indicates this is a restart.
+// A resumed conversation already has the history, so it only gets a short
+// note; otherwise the prompt carries the task and the previous run's context.
+func buildRestartPrompt(tool, task string, restartCount int, rc restartContext, resumed bool) string {
+	var b strings.Builder
+
+	reason := "it crashed unexpectedly"
+	if rc.Reason != "" {
+		reason = rc.Reason
+	}
+
+	if resumed {
+		b.WriteString(fmt.Sprintf("NOTE: This is restart #%d. The previous process exited (%s).\n", restartCount, reason))
+		b.WriteString("Your conversation has been restored. Continue where you left off.\n")
+		b.WriteString("Check git status for any changes made after your last message.\n")
+		return b.String()
+	}
+
+	b.WriteString(fmt.Sprintf("TASK: %s\n\n", task))
+	b.WriteString(fmt.Sprintf("NOTE: This is restart #%d. The previous session ended (%s)\n", restartCount, reason))
+	b.WriteString("and its conversation could not be restored. Below is what it was doing;\n")
+	b.WriteString("continue from where it left off rather than starting over.\n\n")
+
+	if rc.Blocked != nil {
+		b.WriteString(fmt.Sprintf("The previous session reported being BLOCKED: %s\n", rc.Blocked.Summary))
+		for _, blocker := range rc.Blocked.Blockers {
+			b.WriteString(fmt.Sprintf("  - %s\n", blocker))
+		}
+		b.WriteString("\n")
+	}
+
+	if rc.DiffStat != "" {
+		b.WriteString("Changes made since the session was spawned (git diff --stat):\n")
+		b.WriteString(rc.DiffStat + "\n\n")
+	}
+	if rc.Diff != "" {
+		b.WriteString("```diff\n" + rc.Diff + "\n```\n\n")
+	}
+	if rc.DiffStat == "" && rc.Diff == "" {
+		b.WriteString("Check git status to see what was done previously.\n\n")
+	}
+
+	if rc.Transcript != "" {
+		b.WriteString("Last output from the previous session:\n")
+		b.WriteString("```\n" + rc.Transcript + "\n```\n\n")
+	}
+
+	b.WriteString("You have full permissions. Complete the task.\n\n")
+	b.WriteString("IMPORTANT: When you finish this task, you MUST publish a completion promise.\n")
+
+	if tool == "codex" {
+		b.WriteString("Run this shell command: coders promise \"Brief summary of what you accomplished\"\n")
+		b.WriteString("\nThis notifies the orchestrator and dashboard that your work is complete.\n")
+		b.WriteString("If you get blocked, use: coders promise \"Reason for being blocked\" --status blocked\n")
+	} else {
+		b.WriteString("/coders:promise \"Brief summary of what you accomplished\"\n")
+		b.WriteString("\nThis notifies the orchestrator and dashboard that your work is complete.\n")
+		b.WriteString("If you get blocked, use: /coders:promise \"Reason for being blocked\" --status blocked\n")
+	}
+
+	return b.String()
+}
+
+// startCrashWatcher starts a background crash watcher process for a session.
+func startCrashWatcher(sessionID string) error {
+	// Get the path to this executable
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	// Build crash watcher command args
+	args := []string{"crash-watcher", "--session", sessionID}
+
+	// Start crash watcher as a background process
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+	cmd.Stdin = nil
+
+	// Detach from parent process
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start crash watcher: %w", err)
+	}
+
+	// Don't wait for it - let it run in background
+	go func() {
+		cmd.Wait()
+	}()
+
+	return nil
+}
+
+// storeSessionState saves the session state to Redis for crash recovery
+// and parent/child tracking.
+func storeSessionState(state *types.SessionState) error {
+	// Load config to check if Redis is available
+	cfg, err := config.Get()
+	if err != nil || cfg.RedisURL == "" {
+		return fmt.Errorf("Redis configuration not available")
+	}
+
+	// Connect to Redis
+	redisClient, err := redis.NewClient()
+	if err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	defer redisClient.Close()
+
+	state.RestartCount = 0
+	state.CreatedAt = time.Now().UnixMilli()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return redisClient.SetSessionState(ctx, state)
+}
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/limits"
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/notify"
+	"github.com/Jayphen/coders/internal/prompt"
+	"github.com/Jayphen/coders/internal/redis"
+	"github.com/Jayphen/coders/internal/tui"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+const (
+	// healthCheckInterval is how often to run health checks in watch mode.
+	healthCheckInterval = 30 * time.Second
+	// outputStaleThreshold is how long output can remain unchanged before being considered stuck.
+	outputStaleThreshold = 5 * time.Minute
+)
+
+var (
+	healthCheckJSON  bool
+	healthCheckWatch bool
+	healthCheckQuiet bool
+)
+
+func newHealthcheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "healthcheck",
+		Short: "Check health of coder sessions",
+		Long: `Run health checks on all coder sessions to detect stuck or unresponsive sessions.
+
+Health checks examine:
+- Heartbeat timestamps to detect sessions that haven't reported recently
+- tmux session existence to detect terminated sessions
+- Pane output changes to detect stuck sessions (output hasn't changed for 5+ minutes)
+- Process state to detect unresponsive sessions
+- Tool-specific prompts to detect sessions waiting for user input
+  (permission and confirmation prompts, or an idle input box)
+- Resource use from heartbeats against limits set with 'coders spawn
+  --max-memory/--max-cpu/--max-procs', to detect sessions at their limits
+
+Use --watch to run continuously and publish health data to Redis for the dashboard.
+
+In watch mode, remediation rules from the config file (remediation.rules) can
+act on unhealthy sessions: nudge after 5m stuck, press Escape and re-prompt,
+restart from stored session state, or kill and mark the task blocked. Each
+step runs once until the session has stayed healthy for remediation.grace,
+and restarts count against the session's --max-restarts. Each action is
+recorded as a session event. Enable with remediation.enabled: true.`,
+		RunE: runHealthcheck,
+	}
+
+	cmd.Flags().BoolVar(&healthCheckJSON, "json", false, "Output in JSON format")
+	cmd.Flags().BoolVar(&healthCheckWatch, "watch", false, "Run continuously, publishing to Redis")
+	cmd.Flags().BoolVar(&healthCheckQuiet, "quiet", false, "Only output problems (non-healthy sessions)")
+
+	return cmd
+}
+
+func runHealthcheck(cmd *cobra.Command, args []string) error {
+	// Connect to Redis
+	redisClient, err := redis.NewClient()
+	if err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	defer redisClient.Close()
+
+	if healthCheckWatch {
+		return runHealthcheckWatch(redisClient)
+	}
+
+	// One-shot health check
+	summary, err := performHealthCheck(redisClient)
+	if err != nil {
+		return err
+	}
+
+	return outputHealthSummary(summary)
+}
+
+func runHealthcheckWatch(redisClient *redis.Client) error {
+	fmt.Printf("[Healthcheck] Starting watch mode, checking every %v\n", healthCheckInterval)
+
+	// Set up signal handling
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	// Run immediately, then on interval
+	runHealthcheckCycle(redisClient)
+
+	for {
+		select {
+		case <-ticker.C:
+			runHealthcheckCycle(redisClient)
+		case sig := <-sigChan:
+			fmt.Printf("\n[Healthcheck] Received %v, shutting down...\n", sig)
+			return nil
+		}
+	}
+}
+
+// runHealthcheckCycle performs one watch-mode pass: check, publish, and
+// enforce the parent/child lifecycle rules.
+func runHealthcheckCycle(redisClient *redis.Client) {
+	summary, err := performHealthCheck(redisClient)
+	if err != nil {
+		fmt.Printf("[Healthcheck] Error: %v\n", err)
+		return
+	}
+	publishHealthSummary(redisClient, summary)
+	notifyHealthEvents(redisClient, summary)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	applyRemediations(ctx, redisClient, summary, time.Now())
+
+	fmt.Printf("[Healthcheck] Published at %s - %d healthy, %d stale, %d dead, %d stuck, %d waiting, %d over limits\n",
+		time.Now().Format("15:04:05"),
+		summary.Healthy, summary.Stale, summary.Dead, summary.Stuck, summary.WaitingInput, summary.OverLimit)
+
+	if sessions, err := mux.ListSessions(); err == nil {
+		for _, name := range reapOrphanedSessions(ctx, redisClient, sessions) {
+			fmt.Printf("[Healthcheck] Killed orphaned child session: %s\n", name)
+		}
+	}
+}
+
+func performHealthCheck(redisClient *redis.Client) (*types.HealthCheckSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Get current tmux sessions
+	sessions, err := mux.ListSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	// Get heartbeats and previous health checks from Redis
+	heartbeats, _ := redisClient.GetHeartbeats(ctx)
+	prevHealthChecks, _ := redisClient.GetHealthChecks(ctx)
+	promises, _ := redisClient.GetPromises(ctx)
+	states, _ := redisClient.GetSessionStates(ctx)
+
+	remediationGrace := config.DefaultRemediationGrace
+	if cfg, err := config.Get(); err == nil {
+		remediationGrace = cfg.Remediation.Grace
+	}
+
+	now := time.Now()
+	summary := &types.HealthCheckSummary{
+		Timestamp:     now.UnixMilli(),
+		TotalSessions: len(sessions),
+		Sessions:      make([]types.HealthCheckResult, 0, len(sessions)),
+	}
+
+	for _, session := range sessions {
+		prevCheck := prevHealthChecks[session.Name]
+		var sessionLimits *types.ResourceLimits
+		if state := states[session.Name]; state != nil {
+			sessionLimits = state.Limits
+		}
+		result := checkSessionHealth(session, heartbeats[session.Name], prevCheck, promises[session.Name], sessionLimits, now)
+
+		trackStatus(&result, prevCheck, remediationGrace)
+
+		// Store individual health check result
+		if err := redisClient.SetHealthCheck(ctx, &result); err != nil {
+			fmt.Printf("[Healthcheck] Failed to store result for %s: %v\n", session.Name, err)
+		}
+
+		summary.Sessions = append(summary.Sessions, result)
+
+		// Update counts
+		switch result.Status {
+		case types.HealthHealthy:
+			summary.Healthy++
+		case types.HealthStale:
+			summary.Stale++
+		case types.HealthDead:
+			summary.Dead++
+		case types.HealthStuck:
+			summary.Stuck++
+		case types.HealthUnresponsive:
+			summary.Unresponsive++
+		case types.HealthWaitingInput:
+			summary.WaitingInput++
+		case types.HealthOverLimit:
+			summary.OverLimit++
+		}
+	}
+
+	return summary, nil
+}
+
+func checkSessionHealth(session types.Session, heartbeat *types.HeartbeatData, prevCheck *types.HealthCheckResult, promise *types.CoderPromise, sessionLimits *types.ResourceLimits, now time.Time) types.HealthCheckResult {
+	result := types.HealthCheckResult{
+		SessionID:      session.Name,
+		Timestamp:      now.UnixMilli(),
+		TmuxAlive:      true, // We got this session from mux.ListSessions
+		ProcessRunning: true, // Assume running until proven otherwise
+	}
+
+	// Check if session has a promise (completed) - skip deep health checks
+	if promise != nil {
+		result.Status = types.HealthHealthy
+		result.Message = "Session completed with promise"
+		return result
+	}
+
+	// Check tmux pane process
+	pids, err := mux.PanePIDs(session.Name)
+	if err != nil || len(pids) == 0 {
+		result.ProcessRunning = false
+		result.Status = types.HealthUnresponsive
+		result.Message = "No processes found in tmux pane"
+		return result
+	}
+
+	// Capture recent pane output for stuck and prompt detection
+	output := capturePaneOutput(session.Name)
+	outputHash := hashPaneOutput(output)
+	result.OutputHash = outputHash
+
+	if heartbeat != nil {
+		result.HeartbeatAge = now.UnixMilli() - heartbeat.Timestamp
+	}
+
+	// A runaway process tree matters more than what the CLI is showing.
+	// Only a fresh heartbeat's figures are current enough to judge.
+	if sessionLimits != nil && heartbeat != nil && redis.DetermineHeartbeatStatus(heartbeat) == types.HeartbeatHealthy {
+		if breaches := limits.Breaches(*sessionLimits, heartbeat.Usage); len(breaches) > 0 {
+			result.Status = types.HealthOverLimit
+			result.Message = "At resource limits: " + strings.Join(breaches, ", ")
+			return result
+		}
+	}
+
+	// A CLI sitting at a prompt is waiting for a human, not stuck.
+	// The orchestrator idling at its input box is its normal state.
+	if p, ok := prompt.Detect(session.Tool, output); ok && !session.IsOrchestrator {
+		// An empty input box only counts once the output has settled
+		settled := prevCheck != nil && prevCheck.OutputHash == outputHash
+		if p.Kind != prompt.KindIdle || settled {
+			result.Status = types.HealthWaitingInput
+			result.Question = p.Question
+			result.Message = waitingInputMessage(p)
+			return result
+		}
+	}
+
+	// Check heartbeat status
+	if heartbeat != nil {
+		heartbeatStatus := redis.DetermineHeartbeatStatus(heartbeat)
+		switch heartbeatStatus {
+		case types.HeartbeatHealthy:
+			// Check for stuck output (output unchanged for too long)
+			if prevCheck != nil && prevCheck.OutputHash == outputHash && prevCheck.OutputHash != "" {
+				// Output hasn't changed since last check
+				if prevCheck.OutputStaleFor > 0 {
+					result.OutputStaleFor = prevCheck.OutputStaleFor + (now.UnixMilli() - prevCheck.Timestamp)
+				} else {
+					result.OutputStaleFor = now.UnixMilli() - prevCheck.Timestamp
+				}
+				result.LastOutputHash = prevCheck.OutputHash
+				result.LastCheckTime = prevCheck.Timestamp
+
+				if result.OutputStaleFor > outputStaleThreshold.Milliseconds() {
+					result.Status = types.HealthStuck
+					result.Message = fmt.Sprintf("Output unchanged for %s", formatDuration(time.Duration(result.OutputStaleFor)*time.Millisecond))
+					return result
+				}
+			} else {
+				// Output changed, reset stale counter
+				result.OutputStaleFor = 0
+			}
+
+			result.Status = types.HealthHealthy
+			result.Message = "Session healthy"
+
+		case types.HeartbeatStale:
+			result.Status = types.HealthStale
+			result.Message = fmt.Sprintf("Heartbeat stale (%s old)", formatDuration(time.Duration(result.HeartbeatAge)*time.Millisecond))
+
+		case types.HeartbeatDead:
+			result.Status = types.HealthDead
+			result.Message = fmt.Sprintf("No heartbeat for %s", formatDuration(time.Duration(result.HeartbeatAge)*time.Millisecond))
+		}
+	} else {
+		// No heartbeat at all
+		// Special case: orchestrator doesn't always have heartbeat
+		if session.IsOrchestrator {
+			result.Status = types.HealthHealthy
+			result.Message = "Orchestrator session"
+			return result
+		}
+
+		result.Status = types.HealthDead
+		result.Message = "No heartbeat data"
+	}
+
+	return result
+}
+
+// capturePaneOutput returns the last 50 lines of a session's pane.
+func capturePaneOutput(sessionName string) string {
+	out, err := mux.CapturePane(sessionName, mux.CaptureOptions{Lines: 50})
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// hashPaneOutput hashes pane output for change detection.
+func hashPaneOutput(output string) string {
+	if output == "" {
+		return ""
+	}
+	hash := md5.Sum([]byte(output))
+	return hex.EncodeToString(hash[:])
+}
+
+// waitingInputMessage describes a detected prompt.
+func waitingInputMessage(p prompt.Prompt) string {
+	switch p.Kind {
+	case prompt.KindPermission:
+		return "Waiting for permission"
+	case prompt.KindConfirmation:
+		return "Waiting for confirmation"
+	default:
+		return "Waiting for input"
+	}
+}
+
+// notifyHealthEvents sends notifications for sessions that have started
+// waiting for input or reached their resource limits, or are stuck. Stuck
+// sessions are reported every cycle with their stuck duration; the router's
+// dedup window and rule durations decide what is actually delivered, and the
+// parent session is only told once per stuck episode.
+func notifyHealthEvents(redisClient *redis.Client, summary *types.HealthCheckSummary) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, result := range summary.Sessions {
+		name := strings.TrimPrefix(result.SessionID, mux.SessionPrefix)
+
+		switch result.Status {
+		case types.HealthWaitingInput:
+			if result.StatusSince != result.Timestamp {
+				continue // Already notified for this episode
+			}
+			message := result.Message
+			if result.Question != "" {
+				message = result.Question
+			}
+			fmt.Printf("[Healthcheck] %s is waiting for input: %s\n", result.SessionID, message)
+			emitSessionEvent(ctx, redisClient, notify.Event{
+				Type:      notify.EventWaitingInput,
+				Title:     fmt.Sprintf("Coder waiting: %s", name),
+				Message:   message,
+				SessionID: result.SessionID,
+				DedupKey:  fmt.Sprintf("%s|%s|%d", notify.EventWaitingInput, result.SessionID, result.StatusSince),
+			})
+
+		case types.HealthOverLimit:
+			if result.StatusSince != result.Timestamp {
+				continue // Already notified for this episode
+			}
+			fmt.Printf("[Healthcheck] %s is over its limits: %s\n", result.SessionID, result.Message)
+			emitSessionEvent(ctx, redisClient, notify.Event{
+				Type:      notify.EventOverLimit,
+				Title:     fmt.Sprintf("Coder over limits: %s", name),
+				Message:   result.Message,
+				SessionID: result.SessionID,
+				DedupKey:  fmt.Sprintf("%s|%s|%d", notify.EventOverLimit, result.SessionID, result.StatusSince),
+			})
+
+		case types.HealthStuck:
+			stuckFor := time.Duration(result.OutputStaleFor) * time.Millisecond
+			emitSessionEvent(ctx, redisClient, notify.Event{
+				Type:      notify.EventStuck,
+				Title:     fmt.Sprintf("Coder stuck: %s", name),
+				Message:   fmt.Sprintf("no output for %s", formatDuration(stuckFor)),
+				SessionID: result.SessionID,
+				Duration:  stuckFor,
+				DedupKey:  fmt.Sprintf("%s|%s|%d", notify.EventStuck, result.SessionID, result.StatusSince),
+			})
+		}
+	}
+}
+
+func publishHealthSummary(redisClient *redis.Client, summary *types.HealthCheckSummary) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := redisClient.SetHealthSummary(ctx, summary); err != nil {
+		fmt.Printf("[Healthcheck] Failed to publish summary: %v\n", err)
+	}
+}
+
+func outputHealthSummary(summary *types.HealthCheckSummary) error {
+	if healthCheckJSON {
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	// Pretty print
+	if summary.TotalSessions == 0 {
+		fmt.Println("No coder sessions found")
+		return nil
+	}
+
+	// Header
+	header := fmt.Sprintf("%-28s %-12s %-12s %s", "SESSION", "STATUS", "HEARTBEAT", "MESSAGE")
+	fmt.Println(lipgloss.NewStyle().Bold(true).Foreground(tui.ColorGray).Render(header))
+	fmt.Println(strings.Repeat("-", 80))
+
+	for _, result := range summary.Sessions {
+		if healthCheckQuiet && result.Status == types.HealthHealthy {
+			continue
+		}
+
+		name := strings.TrimPrefix(result.SessionID, mux.SessionPrefix)
+		if len(name) > 26 {
+			name = name[:23] + "..."
+		}
+
+		// Status styling
+		var statusStr string
+		switch result.Status {
+		case types.HealthHealthy:
+			statusStr = tui.StatusHealthy.Render("● healthy")
+		case types.HealthStale:
+			statusStr = tui.StatusStale.Render("◐ stale")
+		case types.HealthDead:
+			statusStr = tui.StatusDead.Render("○ dead")
+		case types.HealthStuck:
+			statusStr = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6B6B")).Render("◉ stuck")
+		case types.HealthUnresponsive:
+			statusStr = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF4444")).Render("✗ unresponsive")
+		case types.HealthWaitingInput:
+			statusStr = tui.StatusWaiting.Render(tui.IndicatorWaiting + " waiting")
+		case types.HealthOverLimit:
+			statusStr = tui.StatusOverLimit.Render(tui.IndicatorOverLimit + " over-limit")
+		}
+
+		// Heartbeat age
+		heartbeatStr := "-"
+		if result.HeartbeatAge > 0 {
+			heartbeatStr = formatDuration(time.Duration(result.HeartbeatAge) * time.Millisecond)
+		}
+
+		// Message
+		message := result.Message
+		if result.Question != "" {
+			message = result.Question
+		}
+		if len(message) > 30 {
+			message = message[:27] + "..."
+		}
+
+		fmt.Printf("%-28s %-12s %-12s %s\n", name, statusStr, heartbeatStr, message)
+	}
+
+	fmt.Println()
+	fmt.Printf("Summary: %d total, %d healthy, %d stale, %d dead, %d stuck, %d unresponsive, %d waiting for input, %d over limits\n",
+		summary.TotalSessions, summary.Healthy, summary.Stale, summary.Dead, summary.Stuck, summary.Unresponsive, summary.WaitingInput, summary.OverLimit)
+
+	return nil
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	if d < time.Hour {
+		return fmt.Sprintf("%dm%ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+func newInitCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "init",
+		Short: "Initialize coders: start orchestrator and TUI",
+		Long: `Initialize the coders environment by:
+1. Starting the orchestrator session if not already running
+2. Starting the TUI in the background for monitoring
+3. Attaching to the orchestrator session
+
+This is the recommended way to start a coders workflow.`,
+		RunE: runInit,
+	}
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	// Step 1: Ensure orchestrator is running
+	orchestratorRunning := mux.SessionExists(mux.OrchestratorSession)
+
+	if !orchestratorRunning {
+		fmt.Println("🚀 Starting orchestrator session...")
+		if err := createOrchestratorSession(); err != nil {
+			return fmt.Errorf("failed to start orchestrator: %w", err)
+		}
+		fmt.Printf("\033[32m✅ Orchestrator started: %s\033[0m\n", mux.OrchestratorSession)
+	} else {
+		fmt.Printf("\033[32m✅ Orchestrator already running: %s\033[0m\n", mux.OrchestratorSession)
+	}
+
+	// Step 2: Ensure TUI is running in background
+	tuiRunning := mux.SessionExists(mux.TUISession)
+
+	if !tuiRunning {
+		fmt.Println("📊 Starting TUI in background...")
+		if err := startTUIBackground(); err != nil {
+			// Non-fatal - we can still attach to orchestrator
+			fmt.Printf("\033[33m⚠️  Failed to start TUI: %v\033[0m\n", err)
+		} else {
+			fmt.Printf("\033[32m✅ TUI started: %s\033[0m\n", mux.TUISession)
+		}
+	} else {
+		fmt.Printf("\033[32m✅ TUI already running: %s\033[0m\n", mux.TUISession)
+	}
+
+	// Step 3: Attach to orchestrator
+	fmt.Println("\n🔗 Attaching to orchestrator...")
+	fmt.Printf("   (TUI running in background: %s)\n\n", mux.AttachHint(mux.TUISession))
+
+	// Wait a moment for everything to settle
+	time.Sleep(500 * time.Millisecond)
+
+	return mux.AttachSession(mux.OrchestratorSession)
+}
+
+// startTUIBackground starts the TUI in a detached session.
+func startTUIBackground() error {
+	// Get the path to this executable
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	return mux.CreateSession(mux.TUISession, "", tuiSessionCommand(exe), types.SessionMeta{})
+}
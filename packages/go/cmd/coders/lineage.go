@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/logging"
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/redis"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+// detectParentSession returns the parent for a new session: the explicit
+// --parent value if given, otherwise the session we are running inside.
+func detectParentSession(explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	return os.Getenv("CODERS_SESSION_ID")
+}
+
+// sessionParents builds a child -> parent map from stored session state,
+// falling back to heartbeat data for sessions without state.
+func sessionParents(ctx context.Context, redisClient *redis.Client) map[string]string {
+	parents := make(map[string]string)
+	if redisClient == nil {
+		return parents
+	}
+
+	if heartbeats, err := redisClient.GetHeartbeats(ctx); err == nil {
+		for id, hb := range heartbeats {
+			if hb.ParentSessionID != "" {
+				parents[id] = hb.ParentSessionID
+			}
+		}
+	}
+
+	// Session state is the durable record, so it wins over heartbeats
+	if states, err := redisClient.GetSessionStates(ctx); err == nil {
+		for id, state := range states {
+			if state.ParentSessionID != "" {
+				parents[id] = state.ParentSessionID
+			}
+		}
+	}
+
+	return parents
+}
+
+// lookupParentSession returns the recorded parent of a single session.
+func lookupParentSession(ctx context.Context, redisClient *redis.Client, sessionID string) string {
+	if redisClient == nil {
+		return ""
+	}
+	if state, err := redisClient.GetSessionState(ctx, sessionID); err == nil && state != nil && state.ParentSessionID != "" {
+		return state.ParentSessionID
+	}
+	return sessionParents(ctx, redisClient)[sessionID]
+}
+
+// descendantSessions returns every session below root in the parent map,
+// ordered deepest first so that children are killed before their parents.
+func descendantSessions(root string, parents map[string]string) []string {
+	children := make(map[string][]string)
+	for child, parent := range parents {
+		children[parent] = append(children[parent], child)
+	}
+
+	depth := make(map[string]int)
+	visited := map[string]bool{root: true}
+	queue := []string{root}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		kids := children[current]
+		sort.Strings(kids)
+		for _, child := range kids {
+			if visited[child] {
+				continue
+			}
+			visited[child] = true
+			depth[child] = depth[current] + 1
+			queue = append(queue, child)
+		}
+	}
+
+	result := make([]string, 0, len(depth))
+	for id := range depth {
+		result = append(result, id)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if depth[result[i]] != depth[result[j]] {
+			return depth[result[i]] > depth[result[j]]
+		}
+		return result[i] < result[j]
+	})
+
+	return result
+}
+
+// killChildSessions kills every live descendant of parentID.
+// Returns the names of the sessions that were killed.
+func killChildSessions(ctx context.Context, redisClient *redis.Client, parentID string) []string {
+	log := logging.WithCommand("kill").WithSessionID(parentID)
+
+	var killed []string
+	for _, child := range descendantSessions(parentID, sessionParents(ctx, redisClient)) {
+		if !mux.SessionExists(child) {
+			continue
+		}
+		if err := killSessionWithCleanup(child, redisClient, ctx); err != nil {
+			log.WithError(err).WithField("child", child).Warn("failed to kill child session")
+			continue
+		}
+		log.WithField("child", child).Info("killed child session")
+		killed = append(killed, child)
+	}
+	return killed
+}
+
+// reapOrphanedSessions kills sessions whose parent no longer exists, when
+// sessions.kill_children_on_parent_exit is enabled.
+func reapOrphanedSessions(ctx context.Context, redisClient *redis.Client, sessions []types.Session) []string {
+	cfg, err := config.Get()
+	if err != nil || !cfg.Sessions.KillChildrenOnParentExit || redisClient == nil {
+		return nil
+	}
+	states, err := redisClient.GetSessionStates(ctx)
+	if err != nil {
+		return nil
+	}
+
+	alive := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		alive[s.Name] = true
+	}
+
+	parents := sessionParents(ctx, redisClient)
+	var reaped []string
+	for _, child := range orphanedSessions(parents, alive, states, time.Now()) {
+		// Kill the orphan's own subtree first, then the orphan itself
+		reaped = append(reaped, killChildSessions(ctx, redisClient, child)...)
+		if err := killSessionWithCleanup(child, redisClient, ctx); err == nil {
+			logging.WithCommand("healthcheck").WithSessionID(child).
+				WithField("parent", parents[child]).Info("killed orphaned child session")
+			reaped = append(reaped, child)
+		}
+	}
+	return reaped
+}
+
+// restartWindow is how long a parent in the middle of a restart is expected
+// to be missing. A restart that takes longer has failed.
+const restartWindow = 2 * time.Minute
+
+// orphanedSessions returns the live sessions whose parent is gone for good.
+// A parent missing from alive may only be down for a restart: it is gone
+// once its state has been removed, as kill and the crash watcher do when
+// giving up, or once it won't be restarted and isn't being restarted.
+func orphanedSessions(parents map[string]string, alive map[string]bool, states map[string]*types.SessionState, now time.Time) []string {
+	var orphans []string
+	for child, parent := range parents {
+		if !alive[child] || alive[parent] {
+			continue
+		}
+		if state := states[parent]; state != nil {
+			if state.RestartOnCrash && state.RestartCount < state.MaxRestarts {
+				continue
+			}
+			if state.RestartingAt > 0 && now.Sub(time.UnixMilli(state.RestartingAt)) < restartWindow {
+				continue
+			}
+		}
+		orphans = append(orphans, child)
+	}
+	sort.Strings(orphans)
+	return orphans
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jayphen/coders/internal/types"
+)
+
+func TestDescendantSessions(t *testing.T) {
+	parents := map[string]string{
+		"coder-a":   "coder-root",
+		"coder-b":   "coder-root",
+		"coder-a1":  "coder-a",
+		"coder-a1x": "coder-a1",
+		"coder-z":   "coder-other",
+	}
+
+	got := descendantSessions("coder-root", parents)
+	want := []string{"coder-a1x", "coder-a1", "coder-a", "coder-b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("descendantSessions() = %v, want %v", got, want)
+	}
+
+	if got := descendantSessions("coder-b", parents); len(got) != 0 {
+		t.Errorf("expected no descendants for leaf, got %v", got)
+	}
+}
+
+func TestDescendantSessionsCycle(t *testing.T) {
+	parents := map[string]string{
+		"coder-a": "coder-b",
+		"coder-b": "coder-a",
+	}
+
+	got := descendantSessions("coder-a", parents)
+	want := []string{"coder-b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("descendantSessions() = %v, want %v", got, want)
+	}
+}
+
+func TestDetectParentSession(t *testing.T) {
+	t.Setenv("CODERS_SESSION_ID", "coder-env")
+
+	if got := detectParentSession("coder-flag"); got != "coder-flag" {
+		t.Errorf("explicit parent should win, got %s", got)
+	}
+	if got := detectParentSession(""); got != "coder-env" {
+		t.Errorf("expected parent from environment, got %s", got)
+	}
+}
+
+func TestOrphanedSessions(t *testing.T) {
+	now := time.Now()
+	parents := map[string]string{
+		"coder-killed-kid":     "coder-killed",
+		"coder-restarting-kid": "coder-restarting",
+		"coder-gave-up-kid":    "coder-gave-up",
+		"coder-no-restart-kid": "coder-no-restart",
+		"coder-live-kid":       "coder-live",
+		"coder-manual-kid":     "coder-manual",
+		"coder-stalled-kid":    "coder-stalled",
+		"coder-dead-kid":       "coder-killed",
+	}
+	alive := map[string]bool{
+		"coder-killed-kid":     true,
+		"coder-restarting-kid": true,
+		"coder-gave-up-kid":    true,
+		"coder-no-restart-kid": true,
+		"coder-live-kid":       true,
+		"coder-live":           true,
+		"coder-manual-kid":     true,
+		"coder-stalled-kid":    true,
+	}
+	states := map[string]*types.SessionState{
+		"coder-restarting": {RestartOnCrash: true, RestartCount: 1, MaxRestarts: 3},
+		"coder-gave-up":    {RestartOnCrash: true, RestartCount: 3, MaxRestarts: 3},
+		"coder-no-restart": {},
+		"coder-live":       {},
+		// Being restarted by hand, and a restart that never finished
+		"coder-manual":  {RestartingAt: now.Add(-time.Second).UnixMilli()},
+		"coder-stalled": {RestartingAt: now.Add(-time.Hour).UnixMilli()},
+	}
+
+	got := orphanedSessions(parents, alive, states, now)
+	want := []string{"coder-gave-up-kid", "coder-killed-kid", "coder-no-restart-kid", "coder-stalled-kid"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orphanedSessions() = %v, want %v", got, want)
+	}
+}
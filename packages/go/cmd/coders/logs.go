@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/logging"
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/transcript"
+)
+
+var (
+	logsFollow bool
+	logsRaw    bool
+	logsLines  int
+
+	transcriptWriterSessionID string
+)
+
+func newLogsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logs <session>",
+		Short: "Show a session's transcript",
+		Long: `Show the full output transcript of a coder session.
+
+Spawned sessions record everything written to their pane (via tmux
+pipe-pane) to rotated files under ~/.local/state/coders/transcripts.
+Transcripts survive crash restarts and are archived when the session is
+killed; for a killed session the most recent archive is shown.
+
+Examples:
+  coders logs claude-fix-auth            # Last 100 lines
+  coders logs claude-fix-auth -n 0       # Whole transcript
+  coders logs claude-fix-auth --follow   # Stream new output
+  coders logs claude-fix-auth --raw      # Include terminal escape codes`,
+		Args: cobra.ExactArgs(1),
+		RunE: runLogs,
+	}
+
+	cmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep printing new output")
+	cmd.Flags().BoolVar(&logsRaw, "raw", false, "Show output with ANSI escape codes")
+	cmd.Flags().IntVarP(&logsLines, "lines", "n", 100, "Number of lines to show (0 for all)")
+
+	return cmd
+}
+
+func newTranscriptWriterCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    "transcript-writer",
+		Short:  "Record pane output from stdin to a session transcript",
+		Hidden: true, // Internal command, started via tmux pipe-pane by spawn
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if transcriptWriterSessionID == "" {
+				return fmt.Errorf("session ID required")
+			}
+			cfg, err := config.Get()
+			if err != nil {
+				return err
+			}
+			return transcript.Record(transcriptWriterSessionID, os.Stdin, cfg.Transcripts.MaxSize, cfg.Transcripts.MaxBackups)
+		},
+	}
+
+	cmd.Flags().StringVar(&transcriptWriterSessionID, "session", "", "Session ID to record")
+
+	return cmd
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	sessionID := args[0]
+	if _, _, err := transcript.Locate(sessionID); err != nil {
+		sessionID = mux.SessionPrefix + args[0]
+	}
+
+	dir, archived, err := transcript.Locate(sessionID)
+	if err != nil {
+		if errors.Is(err, transcript.ErrNotFound) {
+			return fmt.Errorf("no transcript for '%s' (transcripts are recorded for sessions spawned with transcripts.enabled)", args[0])
+		}
+		return err
+	}
+
+	files := transcript.Files(dir, logsRaw)
+	if len(files) == 0 {
+		if logsRaw {
+			return fmt.Errorf("no raw transcript for '%s'", args[0])
+		}
+		return fmt.Errorf("no transcript for '%s'", args[0])
+	}
+
+	data, err := transcript.Tail(files, logsLines)
+	if err != nil {
+		return fmt.Errorf("failed to read transcript: %w", err)
+	}
+	os.Stdout.Write(data)
+
+	if !logsFollow {
+		if archived {
+			fmt.Fprintf(os.Stderr, "\n\033[33m💡 Archived transcript: %s\033[0m\n", dir)
+		}
+		return nil
+	}
+	if archived {
+		return fmt.Errorf("session '%s' has been killed; its transcript is archived at %s", args[0], dir)
+	}
+
+	current := transcript.CurrentFile(dir, logsRaw)
+	var offset int64
+	if info, err := os.Stat(current); err == nil {
+		offset = info.Size()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return transcript.Follow(ctx, current, offset, os.Stdout, 250*time.Millisecond)
+}
+
+// startTranscript pipes a session's pane output into its transcript.
+func startTranscript(sessionID string) error {
+	cfg, err := config.Get()
+	if err != nil || !cfg.Transcripts.Enabled {
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	// The pipe command runs in the tmux server's environment, so pass the state dir explicitly
+	command := fmt.Sprintf("CODERS_STATE_DIR=%s %s transcript-writer --session %s",
+		shellEscape(config.StateDir()), shellEscape(exe), shellEscape(sessionID))
+	if err := mux.PipePane(sessionID, command); err != nil && !errors.Is(err, mux.ErrUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// archiveTranscript moves a killed session's transcript to the archive.
+func archiveTranscript(sessionID string) {
+	dir, err := transcript.Archive(sessionID)
+	log := logging.WithCommand("logs").WithSessionID(sessionID)
+	if err != nil {
+		log.WithError(err).Warn("failed to archive transcript")
+		return
+	}
+	if dir != "" {
+		log.WithField("archive", dir).Debug("archived transcript")
+	}
+}
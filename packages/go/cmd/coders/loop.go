@@ -0,0 +1,651 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/logging"
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/notify"
+	"github.com/Jayphen/coders/internal/redis"
+	"github.com/Jayphen/coders/internal/routing"
+	"github.com/Jayphen/coders/internal/tasksource"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+var (
+	loopTodolist      string
+	loopCwd           string
+	loopTool          string
+	loopModel         string
+	loopMaxConcurrent int
+	loopStopOnBlocked bool
+	loopBackground    bool
+	loopWait          bool
+	loopID            string
+	loopSources       []string // Multi-source task specifications
+	loopOnlyReady     bool     // Only process tasks with no blockers
+)
+
+const promiseCheckInterval = 5 * time.Second
+
+func newLoopCmd() *cobra.Command {
+	cfg, _ := config.Get()
+	defaultTool := config.DefaultDefaultTool
+	if cfg != nil {
+		defaultTool = cfg.DefaultTool
+	}
+
+	cmd := &cobra.Command{
+		Use:   "loop",
+		Short: "Run tasks from multiple sources in a loop",
+		Long: `Automatically spawn coder sessions for each task from one or more task sources.
+
+The loop runner can pull tasks from multiple sources:
+  - Todolist files (markdown format: [ ] task description)
+  - Beads issues (git-backed issue tracker)
+  - Linear issues
+  - GitHub issues
+
+Multi-source support allows mixing tasks from different systems in a single loop.
+
+Features:
+  - Multi-source task aggregation (beads, Linear, GitHub, todolist files)
+  - Routes each task to a tool and model with the routing rules (matched on
+    labels, priority, source, title and paths), defaulting to --tool
+  - Falls back along the routing chain when a tool's quota runs out, and
+    returns to the task's tool once it resets (see 'routing' in the config file)
+  - Saves state to Redis for recovery
+  - Can stop on blocked tasks or continue
+  - Can be paused, resumed, skipped or cancelled with 'coders loop-control' or the TUI
+  - Runs in background by default (use --wait for blocking mode)
+  - Supports recursive loops (coder can spawn sub-loops with --wait)
+
+Examples:
+  # Legacy todolist mode (backward compatible)
+  coders loop --todolist tasks.txt --cwd ~/project
+
+  # Multi-source mode
+  coders loop --source "beads:cwd=." --cwd ~/project
+  coders loop --source "todolist:path=tasks.txt" --source "beads:cwd=." --cwd ~/project
+  coders loop --source "linear:team=TEAM123" --cwd ~/project
+  coders loop --source "github:owner=user,repo=myrepo" --cwd ~/project
+
+  # Only ready tasks (no blockers)
+  coders loop --source "beads:cwd=." --only-ready --cwd ~/project
+
+Recursive loops (from within a coder session):
+  coders loop --wait --todolist subtasks.txt --cwd .
+  # Blocks until all subtasks complete, then coder continues`,
+		RunE: runLoop,
+	}
+
+	cmd.Flags().StringVar(&loopTodolist, "todolist", "", "Path to todolist file (legacy, use --source instead)")
+	cmd.Flags().StringSliceVar(&loopSources, "source", []string{}, "Task source specification (format: type:param=value,...)")
+	cmd.Flags().StringVar(&loopCwd, "cwd", "", "Working directory for spawned sessions (required)")
+	cmd.Flags().StringVar(&loopTool, "tool", defaultTool, "AI tool to use (claude, gemini, codex, opencode)")
+	cmd.Flags().StringVar(&loopModel, "model", "", "Model to use")
+	cmd.Flags().IntVar(&loopMaxConcurrent, "max-concurrent", 1, "Maximum concurrent sessions (not yet implemented)")
+	cmd.Flags().BoolVar(&loopStopOnBlocked, "stop-on-blocked", false, "Stop loop if a task is blocked")
+	cmd.Flags().BoolVar(&loopOnlyReady, "only-ready", false, "Only process tasks with no blockers")
+	cmd.Flags().BoolVar(&loopBackground, "background", true, "Run in background")
+	cmd.Flags().BoolVarP(&loopWait, "wait", "w", false, "Wait for loop to complete (blocks until done, enables recursive loops)")
+	cmd.Flags().StringVar(&loopID, "loop-id", "", "Custom loop ID (auto-generated if not set)")
+
+	cmd.MarkFlagRequired("cwd")
+
+	return cmd
+}
+
+func runLoop(cmd *cobra.Command, args []string) error {
+	log := logging.WithCommand("loop")
+
+	// Validate inputs - either --todolist or --source must be specified
+	if loopTodolist == "" && len(loopSources) == 0 {
+		return fmt.Errorf("either --todolist or --source must be specified")
+	}
+
+	if loopCwd == "" {
+		return fmt.Errorf("--cwd is required")
+	}
+
+	// Convert legacy --todolist to source spec
+	sourceSpecs := loopSources
+	if loopTodolist != "" {
+		todolistPath, err := filepath.Abs(loopTodolist)
+		if err != nil {
+			return fmt.Errorf("failed to resolve todolist path: %w", err)
+		}
+		sourceSpecs = append([]string{fmt.Sprintf("todolist:path=%s", todolistPath)}, sourceSpecs...)
+	}
+
+	// Resolve working directory
+	cwdPath, err := resolveDirectory(loopCwd)
+	if err != nil {
+		return fmt.Errorf("failed to resolve working directory: %w", err)
+	}
+
+	// Generate loop ID if not set
+	if loopID == "" {
+		loopID = fmt.Sprintf("loop-%d", time.Now().Unix())
+	}
+
+	log.WithFields(map[string]interface{}{
+		"sources": sourceSpecs,
+		"cwd":     cwdPath,
+		"tool":    loopTool,
+		"loopId":  loopID,
+		"wait":    loopWait,
+	}).Info("starting loop")
+
+	// --wait flag overrides --background (enables recursive loops from within coders)
+	if loopWait {
+		loopBackground = false
+	}
+
+	// If background mode, spawn ourselves as a background process
+	if loopBackground {
+		return runLoopInBackground(sourceSpecs, cwdPath)
+	}
+
+	// Run in foreground
+	return executeLoopWithSources(sourceSpecs, cwdPath)
+}
+
+func runLoopInBackground(sourceSpecs []string, cwdPath string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	// Build args for background process
+	bgArgs := []string{
+		"loop",
+		"--cwd", cwdPath,
+		"--tool", loopTool,
+		"--background=false", // Don't recurse
+		"--loop-id", loopID,
+	}
+
+	// Add source specs
+	for _, spec := range sourceSpecs {
+		bgArgs = append(bgArgs, "--source", spec)
+	}
+
+	if loopModel != "" {
+		bgArgs = append(bgArgs, "--model", loopModel)
+	}
+	if loopStopOnBlocked {
+		bgArgs = append(bgArgs, "--stop-on-blocked")
+	}
+	if loopOnlyReady {
+		bgArgs = append(bgArgs, "--only-ready")
+	}
+
+	logFile := fmt.Sprintf("/tmp/coders-loop-%s.log", loopID)
+
+	// Create log file
+	f, err := os.Create(logFile)
+	if err != nil {
+		return fmt.Errorf("failed to create log file: %w", err)
+	}
+
+	bgCmd := exec.Command(exe, bgArgs...)
+	bgCmd.Stdout = f
+	bgCmd.Stderr = f
+	bgCmd.Stdin = nil
+	bgCmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+
+	if err := bgCmd.Start(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to start background loop: %w", err)
+	}
+
+	// Don't wait - let it run in background
+	go func() {
+		bgCmd.Wait()
+		f.Close()
+	}()
+
+	fmt.Printf("\033[34m🔄 Starting multi-source loop\033[0m\n")
+	fmt.Printf("   📂 Sources: %v\n", sourceSpecs)
+	fmt.Printf("   📁 Working directory: %s\n", cwdPath)
+	fmt.Printf("   🤖 Tool: %s\n", loopTool)
+	fmt.Printf("   🆔 Loop ID: %s\n", loopID)
+	fmt.Printf("\n\033[32m✅ Loop started in background\033[0m\n")
+	fmt.Printf("   📋 Log: %s\n", logFile)
+	fmt.Printf("   💡 Check status: coders loop-status --loop-id %s\n", loopID)
+
+	return nil
+}
+
+// executeLoopWithSources executes a loop using the new multi-source TaskSource interface
+func executeLoopWithSources(sourceSpecs []string, cwdPath string) error {
+	log := logging.WithCommand("loop")
+
+	cfg, err := config.Get()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := routing.ValidateRules(cfg.Routing.Rules); err != nil {
+		return err
+	}
+	chain, err := routing.Chain(cfg.Routing, cwdPath, loopTool)
+	if err != nil {
+		return err
+	}
+	router := routing.New(cfg.Routing, chain)
+
+	fmt.Printf("\033[34m🔄 Starting Multi-Source Loop\033[0m\n")
+	fmt.Printf("   📂 Sources: %v\n", sourceSpecs)
+	fmt.Printf("   📁 Working directory: %s\n", cwdPath)
+	fmt.Printf("   🤖 Tool: %s\n", loopTool)
+	if len(chain) > 1 {
+		fmt.Printf("   🔀 Fallback: %s\n", strings.Join(chain, " -> "))
+	}
+	fmt.Printf("   🆔 Loop ID: %s\n", loopID)
+	fmt.Println()
+
+	// Create task sources
+	multiSource, err := tasksource.CreateMultiSourceFromStrings(sourceSpecs)
+	if err != nil {
+		return fmt.Errorf("failed to create task sources: %w", err)
+	}
+	defer multiSource.Close()
+
+	// Set up context for cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Build task filter
+	filter := &tasksource.TaskFilter{
+		Status:    []tasksource.TaskStatus{tasksource.TaskStatusOpen, tasksource.TaskStatusInProgress},
+		OnlyReady: loopOnlyReady,
+	}
+
+	// List tasks from all sources
+	tasks, err := multiSource.ListTasks(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	fmt.Printf("📋 Found %d tasks from %d source(s)\n\n", len(tasks), len(multiSource.Sources()))
+
+	run := newLoopRun(types.LoopState{
+		LoopID:      loopID,
+		Sources:     sourceSpecs,
+		Cwd:         cwdPath,
+		CurrentTool: router.Current(),
+	}, tasks)
+
+	// Set up signal handling for graceful shutdown. The loop records the
+	// cancellation itself once the context is done.
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		<-sigChan
+		fmt.Println("\n\033[33m⏹️  Loop interrupted by user\033[0m")
+		cancel()
+	}()
+
+	if len(tasks) == 0 {
+		fmt.Println("\033[32m✅ All tasks already completed!\033[0m")
+		run.state.Status = types.LoopCompleted
+		run.save()
+		return nil
+	}
+
+	// Execute tasks sequentially
+	prevSession := ""
+	for i, task := range tasks {
+		// Pause and cancel commands take effect between tasks
+		if err := run.checkpoint(ctx); err != nil {
+			fmt.Println("\n\033[33m⏹️  Loop cancelled\033[0m")
+			return run.finish(types.LoopCancelled)
+		}
+
+		// The routing rules pick the task's preferred tool and model
+		decision := routing.Route(cfg.Routing.Rules, routing.TaskInput(task))
+		if decision.Matched() {
+			router.Prefer(decision.Tool, "rule "+decision.Match)
+		} else {
+			router.Prefer(loopTool, "no routing rule matched")
+		}
+		currentTool := run.routeTool(ctx, router, prevSession, i)
+		model := loopModel
+		if decision.Matched() && decision.Model != "" && currentTool == decision.Tool {
+			model = decision.Model
+		}
+		run.start(i, currentTool)
+
+		// Spawn task
+		sessionName, err := spawnLoopTaskFromSource(task, i, len(tasks), currentTool, model, decision, cwdPath)
+		if err != nil {
+			fmt.Printf("\033[31m❌ Failed to spawn task: %v\033[0m\n", err)
+			return run.finish(types.LoopFailed)
+		}
+		prevSession = sessionName
+		run.setSession(mux.SessionPrefix + sessionName)
+
+		// Wait for promise
+		promise, err := waitForLoopPromise(ctx, sessionName, run)
+		switch {
+		case errors.Is(err, errTaskSkipped):
+			fmt.Printf("\n\033[33m⏭️  Task %d/%d skipped\033[0m\n", i+1, len(tasks))
+			run.skip()
+			continue
+		case errors.Is(err, errLoopCancelled), ctx.Err() != nil:
+			fmt.Println("\n\033[33m⏹️  Loop cancelled\033[0m")
+			return run.finish(types.LoopCancelled)
+		case err != nil:
+			fmt.Printf("\033[31m❌ Failed waiting for promise: %v\033[0m\n", err)
+			return run.finish(types.LoopFailed)
+		}
+
+		// Check if blocked
+		if promise.Status == types.PromiseBlocked {
+			fmt.Printf("\n\033[33m🚫 Task blocked: %s\033[0m\n", promise.Summary)
+			run.block(promise.Summary)
+
+			// Mark task as blocked in source
+			if err := multiSource.MarkBlocked(ctx, task.ID, promise.Summary); err != nil {
+				log.WithError(err).Warn("failed to mark task as blocked")
+			}
+
+			if loopStopOnBlocked {
+				fmt.Println("\033[33m⏸️  Stopping loop (--stop-on-blocked enabled)\033[0m")
+				return run.finish(types.LoopBlocked)
+			}
+			fmt.Println("\033[33m⚠️  Continuing despite blocked status...\033[0m")
+			continue // Skip marking as complete
+		}
+
+		// Mark task as complete in its source
+		result, err := multiSource.MarkComplete(ctx, task.ID)
+		if err != nil {
+			log.WithError(err).Warn("failed to mark task complete")
+		} else {
+			fmt.Printf("\033[32m✅ %s\033[0m\n", result.Message)
+		}
+
+		run.complete()
+		fmt.Printf("\033[32m✅ Task %d/%d completed\033[0m\n", i+1, len(tasks))
+
+		// Small delay before next task
+		time.Sleep(2 * time.Second)
+	}
+
+	fmt.Println("\n\033[32m🎉 Loop completed!\033[0m")
+	return run.finish(types.LoopCompleted)
+}
+
+// spawnLoopTaskFromSource spawns a coder session for a task from a TaskSource
+func spawnLoopTaskFromSource(task tasksource.Task, index, total int, tool, model string, decision routing.Decision, cwd string) (string, error) {
+	// Build the task description with completion instructions and source context
+	sourceInfo := fmt.Sprintf("[Source: %s, ID: %s]", task.Source, task.SourceID)
+	fullTask := fmt.Sprintf("%s %s. When complete, commit changes and push to GitHub, then publish a completion promise.", task.Title, sourceInfo)
+
+	// Generate the session name using the same logic as spawn command
+	sessionName := generateSessionName(tool, fullTask)
+
+	fmt.Printf("\n\033[34m🚀 Spawning task %d/%d\033[0m\n", index+1, total)
+	fmt.Printf("   📝 Task: %s\n", task.Title)
+	fmt.Printf("   🔖 Source: %s (%s)\n", task.Source, task.SourceID)
+	if task.Priority >= 0 && task.Priority <= 4 {
+		fmt.Printf("   🔥 Priority: P%d\n", task.Priority)
+	}
+	fmt.Printf("   🤖 Tool: %s\n", tool)
+	if model != "" {
+		fmt.Printf("   🧠 Model: %s\n", model)
+	}
+	if decision.Matched() {
+		fmt.Printf("   🧭 Rule: %s\n", decision.Match)
+	}
+
+	// Build spawn command args
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	spawnArgs := []string{
+		"spawn", tool,
+		"--cwd", cwd,
+		"--task", fullTask,
+		"--loop-id", loopID,
+		"--task-id", task.ID,
+	}
+	if model != "" {
+		spawnArgs = append(spawnArgs, "--model", model)
+	}
+
+	// Run spawn command
+	spawnCmd := exec.Command(exe, spawnArgs...)
+	spawnCmd.Stdout = os.Stdout
+	spawnCmd.Stderr = os.Stderr
+
+	if err := spawnCmd.Run(); err != nil {
+		return "", fmt.Errorf("spawn failed: %w", err)
+	}
+
+	return sessionName, nil
+}
+
+// waitForLoopPromise waits for a promise from a session, applying control
+// commands sent to the loop while it waits. A skip kills the session and
+// returns errTaskSkipped; a cancel returns errLoopCancelled.
+func waitForLoopPromise(ctx context.Context, sessionName string, run *loopRun) (*types.CoderPromise, error) {
+	rdb, err := redis.GetClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	sessionID := mux.SessionPrefix + sessionName
+
+	fmt.Printf("\n\033[33m⏳ Waiting for promise from %s...\033[0m\n", sessionName)
+
+	ticker := time.NewTicker(promiseCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+			switch run.poll(ctx) {
+			case types.LoopCommandCancel:
+				return nil, errLoopCancelled
+			case types.LoopCommandSkip:
+				if err := killSessionWithCleanup(sessionID, rdb, ctx); err != nil {
+					fmt.Printf("\033[33m⚠️  Failed to kill %s: %v\033[0m\n", sessionName, err)
+				}
+				return nil, errTaskSkipped
+			}
+
+			promise, err := rdb.GetPromise(sessionID)
+			if err != nil {
+				// Key doesn't exist yet, keep waiting
+				continue
+			}
+			if promise != nil {
+				fmt.Printf("\n\033[32m✅ Promise received from %s\033[0m\n", sessionName)
+				fmt.Printf("   📋 Status: %s\n", promise.Status)
+				fmt.Printf("   💬 Summary: %s\n", promise.Summary)
+				return promise, nil
+			}
+		}
+	}
+}
+
+// routeTool picks the tool for the task at index. The quota last reported
+// by the previous task's session and by any other running sessions is
+// taken into account first, along with usage limit warnings in the previous
+// session's output.
+func (r *loopRun) routeTool(ctx context.Context, router *routing.Router, prevSession string, index int) string {
+	now := time.Now()
+	if prevSession != "" {
+		tool := router.Current()
+		if r.rdb != nil {
+			history, err := r.rdb.GetHeartbeatHistory(ctx, mux.SessionPrefix+prevSession)
+			if err == nil && len(history) > 0 {
+				router.Observe(tool, history[0].Usage, time.UnixMilli(history[0].Timestamp))
+			}
+		}
+		if checkForUsageWarning(prevSession) {
+			router.Exhaust(tool, "usage limit warning", now)
+		}
+	}
+	if r.rdb != nil {
+		if heartbeats, err := r.rdb.GetHeartbeats(ctx); err == nil {
+			tools := sessionTools(ctx, r.rdb)
+			for sessionID, hb := range heartbeats {
+				if tool, ok := tools[sessionID]; ok {
+					router.Observe(tool, hb.Usage, time.UnixMilli(hb.Timestamp))
+				}
+			}
+		}
+	}
+
+	tool, sw := router.Next(index, now)
+	if sw != nil {
+		fmt.Printf("\n\033[33m🔀 Switching from %s to %s: %s\033[0m\n", sw.From, sw.To, sw.Reason)
+		logging.WithCommand("loop").WithFields(map[string]interface{}{
+			"loopId": r.state.LoopID,
+			"from":   sw.From,
+			"to":     sw.To,
+			"reason": sw.Reason,
+		}).Info("switching tool")
+		r.switchTool(*sw)
+	}
+	return tool
+}
+
+// sessionTools maps each session to the tool it runs, from stored session
+// state, or else the multiplexer's session metadata.
+func sessionTools(ctx context.Context, redisClient *redis.Client) map[string]string {
+	tools := make(map[string]string)
+	if sessions, err := mux.ListSessions(); err == nil {
+		for _, s := range sessions {
+			if types.IsValidTool(s.Tool) {
+				tools[s.Name] = s.Tool
+			}
+		}
+	}
+	if redisClient != nil {
+		if states, err := redisClient.GetSessionStates(ctx); err == nil {
+			for id, state := range states {
+				if state.Tool != "" {
+					tools[id] = state.Tool
+				}
+			}
+		}
+	}
+	return tools
+}
+
+// checkForUsageWarning checks if Claude has shown a usage warning in the session output
+func checkForUsageWarning(sessionName string) bool {
+	sessionID := mux.SessionPrefix + sessionName
+
+	// Capture recent output from the session
+	output, err := mux.CapturePane(sessionID, mux.CaptureOptions{Lines: 100})
+	if err != nil {
+		return false
+	}
+
+	// Look for Claude's usage warning patterns
+	warningPatterns := []*regexp.Regexp{
+		regexp.MustCompile(`(?i)approaching.*usage\s*limit`),
+		regexp.MustCompile(`9[0-9]%.*limit`),
+		regexp.MustCompile(`(?i)usage.*limit.*reached`),
+		regexp.MustCompile(`(?i)exceeded.*limit`),
+	}
+
+	for _, pattern := range warningPatterns {
+		if pattern.MatchString(output) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notifyLoopComplete sends a notification when a loop finishes
+func notifyLoopComplete(loopID string, taskCount int, status string) error {
+	log := logging.WithCommand("loop")
+
+	rdb, err := redis.GetClient()
+	if err != nil {
+		log.WithError(err).Warn("failed to connect to Redis for notification")
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	notification := &types.LoopNotification{
+		LoopID:    loopID,
+		Timestamp: time.Now().UnixMilli(),
+		TaskCount: taskCount,
+		Status:    status,
+	}
+
+	// Add a descriptive message based on status
+	switch status {
+	case "completed":
+		notification.Message = fmt.Sprintf("Loop completed successfully with %d tasks", taskCount)
+	case "cancelled":
+		notification.Message = fmt.Sprintf("Loop cancelled after processing %d tasks", taskCount)
+	case "blocked":
+		notification.Message = fmt.Sprintf("Loop stopped on a blocked task after %d tasks", taskCount)
+	case "failed":
+		notification.Message = fmt.Sprintf("Loop failed after processing %d tasks", taskCount)
+	default:
+		notification.Message = fmt.Sprintf("Loop finished with status '%s' after %d tasks", status, taskCount)
+	}
+
+	ctx := context.Background()
+	if err := rdb.SetLoopNotification(ctx, notification); err != nil {
+		log.WithError(err).Warn("failed to store loop notification")
+		return fmt.Errorf("failed to store notification: %w", err)
+	}
+
+	log.WithFields(map[string]interface{}{
+		"loopId":    loopID,
+		"taskCount": taskCount,
+		"status":    status,
+	}).Info("loop notification sent")
+
+	// Send tmux display-message notification to parent session
+	tmuxMessage := fmt.Sprintf("Loop %s %s: %d tasks", loopID, status, taskCount)
+	if err := mux.DisplayMessage("", tmuxMessage); err != nil {
+		log.WithError(err).Debug("failed to send tmux display message (non-fatal)")
+		// Non-fatal - continue even if tmux notification fails
+	}
+
+	// Route through the configured notification channels
+	event := notify.Event{
+		Type:    notify.EventLoopFinished,
+		Title:   fmt.Sprintf("Loop %s", status),
+		Message: notification.Message,
+		LoopID:  loopID,
+		Fields:  map[string]string{"status": status, "taskCount": fmt.Sprintf("%d", taskCount)},
+	}
+	if err := notify.Emit(ctx, event); err != nil {
+		log.WithError(err).Warn("failed to deliver loop notification")
+	}
+
+	return nil
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jayphen/coders/internal/logging"
+	"github.com/Jayphen/coders/internal/redis"
+	"github.com/Jayphen/coders/internal/tasksource"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+var (
+	// errLoopCancelled is returned while waiting on a task when a cancel
+	// command arrives.
+	errLoopCancelled = errors.New("loop cancelled")
+	// errTaskSkipped is returned while waiting on a task when a skip command
+	// arrives and the task's session has been killed.
+	errTaskSkipped = errors.New("task skipped")
+)
+
+func newLoopControlCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "loop-control <loop-id> <pause|resume|cancel|skip>",
+		Short: "Pause, resume, cancel or skip a task in a running loop",
+		Long: `Send a control command to a running loop.
+
+Commands:
+  pause   Let the current task finish, then wait before starting the next
+  resume  Continue a paused loop
+  cancel  Stop the loop now, leaving the current session running
+  skip    Kill the current task's session and move on to the next task
+
+Commands are queued in Redis and picked up by the loop within a few seconds.
+The TUI's loops tab sends the same commands.
+
+Examples:
+  coders loop-control loop-1234567890 pause
+  coders loop-control loop-1234567890 skip`,
+		Args: cobra.ExactArgs(2),
+		RunE: runLoopControl,
+	}
+}
+
+func runLoopControl(cmd *cobra.Command, args []string) error {
+	id, command := args[0], types.LoopCommand(args[1])
+	switch command {
+	case types.LoopCommandPause, types.LoopCommandResume, types.LoopCommandCancel, types.LoopCommandSkip:
+	default:
+		return fmt.Errorf("unknown loop command %q (use pause, resume, cancel or skip)", args[1])
+	}
+
+	rdb, err := redis.GetClient()
+	if err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	ctx := context.Background()
+	state, err := rdb.GetLoopState(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get loop state: %w", err)
+	}
+	if state == nil {
+		return fmt.Errorf("no loop found with ID: %s", id)
+	}
+	if state.Status.Finished() {
+		return fmt.Errorf("loop %s has already finished (%s)", id, state.Status)
+	}
+
+	if err := rdb.SendLoopCommand(ctx, id, command); err != nil {
+		return fmt.Errorf("failed to send command: %w", err)
+	}
+
+	fmt.Printf("Sent %s to loop %s\n", command, id)
+	return nil
+}
+
+// loopRun tracks a loop's progress in Redis and applies the control
+// commands sent to it.
+type loopRun struct {
+	rdb   *redis.Client
+	state *types.LoopState
+}
+
+// newLoopRun starts tracking a loop over tasks. Progress is not saved if
+// Redis is unavailable, and control commands are then ignored.
+func newLoopRun(state types.LoopState, tasks []tasksource.Task) *loopRun {
+	now := time.Now().UnixMilli()
+	state.Status = types.LoopRunning
+	state.TotalTasks = len(tasks)
+	state.StartedAt = now
+	for _, task := range tasks {
+		state.Queue = append(state.Queue, types.LoopTask{ID: task.ID, Title: task.Title, Source: string(task.Source)})
+	}
+
+	rdb, err := redis.GetClient()
+	if err != nil {
+		logging.WithCommand("loop").WithError(err).Warn("failed to connect to Redis, loop progress will not be saved")
+		rdb = nil
+	}
+	run := &loopRun{rdb: rdb, state: &state}
+	run.save()
+	return run
+}
+
+// save stores the loop's progress.
+func (r *loopRun) save() {
+	if r.rdb == nil {
+		return
+	}
+	r.state.UpdatedAt = time.Now().UnixMilli()
+	if err := r.rdb.SetLoopState(context.Background(), r.state); err != nil {
+		logging.WithCommand("loop").WithError(err).Warn("failed to save loop state")
+	}
+}
+
+// start records that the task at index is being worked on with tool.
+func (r *loopRun) start(index int, tool string) {
+	r.state.CurrentTaskIndex = index
+	r.state.CurrentTool = tool
+	if len(r.state.Queue) > 0 {
+		task := r.state.Queue[0]
+		r.state.Queue = r.state.Queue[1:]
+		r.state.CurrentTask = &task
+	}
+	r.save()
+}
+
+// switchTool records the loop changing the tool it runs tasks with.
+func (r *loopRun) switchTool(sw types.ToolSwitch) {
+	r.state.ToolSwitches = append(r.state.ToolSwitches, sw)
+	r.state.CurrentTool = sw.To
+	r.save()
+}
+
+// setSession records the session working on the current task.
+func (r *loopRun) setSession(sessionID string) {
+	if r.state.CurrentTask != nil {
+		r.state.CurrentTask.SessionID = sessionID
+	}
+	r.save()
+}
+
+// complete records that the current task finished.
+func (r *loopRun) complete() {
+	r.state.CompletedTasks++
+	r.state.CurrentTask = nil
+	r.save()
+}
+
+// block records that the current task ended blocked.
+func (r *loopRun) block(reason string) {
+	if r.state.CurrentTask != nil {
+		task := *r.state.CurrentTask
+		task.Reason = reason
+		r.state.Blocked = append(r.state.Blocked, task)
+	}
+	r.state.CurrentTask = nil
+	r.save()
+}
+
+// skip records that the current task was skipped.
+func (r *loopRun) skip() {
+	if r.state.CurrentTask != nil {
+		task := *r.state.CurrentTask
+		task.Reason = "skipped"
+		r.state.Skipped = append(r.state.Skipped, task)
+	}
+	r.state.CurrentTask = nil
+	r.save()
+}
+
+// finish records the loop's final status and sends the loop notification.
+// It always returns nil so loops can return it directly.
+func (r *loopRun) finish(status types.LoopStatus) error {
+	r.state.Status = status
+	if status == types.LoopCompleted {
+		r.state.CurrentTask = nil
+	}
+	r.save()
+	if err := notifyLoopComplete(r.state.LoopID, r.state.CompletedTasks, string(status)); err != nil {
+		logging.WithCommand("loop").WithError(err).Warn("failed to send loop notification")
+	}
+	return nil
+}
+
+// poll applies queued control commands and returns the last one that
+// needs the caller to act: cancel or skip. Pause and resume only change
+// the status, since pausing takes effect between tasks.
+func (r *loopRun) poll(ctx context.Context) types.LoopCommand {
+	if r.rdb == nil {
+		return ""
+	}
+	var action types.LoopCommand
+	for {
+		command, err := r.rdb.PopLoopCommand(ctx, r.state.LoopID)
+		if err != nil {
+			logging.WithCommand("loop").WithError(err).Warn("failed to read loop commands")
+			return action
+		}
+		switch command {
+		case "":
+			return action
+		case types.LoopCommandPause:
+			if r.state.Status != types.LoopPaused {
+				fmt.Println("\n\033[33m⏸️  Pausing loop after the current task\033[0m")
+				r.state.Status = types.LoopPaused
+				r.save()
+			}
+		case types.LoopCommandResume:
+			if r.state.Status == types.LoopPaused {
+				fmt.Println("\n\033[34m▶️  Resuming loop\033[0m")
+				r.state.Status = types.LoopRunning
+				r.save()
+			}
+		case types.LoopCommandCancel:
+			return types.LoopCommandCancel
+		case types.LoopCommandSkip:
+			action = types.LoopCommandSkip
+		}
+	}
+}
+
+// checkpoint runs between tasks. It waits while the loop is paused and
+// returns errLoopCancelled if the loop is cancelled.
+func (r *loopRun) checkpoint(ctx context.Context) error {
+	ticker := time.NewTicker(promiseCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		// Skips only apply to a running task
+		if r.poll(ctx) == types.LoopCommandCancel {
+			return errLoopCancelled
+		}
+		if r.state.Status != types.LoopPaused {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// sortLoopStates orders loops newest first.
+func sortLoopStates(states []*types.LoopState) {
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].StartedAt != states[j].StartedAt {
+			return states[i].StartedAt > states[j].StartedAt
+		}
+		return states[i].LoopID > states[j].LoopID
+	})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jayphen/coders/internal/redis"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+var loopStatusID string
+
+func newLoopStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "loop-status",
+		Short: "Check the status of a running loop",
+		Long: `Check the status of a loop runner.
+
+Shows the current state including:
+  - Tasks completed and total tasks
+  - The current task, its session and the tool being used
+  - Tasks still queued, and tasks that were blocked or skipped
+  - Loop status (running, paused, completed, cancelled, blocked, failed)
+
+Use 'coders loop-control' or the TUI's loops tab to pause, resume, cancel
+or skip tasks.
+
+Examples:
+  coders loop-status --loop-id loop-1234567890
+  coders loop-status  # Lists all recent loops`,
+		RunE: runLoopStatus,
+	}
+
+	cmd.Flags().StringVar(&loopStatusID, "loop-id", "", "Loop ID to check (lists all if not specified)")
+
+	return cmd
+}
+
+func runLoopStatus(cmd *cobra.Command, args []string) error {
+	rdb, err := redis.GetClient()
+	if err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	ctx := context.Background()
+
+	if loopStatusID != "" {
+		// Show specific loop
+		return showLoopStatus(ctx, rdb, loopStatusID)
+	}
+
+	// List all loops
+	return listAllLoops(ctx, rdb)
+}
+
+func showLoopStatus(ctx context.Context, rdb *redis.Client, loopID string) error {
+	state, err := rdb.GetLoopState(ctx, loopID)
+	if err != nil {
+		return fmt.Errorf("failed to get loop state: %w", err)
+	}
+
+	if state == nil {
+		fmt.Printf("\033[33m⚠️  No loop found with ID: %s\033[0m\n", loopID)
+		return nil
+	}
+
+	printLoopState(state)
+	return nil
+}
+
+func listAllLoops(ctx context.Context, rdb *redis.Client) error {
+	stateMap, err := rdb.GetLoopStates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get loop states: %w", err)
+	}
+
+	if len(stateMap) == 0 {
+		fmt.Println("No active loops found.")
+		return nil
+	}
+
+	states := make([]*types.LoopState, 0, len(stateMap))
+	for _, state := range stateMap {
+		states = append(states, state)
+	}
+	sortLoopStates(states)
+
+	fmt.Printf("Found %d loop(s):\n\n", len(states))
+
+	for _, state := range states {
+		printLoopState(state)
+		fmt.Println()
+	}
+
+	return nil
+}
+
+func printLoopState(state *types.LoopState) {
+	statusColor := "\033[33m" // yellow
+	statusIcon := "⏸️"
+
+	switch state.Status {
+	case types.LoopRunning:
+		statusColor = "\033[34m" // blue
+		statusIcon = "🔄"
+	case types.LoopCompleted:
+		statusColor = "\033[32m" // green
+		statusIcon = "✅"
+	case types.LoopPaused:
+		statusColor = "\033[33m" // yellow
+		statusIcon = "⏸️"
+	case types.LoopCancelled:
+		statusColor = "\033[90m" // gray
+		statusIcon = "⏹️"
+	case types.LoopBlocked, types.LoopFailed:
+		statusColor = "\033[31m" // red
+		statusIcon = "🚫"
+	}
+
+	fmt.Printf("%s%s Loop: %s\033[0m\n", statusColor, statusIcon, state.LoopID)
+	fmt.Printf("   📋 Status: %s\n", state.Status)
+	if state.TodolistPath != "" {
+		fmt.Printf("   📂 Todolist: %s\n", state.TodolistPath)
+	}
+	if len(state.Sources) > 0 {
+		fmt.Printf("   📂 Sources: %s\n", strings.Join(state.Sources, ", "))
+	}
+	fmt.Printf("   📁 Working directory: %s\n", state.Cwd)
+	fmt.Printf("   🤖 Tool: %s\n", state.CurrentTool)
+	fmt.Printf("   📊 Progress: %d/%d tasks (%d completed)\n", state.Processed(), state.TotalTasks, state.CompletedTasks)
+
+	if state.TotalTasks > 0 {
+		pct := float64(state.Processed()) / float64(state.TotalTasks) * 100
+		fmt.Printf("   📈 %.0f%% complete\n", pct)
+	}
+	if task := state.CurrentTask; task != nil {
+		fmt.Printf("   📝 Current: %s\n", task.Title)
+		if task.SessionID != "" {
+			fmt.Printf("   🖥️  Session: %s\n", task.SessionID)
+		}
+	}
+	if len(state.Queue) > 0 {
+		fmt.Printf("   ⏳ Queued: %d task(s)\n", len(state.Queue))
+	}
+	for _, task := range state.Blocked {
+		fmt.Printf("   🚫 Blocked: %s (%s)\n", task.Title, task.Reason)
+	}
+	for _, task := range state.Skipped {
+		fmt.Printf("   ⏭️  Skipped: %s\n", task.Title)
+	}
+	for _, sw := range state.ToolSwitches {
+		fmt.Printf("   🔀 Task %d: %s -> %s (%s)\n", sw.TaskIndex+1, sw.From, sw.To, sw.Reason)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/logging"
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/notify"
+	"github.com/Jayphen/coders/internal/redis"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+// Per-session notification overrides (spawn --notify)
+const (
+	notifyAll  = "all"
+	notifyNone = "none"
+)
+
+// parseNotifyOverride splits a --notify value into channel names.
+// Returns nil for "all" (use routing rules) and an empty slice for "none".
+func parseNotifyOverride(value string) []string {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "", notifyAll:
+		return nil
+	case notifyNone:
+		return []string{}
+	}
+
+	var channels []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			channels = append(channels, name)
+		}
+	}
+	return channels
+}
+
+// validateNotifyOverride checks that every channel in a --notify value is configured.
+func validateNotifyOverride(value string) error {
+	channels := parseNotifyOverride(value)
+	if len(channels) == 0 {
+		return nil
+	}
+	router, err := notify.Default()
+	if err != nil {
+		return fmt.Errorf("invalid notification config: %w", err)
+	}
+	for _, name := range channels {
+		if !router.HasChannel(name) {
+			return fmt.Errorf("unknown notification channel '%s' (configured: %s)", name, strings.Join(router.Channels(), ", "))
+		}
+	}
+	return nil
+}
+
+// parentDedupTTL is how long a dedup key suppresses repeated parent messages.
+const parentDedupTTL = 24 * time.Hour
+
+// parentMessages remembers which dedup keys have already been shown to a
+// parent session. Events like stuck are emitted every healthcheck cycle so
+// routing rules can match on their duration, but the parent's status line
+// should only flash once per episode.
+var parentMessages = struct {
+	sync.Mutex
+	sent map[string]time.Time
+}{sent: make(map[string]time.Time)}
+
+// claimParentMessage reports whether an event with key should be shown to the
+// parent, recording it if so. Events without a dedup key are always shown.
+func claimParentMessage(key string, now time.Time) bool {
+	if key == "" {
+		return true
+	}
+
+	parentMessages.Lock()
+	defer parentMessages.Unlock()
+
+	for k, t := range parentMessages.sent {
+		if now.Sub(t) >= parentDedupTTL {
+			delete(parentMessages.sent, k)
+		}
+	}
+	if _, ok := parentMessages.sent[key]; ok {
+		return false
+	}
+	parentMessages.sent[key] = now
+	return true
+}
+
+// emitSessionEvent is the single notification path for session events: a
+// tmux display-message to the parent session, once per dedup key, then
+// delivery through the notification router honoring the session's --notify
+// override. Returns the parent session that was notified, if any.
+func emitSessionEvent(ctx context.Context, redisClient *redis.Client, event notify.Event) string {
+	log := logging.WithCommand("notify").WithSessionID(event.SessionID).
+		WithField("event", string(event.Type))
+
+	var state *types.SessionState
+	if redisClient != nil {
+		state, _ = redisClient.GetSessionState(ctx, event.SessionID)
+	}
+
+	override := ""
+	parentID := ""
+	if state != nil {
+		override = state.Notify
+		parentID = state.ParentSessionID
+	} else {
+		parentID = lookupParentSession(ctx, redisClient, event.SessionID)
+	}
+
+	channels := parseNotifyOverride(override)
+	if channels != nil && len(channels) == 0 {
+		log.Debug("notifications disabled for session")
+		return ""
+	}
+
+	notifiedParent := ""
+	if parentID != "" && shouldNotifyParent(event) && claimParentMessage(event.DedupKey, time.Now()) {
+		if err := mux.DisplayMessage(parentID, parentMessage(event)); err != nil {
+			log.WithError(err).Debug("failed to notify parent session")
+		} else {
+			notifiedParent = parentID
+		}
+	}
+
+	router, err := notify.Default()
+	if err != nil {
+		log.WithError(err).Warn("invalid notification config")
+		return notifiedParent
+	}
+	if channels != nil {
+		_, err = router.RouteTo(ctx, channels, event)
+	} else {
+		_, err = router.Route(ctx, event)
+	}
+	if err != nil {
+		log.WithError(err).Warn("failed to deliver notification")
+	}
+
+	return notifiedParent
+}
+
+// shouldNotifyParent reports whether the parent session should see an event.
+// Promise messages can be turned off with sessions.notify_parent_on_promise.
+func shouldNotifyParent(event notify.Event) bool {
+	switch event.Type {
+	case notify.EventPromiseCompleted, notify.EventPromiseBlocked, notify.EventPromiseNeedsReview:
+		cfg, err := config.Get()
+		return err != nil || cfg.Sessions.NotifyParentOnPromise
+	}
+	return true
+}
+
+// parentMessage formats an event for a tmux display-message in the parent session.
+func parentMessage(event notify.Event) string {
+	return fmt.Sprintf("Child %s: %s", event.SessionID, event.Message)
+}
+
+// promiseEvent builds the notification event for a published promise.
+func promiseEvent(promise *types.CoderPromise) notify.Event {
+	name := strings.TrimPrefix(promise.SessionID, mux.SessionPrefix)
+
+	event := notify.Event{
+		SessionID: promise.SessionID,
+		Message:   fmt.Sprintf("%s: %s", promise.Status, promise.Summary),
+		Fields:    map[string]string{"status": string(promise.Status)},
+	}
+	switch promise.Status {
+	case types.PromiseBlocked:
+		event.Type = notify.EventPromiseBlocked
+		event.Title = fmt.Sprintf("Coder blocked: %s", name)
+		if len(promise.Blockers) > 0 {
+			event.Message += " (" + strings.Join(promise.Blockers, ", ") + ")"
+		}
+	case types.PromiseNeedsReview:
+		event.Type = notify.EventPromiseNeedsReview
+		event.Title = fmt.Sprintf("Coder needs review: %s", name)
+	default:
+		event.Type = notify.EventPromiseCompleted
+		event.Title = fmt.Sprintf("Coder completed: %s", name)
+	}
+	return event
+}
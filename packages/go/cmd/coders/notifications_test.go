@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jayphen/coders/internal/notify"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+func TestParseNotifyOverride(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", nil},
+		{"all", nil},
+		{"none", []string{}},
+		{"phone", []string{"phone"}},
+		{" phone, team ,", []string{"phone", "team"}},
+	}
+
+	for _, tt := range tests {
+		got := parseNotifyOverride(tt.value)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseNotifyOverride(%q) = %#v, want %#v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestPromiseEvent(t *testing.T) {
+	tests := []struct {
+		status   types.PromiseStatus
+		wantType notify.EventType
+	}{
+		{types.PromiseCompleted, notify.EventPromiseCompleted},
+		{types.PromiseBlocked, notify.EventPromiseBlocked},
+		{types.PromiseNeedsReview, notify.EventPromiseNeedsReview},
+	}
+
+	for _, tt := range tests {
+		event := promiseEvent(&types.CoderPromise{
+			SessionID: "coder-claude-auth",
+			Status:    tt.status,
+			Summary:   "Done",
+			Blockers:  []string{"needs API key"},
+		})
+		if event.Type != tt.wantType {
+			t.Errorf("status %s: type = %s, want %s", tt.status, event.Type, tt.wantType)
+		}
+		if event.SessionID != "coder-claude-auth" {
+			t.Errorf("status %s: session = %s", tt.status, event.SessionID)
+		}
+	}
+
+	blocked := promiseEvent(&types.CoderPromise{SessionID: "coder-x", Status: types.PromiseBlocked, Summary: "Stuck", Blockers: []string{"creds"}})
+	if blocked.Message != "blocked: Stuck (creds)" {
+		t.Errorf("blocked message = %q", blocked.Message)
+	}
+	if blocked.Title != "Coder blocked: x" {
+		t.Errorf("blocked title = %q", blocked.Title)
+	}
+}
+
+func TestClaimParentMessage(t *testing.T) {
+	now := time.Now()
+	key := "stuck|coder-claude-child|1700000000000"
+
+	if !claimParentMessage(key, now) {
+		t.Fatal("first stuck event was not shown to the parent")
+	}
+	if claimParentMessage(key, now.Add(30*time.Second)) {
+		t.Error("repeated stuck event was shown to the parent again")
+	}
+	if !claimParentMessage("stuck|coder-claude-child|1700000900000", now.Add(time.Minute)) {
+		t.Error("new stuck episode was not shown to the parent")
+	}
+	if !claimParentMessage(key, now.Add(parentDedupTTL)) {
+		t.Error("dedup key did not expire")
+	}
+	if !claimParentMessage("", now) || !claimParentMessage("", now) {
+		t.Error("events without a dedup key must always be shown")
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/logging"
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/redis"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+const (
+	defaultNudgeMessage = "Your output hasn't changed in a while. If you are still working, carry on. " +
+		"If you are stuck, explain what is blocking you, or run: coders promise \"reason\" --status blocked"
+	defaultRepromptMessage = "Please continue with your task. If you cannot make progress, " +
+		"run: coders promise \"reason\" --status blocked"
+)
+
+// remediationKey identifies a rule within a single episode.
+func remediationKey(rule config.RemediationRule) string {
+	return fmt.Sprintf("%s@%s", rule.Action, rule.After)
+}
+
+// trackStatus carries over from the previous check how long the session has
+// had its status, and its remediation episode. An episode starts when a
+// session becomes unhealthy and survives brief recoveries: a nudge that gets
+// the agent to print something makes it look healthy for a check or two, and
+// resetting then would repeat the nudge forever. The episode only ends once
+// the session has been healthy for grace, or turns unhealthy in another way.
+func trackStatus(result, prev *types.HealthCheckResult, grace time.Duration) {
+	result.StatusSince = result.Timestamp
+	if prev != nil && prev.Status == result.Status && prev.StatusSince > 0 {
+		result.StatusSince = prev.StatusSince
+	}
+
+	ongoing := prev != nil && prev.EpisodeSince > 0
+	healthyFor := time.Duration(result.Timestamp-result.StatusSince) * time.Millisecond
+	switch {
+	case result.Status == types.HealthHealthy && (!ongoing || healthyFor >= grace):
+		return
+	case ongoing && (result.Status == types.HealthHealthy || result.Status == prev.EpisodeStatus):
+		result.EpisodeStatus = prev.EpisodeStatus
+		result.EpisodeSince = prev.EpisodeSince
+		result.Remediations = prev.Remediations
+	default:
+		result.EpisodeStatus = result.Status
+		result.EpisodeSince = result.StatusSince
+		// A session only counts as stuck once its output has been unchanged
+		// for a while, so stuck rules count from when it stopped changing
+		if result.Status == types.HealthStuck && result.OutputStaleFor > 0 {
+			result.EpisodeSince = result.Timestamp - result.OutputStaleFor
+		}
+	}
+}
+
+// dueRemediation returns the rule to apply to a session that has had status
+// for statusFor, along with the keys to mark as applied. When several rules
+// are due at once (e.g. the watcher was not running), only the latest one
+// runs and the earlier steps are marked applied without running.
+func dueRemediation(rules []config.RemediationRule, status types.HealthStatus, statusFor time.Duration, applied []string) (config.RemediationRule, []string, bool) {
+	done := make(map[string]bool, len(applied))
+	for _, key := range applied {
+		done[key] = true
+	}
+
+	var due []config.RemediationRule
+	for _, rule := range rules {
+		if types.HealthStatus(rule.Status) != status || rule.After > statusFor {
+			continue
+		}
+		if done[remediationKey(rule)] {
+			continue
+		}
+		due = append(due, rule)
+	}
+	if len(due) == 0 {
+		return config.RemediationRule{}, nil, false
+	}
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].After < due[j].After })
+
+	keys := make([]string, 0, len(due))
+	for _, rule := range due {
+		keys = append(keys, remediationKey(rule))
+	}
+	return due[len(due)-1], keys, true
+}
+
+// applyRemediations runs configured remediation rules against the results of
+// a health check. Applied rules are stored on the health check result so each
+// step runs once per episode, and the durations in the rules count from the
+// start of the episode.
+func applyRemediations(ctx context.Context, redisClient *redis.Client, summary *types.HealthCheckSummary, now time.Time) {
+	cfg, err := config.Get()
+	if err != nil || !cfg.Remediation.Enabled || len(cfg.Remediation.Rules) == 0 {
+		return
+	}
+
+	for i := range summary.Sessions {
+		result := &summary.Sessions[i]
+		// Nothing runs while a session is in its grace period
+		if result.Status != result.EpisodeStatus || result.EpisodeSince == 0 || result.SessionID == mux.OrchestratorSession {
+			continue
+		}
+
+		statusFor := now.Sub(time.UnixMilli(result.EpisodeSince))
+		rule, keys, ok := dueRemediation(cfg.Remediation.Rules, result.Status, statusFor, result.Remediations)
+		if !ok {
+			continue
+		}
+
+		// Mark the rule applied before acting so a failing action is not retried every cycle
+		result.Remediations = append(result.Remediations, keys...)
+		if err := redisClient.SetHealthCheck(ctx, result); err != nil {
+			fmt.Printf("[Healthcheck] Failed to store result for %s: %v\n", result.SessionID, err)
+		}
+
+		event := &types.SessionEvent{
+			SessionID: result.SessionID,
+			Timestamp: now.UnixMilli(),
+			Type:      "remediation",
+			Action:    rule.Action,
+			Status:    result.Status,
+			Message:   fmt.Sprintf("%s after %s %s", rule.Action, formatDuration(statusFor), result.Status),
+		}
+
+		log := logging.WithCommand("healthcheck").WithSessionID(result.SessionID).WithFields(map[string]interface{}{
+			"action":     rule.Action,
+			"status":     string(result.Status),
+			"status_for": statusFor.String(),
+		})
+
+		if err := runRemediation(ctx, redisClient, result.SessionID, rule); err != nil {
+			event.Error = err.Error()
+			log.WithError(err).Warn("remediation failed")
+			fmt.Printf("[Healthcheck] Remediation %s failed for %s: %v\n", rule.Action, result.SessionID, err)
+		} else {
+			log.Info("remediation applied")
+			fmt.Printf("[Healthcheck] Remediation: %s %s\n", result.SessionID, event.Message)
+		}
+
+		if err := redisClient.RecordEvent(ctx, event); err != nil {
+			log.WithError(err).Debug("failed to record remediation event")
+		}
+	}
+}
+
+// runRemediation performs a single remediation action on a session.
+func runRemediation(ctx context.Context, redisClient *redis.Client, sessionID string, rule config.RemediationRule) error {
+	switch rule.Action {
+	case config.RemediationNudge:
+		return mux.SendKeys(sessionID, valueOrDefault(rule.Message, defaultNudgeMessage))
+
+	case config.RemediationEscape:
+		if err := mux.SendRawKeys(sessionID, "Escape"); err != nil {
+			return err
+		}
+		time.Sleep(500 * time.Millisecond)
+		return mux.SendKeys(sessionID, valueOrDefault(rule.Message, defaultRepromptMessage))
+
+	case config.RemediationRestart:
+		state, err := redisClient.GetSessionState(ctx, sessionID)
+		if err != nil {
+			return fmt.Errorf("failed to get session state: %w", err)
+		}
+		if state == nil {
+			return fmt.Errorf("no session state to restart from")
+		}
+		// Remediation restarts share the crash watcher's budget
+		if state.RestartCount >= state.MaxRestarts {
+			return fmt.Errorf("max restarts (%d) reached", state.MaxRestarts)
+		}
+		return startRestart(sessionID, fmt.Sprintf("restarted by remediation after %s %s", formatDuration(rule.After), rule.Status))
+
+	case config.RemediationKill:
+		// Publish a blocked promise first so loops mark the task blocked
+		promise := &types.CoderPromise{
+			SessionID: sessionID,
+			Timestamp: time.Now().UnixMilli(),
+			Summary:   valueOrDefault(rule.Message, fmt.Sprintf("Killed by remediation after %s %s", formatDuration(rule.After), rule.Status)),
+			Status:    types.PromiseBlocked,
+		}
+		if err := redisClient.SetPromise(ctx, promise); err != nil {
+			return fmt.Errorf("failed to publish blocked promise: %w", err)
+		}
+		// Remove session state so the crash watcher does not restart it
+		if err := redisClient.DeleteSessionState(ctx, sessionID); err != nil {
+			return fmt.Errorf("failed to delete session state: %w", err)
+		}
+		if err := mux.KillSession(sessionID); err != nil {
+			return err
+		}
+		archiveTranscript(sessionID)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown remediation action %q", rule.Action)
+	}
+}
+
+// startRestart restarts a session with a background 'coders restart', so
+// the health check cycle doesn't wait for the tool to come back up.
+func startRestart(sessionID, reason string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	cmd := exec.Command(exe, "restart", sessionID, "--reason", reason)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start restart: %w", err)
+	}
+
+	// Don't wait for it - let it run in background
+	go func() {
+		cmd.Wait()
+	}()
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+func TestDueRemediation(t *testing.T) {
+	rules := config.DefaultRemediationRules()
+
+	tests := []struct {
+		name       string
+		status     types.HealthStatus
+		statusFor  time.Duration
+		applied    []string
+		wantOK     bool
+		wantAction string
+		wantKeys   []string
+	}{
+		{
+			name:      "not due yet",
+			status:    types.HealthStuck,
+			statusFor: 4 * time.Minute,
+		},
+		{
+			name:       "first step",
+			status:     types.HealthStuck,
+			statusFor:  6 * time.Minute,
+			wantOK:     true,
+			wantAction: config.RemediationNudge,
+			wantKeys:   []string{"nudge@5m0s"},
+		},
+		{
+			name:      "step already applied",
+			status:    types.HealthStuck,
+			statusFor: 7 * time.Minute,
+			applied:   []string{"nudge@5m0s"},
+		},
+		{
+			name:       "next step",
+			status:     types.HealthStuck,
+			statusFor:  11 * time.Minute,
+			applied:    []string{"nudge@5m0s"},
+			wantOK:     true,
+			wantAction: config.RemediationEscape,
+			wantKeys:   []string{"escape@10m0s"},
+		},
+		{
+			name:       "skips to latest due step",
+			status:     types.HealthStuck,
+			statusFor:  25 * time.Minute,
+			wantOK:     true,
+			wantAction: config.RemediationRestart,
+			wantKeys:   []string{"nudge@5m0s", "escape@10m0s", "restart@20m0s"},
+		},
+		{
+			name:      "other status",
+			status:    types.HealthDead,
+			statusFor: time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, keys, ok := dueRemediation(rules, tt.status, tt.statusFor, tt.applied)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if rule.Action != tt.wantAction {
+				t.Errorf("action = %q, want %q", rule.Action, tt.wantAction)
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("keys = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestRemediationEscalatesThroughRecoveries(t *testing.T) {
+	rules := config.DefaultRemediationRules()
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Each step is one healthcheck cycle: the session's status at that
+	// minute and the action remediation should take, if any
+	steps := []struct {
+		minute int
+		status types.HealthStatus
+		want   string
+	}{
+		{0, types.HealthStuck, ""},
+		{5, types.HealthStuck, config.RemediationNudge},
+		{6, types.HealthHealthy, ""}, // The nudge got the agent to print something
+		{7, types.HealthHealthy, ""},
+		{9, types.HealthStuck, ""},
+		{10, types.HealthStuck, config.RemediationEscape},
+		{11, types.HealthHealthy, ""},
+		{20, types.HealthStuck, config.RemediationRestart},
+		{21, types.HealthHealthy, ""},
+		{31, types.HealthHealthy, ""}, // Healthy for the grace period: the episode is over
+		{32, types.HealthStuck, ""},
+		{37, types.HealthStuck, config.RemediationNudge},
+	}
+
+	var prev *types.HealthCheckResult
+	for _, step := range steps {
+		now := start.Add(time.Duration(step.minute) * time.Minute)
+		result := &types.HealthCheckResult{SessionID: "coder-test", Timestamp: now.UnixMilli(), Status: step.status}
+		trackStatus(result, prev, config.DefaultRemediationGrace)
+
+		got := ""
+		if result.Status == result.EpisodeStatus {
+			statusFor := now.Sub(time.UnixMilli(result.EpisodeSince))
+			if rule, keys, ok := dueRemediation(rules, result.Status, statusFor, result.Remediations); ok {
+				got = rule.Action
+				result.Remediations = append(result.Remediations, keys...)
+			}
+		}
+		if got != step.want {
+			t.Errorf("minute %d (%s): action = %q, want %q", step.minute, step.status, got, step.want)
+		}
+		prev = result
+	}
+}
+
+func TestStuckRulesCountFromStaleOutput(t *testing.T) {
+	rules := config.DefaultRemediationRules()
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Output stopped changing at start, and the session is stuck once it
+	// has been unchanged for 5m
+	var prev *types.HealthCheckResult
+	for _, step := range []struct {
+		minute int
+		want   string
+	}{
+		{5, config.RemediationNudge},
+		{9, ""},
+		{10, config.RemediationEscape},
+	} {
+		now := start.Add(time.Duration(step.minute) * time.Minute)
+		result := &types.HealthCheckResult{
+			Timestamp:      now.UnixMilli(),
+			Status:         types.HealthStuck,
+			OutputStaleFor: (time.Duration(step.minute) * time.Minute).Milliseconds(),
+		}
+		trackStatus(result, prev, config.DefaultRemediationGrace)
+
+		got := ""
+		if rule, keys, ok := dueRemediation(rules, result.Status, now.Sub(time.UnixMilli(result.EpisodeSince)), result.Remediations); ok {
+			got = rule.Action
+			result.Remediations = append(result.Remediations, keys...)
+		}
+		if got != step.want {
+			t.Errorf("minute %d: action = %q, want %q", step.minute, got, step.want)
+		}
+		prev = result
+	}
+}
+
+func TestTrackStatusNewEpisodeOnOtherStatus(t *testing.T) {
+	prev := &types.HealthCheckResult{
+		Timestamp:     1000,
+		Status:        types.HealthStuck,
+		StatusSince:   500,
+		EpisodeStatus: types.HealthStuck,
+		EpisodeSince:  500,
+		Remediations:  []string{"nudge@5m0s"},
+	}
+	result := &types.HealthCheckResult{Timestamp: 2000, Status: types.HealthWaitingInput}
+	trackStatus(result, prev, time.Minute)
+
+	if result.EpisodeStatus != types.HealthWaitingInput || result.EpisodeSince != 2000 || len(result.Remediations) != 0 {
+		t.Errorf("episode = %s since %d with %v, want a new waiting-input episode since 2000",
+			result.EpisodeStatus, result.EpisodeSince, result.Remediations)
+	}
+}
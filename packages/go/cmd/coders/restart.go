@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/redis"
+)
+
+var restartReason string
+
+func newRestartCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restart <session>...",
+		Short: "Restart coder sessions",
+		Long: `Restart one or more sessions by name or partial match.
+
+Each session is killed and relaunched from its stored state with the same
+tool, task, directory and options. Tools that can resume a conversation
+reopen it; others are told what the previous run did. A completed
+session's promise is cleared so it shows as active again.
+
+Examples:
+  coders restart claude-fix-bug
+  coders restart claude-api claude-web --reason "main was rebased"`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runRestart,
+	}
+
+	cmd.Flags().StringVar(&restartReason, "reason", "", "Reason passed on to the restarted tool")
+
+	return cmd
+}
+
+func runRestart(cmd *cobra.Command, args []string) error {
+	redisClient, err := redis.NewClient()
+	if err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	defer redisClient.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	states, err := redisClient.GetSessionStates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get session states: %w", err)
+	}
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	reason := "restarted by the user"
+	if restartReason != "" {
+		reason = restartReason
+	}
+
+	failed := 0
+	for _, query := range args {
+		name := matchSessionName(names, query)
+		if name == "" {
+			fmt.Printf("No restartable session matching '%s' found\n", query)
+			failed++
+			continue
+		}
+		if err := restartByUser(redisClient, name, reason); err != nil {
+			fmt.Printf("Failed to restart %s: %v\n", name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("Restarted: %s\n", strings.TrimPrefix(name, mux.SessionPrefix))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d session(s) not restarted", failed, len(args))
+	}
+	return nil
+}
+
+// restartByUser restarts a session on request. A completed session's crash
+// watcher has already exited, so a new one is started if the session had
+// restart-on-crash enabled.
+func restartByUser(redisClient *redis.Client, sessionID, reason string) error {
+	ctx := context.Background()
+	state, err := redisClient.GetSessionState(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session state: %w", err)
+	}
+	if state == nil {
+		return fmt.Errorf("no session state to restart from")
+	}
+
+	promise, _ := redisClient.GetPromise(sessionID)
+	if promise != nil {
+		if err := redisClient.DeletePromise(ctx, sessionID); err != nil {
+			return fmt.Errorf("failed to clear promise: %w", err)
+		}
+	}
+
+	if err := restartSession(redisClient, state, reason); err != nil {
+		return err
+	}
+
+	if promise != nil && state.RestartOnCrash {
+		if err := startCrashWatcher(sessionID); err != nil {
+			fmt.Printf("Warning: failed to start crash watcher: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// matchSessionName finds a session by exact name, name without the prefix,
+// or substring, in that order.
+func matchSessionName(names []string, query string) string {
+	for _, name := range names {
+		if name == query || name == mux.SessionPrefix+query {
+			return name
+		}
+	}
+	for _, name := range names {
+		if strings.Contains(name, query) {
+			return name
+		}
+	}
+	return ""
+}
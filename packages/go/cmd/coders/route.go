@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/routing"
+	"github.com/Jayphen/coders/internal/tasksource"
+)
+
+var (
+	routeSources  []string
+	routeLabels   []string
+	routePriority int
+	routeCwd      string
+)
+
+func newRouteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "route",
+		Short: "Show how tasks are routed to tools",
+		Long:  `Show how the routing rules in the config file pick tools for tasks.`,
+	}
+
+	cmd.AddCommand(newRouteExplainCmd())
+
+	return cmd
+}
+
+func newRouteExplainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain <task-id | task text>",
+		Short: "Show which routing rule a task matches",
+		Long: `Show how each routing rule fares against a task, which rule fired and
+the tool, model and fallback chain the task would run with.
+
+The task is looked up by ID in the task sources given with --source (or
+task_sources from the config file); anything else is taken as the task's
+text, with --label and --priority describing it further. Rules are checked
+in order and the first whose conditions all match fires.
+
+Examples:
+  coders route explain bd-42
+  coders route explain --source "github:owner=me,repo=app" 17
+  coders route explain "Fix the flaky test in web/src/nav.test.ts"
+  coders route explain --label frontend --priority 0 "Redesign the navbar"`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runRouteExplain,
+	}
+
+	cmd.Flags().StringSliceVar(&routeSources, "source", nil, "Task sources to look the task up in (format of loop --source)")
+	cmd.Flags().StringSliceVar(&routeLabels, "label", nil, "Labels of a task given as text")
+	cmd.Flags().IntVar(&routePriority, "priority", -1, "Priority (0-4) of a task given as text")
+	cmd.Flags().StringVar(&routeCwd, "cwd", "", "Working directory, for the project's fallback chain (default: current directory)")
+
+	return cmd
+}
+
+func runRouteExplain(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Get()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	text := strings.Join(args, " ")
+	in := routing.TextInput(text)
+	in.Labels = routeLabels
+	in.Priority = routePriority
+	from := ""
+
+	specs := routeSources
+	if len(specs) == 0 {
+		specs = cfg.TaskSources
+	}
+	if task := lookupRouteTask(specs, text); task != nil {
+		in = routing.TaskInput(*task)
+		from = fmt.Sprintf("%s %s", task.Source, task.ID)
+	}
+
+	fmt.Printf("Task: %s\n", in.Title)
+	if from != "" {
+		fmt.Printf("  From: %s\n", from)
+	}
+	if in.Priority >= 0 {
+		fmt.Printf("  Priority: P%d\n", in.Priority)
+	}
+	if len(in.Labels) > 0 {
+		fmt.Printf("  Labels: %s\n", strings.Join(in.Labels, ", "))
+	}
+	if paths := in.Paths(); len(paths) > 0 {
+		fmt.Printf("  Paths: %s\n", strings.Join(paths, ", "))
+	}
+	fmt.Println()
+
+	decision := routing.Route(cfg.Routing.Rules, in)
+	if len(cfg.Routing.Rules) == 0 {
+		fmt.Println("No routing rules configured")
+	} else {
+		fmt.Println("Rules:")
+		for i, result := range routing.Explain(cfg.Routing.Rules, in) {
+			target := result.Rule.Tool
+			if result.Rule.Model != "" {
+				target += " (" + result.Rule.Model + ")"
+			}
+			line := fmt.Sprintf("  %d. %s -> %s", i+1, result.Rule.Match, target)
+			switch {
+			case result.Err != nil:
+				fmt.Printf("\033[31m%s  invalid: %v\033[0m\n", line, result.Err)
+			case i == decision.Rule:
+				fmt.Printf("\033[32m%s  ✓ fired\033[0m\n", line)
+			case result.Matched():
+				fmt.Printf("%s  \033[2mmatches, but rule %d fired first\033[0m\n", line, decision.Rule+1)
+			default:
+				fmt.Printf("%s  \033[2mno %s\033[0m\n", line, strings.Join(result.Failed, ", "))
+			}
+		}
+	}
+	fmt.Println()
+
+	tool, model := cfg.DefaultTool, cfg.DefaultModel
+	if decision.Matched() {
+		tool = decision.Tool
+		if decision.Model != "" {
+			model = decision.Model
+		}
+		fmt.Printf("Routed to %s by rule %d (%s)\n", tool, decision.Rule+1, decision.Match)
+	} else {
+		fmt.Printf("No rule matched: %s (default_tool, or the loop's --tool)\n", tool)
+	}
+	if model != "" {
+		fmt.Printf("  Model: %s\n", model)
+	}
+
+	cwd := routeCwd
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	} else if resolved, err := resolveDirectory(cwd); err == nil {
+		cwd = resolved
+	}
+	if chain, err := routing.Chain(cfg.Routing, cwd, tool); err != nil {
+		fmt.Printf("  Fallback: \033[31m%v\033[0m\n", err)
+	} else if len(chain) > 1 {
+		fmt.Printf("  Fallback (loops, when out of quota): %s\n", strings.Join(chain, " -> "))
+	}
+	return nil
+}
+
+// lookupRouteTask finds the task with id in the task sources, if any.
+func lookupRouteTask(specs []string, id string) *tasksource.Task {
+	if len(specs) == 0 || strings.ContainsAny(id, " \t\n") {
+		return nil
+	}
+	sources, err := tasksource.CreateMultiSourceFromStrings(specs)
+	if err != nil {
+		return nil
+	}
+	defer sources.Close()
+	task, err := sources.GetTask(context.Background(), id)
+	if err != nil {
+		return nil
+	}
+	return task
+}
@@ -0,0 +1,876 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/crash"
+	"github.com/Jayphen/coders/internal/limits"
+	"github.com/Jayphen/coders/internal/logging"
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/proc"
+	"github.com/Jayphen/coders/internal/routing"
+	"github.com/Jayphen/coders/internal/sandbox"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+var (
+	spawnTool           string
+	spawnTask           string
+	spawnCwd            string
+	spawnModel          string
+	spawnHeartbeat      bool
+	spawnAttach         bool
+	spawnOllama         bool
+	spawnRestartOnCrash bool
+	spawnMaxRestarts    int
+	spawnWorktree       bool
+	spawnParent         string
+	spawnNotify         string
+	spawnLoopID         string
+	spawnTaskID         string
+	spawnMaxMemory      string
+	spawnMaxCPU         float64
+	spawnMaxProcs       int
+	spawnSandbox        bool
+	spawnSandboxNetwork bool
+	spawnIgnoreBudget   bool
+)
+
+func newSpawnCmd() *cobra.Command {
+	// Load config for defaults
+	cfg, _ := config.Get()
+	defaultTool := config.DefaultDefaultTool
+	defaultHeartbeat := config.DefaultDefaultHeartbeat
+	defaultModel := ""
+	defaultSandbox := config.DefaultSandboxDefault
+	defaultSandboxNetwork := config.DefaultSandboxNetwork
+	if cfg != nil {
+		defaultTool = cfg.DefaultTool
+		defaultHeartbeat = cfg.DefaultHeartbeat
+		defaultModel = cfg.DefaultModel
+		defaultSandbox = cfg.Sandbox.Default
+		defaultSandboxNetwork = cfg.Sandbox.Network
+	}
+
+	cmd := &cobra.Command{
+		Use:   "spawn [tool]",
+		Short: "Spawn a new coder session",
+		Long: `Spawn a new AI coding session in tmux.
+
+Supported tools: claude, gemini, codex, opencode
+
+Examples:
+  coders spawn claude --task "Fix the login bug"
+  coders spawn gemini --task "Add unit tests" --cwd ~/projects/myapp
+  coders spawn codex --task "Refactor auth module" --model gpt-4
+  coders spawn --attach  # Spawn and attach immediately
+  coders spawn --restart-on-crash --task "Long running task"  # Auto-restart on crash
+  coders spawn --worktree --task "Feature branch work"  # Create git worktree
+  coders spawn --parent coder-claude-lead --task "Subtask"  # Explicit parent session
+  coders spawn --task-id bd-42 --task "Fix the flaky test"  # Link to a task-source task
+  coders spawn --notify phone,team --task "Overnight run"  # Only notify these channels
+  coders spawn --max-memory 4G --max-cpu 2 --task "Run the test suite"  # Cap resources
+  coders spawn --worktree --sandbox --task "Untrusted refactor"  # Only the worktree is writable
+
+Git Worktree:
+  With --worktree, a new git worktree is created for isolated development.
+  The worktree is created in .coders/worktrees/<session-name> with a branch
+  named session/<session-name>. This allows working on features in isolation
+  without affecting the main working directory.
+
+Parent Sessions:
+  A session spawned from inside another coder session records that session
+  as its parent (detected from CODERS_SESSION_ID). Use --parent to set it
+  explicitly. The parent is stored in the session state so 'coders kill --tree'
+  and the TUI tree view can follow it.
+
+Notifications:
+  Promise, crash and health events for the session are shown in the parent
+  session and sent through the notification rules in the config file.
+  --notify overrides the rules for this session: "all" (default) uses the
+  rules, "none" silences the session, and a comma-separated list of channel
+  names sends every event to exactly those channels.
+
+Crash Recovery:
+  With --restart-on-crash, the session will automatically restart if the CLI
+  process crashes or dies unexpectedly. Session state is stored in Redis so
+  it can be restored with the same task/prompt. Use --max-restarts to limit
+  the number of automatic restarts (default: 3). Restarts back off
+  exponentially, wait longer after rate limits, and are not attempted after
+  authentication failures (see crash_recovery in the config file).
+
+Resource Limits:
+  --max-memory, --max-cpu and --max-procs cap the tool's process tree. They
+  are enforced with cgroup v2: a systemd user scope when one is available,
+  otherwise a cgroup under /sys/fs/cgroup/coders if that is writable. Without
+  cgroup v2, memory is capped with ulimit -v (address space, which some
+  runtimes reserve generously) and CPU and process limits are only
+  monitored. Heartbeats record each session's CPU, memory and process count,
+  and 'coders healthcheck' reports sessions at their limits as over-limit.
+
+Sandboxing:
+  The tools run with their permission prompts disabled. With --sandbox
+  (Linux only) the tool can only write to its working directory, the
+  repository's git directory and its own state directories; the rest of the
+  filesystem is read-only and credentials such as ~/.ssh are hidden, as are
+  the tmux and zellij sockets unless sandbox.allow_multiplexer is set. It
+  uses bubblewrap when installed, otherwise unprivileged user namespaces.
+  --sandbox-network=false cuts off the network too, which also stops the
+  tool reaching its API and Redis. Sandboxed claude sessions get their own
+  config directory: they share transcripts and the login with ~/.claude,
+  can't change its settings, hooks or commands, and start from a copy of
+  ~/.claude.json. The policy is the sandbox section of the config file.
+
+Routing:
+  Without a tool argument or --tool, the routing rules in the config file
+  may pick the tool, and the model unless --model is given, from the task's
+  title and the file paths it mentions. 'coders route explain' shows which
+  rule a task matches.
+
+Budgets:
+  The budget section of the config file caps daily and weekly spend, as
+  recorded in the usage ledger ('coders usage'). Once a cap is reached spawn
+  warns, or with action block refuses to spawn unless --ignore-budget is
+  given.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runSpawn,
+	}
+
+	cmd.Flags().StringVarP(&spawnTool, "tool", "t", defaultTool, "AI tool to use (claude, gemini, codex, opencode)")
+	cmd.Flags().StringVar(&spawnTask, "task", "", "Task description")
+	cmd.Flags().StringVar(&spawnCwd, "cwd", "", "Working directory (supports zoxide queries)")
+	cmd.Flags().StringVar(&spawnModel, "model", defaultModel, "Model to use (tool-specific)")
+	cmd.Flags().BoolVar(&spawnHeartbeat, "heartbeat", defaultHeartbeat, "Enable heartbeat monitoring")
+	cmd.Flags().BoolVarP(&spawnAttach, "attach", "a", false, "Attach to session after spawning")
+	cmd.Flags().BoolVar(&spawnOllama, "ollama", false, "Use Ollama backend (requires CODERS_OLLAMA_BASE_URL and CODERS_OLLAMA_AUTH_TOKEN)")
+	cmd.Flags().BoolVar(&spawnRestartOnCrash, "restart-on-crash", false, "Automatically restart session if it crashes (requires Redis)")
+	cmd.Flags().IntVar(&spawnMaxRestarts, "max-restarts", 3, "Maximum number of automatic restarts (default: 3)")
+	cmd.Flags().BoolVar(&spawnWorktree, "worktree", false, "Create a git worktree for isolated development")
+	cmd.Flags().StringVar(&spawnParent, "parent", "", "Parent session ID (defaults to CODERS_SESSION_ID when spawned from a coder session)")
+	cmd.Flags().StringVar(&spawnNotify, "notify", notifyAll, "Notifications for this session: all, none, or comma-separated channel names")
+	cmd.Flags().StringVar(&spawnLoopID, "loop-id", "", "ID of the loop spawning this session")
+	_ = cmd.Flags().MarkHidden("loop-id") // Set by the loop runner
+	cmd.Flags().StringVar(&spawnTaskID, "task-id", "", "ID of the task-source task (beads, Linear, GitHub, todolist) this session works on")
+	cmd.Flags().StringVar(&spawnMaxMemory, "max-memory", "", "Memory limit for the tool's processes (e.g. 512M, 4G)")
+	cmd.Flags().Float64Var(&spawnMaxCPU, "max-cpu", 0, "CPU limit in CPUs (e.g. 1.5)")
+	cmd.Flags().IntVar(&spawnMaxProcs, "max-procs", 0, "Maximum number of processes in the tool's process tree")
+	cmd.Flags().BoolVar(&spawnSandbox, "sandbox", defaultSandbox, "Run the tool in a sandbox where only the working directory is writable (Linux)")
+	cmd.Flags().BoolVar(&spawnSandboxNetwork, "sandbox-network", defaultSandboxNetwork, "Allow network access inside the sandbox")
+	cmd.Flags().BoolVar(&spawnIgnoreBudget, "ignore-budget", false, "Spawn even if a budget cap with action block has been reached")
+
+	return cmd
+}
+
+func runSpawn(cmd *cobra.Command, args []string) error {
+	log := logging.WithCommand("spawn")
+
+	// Get tool from arg or flag
+	tool := spawnTool
+	if len(args) > 0 {
+		tool = args[0]
+	}
+
+	// Without a tool, the routing rules may pick one for the task
+	if len(args) == 0 && !cmd.Flags().Changed("tool") && spawnTask != "" {
+		if cfg, err := config.Get(); err == nil {
+			if d := routing.Route(cfg.Routing.Rules, routing.TextInput(spawnTask)); d.Matched() {
+				tool = d.Tool
+				if d.Model != "" && !cmd.Flags().Changed("model") {
+					spawnModel = d.Model
+				}
+				fmt.Printf("🧭 Routed to %s by rule %q\n", tool, d.Match)
+			}
+		}
+	}
+
+	log.Debugf("starting spawn with tool=%s, task=%s", tool, spawnTask)
+
+	// Validate tool
+	validTools := map[string]bool{
+		"claude": true, "gemini": true, "codex": true, "opencode": true,
+	}
+	if !validTools[tool] {
+		log.Errorf("invalid tool: %s", tool)
+		return fmt.Errorf("invalid tool '%s': must be claude, gemini, codex, or opencode", tool)
+	}
+
+	// Validate Ollama settings if --ollama is set
+	if spawnOllama {
+		if tool != "claude" {
+			return fmt.Errorf("--ollama flag is only supported with claude tool")
+		}
+		cfg, err := config.Get()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if cfg.Ollama.BaseURL == "" {
+			return fmt.Errorf("--ollama requires CODERS_OLLAMA_BASE_URL environment variable or ollama.base_url in config")
+		}
+		if cfg.Ollama.AuthToken == "" && cfg.Ollama.APIKey == "" {
+			return fmt.Errorf("--ollama requires CODERS_OLLAMA_AUTH_TOKEN/API_KEY or ollama.auth_token/api_key in config")
+		}
+	}
+
+	// Check the spending caps before creating anything
+	if cfg, err := config.Get(); err == nil {
+		if err := checkSpawnBudget(cfg.Budget, spawnIgnoreBudget); err != nil {
+			log.WithError(err).Warn("budget reached")
+			return err
+		}
+	}
+
+	// Resolve working directory
+	cwd := spawnCwd
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	} else {
+		resolved, err := resolveDirectory(cwd)
+		if err != nil {
+			return fmt.Errorf("failed to resolve directory '%s': %w", cwd, err)
+		}
+		cwd = resolved
+	}
+
+	// Generate session name (needed before worktree creation)
+	sessionName := generateSessionName(tool, spawnTask)
+	sessionID := mux.SessionPrefix + sessionName
+
+	// Create git worktree if requested
+	var worktreePath string
+	if spawnWorktree {
+		var err error
+		worktreePath, err = createWorktree(cwd, sessionName)
+		if err != nil {
+			return fmt.Errorf("failed to create worktree: %w", err)
+		}
+		cwd = worktreePath
+		fmt.Printf("\033[32m✅ Created git worktree: %s\033[0m\n", worktreePath)
+	}
+
+	// Create logger with session context
+	log = log.WithSessionID(sessionID)
+
+	// Check if session already exists
+	if mux.SessionExists(sessionID) {
+		log.Warn("session already exists")
+		return fmt.Errorf("session '%s' already exists", sessionID)
+	}
+
+	// Resolve the parent session, either explicit or the session we were spawned from
+	parentSessionID := detectParentSession(spawnParent)
+	if spawnParent != "" && !mux.SessionExists(parentSessionID) {
+		if !mux.SessionExists(mux.SessionPrefix + parentSessionID) {
+			return fmt.Errorf("parent session '%s' does not exist", spawnParent)
+		}
+		parentSessionID = mux.SessionPrefix + parentSessionID
+	}
+
+	if err := validateNotifyOverride(spawnNotify); err != nil {
+		return err
+	}
+
+	resourceLimits, err := spawnResourceLimits()
+	if err != nil {
+		return err
+	}
+
+	// Name the conversation where the tool allows it, so a crash restart can resume it
+	conversationID := newConversationID(tool)
+	spawnCommit := gitHead(cwd)
+
+	// Build the command to run
+	toolCmd := buildToolCommand(tool, spawnTask, spawnModel, sessionID, spawnOllama) +
+		conversationArgs(tool, conversationID, false)
+	var sandboxBackend sandbox.Backend
+	if spawnSandbox {
+		toolCmd, sandboxBackend, err = applySandbox(toolCmd, cwd, spawnSandboxNetwork)
+		if err != nil {
+			return fmt.Errorf("failed to sandbox session: %w", err)
+		}
+	}
+	toolCmd = applyResourceLimits(sessionID, toolCmd, resourceLimits)
+
+	// Get user's shell
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/bash"
+	}
+
+	// Create prompt for tools that need it (but don't use stdin for codex)
+	var prompt string
+	sendPromptViaTmux := false
+	if spawnTask != "" && (tool == "claude" || tool == "opencode") {
+		prompt = buildPrompt(tool, spawnTask)
+	} else if spawnTask != "" && tool == "codex" {
+		// Codex requires TTY, so we'll send the prompt via tmux send-keys instead
+		prompt = buildPrompt(tool, spawnTask)
+		sendPromptViaTmux = true
+	}
+
+	// Build full tmux command, recording the tool's exit status for the crash watcher
+	crash.ClearExitCode(sessionID)
+	var fullCmd string
+	if prompt != "" && !sendPromptViaTmux {
+		// For tools that accept stdin (claude, opencode)
+		promptFile := fmt.Sprintf("/tmp/coders-prompt-%d.txt", time.Now().UnixNano())
+		if err := os.WriteFile(promptFile, []byte(prompt), 0644); err != nil {
+			return fmt.Errorf("failed to write prompt file: %w", err)
+		}
+		fullCmd = fmt.Sprintf("cd %s && %s < %s; %s; exec %s",
+			shellEscape(cwd), toolCmd, promptFile, exitStatusCommand(sessionID), shell)
+	} else {
+		// For tools that don't need stdin or codex
+		fullCmd = fmt.Sprintf("cd %s && %s; %s; exec %s",
+			shellEscape(cwd), toolCmd, exitStatusCommand(sessionID), shell)
+	}
+
+	// Create the multiplexer session
+	log.Info("creating session")
+	fmt.Printf("Creating session: %s\n", sessionID)
+	meta := types.SessionMeta{
+		Tool:     tool,
+		Task:     spawnTask,
+		Parent:   parentSessionID,
+		Loop:     spawnLoopID,
+		TaskID:   spawnTaskID,
+		Worktree: worktreePath,
+	}
+	if err := mux.CreateSession(sessionID, cwd, fullCmd, meta); err != nil {
+		log.WithError(err).Error("failed to create session")
+		return fmt.Errorf("failed to create %s session: %w", mux.Default().Name(), err)
+	}
+
+	// Record the full pane output before the tool starts writing
+	if err := startTranscript(sessionID); err != nil {
+		log.WithError(err).Warn("failed to start transcript")
+	}
+
+	log.WithFields(map[string]interface{}{
+		"tool":   tool,
+		"task":   spawnTask,
+		"cwd":    cwd,
+		"model":  spawnModel,
+		"ollama": spawnOllama,
+		"parent": parentSessionID,
+	}).Info("session created successfully")
+	fmt.Printf("\033[32m✅ Created session: %s\033[0m\n", sessionID)
+	fmt.Printf("   Tool: %s\n", tool)
+	if spawnTask != "" {
+		fmt.Printf("   Task: %s\n", spawnTask)
+	}
+	fmt.Printf("   Directory: %s\n", cwd)
+	if parentSessionID != "" {
+		fmt.Printf("   Parent: %s\n", parentSessionID)
+	}
+	if spawnTaskID != "" {
+		fmt.Printf("   Task ID: %s\n", spawnTaskID)
+	}
+	if spawnSandbox {
+		network := "no network"
+		if spawnSandboxNetwork {
+			network = "network allowed"
+		}
+		fmt.Printf("   Sandbox: %s, %s\n", sandboxBackend, network)
+	}
+	if resourceLimits != nil {
+		fmt.Printf("   Limits: %s\n", describeLimits(*resourceLimits))
+		switch limits.Method(resourceLimits.Method) {
+		case limits.MethodUlimit:
+			fmt.Printf("\033[33m⚠️  cgroup v2 unavailable: memory capped with ulimit, CPU and process limits only monitored\033[0m\n")
+		case limits.MethodNone:
+			fmt.Printf("\033[33m⚠️  cgroup v2 unavailable: limits are only monitored\033[0m\n")
+		}
+	}
+
+	// Wait for CLI to be ready
+	fmt.Printf("⏳ Waiting for %s to start...\n", tool)
+	if ready := waitForCLIReady(sessionID, tool, 10*time.Second); ready {
+		fmt.Printf("\033[32m✅ %s is running\033[0m\n", tool)
+	} else {
+		fmt.Printf("\033[33m⚠️  Timeout waiting for %s (session created but process may still be starting)\033[0m\n", tool)
+	}
+
+	// Send prompt via tmux if needed (for codex)
+	if sendPromptViaTmux && prompt != "" {
+		// Wait a bit for CLI to be fully ready
+		time.Sleep(2 * time.Second)
+
+		// Send the prompt line by line
+		lines := strings.Split(prompt, "\n")
+		for _, line := range lines {
+			if err := mux.SendKeys(sessionID, line); err != nil {
+				log.WithError(err).Warn("failed to send prompt line")
+			}
+		}
+		fmt.Printf("\033[32m✅ Sent task prompt to session\033[0m\n")
+	}
+
+	// Start heartbeat if enabled
+	if spawnHeartbeat {
+		if err := startHeartbeat(sessionID, spawnTask, parentSessionID); err != nil {
+			fmt.Printf("\033[33m⚠️  Failed to start heartbeat: %v\033[0m\n", err)
+		} else {
+			fmt.Printf("\033[32m💓 Heartbeat enabled\033[0m\n")
+		}
+	}
+
+	// Store session state so the parent link survives, and start the crash watcher if enabled
+	state := &types.SessionState{
+		SessionID:        sessionID,
+		SessionName:      sessionName,
+		Tool:             tool,
+		Task:             spawnTask,
+		Cwd:              cwd,
+		Model:            spawnModel,
+		ParentSessionID:  parentSessionID,
+		LoopID:           spawnLoopID,
+		TaskID:           spawnTaskID,
+		Worktree:         worktreePath,
+		Notify:           spawnNotify,
+		ConversationID:   conversationID,
+		SpawnCommit:      spawnCommit,
+		Limits:           resourceLimits,
+		Sandbox:          spawnSandbox,
+		SandboxNetwork:   spawnSandboxNetwork,
+		UseOllama:        spawnOllama,
+		HeartbeatEnabled: spawnHeartbeat,
+		RestartOnCrash:   spawnRestartOnCrash,
+		MaxRestarts:      spawnMaxRestarts,
+	}
+	if err := storeSessionState(state); err != nil {
+		log.WithError(err).Debug("failed to store session state")
+		if spawnRestartOnCrash {
+			fmt.Printf("\033[33m⚠️  Failed to store session state for crash recovery: %v\033[0m\n", err)
+			fmt.Printf("\033[33m   Crash recovery will not be available for this session.\033[0m\n")
+		}
+	} else if spawnRestartOnCrash {
+		if err := startCrashWatcher(sessionID); err != nil {
+			fmt.Printf("\033[33m⚠️  Failed to start crash watcher: %v\033[0m\n", err)
+		} else {
+			fmt.Printf("\033[32m🔄 Crash recovery enabled (max %d restarts)\033[0m\n", spawnMaxRestarts)
+		}
+	}
+
+	// Print attach instructions
+	fmt.Printf("\n\033[33m💡 Attach: coders attach %s\033[0m\n", sessionName)
+	fmt.Printf("\033[33m💡 Or: %s\033[0m\n", mux.AttachHint(sessionID))
+
+	// Optionally attach
+	if spawnAttach {
+		fmt.Println("\nAttaching...")
+		return mux.AttachSession(sessionID)
+	}
+
+	return nil
+}
+
+// generateSessionName creates a session name from tool and task.
+func generateSessionName(tool, task string) string {
+	if task == "" {
+		return fmt.Sprintf("%s-%d", tool, time.Now().Unix()%10000)
+	}
+
+	// Slugify task
+	slug := strings.ToLower(task)
+	slug = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		if r == ' ' || r == '-' || r == '_' {
+			return '-'
+		}
+		return -1
+	}, slug)
+
+	// Remove consecutive dashes and trim
+	for strings.Contains(slug, "--") {
+		slug = strings.ReplaceAll(slug, "--", "-")
+	}
+	slug = strings.Trim(slug, "-")
+
+	// Truncate if too long
+	if len(slug) > 30 {
+		slug = slug[:30]
+		slug = strings.TrimRight(slug, "-")
+	}
+
+	if slug == "" {
+		slug = fmt.Sprintf("%d", time.Now().Unix()%10000)
+	}
+
+	return fmt.Sprintf("%s-%s", tool, slug)
+}
+
+// buildToolCommand builds the command to run the AI tool.
+func buildToolCommand(tool, task, model, sessionID string, useOllama bool) string {
+	var cmd string
+	modelArg := ""
+	if model != "" {
+		modelArg = fmt.Sprintf(" --model %s", shellEscape(model))
+	}
+
+	// Set environment variables
+	// Unset CLAUDECODE to allow nested Claude Code sessions
+	envVars := fmt.Sprintf("CLAUDECODE= CODERS_SESSION_ID=%s", sessionID)
+
+	// Add Ollama env var mappings if --ollama flag is set
+	if useOllama {
+		cfg, _ := config.Get()
+		baseURL := cfg.Ollama.BaseURL
+		authToken := cfg.Ollama.AuthToken
+		apiKey := cfg.Ollama.APIKey
+
+		// Ensure URL has protocol
+		if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+			baseURL = "https://" + baseURL
+		}
+
+		envVars += fmt.Sprintf(" ANTHROPIC_BASE_URL=%s", shellEscape(baseURL))
+		// Set API_KEY to empty string to prevent Claude Code from falling back to Anthropic
+		// and use AUTH_TOKEN for Bearer auth (required by most Ollama proxies)
+		envVars += " ANTHROPIC_API_KEY=''"
+		if authToken != "" {
+			envVars += fmt.Sprintf(" ANTHROPIC_AUTH_TOKEN=%s", shellEscape(authToken))
+		} else if apiKey != "" {
+			// Fallback to API_KEY if AUTH_TOKEN not set
+			envVars += fmt.Sprintf(" ANTHROPIC_AUTH_TOKEN=%s", shellEscape(apiKey))
+		}
+	}
+
+	switch tool {
+	case "claude":
+		cmd = fmt.Sprintf("%s claude --dangerously-skip-permissions%s", envVars, modelArg)
+	case "gemini":
+		if task != "" {
+			escapedTask := shellEscape(task)
+			cmd = fmt.Sprintf("%s gemini --yolo%s --prompt-interactive %s", envVars, modelArg, escapedTask)
+		} else {
+			cmd = fmt.Sprintf("%s gemini --yolo%s", envVars, modelArg)
+		}
+	case "codex":
+		cmd = fmt.Sprintf("%s codex --dangerously-bypass-approvals-and-sandbox%s", envVars, modelArg)
+	case "opencode":
+		cmd = fmt.Sprintf("%s opencode%s", envVars, modelArg)
+	}
+
+	return cmd
+}
+
+// buildPrompt creates the initial prompt for tools that accept stdin.
+func buildPrompt(tool, task string) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("TASK: %s\n\n", task))
+	b.WriteString("You have full permissions. Complete the task.\n\n")
+	b.WriteString("⚠️  IMPORTANT: When you finish this task, you MUST publish a completion promise.\n")
+
+	if tool == "codex" {
+		b.WriteString("Run this shell command: coders promise \"Brief summary of what you accomplished\"\n")
+		b.WriteString("\nThis notifies the orchestrator and dashboard that your work is complete.\n")
+		b.WriteString("If you get blocked, use: coders promise \"Reason for being blocked\" --status blocked\n")
+	} else {
+		b.WriteString("/coders:promise \"Brief summary of what you accomplished\"\n")
+		b.WriteString("\nThis notifies the orchestrator and dashboard that your work is complete.\n")
+		b.WriteString("If you get blocked, use: /coders:promise \"Reason for being blocked\" --status blocked\n")
+	}
+
+	return b.String()
+}
+
+// resolveDirectory resolves a directory path, with zoxide support.
+func resolveDirectory(path string) (string, error) {
+	// First check if it's a direct path
+	if filepath.IsAbs(path) {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return path, nil
+		}
+	}
+
+	// Try relative to cwd
+	cwd, _ := os.Getwd()
+	absPath := filepath.Join(cwd, path)
+	if info, err := os.Stat(absPath); err == nil && info.IsDir() {
+		return absPath, nil
+	}
+
+	// Try zoxide
+	if isZoxideAvailable() {
+		out, err := exec.Command("zoxide", "query", path).Output()
+		if err == nil {
+			resolved := strings.TrimSpace(string(out))
+			if info, err := os.Stat(resolved); err == nil && info.IsDir() {
+				return resolved, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("directory not found: %s", path)
+}
+
+// isZoxideAvailable checks if zoxide is installed.
+func isZoxideAvailable() bool {
+	_, err := exec.LookPath("zoxide")
+	return err == nil
+}
+
+// applySandbox wraps the tool command in the sandbox policy from the config
+// file, confining writes to cwd.
+func applySandbox(toolCmd, cwd string, network bool) (string, sandbox.Backend, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to load config: %w", err)
+	}
+	backend, err := sandbox.Detect(cfg.Sandbox.Backend)
+	if err != nil {
+		return "", "", err
+	}
+	wrapped, err := sandbox.Wrap(backend, sandbox.NewPolicy(cfg.Sandbox, cwd, network), toolCmd)
+	if err != nil {
+		return "", "", err
+	}
+	return wrapped, backend, nil
+}
+
+// spawnResourceLimits returns the limits requested with --max-memory,
+// --max-cpu and --max-procs, or nil if there are none.
+func spawnResourceLimits() (*types.ResourceLimits, error) {
+	memory, err := limits.ParseMemory(spawnMaxMemory)
+	if err != nil {
+		return nil, fmt.Errorf("--max-memory: %w", err)
+	}
+	if spawnMaxCPU < 0 {
+		return nil, fmt.Errorf("--max-cpu must not be negative")
+	}
+	if spawnMaxProcs < 0 {
+		return nil, fmt.Errorf("--max-procs must not be negative")
+	}
+	l := types.ResourceLimits{MaxMemory: memory, MaxCPU: spawnMaxCPU, MaxProcs: spawnMaxProcs}
+	if l.IsZero() {
+		return nil, nil
+	}
+	return &l, nil
+}
+
+// applyResourceLimits wraps the tool command in l, recording how the limits
+// are enforced.
+func applyResourceLimits(sessionID, toolCmd string, l *types.ResourceLimits) string {
+	if l == nil {
+		return toolCmd
+	}
+	wrapped, method := limits.Wrap(sessionID, toolCmd, *l)
+	l.Method = string(method)
+	return wrapped
+}
+
+// describeLimits summarizes resource limits for display.
+func describeLimits(l types.ResourceLimits) string {
+	var parts []string
+	if l.MaxMemory > 0 {
+		parts = append(parts, limits.FormatMemory(l.MaxMemory)+" memory")
+	}
+	if l.MaxCPU > 0 {
+		parts = append(parts, strconv.FormatFloat(l.MaxCPU, 'f', -1, 64)+" CPUs")
+	}
+	if l.MaxProcs > 0 {
+		parts = append(parts, fmt.Sprintf("%d processes", l.MaxProcs))
+	}
+	desc := strings.Join(parts, ", ")
+	if l.Method != "" {
+		desc += " (" + l.Method + ")"
+	}
+	return desc
+}
+
+// waitForCLIReady waits for the CLI process to start in the session. It gives
+// up early if the tool exits before it's seen running.
+func waitForCLIReady(sessionID, tool string, timeout time.Duration) bool {
+	log := logging.WithCommand("spawn").WithSessionID(sessionID)
+
+	processNames := map[string]string{
+		"claude":   "claude",
+		"gemini":   "gemini",
+		"codex":    "codex",
+		"opencode": "opencode",
+	}
+	processName := processNames[tool]
+
+	start := time.Now()
+	iteration := 0
+
+	for time.Since(start) < timeout {
+		iteration++
+		iterStart := time.Now()
+
+		if code := crash.ReadExitCode(sessionID); code != nil {
+			log.Warnf("CLI exited with status %d before it was ready", *code)
+			return false
+		}
+
+		// Get pane PID
+		tmuxStart := time.Now()
+		pids, err := mux.PanePIDs(sessionID)
+		tmuxDuration := time.Since(tmuxStart)
+
+		if err != nil {
+			log.Debugf("iter %d: multiplexer error after %v: %v", iteration, tmuxDuration, err)
+			time.Sleep(100 * time.Millisecond) // Reduced from 500ms
+			continue
+		}
+
+		if len(pids) == 0 {
+			log.Debugf("iter %d: empty pane PID after %v", iteration, tmuxDuration)
+			time.Sleep(100 * time.Millisecond) // Reduced from 500ms
+			continue
+		}
+
+		// Look for the tool anywhere in the pane's process tree, so wrappers
+		// like "node /usr/bin/claude" or an extra shell level still match
+		procStart := time.Now()
+		tree, err := proc.Tree(pids[0])
+		if err != nil {
+			log.Debugf("iter %d: process table error after %v (tmux: %v): %v",
+				iteration, time.Since(procStart), tmuxDuration, err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		found := false
+		for _, p := range tree {
+			if !p.Alive() {
+				continue
+			}
+			// Unknown tools count as ready once the pane starts anything
+			if processName == "" {
+				found = p.PID != pids[0]
+			} else {
+				found = p.Runs(processName)
+			}
+			if found {
+				break
+			}
+		}
+
+		procDuration := time.Since(procStart)
+		iterDuration := time.Since(iterStart)
+		if found {
+			log.Infof("CLI ready after %d iterations, %v total (iter: %v, tmux: %v, proc: %v for %d processes)",
+				iteration, time.Since(start), iterDuration, tmuxDuration, procDuration, len(tree))
+			return true
+		}
+
+		log.Debugf("iter %d: no match in %d processes after %v (tmux: %v, proc: %v)",
+			iteration, len(tree), iterDuration, tmuxDuration, procDuration)
+
+		time.Sleep(100 * time.Millisecond) // Reduced from 500ms
+	}
+
+	totalDuration := time.Since(start)
+	log.Warnf("CLI not ready after %d iterations, %v total (timeout)", iteration, totalDuration)
+	return false
+}
+
+// shellEscape escapes a string for safe use in shell commands.
+func shellEscape(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "'\"'\"'") + "'"
+}
+
+// startHeartbeat starts a background heartbeat process for the session.
+func startHeartbeat(sessionID, task, parentSessionID string) error {
+	// Get the path to this executable
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	// Build heartbeat command args
+	args := []string{"heartbeat", "--session", sessionID}
+	if task != "" {
+		args = append(args, "--task", task)
+	}
+	if parentSessionID != "" {
+		args = append(args, "--parent", parentSessionID)
+	}
+
+	// Start heartbeat as a background process
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+	cmd.Stdin = nil
+
+	// Detach from parent process
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start heartbeat: %w", err)
+	}
+
+	// Don't wait for it - let it run in background
+	go func() {
+		cmd.Wait()
+	}()
+
+	return nil
+}
+
+// createWorktree creates a git worktree for isolated development.
+func createWorktree(basePath, sessionName string) (string, error) {
+	// Find git root
+	gitRoot, err := findGitRoot(basePath)
+	if err != nil {
+		return "", fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	// Create worktrees directory in git root
+	worktreesDir := filepath.Join(gitRoot, ".coders", "worktrees")
+	if err := os.MkdirAll(worktreesDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create worktrees directory: %w", err)
+	}
+
+	// Create worktree path
+	worktreePath := filepath.Join(worktreesDir, sessionName)
+
+	// Check if worktree already exists
+	if _, err := os.Stat(worktreePath); err == nil {
+		return "", fmt.Errorf("worktree already exists: %s", worktreePath)
+	}
+
+	// Create branch name
+	branchName := fmt.Sprintf("session/%s", sessionName)
+
+	// Check if branch already exists
+	checkBranchCmd := exec.Command("git", "-C", gitRoot, "rev-parse", "--verify", branchName)
+	if checkBranchCmd.Run() == nil {
+		return "", fmt.Errorf("branch already exists: %s", branchName)
+	}
+
+	// Create the worktree with a new branch
+	createCmd := exec.Command("git", "-C", gitRoot, "worktree", "add", "-b", branchName, worktreePath)
+	if output, err := createCmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to create worktree: %w\nOutput: %s", err, string(output))
+	}
+
+	return worktreePath, nil
+}
+
+// findGitRoot finds the root of the git repository.
+func findGitRoot(startPath string) (string, error) {
+	cmd := exec.Command("git", "-C", startPath, "rev-parse", "--show-toplevel")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/tmux"
+	"github.com/Jayphen/coders/internal/tui"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+func newTUICmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tui",
+		Short: "Launch the terminal user interface",
+		Long:  `Launch the interactive TUI for managing coder sessions.`,
+		RunE:  runTUI,
+	}
+}
+
+func runTUI(cmd *cobra.Command, args []string) error {
+	// If not inside the multiplexer or no TTY, launch TUI in its own session
+	if !mux.InsideSession() || !hasTTY() {
+		return launchInMuxSession()
+	}
+
+	// We're inside the multiplexer with a TTY - run the TUI directly
+	model := tui.NewModel(Version)
+	p := tea.NewProgram(
+		&model,
+		tea.WithAltScreen(),
+		tea.WithMouseCellMotion(),
+	)
+
+	if _, err := p.Run(); err != nil {
+		return fmt.Errorf("error running TUI: %w", err)
+	}
+
+	return nil
+}
+
+func hasTTY() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return (fi.Mode() & os.ModeCharDevice) != 0
+}
+
+func launchInMuxSession() error {
+	if mux.SessionExists(mux.TUISession) {
+		if hasTTY() {
+			// Session exists and we have a TTY, attach to it
+			return mux.AttachSession(mux.TUISession)
+		}
+		// No TTY - tell user how to attach
+		fmt.Printf("\033[32m✓ TUI session already running\033[0m\n")
+		fmt.Printf("  Attach with: %s\n", mux.AttachHint(mux.TUISession))
+		return nil
+	}
+
+	// Get the path to this executable
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	if hasTTY() {
+		// Create new session running the TUI and attach
+		if err := mux.CreateSession(mux.TUISession, "", shellEscape(exe)+" tui", types.SessionMeta{}); err != nil {
+			return fmt.Errorf("failed to create TUI session: %w", err)
+		}
+		return mux.AttachSession(mux.TUISession)
+	}
+
+	// No TTY - create detached session
+	if err := mux.CreateSession(mux.TUISession, "", tuiSessionCommand(exe), types.SessionMeta{}); err != nil {
+		return fmt.Errorf("failed to create TUI session: %w", err)
+	}
+
+	fmt.Printf("\033[32m✓ TUI session started\033[0m\n")
+	fmt.Printf("  Attach with: %s\n", mux.AttachHint(mux.TUISession))
+	return nil
+}
+
+// tuiSessionCommand is the command run in a detached TUI session. Under tmux
+// it waits for a client before starting, so the TUI doesn't render before
+// anyone is attached.
+func tuiSessionCommand(exe string) string {
+	if mux.Default().Name() != "tmux" {
+		return shellEscape(exe) + " tui"
+	}
+	return fmt.Sprintf("while [ $(%s list-clients -t %s 2>/dev/null | wc -l) -eq 0 ]; do sleep 0.1; done; %s tui",
+		tmux.ShellCommand(), mux.TUISession, shellEscape(exe))
+}
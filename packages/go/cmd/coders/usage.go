@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/tui"
+	"github.com/Jayphen/coders/internal/usage"
+)
+
+var (
+	usageSince string
+	usageBy    string
+	usageJSON  bool
+	usageCSV   bool
+)
+
+func newUsageCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Report the cost and tokens spent by sessions",
+		Long: `Report the cost and tokens spent by coder sessions, from the usage ledger.
+
+Heartbeats record the cost and tokens each session's tool reports, so
+sessions spawned with --heartbeat=false aren't counted, nor tools that don't
+print their usage. The ledger is stored under the state directory
+(~/.local/state/coders/usage by default).
+
+--since takes a duration (12h, 7d, 2w), a date (2026-01-31), "today" or
+"week" (since Monday). Spend is grouped by tool, loop, project (the
+session's git repository) or session. The daily and weekly budgets from the
+config file are shown after the report.
+
+Examples:
+  coders usage                      # Last 7 days by tool
+  coders usage --since today --by project
+  coders usage --since 30d --by loop --csv > loops.csv
+  coders usage --by session --json`,
+		RunE: runUsage,
+	}
+
+	cmd.Flags().StringVar(&usageSince, "since", "7d", "Start of the report: a duration, a date, today or week")
+	cmd.Flags().StringVar(&usageBy, "by", usage.ByTool, "Group by tool, loop, project or session")
+	cmd.Flags().BoolVar(&usageJSON, "json", false, "Output in JSON format")
+	cmd.Flags().BoolVar(&usageCSV, "csv", false, "Output in CSV format")
+
+	return cmd
+}
+
+func runUsage(cmd *cobra.Command, args []string) error {
+	if usageJSON && usageCSV {
+		return fmt.Errorf("--json and --csv can't be used together")
+	}
+	now := time.Now()
+	since, err := parseSince(usageSince, now)
+	if err != nil {
+		return err
+	}
+
+	// Read from the start of the week too, for the budgets
+	from := since
+	if week := usage.WeekStart(now); week.Before(from) {
+		from = week
+	}
+	entries, err := usage.Read(usage.Path(), from)
+	if err != nil {
+		return fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	var inRange []usage.Entry
+	for _, e := range entries {
+		if !e.Time.Before(since) {
+			inRange = append(inRange, e)
+		}
+	}
+
+	groups, err := usage.Summarize(inRange, usageBy)
+	if err != nil {
+		return err
+	}
+	var budgets []usage.BudgetStatus
+	if cfg, err := config.Get(); err == nil {
+		budgets = usage.CheckBudget(cfg.Budget, entries, now)
+	}
+
+	switch {
+	case usageJSON:
+		return printUsageJSON(since, groups, budgets)
+	case usageCSV:
+		return printUsageCSV(groups)
+	}
+	printUsageTable(since, groups, budgets)
+	return nil
+}
+
+// parseSince parses the start of a usage report.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	switch s {
+	case "today":
+		return usage.DayStart(now), nil
+	case "week":
+		return usage.WeekStart(now), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+	if n := len(s); n > 1 && (s[n-1] == 'd' || s[n-1] == 'w') {
+		if count, err := strconv.Atoi(s[:n-1]); err == nil && count >= 0 {
+			days := count
+			if s[n-1] == 'w' {
+				days *= 7
+			}
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: use a duration (12h, 7d, 2w), a date (2006-01-02), today or week", s)
+}
+
+func printUsageTable(since time.Time, groups []usage.Group, budgets []usage.BudgetStatus) {
+	fmt.Printf("Usage since %s by %s\n\n", since.Format("2006-01-02 15:04"), usageBy)
+	if len(groups) == 0 {
+		fmt.Println("No usage recorded")
+	} else {
+		header := fmt.Sprintf("%-40s %10s %12s %9s", strings.ToUpper(usageBy), "COST", "TOKENS", "SESSIONS")
+		fmt.Println(lipgloss.NewStyle().Bold(true).Foreground(tui.ColorGray).Render(header))
+		fmt.Println(strings.Repeat("-", 74))
+
+		var total usage.Group
+		for _, g := range groups {
+			fmt.Printf("%-40s %10s %12d %9d\n", usageKey(g.Key), formatCost(g.Cost), g.Tokens, g.Sessions)
+			total.Cost += g.Cost
+			total.Tokens += g.Tokens
+		}
+		fmt.Println(strings.Repeat("-", 74))
+		fmt.Printf("%-40s %10s %12d\n", "TOTAL", formatCost(total.Cost), total.Tokens)
+	}
+
+	if len(budgets) > 0 {
+		fmt.Println()
+		for _, b := range budgets {
+			line := fmt.Sprintf("%s budget: %s of %s", capitalize(b.Period), formatCost(b.Spent), formatCost(b.Cap))
+			if b.Reached() {
+				line = "\033[33m" + line + " (reached)\033[0m"
+			}
+			fmt.Println(line)
+		}
+	}
+}
+
+// usageKey shortens a group key for the table.
+func usageKey(key string) string {
+	key = strings.TrimPrefix(key, mux.SessionPrefix)
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(key, home+"/") {
+		key = "~" + key[len(home):]
+	}
+	if len(key) > 40 {
+		key = "..." + key[len(key)-37:]
+	}
+	return key
+}
+
+func printUsageJSON(since time.Time, groups []usage.Group, budgets []usage.BudgetStatus) error {
+	report := struct {
+		Since   time.Time            `json:"since"`
+		By      string               `json:"by"`
+		Groups  []usage.Group        `json:"groups"`
+		Budgets []usage.BudgetStatus `json:"budgets,omitempty"`
+	}{since, usageBy, groups, budgets}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func printUsageCSV(groups []usage.Group) error {
+	w := csv.NewWriter(os.Stdout)
+	_ = w.Write([]string{usageBy, "cost", "tokens", "sessions"})
+	for _, g := range groups {
+		_ = w.Write([]string{
+			g.Key,
+			strconv.FormatFloat(g.Cost, 'f', 4, 64),
+			strconv.Itoa(g.Tokens),
+			strconv.Itoa(g.Sessions),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func formatCost(cost float64) string {
+	return fmt.Sprintf("$%.2f", cost)
+}
+
+// formatBudgetCap describes a budget cap from the config.
+func formatBudgetCap(amount float64) string {
+	if amount <= 0 {
+		return "(none)"
+	}
+	return formatCost(amount)
+}
+
+// checkSpawnBudget checks the budgets before spawning. Once a cap is
+// reached it warns, or with the block action refuses to spawn.
+func checkSpawnBudget(budget config.BudgetConfig, ignore bool) error {
+	if budget.Daily <= 0 && budget.Weekly <= 0 {
+		return nil
+	}
+	now := time.Now()
+	entries, err := usage.Read(usage.Path(), usage.WeekStart(now))
+	if err != nil {
+		return nil
+	}
+	for _, b := range usage.CheckBudget(budget, entries, now) {
+		if !b.Reached() {
+			continue
+		}
+		msg := fmt.Sprintf("%s budget of %s reached (%s spent)", b.Period, formatCost(b.Cap), formatCost(b.Spent))
+		if budget.Action == usage.BudgetBlock && !ignore {
+			return fmt.Errorf("%s; use --ignore-budget to spawn anyway", msg)
+		}
+		fmt.Printf("\033[33m⚠️  %s\033[0m\n", capitalize(msg))
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/usage"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 3, 4, 15, 30, 0, 0, time.Local)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"7d", now.AddDate(0, 0, -7)},
+		{"2w", now.AddDate(0, 0, -14)},
+		{"12h", now.Add(-12 * time.Hour)},
+		{"today", time.Date(2026, 3, 4, 0, 0, 0, 0, time.Local)},
+		{"week", time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local)},
+		{"2026-02-01", time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := parseSince(tt.in, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseSince(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "soon", "xd", "-3d"} {
+		if _, err := parseSince(bad, now); err == nil {
+			t.Errorf("parseSince(%q) should fail", bad)
+		}
+	}
+}
+
+func TestCheckSpawnBudget(t *testing.T) {
+	t.Setenv("CODERS_STATE_DIR", t.TempDir())
+	if err := usage.Append(usage.Path(), usage.Entry{Time: time.Now(), Session: "coder-claude-a", Cost: 12}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkSpawnBudget(config.BudgetConfig{Daily: 20, Action: usage.BudgetBlock}, false); err != nil {
+		t.Errorf("expected no error under the cap, got %v", err)
+	}
+	err := checkSpawnBudget(config.BudgetConfig{Daily: 10, Action: usage.BudgetBlock}, false)
+	if err == nil || !strings.Contains(err.Error(), "daily budget of $10.00 reached ($12.00 spent)") {
+		t.Errorf("expected the daily cap to block, got %v", err)
+	}
+	if err := checkSpawnBudget(config.BudgetConfig{Daily: 10, Action: usage.BudgetBlock}, true); err != nil {
+		t.Errorf("expected --ignore-budget to spawn anyway, got %v", err)
+	}
+	if err := checkSpawnBudget(config.BudgetConfig{Weekly: 10, Action: usage.BudgetWarn}, false); err != nil {
+		t.Errorf("expected the warn action not to block, got %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -32,9 +33,50 @@ type Config struct {
 	// DefaultHeartbeat controls whether heartbeat is enabled by default
 	DefaultHeartbeat bool `yaml:"default_heartbeat"`
 
+	// Multiplexer is the terminal multiplexer that hosts sessions (tmux, zellij)
+	Multiplexer string `yaml:"multiplexer"`
+
+	// TmuxSocket runs sessions on a dedicated tmux server (tmux -L <socket>);
+	// empty uses the user's default server
+	TmuxSocket string `yaml:"tmux_socket"`
+
 	// Ollama configuration
 	Ollama OllamaConfig `yaml:"ollama"`
 
+	// Sessions configures parent/child session lifecycle
+	Sessions SessionsConfig `yaml:"sessions"`
+
+	// Transcripts configures full session output recording
+	Transcripts TranscriptsConfig `yaml:"transcripts"`
+
+	// CrashRecovery configures restart backoff and circuit breaking for --restart-on-crash
+	CrashRecovery CrashRecoveryConfig `yaml:"crash_recovery"`
+
+	// Remediation configures automatic actions taken by healthcheck --watch
+	Remediation RemediationConfig `yaml:"remediation"`
+
+	// Sandbox configures the isolation used by spawn --sandbox
+	Sandbox SandboxConfig `yaml:"sandbox"`
+
+	// TaskSources are the task sources browsed in the TUI's tasks tab, in
+	// the format of loop --source (e.g. "beads:cwd=/path/to/project")
+	TaskSources []string `yaml:"task_sources"`
+
+	// Models are the models offered for each tool in the TUI's spawn form
+	Models map[string][]string `yaml:"models"`
+
+	// SpawnTemplates are presets for the TUI's spawn form
+	SpawnTemplates []SpawnTemplate `yaml:"spawn_templates"`
+
+	// Budget caps the spend recorded in the usage ledger
+	Budget BudgetConfig `yaml:"budget"`
+
+	// Routing picks the tool loops fall back to when one runs out of quota
+	Routing RoutingConfig `yaml:"routing"`
+
+	// Notifications configures notification channels and routing
+	Notifications NotificationsConfig `yaml:"notifications"`
+
 	// Logging configuration
 	Logging LoggingConfig `yaml:"logging"`
 }
@@ -66,6 +108,294 @@ type LoggingConfig struct {
 	Compress bool `yaml:"compress"`
 }
 
+// SessionsConfig holds parent/child session lifecycle configuration.
+type SessionsConfig struct {
+	// KillChildrenOnParentExit kills child sessions when their parent session dies
+	KillChildrenOnParentExit bool `yaml:"kill_children_on_parent_exit"`
+
+	// NotifyParentOnPromise notifies the parent session when a child publishes a promise
+	NotifyParentOnPromise bool `yaml:"notify_parent_on_promise"`
+
+	// RestartContextLines is how many lines of pane output are included in
+	// the prompt when a crashed session is restarted without a resumable conversation
+	RestartContextLines int `yaml:"restart_context_lines"`
+}
+
+// TranscriptsConfig holds session transcript recording configuration.
+type TranscriptsConfig struct {
+	// Enabled records every spawned session's output via tmux pipe-pane
+	Enabled bool `yaml:"enabled"`
+
+	// MaxSize is the size in megabytes at which a transcript file is rotated
+	MaxSize int `yaml:"max_size"`
+
+	// MaxBackups is the number of rotated transcript files to keep per session
+	MaxBackups int `yaml:"max_backups"`
+}
+
+// CrashRecoveryConfig holds the restart policy used by the crash watcher.
+type CrashRecoveryConfig struct {
+	// BaseDelay is the delay before the first restart; it doubles on each restart
+	BaseDelay time.Duration `yaml:"base_delay"`
+
+	// MaxDelay caps the restart delay
+	MaxDelay time.Duration `yaml:"max_delay"`
+
+	// RateLimitDelay is the minimum delay after a crash caused by a rate limit
+	RateLimitDelay time.Duration `yaml:"rate_limit_delay"`
+
+	// CircuitBreakerCrashes stops restarting after this many crashes within
+	// CircuitBreakerWindow (0 disables the breaker)
+	CircuitBreakerCrashes int           `yaml:"circuit_breaker_crashes"`
+	CircuitBreakerWindow  time.Duration `yaml:"circuit_breaker_window"`
+}
+
+// RemediationConfig holds automatic remediation policies for unhealthy sessions.
+type RemediationConfig struct {
+	// Enabled turns on remediation in healthcheck --watch
+	Enabled bool `yaml:"enabled"`
+
+	// Rules are the escalation steps, matched by health status and duration
+	Rules []RemediationRule `yaml:"rules"`
+
+	// Grace is how long a session must stay healthy before its escalation
+	// starts over, so a nudge that briefly revives a session doesn't reset it
+	Grace time.Duration `yaml:"grace"`
+}
+
+// SandboxConfig holds the filesystem and network policy for sandboxed sessions.
+type SandboxConfig struct {
+	// Default sandboxes every spawned session unless --sandbox=false is given
+	Default bool `yaml:"default"`
+
+	// Backend is the sandbox implementation: auto, bwrap or unshare
+	Backend string `yaml:"backend"`
+
+	// Network allows network access; the tools need it to reach their APIs
+	Network bool `yaml:"network"`
+
+	// AllowMultiplexer lets the tool reach the tmux and zellij sockets, so
+	// coders commands it runs can manage sessions. Anything that can talk to
+	// the server can run commands outside the sandbox, so it is off by default.
+	AllowMultiplexer bool `yaml:"allow_multiplexer"`
+
+	// ReadWrite are paths writable in addition to the session's working
+	// directory, its git directory and the tools' own state directories
+	ReadWrite []string `yaml:"read_write"`
+
+	// ReadOnly are paths made read-only. bwrap already makes everything
+	// outside the writable paths read-only; unshare only protects $HOME.
+	ReadOnly []string `yaml:"read_only"`
+
+	// Hide are paths replaced with empty ones, e.g. credentials
+	Hide []string `yaml:"hide"`
+}
+
+// BudgetConfig holds the spending caps checked before spawning.
+type BudgetConfig struct {
+	// Daily caps the spend since midnight, in USD (0 for no cap)
+	Daily float64 `yaml:"daily"`
+
+	// Weekly caps the spend since Monday midnight, in USD (0 for no cap)
+	Weekly float64 `yaml:"weekly"`
+
+	// Action is what spawn does once a cap is reached: warn or block
+	Action string `yaml:"action"`
+}
+
+// RoutingConfig holds the rules that pick a tool for each task, and the
+// fallback chains loops switch tools along when a tool's quota runs out.
+type RoutingConfig struct {
+	// Rules pick the tool and model for loop tasks and for spawns without a
+	// tool; the first rule whose conditions all match wins
+	Rules []RoutingRule `yaml:"rules"`
+
+	// Fallback is the order tools are tried in after the task's own tool
+	Fallback []string `yaml:"fallback"`
+
+	// Projects overrides Fallback for loops working in a directory; the
+	// longest directory containing the loop's working directory wins
+	Projects map[string][]string `yaml:"projects"`
+
+	// SessionLimit and WeeklyLimit are the percentages of a tool's session
+	// and weekly quota, as reported in heartbeats, at which it is switched away from
+	SessionLimit float64 `yaml:"session_limit"`
+	WeeklyLimit  float64 `yaml:"weekly_limit"`
+
+	// SessionReset and WeeklyReset are how long a tool that hit a limit is
+	// avoided, unless a heartbeat shows its quota below the limit sooner
+	SessionReset time.Duration `yaml:"session_reset"`
+	WeeklyReset  time.Duration `yaml:"weekly_reset"`
+}
+
+// RoutingRule sends the tasks matching all of its conditions to a tool.
+type RoutingRule struct {
+	// Match is a space-separated list of conditions: label:<name>, P0-P4,
+	// source:<type>, title:<keyword> or path:<glob> (a file path mentioned
+	// in the task)
+	Match string `yaml:"match"`
+
+	// Tool is the tool the task is run with
+	Tool string `yaml:"tool"`
+
+	// Model is passed to the tool, if set
+	Model string `yaml:"model,omitempty"`
+}
+
+// DefaultRoutingFallback returns the fallback chain used when none is
+// configured.
+func DefaultRoutingFallback() []string {
+	return []string{"claude", "codex"}
+}
+
+// SpawnTemplate is a named preset for the TUI's spawn form. Empty fields
+// keep the form's defaults.
+type SpawnTemplate struct {
+	Name           string `yaml:"name"`
+	Tool           string `yaml:"tool"`
+	Model          string `yaml:"model"`
+	Task           string `yaml:"task"`
+	Cwd            string `yaml:"cwd"`
+	Worktree       bool   `yaml:"worktree"`
+	RestartOnCrash bool   `yaml:"restart_on_crash"`
+	Heartbeat      *bool  `yaml:"heartbeat"`
+}
+
+// DefaultModels returns the model aliases the tools accept without
+// further configuration.
+func DefaultModels() map[string][]string {
+	return map[string][]string{
+		"claude": {"opus", "sonnet", "haiku"},
+	}
+}
+
+// DefaultSandboxHide returns credentials hidden from sandboxed sessions.
+func DefaultSandboxHide() []string {
+	return []string{
+		"~/.ssh", "~/.gnupg", "~/.aws", "~/.config/gcloud", "~/.azure",
+		"~/.kube", "~/.docker", "~/.netrc", "~/.git-credentials",
+	}
+}
+
+// RemediationRule describes one remediation step.
+type RemediationRule struct {
+	// Status is the health status this rule applies to (stuck, stale, dead, unresponsive, waiting-input, over-limit)
+	Status string `yaml:"status"`
+
+	// After is how long the session must have had that status before acting.
+	// For stuck, it counts from when the output stopped changing.
+	After time.Duration `yaml:"after"`
+
+	// Action is what to do: nudge, escape, restart or kill
+	Action string `yaml:"action"`
+
+	// Message is the text sent by nudge and escape, or the blocked reason for kill
+	Message string `yaml:"message,omitempty"`
+}
+
+// Remediation actions
+const (
+	RemediationNudge   = "nudge"
+	RemediationEscape  = "escape"
+	RemediationRestart = "restart"
+	RemediationKill    = "kill"
+)
+
+// DefaultRemediationRules returns the default escalation ladder for stuck sessions.
+func DefaultRemediationRules() []RemediationRule {
+	return []RemediationRule{
+		{Status: "stuck", After: 5 * time.Minute, Action: RemediationNudge},
+		{Status: "stuck", After: 10 * time.Minute, Action: RemediationEscape},
+		{Status: "stuck", After: 20 * time.Minute, Action: RemediationRestart},
+		{Status: "stuck", After: 30 * time.Minute, Action: RemediationKill},
+	}
+}
+
+// NotificationsConfig holds notification channels and the rules that route events to them.
+type NotificationsConfig struct {
+	// Channels are named notification backends
+	Channels map[string]NotificationChannel `yaml:"channels"`
+
+	// Rules map events to channels; every matching rule is applied
+	Rules []NotificationRule `yaml:"rules"`
+
+	// QuietHours suppresses notifications during a daily window
+	QuietHours QuietHoursConfig `yaml:"quiet_hours"`
+
+	// DedupWindow suppresses identical notifications to a channel within this window
+	DedupWindow time.Duration `yaml:"dedup_window"`
+}
+
+// NotificationChannel configures a single notification backend.
+type NotificationChannel struct {
+	// Type is the backend: desktop, webhook, slack, ntfy, gotify, email or exec
+	Type string `yaml:"type"`
+
+	// URL is the webhook URL, or the ntfy/gotify server URL
+	URL string `yaml:"url,omitempty"`
+
+	// Headers are extra HTTP headers sent with webhook requests
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// Topic is the ntfy topic
+	Topic string `yaml:"topic,omitempty"`
+
+	// Token is the ntfy access token or gotify application token
+	Token string `yaml:"token,omitempty"`
+
+	// Command is the shell command run by the exec backend
+	Command string `yaml:"command,omitempty"`
+
+	// SMTPHost and SMTPPort are the mail server for the email backend
+	SMTPHost string `yaml:"smtp_host,omitempty"`
+	SMTPPort int    `yaml:"smtp_port,omitempty"`
+
+	// Username and Password authenticate with the mail server
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+
+	// From and To are the email sender and recipients
+	From string   `yaml:"from,omitempty"`
+	To   []string `yaml:"to,omitempty"`
+}
+
+// NotificationRule routes matching events to channels.
+type NotificationRule struct {
+	// Events are event types (e.g. promise.completed, session.crashed), or "*" for all
+	Events []string `yaml:"events"`
+
+	// Channels are the names of channels to deliver to
+	Channels []string `yaml:"channels"`
+
+	// MinDuration only matches events whose condition has lasted at least this long
+	// (e.g. stuck for more than 30m)
+	MinDuration time.Duration `yaml:"min_duration,omitempty"`
+
+	// IgnoreQuietHours delivers matching events even during quiet hours
+	IgnoreQuietHours bool `yaml:"ignore_quiet_hours,omitempty"`
+}
+
+// QuietHoursConfig is a daily window, in local time, when notifications are held back.
+type QuietHoursConfig struct {
+	// Start and End are HH:MM times; the window may wrap past midnight
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// DefaultNotificationChannels returns the built-in desktop channel.
+func DefaultNotificationChannels() map[string]NotificationChannel {
+	return map[string]NotificationChannel{
+		"desktop": {Type: "desktop"},
+	}
+}
+
+// DefaultNotificationRules sends every event to the desktop.
+func DefaultNotificationRules() []NotificationRule {
+	return []NotificationRule{
+		{Events: []string{"*"}, Channels: []string{"desktop"}},
+	}
+}
+
 // OllamaConfig holds Ollama-specific configuration.
 type OllamaConfig struct {
 	// BaseURL is the Ollama API base URL
@@ -94,6 +424,61 @@ const (
 	DefaultLogCompress        = true
 )
 
+// DefaultMultiplexer is the terminal multiplexer used when none is configured.
+const DefaultMultiplexer = "tmux"
+
+// Default session lifecycle values
+const (
+	DefaultKillChildrenOnParentExit = false
+	DefaultNotifyParentOnPromise    = true
+	DefaultRestartContextLines      = 50
+)
+
+// Default transcript values
+const (
+	DefaultTranscriptsEnabled   = true
+	DefaultTranscriptMaxSize    = 10 // 10 MB
+	DefaultTranscriptMaxBackups = 3
+)
+
+// Default crash recovery values
+const (
+	DefaultCrashBaseDelay        = 5 * time.Second
+	DefaultCrashMaxDelay         = 10 * time.Minute
+	DefaultCrashRateLimitDelay   = time.Minute
+	DefaultCircuitBreakerCrashes = 3
+	DefaultCircuitBreakerWindow  = 10 * time.Minute
+)
+
+// Default remediation values
+const (
+	DefaultRemediationEnabled = false
+	DefaultRemediationGrace   = 10 * time.Minute
+)
+
+// Default sandbox values
+const (
+	DefaultSandboxDefault = false
+	DefaultSandboxBackend = "auto"
+	DefaultSandboxNetwork = true
+
+	DefaultSandboxAllowMultiplexer = false
+)
+
+// DefaultBudgetAction is what spawn does once a budget cap is reached.
+const DefaultBudgetAction = "warn"
+
+// Default routing values
+const (
+	DefaultRoutingSessionLimit = 90
+	DefaultRoutingWeeklyLimit  = 90
+	DefaultRoutingSessionReset = 5 * time.Hour
+	DefaultRoutingWeeklyReset  = 24 * time.Hour
+)
+
+// DefaultNotificationDedupWindow is how long identical notifications are suppressed.
+const DefaultNotificationDedupWindow = 15 * time.Minute
+
 var (
 	globalConfig *Config
 	configOnce   sync.Once
@@ -132,6 +517,53 @@ func Load() (*Config, error) {
 		DashboardPort:     DefaultDashboardPort,
 		DefaultModel:      DefaultDefaultModel,
 		DefaultHeartbeat:  DefaultDefaultHeartbeat,
+		Multiplexer:       DefaultMultiplexer,
+		Sessions: SessionsConfig{
+			KillChildrenOnParentExit: DefaultKillChildrenOnParentExit,
+			NotifyParentOnPromise:    DefaultNotifyParentOnPromise,
+			RestartContextLines:      DefaultRestartContextLines,
+		},
+		Transcripts: TranscriptsConfig{
+			Enabled:    DefaultTranscriptsEnabled,
+			MaxSize:    DefaultTranscriptMaxSize,
+			MaxBackups: DefaultTranscriptMaxBackups,
+		},
+		CrashRecovery: CrashRecoveryConfig{
+			BaseDelay:             DefaultCrashBaseDelay,
+			MaxDelay:              DefaultCrashMaxDelay,
+			RateLimitDelay:        DefaultCrashRateLimitDelay,
+			CircuitBreakerCrashes: DefaultCircuitBreakerCrashes,
+			CircuitBreakerWindow:  DefaultCircuitBreakerWindow,
+		},
+		Remediation: RemediationConfig{
+			Enabled: DefaultRemediationEnabled,
+			Rules:   DefaultRemediationRules(),
+			Grace:   DefaultRemediationGrace,
+		},
+		Sandbox: SandboxConfig{
+			Default:   DefaultSandboxDefault,
+			Backend:   DefaultSandboxBackend,
+			Network:   DefaultSandboxNetwork,
+			Hide:      DefaultSandboxHide(),
+
+			AllowMultiplexer: DefaultSandboxAllowMultiplexer,
+		},
+		Models: DefaultModels(),
+		Budget: BudgetConfig{
+			Action: DefaultBudgetAction,
+		},
+		Routing: RoutingConfig{
+			Fallback:     DefaultRoutingFallback(),
+			SessionLimit: DefaultRoutingSessionLimit,
+			WeeklyLimit:  DefaultRoutingWeeklyLimit,
+			SessionReset: DefaultRoutingSessionReset,
+			WeeklyReset:  DefaultRoutingWeeklyReset,
+		},
+		Notifications: NotificationsConfig{
+			Channels:    DefaultNotificationChannels(),
+			Rules:       DefaultNotificationRules(),
+			DedupWindow: DefaultNotificationDedupWindow,
+		},
 		Logging: LoggingConfig{
 			Level:      DefaultLogLevel,
 			JSON:       DefaultLogJSON,
@@ -211,6 +643,14 @@ func (c *Config) applyEnvOverrides() {
 		c.DefaultHeartbeat = val == "true" || val == "1" || val == "yes"
 	}
 
+	// Multiplexer
+	if val := os.Getenv("CODERS_MULTIPLEXER"); val != "" {
+		c.Multiplexer = val
+	}
+	if val := os.Getenv("CODERS_TMUX_SOCKET"); val != "" {
+		c.TmuxSocket = val
+	}
+
 	// Ollama settings
 	if val := os.Getenv("CODERS_OLLAMA_BASE_URL"); val != "" {
 		c.Ollama.BaseURL = val
@@ -222,6 +662,94 @@ func (c *Config) applyEnvOverrides() {
 		c.Ollama.APIKey = val
 	}
 
+	// Session lifecycle settings
+	if val := os.Getenv("CODERS_KILL_CHILDREN_ON_PARENT_EXIT"); val != "" {
+		c.Sessions.KillChildrenOnParentExit = val == "true" || val == "1" || val == "yes"
+	}
+	if val := os.Getenv("CODERS_NOTIFY_PARENT_ON_PROMISE"); val != "" {
+		c.Sessions.NotifyParentOnPromise = val == "true" || val == "1" || val == "yes"
+	}
+	if val := os.Getenv("CODERS_TRANSCRIPTS_ENABLED"); val != "" {
+		c.Transcripts.Enabled = val == "true" || val == "1" || val == "yes"
+	}
+	if val := os.Getenv("CODERS_RESTART_CONTEXT_LINES"); val != "" {
+		if lines, err := strconv.Atoi(val); err == nil {
+			c.Sessions.RestartContextLines = lines
+		}
+	}
+
+	// Remediation
+	if val := os.Getenv("CODERS_REMEDIATION_ENABLED"); val != "" {
+		c.Remediation.Enabled = val == "true" || val == "1" || val == "yes"
+	}
+
+	// Sandbox
+	if val := os.Getenv("CODERS_SANDBOX"); val != "" {
+		c.Sandbox.Default = val == "true" || val == "1" || val == "yes"
+	}
+	if val := os.Getenv("CODERS_SANDBOX_BACKEND"); val != "" {
+		c.Sandbox.Backend = val
+	}
+	if val := os.Getenv("CODERS_SANDBOX_NETWORK"); val != "" {
+		c.Sandbox.Network = val == "true" || val == "1" || val == "yes"
+	}
+	if val := os.Getenv("CODERS_SANDBOX_ALLOW_MULTIPLEXER"); val != "" {
+		c.Sandbox.AllowMultiplexer = val == "true" || val == "1" || val == "yes"
+	}
+
+	// Task sources, separated by ";" since specs contain commas
+	if val := os.Getenv("CODERS_TASK_SOURCES"); val != "" {
+		c.TaskSources = nil
+		for _, spec := range strings.Split(val, ";") {
+			if spec = strings.TrimSpace(spec); spec != "" {
+				c.TaskSources = append(c.TaskSources, spec)
+			}
+		}
+	}
+
+	// Budget
+	if val := os.Getenv("CODERS_BUDGET_DAILY"); val != "" {
+		if amount, err := strconv.ParseFloat(val, 64); err == nil {
+			c.Budget.Daily = amount
+		}
+	}
+	if val := os.Getenv("CODERS_BUDGET_WEEKLY"); val != "" {
+		if amount, err := strconv.ParseFloat(val, 64); err == nil {
+			c.Budget.Weekly = amount
+		}
+	}
+	if val := os.Getenv("CODERS_BUDGET_ACTION"); val != "" {
+		c.Budget.Action = val
+	}
+
+	// Routing
+	if val := os.Getenv("CODERS_ROUTING_FALLBACK"); val != "" {
+		c.Routing.Fallback = nil
+		for _, tool := range strings.Split(val, ",") {
+			if tool = strings.TrimSpace(tool); tool != "" {
+				c.Routing.Fallback = append(c.Routing.Fallback, tool)
+			}
+		}
+	}
+	if val := os.Getenv("CODERS_ROUTING_SESSION_LIMIT"); val != "" {
+		if pct, err := strconv.ParseFloat(val, 64); err == nil {
+			c.Routing.SessionLimit = pct
+		}
+	}
+	if val := os.Getenv("CODERS_ROUTING_WEEKLY_LIMIT"); val != "" {
+		if pct, err := strconv.ParseFloat(val, 64); err == nil {
+			c.Routing.WeeklyLimit = pct
+		}
+	}
+
+	// Notifications
+	if val := os.Getenv("CODERS_NOTIFY_WEBHOOK_URL"); val != "" {
+		c.addEnvNotificationChannel("webhook", NotificationChannel{Type: "webhook", URL: val})
+	}
+	if val := os.Getenv("CODERS_NOTIFY_SLACK_URL"); val != "" {
+		c.addEnvNotificationChannel("slack", NotificationChannel{Type: "slack", URL: val})
+	}
+
 	// Logging settings
 	if val := os.Getenv("CODERS_LOG_LEVEL"); val != "" {
 		c.Logging.Level = val
@@ -255,6 +783,19 @@ func (c *Config) applyEnvOverrides() {
 	}
 }
 
+// addEnvNotificationChannel adds a channel configured from the environment and
+// routes every event to it.
+func (c *Config) addEnvNotificationChannel(name string, channel NotificationChannel) {
+	if c.Notifications.Channels == nil {
+		c.Notifications.Channels = make(map[string]NotificationChannel)
+	}
+	c.Notifications.Channels[name] = channel
+	c.Notifications.Rules = append(c.Notifications.Rules, NotificationRule{
+		Events:   []string{"*"},
+		Channels: []string{name},
+	})
+}
+
 // Reload forces a reload of the configuration.
 // This resets the global singleton and returns the newly loaded config.
 func Reload() (*Config, error) {
@@ -262,6 +803,22 @@ func Reload() (*Config, error) {
 	return Get()
 }
 
+// StateDir returns the directory for runtime state such as exit codes.
+// Uses CODERS_STATE_DIR, then $XDG_STATE_HOME/coders, then ~/.local/state/coders.
+func StateDir() string {
+	if dir := os.Getenv("CODERS_STATE_DIR"); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "coders")
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "coders")
+	}
+	return filepath.Join(homeDir, ".local", "state", "coders")
+}
+
 // ConfigPaths returns the paths where config files are searched.
 func ConfigPaths() []string {
 	homeDir, err := os.UserHomeDir()
@@ -298,12 +855,226 @@ default_model: ""
 # Enable heartbeat monitoring by default
 default_heartbeat: true
 
+# Terminal multiplexer that hosts sessions (tmux, zellij).
+# Transcripts and in-session messages are only available with tmux.
+multiplexer: tmux
+
+# Run tmux sessions on a dedicated server socket (tmux -L coders) instead of
+# the default tmux server. Attach with 'coders attach' or 'tmux -L coders attach'.
+tmux_socket: ""
+
 # Ollama configuration (for using Ollama as backend)
 ollama:
   base_url: ""
   auth_token: ""
   api_key: ""
 
+# Parent/child session lifecycle
+sessions:
+  # Kill child sessions when their parent session dies
+  kill_children_on_parent_exit: false
+  # Show a tmux message in the parent session when a child publishes a promise
+  notify_parent_on_promise: true
+  # Lines of pane output included in the restart prompt when a crashed
+  # session cannot resume its conversation
+  restart_context_lines: 50
+
+# Full session transcripts, recorded with tmux pipe-pane under
+# ~/.local/state/coders/transcripts and read with 'coders logs'.
+# Transcripts are archived when a session is killed.
+transcripts:
+  enabled: true
+  # Rotate transcript files at this size (MB), keeping max_backups old files
+  max_size: 10
+  max_backups: 3
+
+# Restart policy for sessions spawned with --restart-on-crash.
+# Crashes are classified (process-exit, oom, auth, rate-limit, network,
+# clean-exit). Auth failures are never restarted; rate limits wait at least
+# rate_limit_delay. Delays double on each restart (with jitter) up to
+# max_delay. After circuit_breaker_crashes crashes within
+# circuit_breaker_window the watcher gives up.
+crash_recovery:
+  base_delay: 5s
+  max_delay: 10m
+  rate_limit_delay: 1m
+  circuit_breaker_crashes: 3
+  circuit_breaker_window: 10m
+
+# Automatic remediation by 'coders healthcheck --watch'
+# Rules match a health status (stuck, stale, dead, unresponsive, waiting-input,
+# over-limit) and how long the session has had it; stuck counts from when the
+# output stopped changing. Actions: nudge (send a message), escape (press
+# Escape, then send a message), restart (restart from stored session state),
+# kill (kill the session and publish a blocked promise).
+# Each step runs once per episode; the episode only ends once the session has
+# stayed healthy for the grace period. Restarts count against the session's
+# --max-restarts budget.
+remediation:
+  enabled: false
+  grace: 10m
+  rules:
+    - status: stuck
+      after: 5m
+      action: nudge
+    - status: stuck
+      after: 10m
+      action: escape
+    - status: stuck
+      after: 20m
+      action: restart
+    - status: stuck
+      after: 30m
+      action: kill
+
+# Isolation for sessions spawned with --sandbox (Linux only).
+# bwrap (bubblewrap) mounts the whole filesystem read-only with a private
+# /tmp; unshare (user namespaces, util-linux 2.38+) only makes $HOME and
+# read_only paths read-only. Either way the session's working directory, its
+# git directory, the tools' state directories and read_write paths stay
+# writable, and hide paths are replaced with empty ones. Without network,
+# the tool can't reach its API or Redis, so promises can't be published.
+#
+# The tmux and zellij sockets are hidden unless allow_multiplexer is set: a
+# tool that can reach them can run commands outside the sandbox, but without
+# them coders commands it runs can't manage sessions.
+#
+# Sandboxed claude sessions get their own config directory in the coders
+# state directory. They share transcripts, todos and the login with
+# ~/.claude, see its settings, CLAUDE.md, commands, agents, skills, plugins
+# and hooks read-only, and start from a copy of ~/.claude.json whose changes
+# aren't kept.
+sandbox:
+  default: false
+  backend: auto
+  network: true
+  allow_multiplexer: false
+  read_write: []
+  read_only: []
+  hide:
+    - ~/.ssh
+    - ~/.gnupg
+    - ~/.aws
+    - ~/.config/gcloud
+    - ~/.azure
+    - ~/.kube
+    - ~/.docker
+    - ~/.netrc
+    - ~/.git-credentials
+
+# Task sources browsed in the TUI's tasks tab (press 3), in the format of
+# coders loop --source: todolist:path=..., beads:cwd=..., linear:team=...,
+# github:owner=...,repo=...
+task_sources: []
+#  - beads:cwd=/home/me/projects/myapp
+#  - github:owner=me,repo=myapp
+
+# Models offered for each tool in the TUI's spawn form
+models:
+  claude: [opus, sonnet, haiku]
+#  gemini: [gemini-2.5-pro, gemini-2.5-flash]
+
+# Presets for the TUI's spawn form; empty fields keep the form's defaults
+spawn_templates: []
+#  - name: review
+#    tool: claude
+#    model: opus
+#    task: Review the changes on this branch and list any bugs
+#  - name: feature
+#    tool: claude
+#    worktree: true
+#    restart_on_crash: true
+
+# Spending caps, in USD, checked by spawn against the usage ledger
+# ('coders usage'). The ledger records the cost and tokens each session's
+# tool reports, so sessions without heartbeats aren't counted. The day
+# starts at midnight and the week on Monday, local time. With action warn
+# spawn prints a warning once a cap is reached; with block it refuses to
+# spawn unless --ignore-budget is given. 0 means no cap.
+budget:
+  daily: 0
+  weekly: 0
+  action: warn
+
+# Tool routing. Rules pick the tool, and optionally the model, for each loop
+# task and for spawns without a tool: the first rule whose conditions all
+# match wins, otherwise the loop's --tool or default_tool is used.
+# Conditions: label:<name>, P0-P4 (priority), source:<type> (beads, linear,
+# github, todolist), title:<keyword> and path:<glob>, matched against file
+# paths mentioned in the task ('dir/**' matches everything under dir).
+# 'coders route explain' shows which rule a task matches.
+#
+# Tool fallback for loops. After each task the loop checks the session and
+# weekly quota percentages its tool reported in heartbeats (and the pane for
+# usage limit warnings); a tool at a limit is avoided for the reset period,
+# or until a heartbeat shows it under the limit again, and the loop moves
+# along the chain: the task's tool first (from the rules or --tool), then
+# fallback in order. Once the preferred tool's quota resets, tasks go back
+# to it. Every switch is
+# recorded in the loop state ('coders loop-status').
+routing:
+  rules: []
+  #  - match: label:frontend
+  #    tool: gemini
+  #  - match: P0
+  #    tool: claude
+  #    model: opus
+  #  - match: label:tests
+  #    tool: codex
+  #  - match: source:github path:web/**
+  #    tool: gemini
+  fallback: [claude, codex]
+  session_limit: 90
+  weekly_limit: 90
+  session_reset: 5h
+  weekly_reset: 24h
+  # Chains for loops working under a directory
+  projects: {}
+  #  ~/work/frontend: [claude, gemini, codex]
+
+# Notification channels and routing
+# Event types: promise.completed, promise.blocked, promise.needs-review,
+# session.crashed, session.max-restarts, session.stuck,
+# session.waiting-input, session.over-limit, loop.finished (or "*" for all)
+notifications:
+  channels:
+    desktop:
+      type: desktop
+    # team:
+    #   type: slack
+    #   url: https://hooks.slack.com/services/XXX/YYY/ZZZ
+    # phone:
+    #   type: ntfy
+    #   url: https://ntfy.sh
+    #   topic: my-coders
+    # hook:
+    #   type: webhook
+    #   url: http://localhost:8080/coders
+    # mail:
+    #   type: email
+    #   smtp_host: smtp.example.com
+    #   smtp_port: 587
+    #   username: me@example.com
+    #   password: secret
+    #   from: coders@example.com
+    #   to: [me@example.com]
+    # script:
+    #   type: exec
+    #   command: ~/bin/on-coders-event.sh
+  rules:
+    - events: ["*"]
+      channels: [desktop]
+    # - events: [promise.blocked, session.crashed, session.max-restarts]
+    #   channels: [phone]
+    #   ignore_quiet_hours: true
+    # - events: [session.stuck]
+    #   min_duration: 30m
+    #   channels: [team]
+  # quiet_hours:
+  #   start: "22:00"
+  #   end: "07:00"
+  dedup_window: 15m
+
 # Logging configuration
 logging:
   # Log level (debug, info, warn, error)
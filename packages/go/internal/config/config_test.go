@@ -3,9 +3,12 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -52,12 +55,20 @@ func TestEnvOverrides(t *testing.T) {
 	os.Setenv("CODERS_REDIS_URL", "redis://custom:6380")
 	os.Setenv("CODERS_DASHBOARD_PORT", "8080")
 	os.Setenv("CODERS_DEFAULT_HEARTBEAT", "false")
+	os.Setenv("CODERS_TASK_SOURCES", "beads:cwd=/src/app; github:owner=me,repo=app")
+	os.Setenv("CODERS_BUDGET_DAILY", "12.5")
+	os.Setenv("CODERS_BUDGET_ACTION", "block")
+	os.Setenv("CODERS_ROUTING_FALLBACK", "codex, gemini")
 	defer func() {
+		os.Unsetenv("CODERS_ROUTING_FALLBACK")
+		os.Unsetenv("CODERS_BUDGET_DAILY")
+		os.Unsetenv("CODERS_BUDGET_ACTION")
 		os.Unsetenv("CODERS_DEFAULT_TOOL")
 		os.Unsetenv("CODERS_HEARTBEAT_INTERVAL")
 		os.Unsetenv("CODERS_REDIS_URL")
 		os.Unsetenv("CODERS_DASHBOARD_PORT")
 		os.Unsetenv("CODERS_DEFAULT_HEARTBEAT")
+		os.Unsetenv("CODERS_TASK_SOURCES")
 	}()
 
 	cfg, err := Load()
@@ -84,6 +95,21 @@ func TestEnvOverrides(t *testing.T) {
 	if cfg.DefaultHeartbeat != false {
 		t.Errorf("DefaultHeartbeat = %t, want %t", cfg.DefaultHeartbeat, false)
 	}
+
+	if got := strings.Join(cfg.TaskSources, "|"); got != "beads:cwd=/src/app|github:owner=me,repo=app" {
+		t.Errorf("TaskSources = %q", cfg.TaskSources)
+	}
+
+	if cfg.Budget.Daily != 12.5 || cfg.Budget.Weekly != 0 || cfg.Budget.Action != "block" {
+		t.Errorf("Budget = %+v, want daily 12.5, no weekly cap, block", cfg.Budget)
+	}
+
+	if got := strings.Join(cfg.Routing.Fallback, ","); got != "codex,gemini" {
+		t.Errorf("Routing.Fallback = %q, want codex,gemini", got)
+	}
+	if cfg.Routing.SessionLimit != DefaultRoutingSessionLimit {
+		t.Errorf("Routing.SessionLimit = %v, want the default", cfg.Routing.SessionLimit)
+	}
 }
 
 func TestHeartbeatIntervalSeconds(t *testing.T) {
@@ -147,3 +173,96 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func TestRemediationRules(t *testing.T) {
+	cfg := &Config{Remediation: RemediationConfig{Rules: DefaultRemediationRules()}}
+
+	data := []byte(`
+remediation:
+  enabled: true
+  grace: 2m
+  rules:
+    - status: dead
+      after: 90s
+      action: restart
+    - status: stuck
+      after: 15m
+      action: nudge
+      message: "Keep going"
+`)
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		t.Fatalf("yaml.Unmarshal() failed: %v", err)
+	}
+
+	if !cfg.Remediation.Enabled {
+		t.Error("Remediation.Enabled = false, want true")
+	}
+	if len(cfg.Remediation.Rules) != 2 {
+		t.Fatalf("len(Rules) = %d, want 2 (configured rules replace defaults)", len(cfg.Remediation.Rules))
+	}
+	if cfg.Remediation.Rules[0].After != 90*time.Second {
+		t.Errorf("Rules[0].After = %v, want %v", cfg.Remediation.Rules[0].After, 90*time.Second)
+	}
+	if cfg.Remediation.Rules[1].Message != "Keep going" {
+		t.Errorf("Rules[1].Message = %q, want %q", cfg.Remediation.Rules[1].Message, "Keep going")
+	}
+	if cfg.Remediation.Grace != 2*time.Minute {
+		t.Errorf("Grace = %v, want %v", cfg.Remediation.Grace, 2*time.Minute)
+	}
+}
+
+func TestWriteExampleParses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := WriteExample(path); err != nil {
+		t.Fatalf("WriteExample() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("example config does not parse: %v", err)
+	}
+	if len(cfg.Remediation.Rules) != len(DefaultRemediationRules()) {
+		t.Errorf("example has %d remediation rules, want %d", len(cfg.Remediation.Rules), len(DefaultRemediationRules()))
+	}
+	if cfg.Remediation.Grace != DefaultRemediationGrace {
+		t.Errorf("example remediation grace = %v, want %v", cfg.Remediation.Grace, DefaultRemediationGrace)
+	}
+	if cfg.CrashRecovery.MaxDelay != DefaultCrashMaxDelay || cfg.CrashRecovery.CircuitBreakerWindow != DefaultCircuitBreakerWindow {
+		t.Errorf("example crash_recovery = %+v, want defaults", cfg.CrashRecovery)
+	}
+	if len(cfg.Sandbox.ReadWrite) != 0 || strings.Join(cfg.Sandbox.Hide, ",") != strings.Join(DefaultSandboxHide(), ",") {
+		t.Errorf("example sandbox paths = %+v, want defaults", cfg.Sandbox)
+	}
+	if cfg.Sandbox.Backend != DefaultSandboxBackend || cfg.Sandbox.Network != DefaultSandboxNetwork ||
+		cfg.Sandbox.AllowMultiplexer != DefaultSandboxAllowMultiplexer {
+		t.Errorf("example sandbox = %+v, want defaults", cfg.Sandbox)
+	}
+	if cfg.Budget.Action != DefaultBudgetAction {
+		t.Errorf("example budget = %+v, want the default action", cfg.Budget)
+	}
+	if strings.Join(cfg.Routing.Fallback, ",") != strings.Join(DefaultRoutingFallback(), ",") ||
+		cfg.Routing.SessionReset != DefaultRoutingSessionReset || cfg.Routing.WeeklyReset != DefaultRoutingWeeklyReset {
+		t.Errorf("example routing = %+v, want defaults", cfg.Routing)
+	}
+	if strings.Join(cfg.Models["claude"], ",") != strings.Join(DefaultModels()["claude"], ",") {
+		t.Errorf("example models = %v, want defaults", cfg.Models)
+	}
+}
+
+func TestStateDir(t *testing.T) {
+	t.Setenv("CODERS_STATE_DIR", "/tmp/coders-state")
+	if got := StateDir(); got != "/tmp/coders-state" {
+		t.Errorf("StateDir() = %q with CODERS_STATE_DIR set", got)
+	}
+
+	t.Setenv("CODERS_STATE_DIR", "")
+	t.Setenv("XDG_STATE_HOME", "/tmp/xdg-state")
+	if got := StateDir(); got != filepath.Join("/tmp/xdg-state", "coders") {
+		t.Errorf("StateDir() = %q with XDG_STATE_HOME set", got)
+	}
+}
@@ -0,0 +1,178 @@
+// Package crash classifies why a coder session stopped and decides whether
+// and when the crash watcher should restart it.
+package crash
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Class is the category of a crash.
+type Class string
+
+const (
+	// ClassNone means the session is still running.
+	ClassNone Class = ""
+	// ClassCompleted means the tool exited cleanly after publishing a promise.
+	ClassCompleted Class = "completed"
+	// ClassSessionGone means the tmux session disappeared.
+	ClassSessionGone Class = "session-gone"
+	// ClassProcessExit means the tool exited with an error or signal.
+	ClassProcessExit Class = "process-exit"
+	// ClassOOM means the tool ran out of memory or was killed by the OOM killer.
+	ClassOOM Class = "oom"
+	// ClassAuth means the tool could not authenticate.
+	ClassAuth Class = "auth"
+	// ClassRateLimit means the provider rejected requests due to rate or usage limits.
+	ClassRateLimit Class = "rate-limit"
+	// ClassNetwork means the tool lost its connection to the provider.
+	ClassNetwork Class = "network"
+	// ClassCleanExit means the tool exited with status 0 without publishing a promise.
+	ClassCleanExit Class = "clean-exit"
+)
+
+// Evidence is what the crash watcher observed about a session.
+type Evidence struct {
+	SessionExists bool
+	ToolRunning   bool
+	ExitCode      *int   // Tool exit status, if it was recorded
+	Output        string // Recent pane output
+	HasPromise    bool   // Session published a promise
+}
+
+// Result is the outcome of classifying a session.
+type Result struct {
+	Class    Class
+	Reason   string
+	ExitCode *int
+}
+
+// Crashed reports whether the session stopped without completing.
+func (r Result) Crashed() bool {
+	return r.Class != ClassNone && r.Class != ClassCompleted
+}
+
+// Output patterns, checked in order against recent pane output after the
+// tool has exited. Lowercased before matching.
+var outputPatterns = []struct {
+	class    Class
+	patterns []string
+}{
+	{ClassAuth, []string{
+		"invalid api key",
+		"invalid x-api-key",
+		"authentication_error",
+		"authentication failed",
+		"401 unauthorized",
+		"status 401",
+		"oauth token has expired",
+		"please run /login",
+		"not logged in",
+		"api key not found",
+	}},
+	{ClassRateLimit, []string{
+		"rate limit",
+		"rate_limit",
+		"ratelimit",
+		"429 too many requests",
+		"status 429",
+		"usage limit",
+		"quota exceeded",
+		"resource_exhausted",
+		"overloaded_error",
+	}},
+	{ClassOOM, []string{
+		"out of memory",
+		"heap out of memory",
+		"cannot allocate memory",
+		"oom-kill",
+	}},
+	{ClassNetwork, []string{
+		"econnreset",
+		"econnrefused",
+		"enotfound",
+		"etimedout",
+		"eai_again",
+		"socket hang up",
+		"network error",
+		"getaddrinfo",
+		"connection refused",
+		"connection reset",
+		"fetch failed",
+	}},
+}
+
+// Classify decides whether a session crashed and why. Pane output is only
+// consulted once the tool process is known to have exited with a failure
+// or an unknown status, so ordinary agent output mentioning errors or rate
+// limits is never mistaken for a crash.
+func Classify(ev Evidence) Result {
+	if !ev.SessionExists {
+		return Result{Class: ClassSessionGone, Reason: "tmux session no longer exists"}
+	}
+	if ev.ToolRunning {
+		return Result{Class: ClassNone}
+	}
+
+	res := Result{ExitCode: ev.ExitCode}
+	if ev.ExitCode != nil && *ev.ExitCode == 0 {
+		if ev.HasPromise {
+			res.Class = ClassCompleted
+			res.Reason = "tool exited after publishing a promise"
+		} else {
+			res.Class = ClassCleanExit
+			res.Reason = "tool exited cleanly without publishing a promise"
+		}
+		return res
+	}
+
+	if class, pattern := matchOutput(ev.Output); class != ClassNone {
+		res.Class = class
+		res.Reason = fmt.Sprintf("%s (%q in output)", describeExit(ev.ExitCode), pattern)
+		return res
+	}
+
+	if ev.ExitCode == nil {
+		res.Class = ClassProcessExit
+		res.Reason = "tool process exited"
+		return res
+	}
+
+	switch code := *ev.ExitCode; {
+	case code == 137:
+		// SIGKILL with no other explanation is almost always the OOM killer
+		res.Class = ClassOOM
+		res.Reason = describeExit(ev.ExitCode)
+	default:
+		res.Class = ClassProcessExit
+		res.Reason = describeExit(ev.ExitCode)
+	}
+	return res
+}
+
+// matchOutput returns the first class whose pattern appears in output.
+func matchOutput(output string) (Class, string) {
+	if output == "" {
+		return ClassNone, ""
+	}
+	lower := strings.ToLower(output)
+	for _, group := range outputPatterns {
+		for _, p := range group.patterns {
+			if strings.Contains(lower, p) {
+				return group.class, p
+			}
+		}
+	}
+	return ClassNone, ""
+}
+
+// describeExit formats an exit status, naming the signal for codes above 128.
+func describeExit(code *int) string {
+	if code == nil {
+		return "tool process exited"
+	}
+	if *code > 128 {
+		return fmt.Sprintf("tool exited with status %d (signal %d)", *code, *code-128)
+	}
+	return fmt.Sprintf("tool exited with status %d", *code)
+}
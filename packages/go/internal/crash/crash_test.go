@@ -0,0 +1,117 @@
+package crash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func intPtr(i int) *int { return &i }
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   Evidence
+		want Class
+	}{
+		{"running with error text", Evidence{SessionExists: true, ToolRunning: true, Output: "Error: test failed\n$"}, ClassNone},
+		{"session gone", Evidence{}, ClassSessionGone},
+		{"completed", Evidence{SessionExists: true, ExitCode: intPtr(0), HasPromise: true}, ClassCompleted},
+		{"clean exit", Evidence{SessionExists: true, ExitCode: intPtr(0)}, ClassCleanExit},
+		{"exit status", Evidence{SessionExists: true, ExitCode: intPtr(1)}, ClassProcessExit},
+		{"unknown exit", Evidence{SessionExists: true}, ClassProcessExit},
+		{"sigkill", Evidence{SessionExists: true, ExitCode: intPtr(137)}, ClassOOM},
+		{"heap", Evidence{SessionExists: true, ExitCode: intPtr(134), Output: "FATAL ERROR: Reached heap limit Allocation failed - JavaScript heap out of memory"}, ClassOOM},
+		{"auth", Evidence{SessionExists: true, ExitCode: intPtr(1), Output: "API Error: 401 {\"type\":\"authentication_error\"}"}, ClassAuth},
+		{"login", Evidence{SessionExists: true, ExitCode: intPtr(1), Output: "Invalid API key · Please run /login"}, ClassAuth},
+		{"rate limit", Evidence{SessionExists: true, ExitCode: intPtr(1), Output: "API Error: 429 Too Many Requests"}, ClassRateLimit},
+		{"usage limit", Evidence{SessionExists: true, Output: "Claude usage limit reached. Your limit will reset at 5pm"}, ClassRateLimit},
+		{"completed mentioning rate limit", Evidence{SessionExists: true, ExitCode: intPtr(0), HasPromise: true, Output: "Added retries when the API hits its rate limit\n$"}, ClassCompleted},
+		{"clean exit mentioning auth", Evidence{SessionExists: true, ExitCode: intPtr(0), Output: "Fixed the 401 Unauthorized handler\n$"}, ClassCleanExit},
+		{"network", Evidence{SessionExists: true, ExitCode: intPtr(1), Output: "Error: connect ECONNREFUSED 127.0.0.1:443"}, ClassNetwork},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Classify(tt.ev)
+			if got.Class != tt.want {
+				t.Errorf("Classify() = %q (%s), want %q", got.Class, got.Reason, tt.want)
+			}
+			if got.Crashed() != (tt.want != ClassNone && tt.want != ClassCompleted) {
+				t.Errorf("Crashed() = %t for %q", got.Crashed(), got.Class)
+			}
+		})
+	}
+}
+
+func TestPolicyDecide(t *testing.T) {
+	p := Policy{
+		BaseDelay:      5 * time.Second,
+		MaxDelay:       time.Minute,
+		RateLimitDelay: 2 * time.Minute,
+		BreakerCrashes: 3,
+		BreakerWindow:  10 * time.Minute,
+		Rand:           func() float64 { return 0.5 }, // no jitter
+	}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if d := p.Decide(ClassAuth, 0, nil, now); d.Restart {
+		t.Error("auth failures should never restart")
+	}
+
+	delays := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute}
+	for attempt, want := range delays {
+		d := p.Decide(ClassProcessExit, attempt, []time.Time{now}, now)
+		if !d.Restart || d.Delay != want {
+			t.Errorf("attempt %d: got restart=%t delay=%s, want %s", attempt, d.Restart, d.Delay, want)
+		}
+	}
+
+	// Rate limits wait at least RateLimitDelay, even above MaxDelay
+	if d := p.Decide(ClassRateLimit, 0, []time.Time{now}, now); d.Delay != 2*time.Minute {
+		t.Errorf("rate limit delay = %s, want 2m", d.Delay)
+	}
+
+	recent := []time.Time{now.Add(-8 * time.Minute), now.Add(-2 * time.Minute), now}
+	if d := p.Decide(ClassProcessExit, 2, recent, now); d.Restart {
+		t.Error("expected circuit breaker to stop restarts")
+	}
+	old := []time.Time{now.Add(-30 * time.Minute), now.Add(-2 * time.Minute), now}
+	if d := p.Decide(ClassProcessExit, 2, old, now); !d.Restart {
+		t.Error("crashes outside the window should not open the breaker")
+	}
+}
+
+func TestPolicyJitter(t *testing.T) {
+	p := Policy{BaseDelay: 10 * time.Second, MaxDelay: time.Minute}
+	p.Rand = func() float64 { return 0 }
+	if d := p.delay(p.BaseDelay, 0); d != 8*time.Second {
+		t.Errorf("min jitter delay = %s, want 8s", d)
+	}
+	p.Rand = func() float64 { return 0.999999 }
+	if d := p.delay(p.BaseDelay, 0); d < 11*time.Second || d > 12*time.Second {
+		t.Errorf("max jitter delay = %s, want ~12s", d)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	t.Setenv("CODERS_STATE_DIR", t.TempDir())
+
+	if code := ReadExitCode("coder-test"); code != nil {
+		t.Fatalf("expected no exit code, got %d", *code)
+	}
+	if err := os.MkdirAll(filepath.Dir(ExitCodePath("coder-test")), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ExitCodePath("coder-test"), []byte("42\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := ReadExitCode("coder-test"); code == nil || *code != 42 {
+		t.Errorf("ReadExitCode() = %v, want 42", code)
+	}
+	ClearExitCode("coder-test")
+	if code := ReadExitCode("coder-test"); code != nil {
+		t.Error("expected exit code to be cleared")
+	}
+}
@@ -0,0 +1,35 @@
+package crash
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Jayphen/coders/internal/config"
+)
+
+// ExitCodePath returns the file a session's launch command writes the tool's
+// exit status to.
+func ExitCodePath(sessionID string) string {
+	return filepath.Join(config.StateDir(), "exit", sessionID)
+}
+
+// ReadExitCode returns the recorded exit status of a session's tool, or nil
+// if the tool hasn't exited (or the status wasn't recorded).
+func ReadExitCode(sessionID string) *int {
+	data, err := os.ReadFile(ExitCodePath(sessionID))
+	if err != nil {
+		return nil
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil
+	}
+	return &code
+}
+
+// ClearExitCode removes a recorded exit status before the tool is (re)started.
+func ClearExitCode(sessionID string) {
+	_ = os.Remove(ExitCodePath(sessionID))
+}
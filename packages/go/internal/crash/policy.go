@@ -0,0 +1,118 @@
+package crash
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/Jayphen/coders/internal/config"
+)
+
+// jitterFraction spreads restart delays by ±20% so sessions that crashed
+// together don't all restart at the same moment.
+const jitterFraction = 0.2
+
+// Policy decides whether and when to restart a crashed session.
+type Policy struct {
+	BaseDelay      time.Duration
+	MaxDelay       time.Duration
+	RateLimitDelay time.Duration
+
+	// BreakerCrashes crashes within BreakerWindow open the circuit
+	BreakerCrashes int
+	BreakerWindow  time.Duration
+
+	// Rand returns a value in [0, 1); replaceable for tests
+	Rand func() float64
+}
+
+// Decision is the outcome of applying a policy to a crash.
+type Decision struct {
+	Restart bool
+	Delay   time.Duration
+	Reason  string // Why the session won't be restarted
+}
+
+// PolicyFromConfig builds a policy from crash recovery config.
+func PolicyFromConfig(cfg config.CrashRecoveryConfig) Policy {
+	return Policy{
+		BaseDelay:      cfg.BaseDelay,
+		MaxDelay:       cfg.MaxDelay,
+		RateLimitDelay: cfg.RateLimitDelay,
+		BreakerCrashes: cfg.CircuitBreakerCrashes,
+		BreakerWindow:  cfg.CircuitBreakerWindow,
+		Rand:           rand.Float64,
+	}
+}
+
+// DefaultPolicy returns the policy built from default crash recovery config.
+func DefaultPolicy() Policy {
+	return PolicyFromConfig(config.CrashRecoveryConfig{
+		BaseDelay:             config.DefaultCrashBaseDelay,
+		MaxDelay:              config.DefaultCrashMaxDelay,
+		RateLimitDelay:        config.DefaultCrashRateLimitDelay,
+		CircuitBreakerCrashes: config.DefaultCircuitBreakerCrashes,
+		CircuitBreakerWindow:  config.DefaultCircuitBreakerWindow,
+	})
+}
+
+// Decide returns the restart decision for a crash. attempt is the number of
+// restarts already made; recent holds the times of previous crashes,
+// including this one.
+func (p Policy) Decide(class Class, attempt int, recent []time.Time, now time.Time) Decision {
+	switch class {
+	case ClassNone, ClassCompleted:
+		return Decision{Reason: "session did not crash"}
+	case ClassAuth:
+		return Decision{Reason: "authentication failed; fix credentials and respawn"}
+	}
+
+	if p.BreakerOpen(recent, now) {
+		return Decision{Reason: "circuit breaker open: too many crashes in a short time"}
+	}
+
+	base := p.BaseDelay
+	if class == ClassRateLimit && p.RateLimitDelay > base {
+		base = p.RateLimitDelay
+	}
+	return Decision{Restart: true, Delay: p.delay(base, attempt)}
+}
+
+// BreakerOpen reports whether enough crashes happened within the window to
+// stop restarting.
+func (p Policy) BreakerOpen(crashes []time.Time, now time.Time) bool {
+	if p.BreakerCrashes <= 0 || p.BreakerWindow <= 0 {
+		return false
+	}
+	count := 0
+	for _, t := range crashes {
+		if now.Sub(t) <= p.BreakerWindow {
+			count++
+		}
+	}
+	return count >= p.BreakerCrashes
+}
+
+// delay computes base * 2^attempt with jitter. The result is capped at
+// MaxDelay, except that it never drops below base (the rate limit floor).
+func (p Policy) delay(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	limit := p.MaxDelay
+	if limit < base {
+		limit = base
+	}
+	d := base
+	for i := 0; i < attempt && d < limit; i++ {
+		d *= 2
+	}
+	if d > limit {
+		d = limit
+	}
+
+	r := 0.5
+	if p.Rand != nil {
+		r = p.Rand()
+	}
+	return time.Duration(float64(d) * (1 - jitterFraction + 2*jitterFraction*r))
+}
@@ -0,0 +1,183 @@
+// Package forensics saves and loads crash forensics bundles: everything the
+// crash watcher could capture about a session before it was torn down.
+package forensics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+// MaxBundlesPerSession is how many bundles are kept per session; older ones are pruned.
+const MaxBundlesPerSession = 10
+
+// Bundle file names
+const (
+	metadataFile   = "bundle.json"
+	scrollbackFile = "scrollback.txt"
+	gitStatusFile  = "git-status.txt"
+	gitDiffFile    = "git.diff"
+)
+
+// Pane describes a tmux pane at crash time.
+type Pane struct {
+	ID         string `json:"id"`
+	PID        int    `json:"pid"`
+	Dead       bool   `json:"dead"`
+	DeadStatus string `json:"deadStatus,omitempty"`
+	Command    string `json:"command"`
+}
+
+// Bundle is a crash forensics snapshot. Large text fields are stored in
+// separate files next to bundle.json.
+type Bundle struct {
+	SessionID   string                   `json:"sessionId"`
+	CrashedAt   time.Time                `json:"crashedAt"`
+	Class       string                   `json:"class,omitempty"`
+	Reason      string                   `json:"reason"`
+	ExitCode    *int                     `json:"exitCode,omitempty"`
+	Cwd         string                   `json:"cwd,omitempty"`
+	Panes       []Pane                   `json:"panes,omitempty"`
+	ProcessTree string                   `json:"processTree,omitempty"`
+	Env         map[string]string        `json:"env,omitempty"`
+	State       *types.SessionState      `json:"state,omitempty"`
+	Heartbeats  []types.HeartbeatData    `json:"heartbeats,omitempty"`
+	Events      []types.SessionEvent     `json:"events,omitempty"`
+	HealthCheck *types.HealthCheckResult `json:"healthCheck,omitempty"`
+
+	Scrollback string `json:"-"`
+	GitStatus  string `json:"-"`
+	GitDiff    string `json:"-"`
+
+	// Dir is where the bundle is stored; set by Save and Load
+	Dir string `json:"-"`
+}
+
+// Root returns the directory holding all bundles.
+func Root() string {
+	return filepath.Join(config.StateDir(), "crashes")
+}
+
+// Save writes a bundle under Root()/<session>/<timestamp> and prunes old
+// bundles for the session. Bundles may contain source and terminal output,
+// so they are only readable by the owner.
+func Save(b *Bundle) (string, error) {
+	if b.CrashedAt.IsZero() {
+		b.CrashedAt = time.Now()
+	}
+
+	sessionDir := filepath.Join(Root(), b.SessionID)
+	dir := filepath.Join(sessionDir, b.CrashedAt.Format("20060102-150405.000"))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	files := map[string][]byte{
+		metadataFile:   data,
+		scrollbackFile: []byte(b.Scrollback),
+		gitStatusFile:  []byte(b.GitStatus),
+		gitDiffFile:    []byte(b.GitDiff),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0600); err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	b.Dir = dir
+	prune(sessionDir, MaxBundlesPerSession)
+	return dir, nil
+}
+
+// Load reads a bundle, including its text files, from a directory.
+func Load(dir string) (*Bundle, error) {
+	b, err := loadMetadata(dir)
+	if err != nil {
+		return nil, err
+	}
+	b.Scrollback = readOptional(filepath.Join(dir, scrollbackFile))
+	b.GitStatus = readOptional(filepath.Join(dir, gitStatusFile))
+	b.GitDiff = readOptional(filepath.Join(dir, gitDiffFile))
+	return b, nil
+}
+
+// List returns bundle metadata, newest first. If sessionID is non-empty only
+// that session's bundles are returned.
+func List(sessionID string) ([]*Bundle, error) {
+	pattern := filepath.Join(Root(), "*", "*", metadataFile)
+	if sessionID != "" {
+		pattern = filepath.Join(Root(), sessionID, "*", metadataFile)
+	}
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundles []*Bundle
+	for _, path := range paths {
+		b, err := loadMetadata(filepath.Dir(path))
+		if err != nil {
+			continue
+		}
+		bundles = append(bundles, b)
+	}
+
+	sort.Slice(bundles, func(i, j int) bool {
+		return bundles[i].CrashedAt.After(bundles[j].CrashedAt)
+	})
+	return bundles, nil
+}
+
+func loadMetadata(dir string) (*Bundle, error) {
+	data, err := os.ReadFile(filepath.Join(dir, metadataFile))
+	if err != nil {
+		return nil, err
+	}
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("invalid bundle %s: %w", dir, err)
+	}
+	b.Dir = dir
+	return &b, nil
+}
+
+func readOptional(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// prune removes all but the newest keep bundles in a session directory.
+// Bundle directory names sort chronologically.
+func prune(sessionDir string, keep int) {
+	entries, err := os.ReadDir(sessionDir)
+	if err != nil {
+		return
+	}
+	var dirs []string
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			dirs = append(dirs, e.Name())
+		}
+	}
+	if len(dirs) <= keep {
+		return
+	}
+	sort.Strings(dirs)
+	for _, name := range dirs[:len(dirs)-keep] {
+		_ = os.RemoveAll(filepath.Join(sessionDir, name))
+	}
+}
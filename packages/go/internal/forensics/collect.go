@@ -0,0 +1,200 @@
+package forensics
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/proc"
+	"github.com/Jayphen/coders/internal/tmux"
+)
+
+// maxDiffBytes caps the git diff stored in a bundle.
+const maxDiffBytes = 512 * 1024
+
+// Collect captures pane scrollback, process tree, environment and git state
+// for a session. It must run before the session is killed. Anything that
+// can't be captured is left empty.
+func Collect(sessionID, cwd, baseCommit string) *Bundle {
+	b := &Bundle{SessionID: sessionID, Cwd: cwd}
+
+	// Full scrollback, with wrapped lines joined
+	if out, err := mux.CapturePane(sessionID, mux.CaptureOptions{}); err == nil {
+		b.Scrollback = out + "\n"
+	}
+
+	b.Panes = listPanes(sessionID)
+	if len(b.Panes) == 0 {
+		// Other multiplexers only report pane PIDs
+		pids, _ := mux.PanePIDs(sessionID)
+		for i, pid := range pids {
+			p, _ := proc.Get(pid)
+			b.Panes = append(b.Panes, Pane{
+				ID:      strconv.Itoa(i),
+				PID:     pid,
+				Command: p.Comm,
+			})
+		}
+	}
+	var roots []int
+	for _, p := range b.Panes {
+		roots = append(roots, p.PID)
+	}
+	if len(roots) > 0 {
+		if table, err := proc.Snapshot(); err == nil {
+			b.ProcessTree = buildProcessTree(table, roots)
+		}
+		b.Env = RedactEnv(FilterEnv(processEnv(sessionID, roots[0])))
+	}
+
+	if cwd != "" {
+		if out, err := exec.Command("git", "-C", cwd, "status", "--short", "--branch").Output(); err == nil {
+			b.GitStatus = string(out)
+		}
+		base := baseCommit
+		if base == "" {
+			base = "HEAD"
+		}
+		if out, err := exec.Command("git", "-C", cwd, "diff", base).Output(); err == nil {
+			b.GitDiff = string(out)
+			if len(b.GitDiff) > maxDiffBytes {
+				b.GitDiff = b.GitDiff[:maxDiffBytes] + "\n... (diff truncated)\n"
+			}
+		}
+	}
+
+	return b
+}
+
+// listPanes returns the panes of a tmux session.
+func listPanes(sessionID string) []Pane {
+	if mux.Default().Name() != "tmux" {
+		return nil
+	}
+	out, err := tmux.Command("list-panes", "-s", "-t", sessionID, "-F",
+		"#{pane_id}\t#{pane_pid}\t#{pane_dead}\t#{pane_dead_status}\t#{pane_current_command}").Output()
+	if err != nil {
+		return nil
+	}
+	var panes []Pane
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			continue
+		}
+		pid, _ := strconv.Atoi(fields[1])
+		panes = append(panes, Pane{
+			ID:         fields[0],
+			PID:        pid,
+			Dead:       fields[2] == "1",
+			DeadStatus: fields[3],
+			Command:    fields[4],
+		})
+	}
+	return panes
+}
+
+// buildProcessTree renders roots and their descendants as an indented tree.
+func buildProcessTree(table *proc.Table, roots []int) string {
+	var b strings.Builder
+	visited := make(map[int]bool)
+	var walk func(pid, depth int)
+	walk = func(pid, depth int) {
+		if visited[pid] {
+			return
+		}
+		visited[pid] = true
+		p, ok := table.Get(pid)
+		if !ok {
+			fmt.Fprintf(&b, "%s%d (exited)\n", strings.Repeat("  ", depth), pid)
+			return
+		}
+		fmt.Fprintf(&b, "%s%d %c cpu=%s rss=%dK %s\n", strings.Repeat("  ", depth),
+			p.PID, p.State, p.CPUTime.Round(time.Second), p.RSS/1024, p.Command())
+		for _, child := range table.Children(pid) {
+			walk(child.PID, depth+1)
+		}
+	}
+	for _, root := range roots {
+		walk(root, 0)
+	}
+	return b.String()
+}
+
+// processEnv reads a process's environment from /proc, falling back to the
+// tmux session environment where /proc isn't available.
+func processEnv(sessionID string, pid int) map[string]string {
+	env := make(map[string]string)
+	if data, err := os.ReadFile(fmt.Sprintf("/proc/%d/environ", pid)); err == nil {
+		for _, kv := range strings.Split(string(data), "\x00") {
+			if k, v, ok := strings.Cut(kv, "="); ok {
+				env[k] = v
+			}
+		}
+		return env
+	}
+
+	if mux.Default().Name() != "tmux" {
+		return env
+	}
+	out, err := tmux.Command("show-environment", "-t", sessionID).Output()
+	if err != nil {
+		return env
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if k, v, ok := strings.Cut(line, "="); ok && !strings.HasPrefix(k, "-") {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+// relevantEnvPrefixes selects the variables that affect how coder tools run.
+var relevantEnvPrefixes = []string{
+	"CODERS_", "CLAUDE", "ANTHROPIC_", "OPENAI_", "CODEX_", "GEMINI_", "GOOGLE_",
+	"OPENCODE", "OLLAMA", "NODE_", "HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY",
+	"http_proxy", "https_proxy", "no_proxy", "PATH", "SHELL", "HOME", "USER",
+	"TERM", "LANG", "LC_ALL", "TMUX", "ZELLIJ",
+}
+
+// FilterEnv keeps only variables relevant to diagnosing a crash.
+func FilterEnv(env map[string]string) map[string]string {
+	filtered := make(map[string]string)
+	for k, v := range env {
+		for _, prefix := range relevantEnvPrefixes {
+			if strings.HasPrefix(k, prefix) {
+				filtered[k] = v
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+var (
+	secretKeyPattern   = regexp.MustCompile(`(?i)(KEY|TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIAL|AUTH)`)
+	secretValuePattern = regexp.MustCompile(`^(sk-|ghp_|gho_|github_pat_|xox[bap]-|AIza)`)
+	urlUserinfoPattern = regexp.MustCompile(`://[^/@\s]+@`)
+)
+
+// RedactEnv replaces secret values. Empty values are kept so a deliberately
+// blanked key (e.g. ANTHROPIC_API_KEY="") is still visible.
+func RedactEnv(env map[string]string) map[string]string {
+	redacted := make(map[string]string, len(env))
+	for k, v := range env {
+		switch {
+		case v == "":
+			redacted[k] = v
+		case secretKeyPattern.MatchString(k), secretValuePattern.MatchString(v):
+			redacted[k] = "[REDACTED]"
+		default:
+			redacted[k] = urlUserinfoPattern.ReplaceAllString(v, "://[REDACTED]@")
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,276 @@
+// Package limits applies per-session resource limits and measures what a
+// session's process tree is using.
+//
+// Limits are enforced with cgroup v2 where available: a transient systemd
+// scope when a user systemd instance is running, otherwise a cgroup created
+// directly under /sys/fs/cgroup/coders (which must be writable, e.g. created
+// and chowned by root). As a last resort memory is capped with ulimit; CPU
+// and process counts are then only monitored.
+package limits
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jayphen/coders/internal/proc"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+// Method is how a session's limits are enforced.
+type Method string
+
+const (
+	MethodSystemd Method = "systemd" // transient systemd scope (cgroup v2)
+	MethodCgroup  Method = "cgroup"  // cgroup created directly in cgroupfs (v2)
+	MethodUlimit  Method = "ulimit"  // shell ulimit; memory only
+	MethodNone    Method = "none"
+)
+
+// cgroupRoot is the cgroup v2 mount point.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// breachFraction is how close to a memory or process limit counts as
+// reaching it. Enforced limits never quite report 100%, since the kernel
+// reclaims memory or refuses forks at the limit.
+const breachFraction = 0.95
+
+// Wrap returns command wrapped so it runs under l, and the method used. name
+// identifies the session and must be usable in a unit or directory name.
+func Wrap(name, command string, l types.ResourceLimits) (string, Method) {
+	if l.IsZero() {
+		return command, MethodNone
+	}
+	if !cgroupV2() {
+		return wrapUlimit(command, l)
+	}
+	if systemdAvailable() {
+		return wrapSystemd(name, command, l), MethodSystemd
+	}
+	if dir, err := createCgroup(name, l); err == nil {
+		return wrapCgroup(dir, command), MethodCgroup
+	}
+	return wrapUlimit(command, l)
+}
+
+// Cleanup removes the cgroup created for a session, if any. It must run
+// after the session's processes have exited.
+func Cleanup(name string) {
+	_ = os.Remove(filepath.Join(cgroupRoot, "coders", name))
+}
+
+// cgroupV2 reports whether the unified cgroup hierarchy is mounted.
+func cgroupV2() bool {
+	_, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers"))
+	return err == nil
+}
+
+// systemdAvailable reports whether a user systemd instance can start scopes.
+func systemdAvailable() bool {
+	if _, err := exec.LookPath("systemd-run"); err != nil {
+		return false
+	}
+	return exec.Command("systemctl", "--user", "show-environment").Run() == nil
+}
+
+// wrapSystemd runs command in a transient scope with the limits as unit
+// properties. The unit name carries a timestamp so a restarted session
+// doesn't collide with a scope that hasn't been collected yet.
+func wrapSystemd(name, command string, l types.ResourceLimits) string {
+	args := []string{
+		"systemd-run", "--user", "--scope", "--quiet", "--collect",
+		fmt.Sprintf("--unit=coders-%s-%d", name, time.Now().Unix()),
+	}
+	if l.MaxMemory > 0 {
+		args = append(args, "-p", fmt.Sprintf("MemoryMax=%d", l.MaxMemory))
+	}
+	if l.MaxCPU > 0 {
+		args = append(args, "-p", fmt.Sprintf("CPUQuota=%d%%", int(math.Round(l.MaxCPU*100))))
+	}
+	if l.MaxProcs > 0 {
+		args = append(args, "-p", fmt.Sprintf("TasksMax=%d", l.MaxProcs))
+	}
+	args = append(args, "--", "sh", "-c", command)
+	return shellJoin(args)
+}
+
+// createCgroup creates the session's cgroup under cgroupRoot/coders and
+// writes its limits.
+func createCgroup(name string, l types.ResourceLimits) (string, error) {
+	parent := filepath.Join(cgroupRoot, "coders")
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", err
+	}
+	// Controllers must be enabled in the parent before a child can use them.
+	// The parent holds no processes, so this is allowed.
+	if err := os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+memory +cpu +pids"), 0644); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(parent, name)
+	if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+	files := map[string]string{
+		"memory.max": "max",
+		"cpu.max":    "max 100000",
+		"pids.max":   "max",
+	}
+	if l.MaxMemory > 0 {
+		files["memory.max"] = strconv.FormatInt(l.MaxMemory, 10)
+	}
+	if l.MaxCPU > 0 {
+		// Quota per 100ms period
+		files["cpu.max"] = fmt.Sprintf("%d 100000", int(math.Round(l.MaxCPU*100000)))
+	}
+	if l.MaxProcs > 0 {
+		files["pids.max"] = strconv.Itoa(l.MaxProcs)
+	}
+	for file, value := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0644); err != nil {
+			_ = os.Remove(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// wrapCgroup moves the shell into dir before it execs command.
+func wrapCgroup(dir, command string) string {
+	return fmt.Sprintf("sh -c %s", shellQuote(fmt.Sprintf("echo $$ > %s && exec sh -c %s",
+		shellQuote(filepath.Join(dir, "cgroup.procs")), shellQuote(command))))
+}
+
+// wrapUlimit caps address space with ulimit. ulimit has no CPU rate limit,
+// and RLIMIT_NPROC counts all of the user's processes rather than the
+// session's, so CPU and process limits are left to monitoring.
+func wrapUlimit(command string, l types.ResourceLimits) (string, Method) {
+	if l.MaxMemory == 0 {
+		return command, MethodNone
+	}
+	return fmt.Sprintf("sh -c %s", shellQuote(fmt.Sprintf("ulimit -v %d && exec sh -c %s",
+		l.MaxMemory/1024, shellQuote(command)))), MethodUlimit
+}
+
+// Usage is a sample of a process tree's resource use.
+type Usage struct {
+	Memory    int64 // Resident memory in bytes
+	Processes int
+	CPUTime   time.Duration
+	At        time.Time
+}
+
+// Measure samples the live processes in the trees rooted at roots.
+func Measure(roots []int) (Usage, error) {
+	tree, err := proc.Tree(roots...)
+	if err != nil {
+		return Usage{}, err
+	}
+	u := Usage{At: time.Now()}
+	for _, p := range tree {
+		if !p.Alive() {
+			continue
+		}
+		u.Memory += p.RSS
+		u.Processes++
+		u.CPUTime += p.CPUTime
+	}
+	return u, nil
+}
+
+// CPUPercent returns the CPU use between two samples as a percentage of one
+// CPU. CPU time of processes that exited between the samples is lost, so
+// the result is a lower bound; it is never negative.
+func CPUPercent(prev, cur Usage) float64 {
+	elapsed := cur.At.Sub(prev.At)
+	if prev.At.IsZero() || elapsed <= 0 || cur.CPUTime <= prev.CPUTime {
+		return 0
+	}
+	return float64(cur.CPUTime-prev.CPUTime) / float64(elapsed) * 100
+}
+
+// Breaches describes each limit the usage has reached.
+func Breaches(l types.ResourceLimits, u *types.UsageStats) []string {
+	if u == nil {
+		return nil
+	}
+	var breaches []string
+	if l.MaxMemory > 0 && float64(u.MemoryBytes) >= float64(l.MaxMemory)*breachFraction {
+		breaches = append(breaches, fmt.Sprintf("memory %s of %s", FormatMemory(u.MemoryBytes), FormatMemory(l.MaxMemory)))
+	}
+	if l.MaxCPU > 0 && u.CPUPercent > l.MaxCPU*100 {
+		breaches = append(breaches, fmt.Sprintf("CPU %.0f%% of %.0f%%", u.CPUPercent, l.MaxCPU*100))
+	}
+	if l.MaxProcs > 0 && float64(u.Processes) >= float64(l.MaxProcs)*breachFraction {
+		breaches = append(breaches, fmt.Sprintf("%d of %d processes", u.Processes, l.MaxProcs))
+	}
+	return breaches
+}
+
+// memoryUnits maps size suffixes to multipliers. Both SI-style (G) and
+// binary (GiB) spellings mean powers of 1024, as they do for docker and
+// systemd.
+var memoryUnits = map[string]int64{
+	"":  1,
+	"b": 1,
+	"k": 1 << 10, "kb": 1 << 10, "kib": 1 << 10,
+	"m": 1 << 20, "mb": 1 << 20, "mib": 1 << 20,
+	"g": 1 << 30, "gb": 1 << 30, "gib": 1 << 30,
+	"t": 1 << 40, "tb": 1 << 40, "tib": 1 << 40,
+}
+
+// ParseMemory parses a size such as "512M", "1.5G" or "2GiB" into bytes.
+// An empty string is zero.
+func ParseMemory(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	unit, ok := memoryUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if err != nil || !ok || n < 0 {
+		return 0, fmt.Errorf("invalid memory size %q (use e.g. 512M or 2G)", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// FormatMemory renders a byte count with a binary unit, e.g. "1.5G".
+func FormatMemory(b int64) string {
+	units := []string{"K", "M", "G", "T"}
+	v := float64(b)
+	unit := "B"
+	for _, u := range units {
+		if v < 1024 {
+			break
+		}
+		v /= 1024
+		unit = u
+	}
+	if unit == "B" {
+		return fmt.Sprintf("%dB", b)
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", v), ".0") + unit
+}
+
+// shellQuote single-quotes s for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// shellJoin quotes each argument and joins them into a command line.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,129 @@
+package limits
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jayphen/coders/internal/types"
+)
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"", 0},
+		{"1024", 1024},
+		{"512M", 512 << 20},
+		{"1.5G", 3 << 29},
+		{"2GiB", 2 << 30},
+		{"64 kb", 64 << 10},
+	}
+	for _, tt := range tests {
+		got, err := ParseMemory(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseMemory(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"lots", "2X", "-1G", "G"} {
+		if _, err := ParseMemory(bad); err == nil {
+			t.Errorf("ParseMemory(%q) should fail", bad)
+		}
+	}
+}
+
+func TestFormatMemory(t *testing.T) {
+	tests := map[int64]string{
+		512:       "512B",
+		2048:      "2K",
+		3 << 29:   "1.5G",
+		100 << 20: "100M",
+	}
+	for in, want := range tests {
+		if got := FormatMemory(in); got != want {
+			t.Errorf("FormatMemory(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBreaches(t *testing.T) {
+	l := types.ResourceLimits{MaxMemory: 1 << 30, MaxCPU: 1.5, MaxProcs: 20}
+
+	if got := Breaches(l, &types.UsageStats{MemoryBytes: 512 << 20, CPUPercent: 120, Processes: 5}); len(got) != 0 {
+		t.Errorf("expected no breaches, got %v", got)
+	}
+	got := Breaches(l, &types.UsageStats{MemoryBytes: 1 << 30, CPUPercent: 210, Processes: 20})
+	want := []string{"memory 1G of 1G", "CPU 210% of 150%", "20 of 20 processes"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Breaches = %q, want %q", got, want)
+	}
+	if got := Breaches(types.ResourceLimits{}, &types.UsageStats{MemoryBytes: 1 << 40}); len(got) != 0 {
+		t.Errorf("unlimited session reported breaches: %v", got)
+	}
+}
+
+func TestCPUPercent(t *testing.T) {
+	start := time.Now()
+	prev := Usage{CPUTime: time.Second, At: start}
+	if got := CPUPercent(prev, Usage{CPUTime: 4 * time.Second, At: start.Add(2 * time.Second)}); got != 150 {
+		t.Errorf("CPUPercent = %v, want 150", got)
+	}
+	// Children exiting can make the total go backwards
+	if got := CPUPercent(prev, Usage{CPUTime: 0, At: start.Add(time.Second)}); got != 0 {
+		t.Errorf("CPUPercent = %v, want 0", got)
+	}
+	if got := CPUPercent(Usage{}, Usage{CPUTime: time.Second, At: start}); got != 0 {
+		t.Errorf("CPUPercent without a previous sample = %v, want 0", got)
+	}
+}
+
+func TestWrapSystemd(t *testing.T) {
+	cmd := wrapSystemd("coder-claude-x", "claude --print 'hi'", types.ResourceLimits{MaxMemory: 1 << 30, MaxCPU: 1.5, MaxProcs: 64})
+	for _, want := range []string{
+		`'systemd-run' '--user' '--scope'`,
+		`'--unit=coders-coder-claude-x-`,
+		`'-p' 'MemoryMax=1073741824' '-p' 'CPUQuota=150%' '-p' 'TasksMax=64'`,
+		`'--' 'sh' '-c' 'claude --print '"'"'hi'"'"''`,
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("command missing %s:\n%s", want, cmd)
+		}
+	}
+}
+
+func TestWrapUlimit(t *testing.T) {
+	if cmd, method := wrapUlimit("true", types.ResourceLimits{MaxProcs: 10}); cmd != "true" || method != MethodNone {
+		t.Errorf("process limit alone should not wrap, got %q %s", cmd, method)
+	}
+
+	cmd, method := wrapUlimit(`ulimit -v; echo "it's"`, types.ResourceLimits{MaxMemory: 4 << 30})
+	if method != MethodUlimit {
+		t.Fatalf("method = %s, want ulimit", method)
+	}
+	out, err := exec.Command("sh", "-c", cmd).CombinedOutput()
+	if err != nil {
+		t.Fatalf("wrapped command failed: %v\n%s", err, out)
+	}
+	if got := string(out); got != "4194304\nit's\n" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestWrapNoLimits(t *testing.T) {
+	if cmd, method := Wrap("coder-x", "claude", types.ResourceLimits{}); cmd != "claude" || method != MethodNone {
+		t.Errorf("Wrap without limits = %q %s", cmd, method)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	u, err := Measure([]int{os.Getpid()})
+	if err != nil {
+		t.Fatalf("Measure error: %v", err)
+	}
+	if u.Processes < 1 || u.Memory <= 0 || u.At.IsZero() {
+		t.Errorf("unexpected usage: %+v", u)
+	}
+}
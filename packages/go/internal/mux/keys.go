@@ -0,0 +1,139 @@
+package mux
+
+import "strings"
+
+// Input sent to a session can mix text with key names in angle brackets,
+// e.g. "<Down><Down><Enter>", "y" followed by "<Esc>", or "<C-c>". Text
+// without any key names is typed and submitted with Enter; once a key name
+// is used nothing is pressed that isn't written, so menus and single-key
+// prompts can be answered. Unknown names such as "<div>" are typed as
+// text, and "<lt>" types a literal "<".
+
+// Key is one step of a key sequence: text typed literally, or a named key.
+type Key struct {
+	Text string
+	Name string // tmux-style key name, e.g. Enter, Escape, C-c
+}
+
+// keyNames maps the names accepted in angle brackets, lower-cased, to tmux
+// key names.
+var keyNames = map[string]string{
+	"enter":     "Enter",
+	"cr":        "Enter",
+	"return":    "Enter",
+	"esc":       "Escape",
+	"escape":    "Escape",
+	"tab":       "Tab",
+	"s-tab":     "BTab",
+	"btab":      "BTab",
+	"space":     "Space",
+	"bs":        "BSpace",
+	"backspace": "BSpace",
+	"up":        "Up",
+	"down":      "Down",
+	"left":      "Left",
+	"right":     "Right",
+	"home":      "Home",
+	"end":       "End",
+	"pgup":      "PPage",
+	"pgdn":      "NPage",
+	"del":       "DC",
+}
+
+// keyName returns the tmux key name for a name written in angle brackets,
+// including control and meta keys such as C-c and M-x.
+func keyName(name string) (string, bool) {
+	lower := strings.ToLower(name)
+	if key, ok := keyNames[lower]; ok {
+		return key, true
+	}
+	if len(lower) == 3 && (lower[:2] == "c-" || lower[:2] == "m-") {
+		c := lower[2]
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' {
+			return strings.ToUpper(lower[:1]) + "-" + lower[2:], true
+		}
+	}
+	return "", false
+}
+
+// ParseKeys splits input into text and named keys. named reports whether
+// any key names were found.
+func ParseKeys(input string) (keys []Key, named bool) {
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			keys = append(keys, Key{Text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for rest := input; rest != ""; {
+		open := strings.IndexByte(rest, '<')
+		if open < 0 {
+			text.WriteString(rest)
+			break
+		}
+		text.WriteString(rest[:open])
+		rest = rest[open:]
+
+		end := strings.IndexByte(rest, '>')
+		if end < 0 {
+			text.WriteString(rest)
+			break
+		}
+		name := rest[1:end]
+		switch key, ok := keyName(name); {
+		case strings.EqualFold(name, "lt"):
+			text.WriteByte('<')
+		case ok:
+			flush()
+			keys = append(keys, Key{Name: key})
+			named = true
+		default:
+			// Not a key: type the '<' and look for keys after it
+			text.WriteByte('<')
+			rest = rest[1:]
+			continue
+		}
+		rest = rest[end+1:]
+	}
+	flush()
+	return keys, named
+}
+
+// SendInput sends input written in the key sequence syntax to a session.
+// Plain text is typed and submitted with Enter, as with SendKeys.
+func SendInput(name, input string) error {
+	keys, named := ParseKeys(input)
+	if !named {
+		var text strings.Builder
+		for _, k := range keys {
+			text.WriteString(k.Text)
+		}
+		return SendKeys(name, text.String())
+	}
+
+	// Consecutive key names go in one call
+	var pending []string
+	sendPending := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		err := SendRawKeys(name, pending...)
+		pending = nil
+		return err
+	}
+	for _, k := range keys {
+		if k.Name != "" {
+			pending = append(pending, k.Name)
+			continue
+		}
+		if err := sendPending(); err != nil {
+			return err
+		}
+		if err := SendText(name, k.Text); err != nil {
+			return err
+		}
+	}
+	return sendPending()
+}
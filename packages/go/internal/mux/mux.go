@@ -0,0 +1,170 @@
+// Package mux abstracts the terminal multiplexer that hosts coder sessions.
+// tmux is the default; zellij can be selected with the multiplexer config
+// option or CODERS_MULTIPLEXER.
+package mux
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/limits"
+	"github.com/Jayphen/coders/internal/tmux"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+const (
+	// SessionPrefix is the prefix for all coder sessions.
+	SessionPrefix = tmux.SessionPrefix
+	// TUISession is the name of the TUI's own session.
+	TUISession = tmux.TUISession
+	// OrchestratorSession is the name of the orchestrator session.
+	OrchestratorSession = tmux.OrchestratorSession
+)
+
+// ErrUnsupported is returned for operations a multiplexer can't perform.
+var ErrUnsupported = errors.New("not supported by this multiplexer")
+
+// CaptureOptions controls what CapturePane returns.
+type CaptureOptions struct {
+	// Lines is how many lines to return from the end; 0 returns the whole scrollback
+	Lines int
+	// Escapes keeps colors and attributes as ANSI escape sequences
+	Escapes bool
+}
+
+// Multiplexer is a terminal multiplexer that can host coder sessions.
+type Multiplexer interface {
+	// Name identifies the backend, e.g. "tmux".
+	Name() string
+
+	// CreateSession starts a detached session in cwd running command with
+	// sh -c, recording meta so ListSessions can report it.
+	CreateSession(name, cwd, command string, meta types.SessionMeta) error
+	// ListSessions returns all coder sessions.
+	ListSessions() ([]types.Session, error)
+	// SessionExists reports whether a session is running.
+	SessionExists(name string) bool
+	// KillSession kills a session and every process running in it.
+	KillSession(name string) error
+	// AttachSession attaches the terminal to a session.
+	AttachSession(name string) error
+	// AttachHint is the shell command a user can run to attach to a session.
+	AttachHint(name string) string
+	// AttachSplit shows several sessions side by side in a new window of
+	// the session this process runs in.
+	AttachSplit(names []string) error
+
+	// CapturePane returns recent output from a session's active pane.
+	CapturePane(name string, opts CaptureOptions) (string, error)
+	// SendKeys types text into a session and presses Enter.
+	SendKeys(name, text string) error
+	// SendText types text into a session without pressing Enter.
+	SendText(name, text string) error
+	// SendRawKeys sends tmux-style key names (Enter, Escape, C-c, Up, ...).
+	SendRawKeys(name string, keys ...string) error
+	// PanePIDs returns the PIDs of the processes started in a session's panes.
+	PanePIDs(name string) ([]int, error)
+	// DisplayMessage shows a short message to whoever is attached to a session.
+	DisplayMessage(name, message string) error
+	// PipePane streams all pane output of a session to a shell command.
+	PipePane(name, command string) error
+
+	// InsideSession reports whether this process runs inside the multiplexer.
+	InsideSession() bool
+	// CurrentSession returns the session this process runs in.
+	CurrentSession() (string, error)
+}
+
+// New returns the multiplexer with the given name.
+func New(name string) (Multiplexer, error) {
+	switch name {
+	case "", "tmux":
+		return Tmux{}, nil
+	case "zellij":
+		return Zellij{}, nil
+	default:
+		return nil, fmt.Errorf("unknown multiplexer %q (supported: tmux, zellij)", name)
+	}
+}
+
+var (
+	defaultMux  Multiplexer
+	defaultOnce sync.Once
+)
+
+// Default returns the configured multiplexer, falling back to tmux.
+func Default() Multiplexer {
+	defaultOnce.Do(func() {
+		name := config.DefaultMultiplexer
+		if cfg, err := config.Get(); err == nil {
+			name = cfg.Multiplexer
+		}
+		m, err := New(name)
+		if err != nil {
+			m = Tmux{}
+		}
+		defaultMux = m
+	})
+	return defaultMux
+}
+
+// The functions below call the default multiplexer.
+
+// CreateSession starts a detached session running command.
+func CreateSession(name, cwd, command string, meta types.SessionMeta) error {
+	return Default().CreateSession(name, cwd, command, meta)
+}
+
+// ListSessions returns all coder sessions.
+func ListSessions() ([]types.Session, error) { return Default().ListSessions() }
+
+// SessionExists reports whether a session is running.
+func SessionExists(name string) bool { return Default().SessionExists(name) }
+
+// KillSession kills a session and its processes, and removes any cgroup
+// created for its resource limits.
+func KillSession(name string) error {
+	err := Default().KillSession(name)
+	limits.Cleanup(name)
+	return err
+}
+
+// AttachSession attaches the terminal to a session.
+func AttachSession(name string) error { return Default().AttachSession(name) }
+
+// AttachHint is the command a user can run to attach to a session.
+func AttachHint(name string) string { return Default().AttachHint(name) }
+
+// AttachSplit shows several sessions side by side.
+func AttachSplit(names []string) error { return Default().AttachSplit(names) }
+
+// CapturePane returns recent output from a session's active pane.
+func CapturePane(name string, opts CaptureOptions) (string, error) {
+	return Default().CapturePane(name, opts)
+}
+
+// SendKeys types text into a session and presses Enter.
+func SendKeys(name, text string) error { return Default().SendKeys(name, text) }
+
+// SendText types text into a session without pressing Enter.
+func SendText(name, text string) error { return Default().SendText(name, text) }
+
+// SendRawKeys sends key names to a session.
+func SendRawKeys(name string, keys ...string) error { return Default().SendRawKeys(name, keys...) }
+
+// PanePIDs returns the PIDs of the processes started in a session's panes.
+func PanePIDs(name string) ([]int, error) { return Default().PanePIDs(name) }
+
+// DisplayMessage shows a short message in a session.
+func DisplayMessage(name, message string) error { return Default().DisplayMessage(name, message) }
+
+// PipePane streams a session's pane output to a shell command.
+func PipePane(name, command string) error { return Default().PipePane(name, command) }
+
+// InsideSession reports whether this process runs inside the multiplexer.
+func InsideSession() bool { return Default().InsideSession() }
+
+// CurrentSession returns the session this process runs in.
+func CurrentSession() (string, error) { return Default().CurrentSession() }
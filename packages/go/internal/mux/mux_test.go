@@ -0,0 +1,193 @@
+package mux
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	for name, want := range map[string]string{"": "tmux", "tmux": "tmux", "zellij": "zellij"} {
+		m, err := New(name)
+		if err != nil {
+			t.Fatalf("New(%q) error: %v", name, err)
+		}
+		if m.Name() != want {
+			t.Errorf("New(%q).Name() = %q, want %q", name, m.Name(), want)
+		}
+	}
+
+	if _, err := New("screen"); err == nil {
+		t.Error("expected error for unknown multiplexer")
+	}
+}
+
+func TestParseZellijSessions(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	out := `coder-claude-fix-auth [Created 1h 2m 3s ago]
+coder-orchestrator [Created 2days 5s ago] (current)
+coder-gemini-old [Created 3days ago] (EXITED - attach to resurrect)
+my-work [Created 10m ago]
+coder-bogus-thing [Created 4s ago]
+`
+	sessions := parseZellijSessions(out, now)
+	if len(sessions) != 3 {
+		t.Fatalf("got %d sessions, want 3: %+v", len(sessions), sessions)
+	}
+
+	s := sessions[0]
+	if s.Name != "coder-claude-fix-auth" || s.Tool != "claude" || s.Task != "fix-auth" {
+		t.Errorf("unexpected session: %+v", s)
+	}
+	if s.CreatedAt == nil || !s.CreatedAt.Equal(now.Add(-(time.Hour + 2*time.Minute + 3*time.Second))) {
+		t.Errorf("CreatedAt = %v", s.CreatedAt)
+	}
+
+	if !sessions[1].IsOrchestrator {
+		t.Error("expected orchestrator session")
+	}
+	if want := now.Add(-(48*time.Hour + 5*time.Second)); !sessions[1].CreatedAt.Equal(want) {
+		t.Errorf("orchestrator CreatedAt = %v, want %v", sessions[1].CreatedAt, want)
+	}
+
+	if sessions[2].Tool != "unknown" {
+		t.Errorf("Tool = %q, want unknown", sessions[2].Tool)
+	}
+}
+
+func TestZellijLayout(t *testing.T) {
+	layout := zellijLayout("/tmp/my dir", `cd '/tmp/my dir' && echo "hi\there"`)
+	for _, want := range []string{
+		`pane command="sh" cwd="/tmp/my dir" {`,
+		`args "-c" "cd '/tmp/my dir' && echo \"hi\\there\""`,
+	} {
+		if !strings.Contains(layout, want) {
+			t.Errorf("layout missing %s:\n%s", want, layout)
+		}
+	}
+
+	if got := zellijLayout("", ""); got != "layout {\n    pane command=\"sh\"\n}\n" {
+		t.Errorf("empty layout = %q", got)
+	}
+}
+
+func TestKeyBytes(t *testing.T) {
+	tests := []struct {
+		key  string
+		want []byte
+	}{
+		{"Enter", []byte{13}},
+		{"Escape", []byte{27}},
+		{"Up", []byte{27, '[', 'A'}},
+		{"C-c", []byte{3}},
+		{"C-D", []byte{4}},
+		{"M-x", []byte{27, 'x'}},
+		{"BTab", []byte{27, '[', 'Z'}},
+		{"y", nil},
+		{"C-1", nil},
+	}
+	for _, tt := range tests {
+		if got := keyBytes(tt.key); string(got) != string(tt.want) {
+			t.Errorf("keyBytes(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []Key
+		named bool
+	}{
+		{"fix the tests", []Key{{Text: "fix the tests"}}, false},
+		{"<Down><down><Enter>", []Key{{Name: "Down"}, {Name: "Down"}, {Name: "Enter"}}, true},
+		{"y", []Key{{Text: "y"}}, false},
+		{"n<esc>", []Key{{Text: "n"}, {Name: "Escape"}}, true},
+		{"<C-c>", []Key{{Name: "C-c"}}, true},
+		{"<m-X>", []Key{{Name: "M-x"}}, true},
+		{"use <div> tags<cr>", []Key{{Text: "use <div> tags"}, {Name: "Enter"}}, true},
+		{"a <lt>tab> b", []Key{{Text: "a <tab> b"}}, false},
+		{"1 < 2", []Key{{Text: "1 < 2"}}, false},
+		{"x<<tab>", []Key{{Text: "x<"}, {Name: "Tab"}}, true},
+		{"", nil, false},
+	}
+	for _, tt := range tests {
+		got, named := ParseKeys(tt.input)
+		if !reflect.DeepEqual(got, tt.want) || named != tt.named {
+			t.Errorf("ParseKeys(%q) = %+v, %v; want %+v, %v", tt.input, got, named, tt.want, tt.named)
+		}
+	}
+}
+
+func TestLastLines(t *testing.T) {
+	s := "a\nb\nc\nd"
+	if got := lastLines(s, 2); got != "c\nd" {
+		t.Errorf("lastLines(2) = %q", got)
+	}
+	if got := lastLines(s, 0); got != s {
+		t.Errorf("lastLines(0) = %q", got)
+	}
+	if got := lastLines(s, 10); got != s {
+		t.Errorf("lastLines(10) = %q", got)
+	}
+}
+
+func TestZellijUnsupported(t *testing.T) {
+	z := Zellij{}
+	if err := z.DisplayMessage("coder-x", "hi"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("DisplayMessage error = %v, want ErrUnsupported", err)
+	}
+	if err := z.PipePane("coder-x", "cat"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("PipePane error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestZellijPanePIDsCache(t *testing.T) {
+	if _, err := os.Stat("/proc"); err != nil {
+		t.Skip("needs /proc")
+	}
+	const name = "coders-test-pane-pids"
+	start := func() *exec.Cmd {
+		t.Helper()
+		cmd := exec.Command("sleep", "30")
+		cmd.Env = append(os.Environ(), "ZELLIJ_SESSION_NAME="+name)
+		if err := cmd.Start(); err != nil {
+			t.Skipf("starting sleep: %v", err)
+		}
+		t.Cleanup(func() { cmd.Process.Kill(); cmd.Wait() })
+		return cmd
+	}
+	z := Zellij{}
+	defer forgetPanePIDs(name)
+
+	first := start()
+	pids, err := z.PanePIDs(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{first.Process.Pid}; !reflect.DeepEqual(pids, want) {
+		t.Fatalf("PanePIDs() = %v, want %v", pids, want)
+	}
+	panePIDCache.Lock()
+	cached := panePIDCache.pids[name]
+	panePIDCache.Unlock()
+	if !reflect.DeepEqual(cached, pids) {
+		t.Errorf("cached = %v, want %v", cached, pids)
+	}
+
+	// Once the cached process is gone the session is scanned again
+	first.Process.Kill()
+	first.Wait()
+	second := start()
+	pids, err = z.PanePIDs(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{second.Process.Pid}; !reflect.DeepEqual(pids, want) {
+		t.Errorf("PanePIDs() after exit = %v, want %v", pids, want)
+	}
+}
@@ -0,0 +1,72 @@
+package mux
+
+import (
+	"fmt"
+
+	"github.com/Jayphen/coders/internal/tmux"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+// Tmux hosts sessions in tmux. It delegates to the tmux package.
+type Tmux struct{}
+
+// Name implements Multiplexer.
+func (Tmux) Name() string { return "tmux" }
+
+// CreateSession implements Multiplexer.
+func (Tmux) CreateSession(name, cwd, command string, meta types.SessionMeta) error {
+	return tmux.CreateSession(name, cwd, command, meta)
+}
+
+// ListSessions implements Multiplexer.
+func (Tmux) ListSessions() ([]types.Session, error) { return tmux.ListSessions() }
+
+// SessionExists implements Multiplexer.
+func (Tmux) SessionExists(name string) bool { return tmux.SessionExists(name) }
+
+// KillSession implements Multiplexer.
+func (Tmux) KillSession(name string) error { return tmux.KillSession(name) }
+
+// AttachSession implements Multiplexer.
+func (Tmux) AttachSession(name string) error { return tmux.AttachSession(name) }
+
+// AttachHint implements Multiplexer.
+func (Tmux) AttachHint(name string) string {
+	return fmt.Sprintf("%s attach -t %s", tmux.ShellCommand(), name)
+}
+
+// AttachSplit implements Multiplexer.
+func (Tmux) AttachSplit(names []string) error { return tmux.AttachSplit(names) }
+
+// CapturePane implements Multiplexer.
+func (Tmux) CapturePane(name string, opts CaptureOptions) (string, error) {
+	return tmux.Capture(name, opts.Lines, opts.Escapes)
+}
+
+// SendKeys implements Multiplexer.
+func (Tmux) SendKeys(name, text string) error { return tmux.SendKeys(name, text) }
+
+// SendText implements Multiplexer.
+func (Tmux) SendText(name, text string) error { return tmux.SendText(name, text) }
+
+// SendRawKeys implements Multiplexer.
+func (Tmux) SendRawKeys(name string, keys ...string) error {
+	return tmux.SendRawKeys(name, keys...)
+}
+
+// PanePIDs implements Multiplexer.
+func (Tmux) PanePIDs(name string) ([]int, error) { return tmux.GetPanePIDs(name) }
+
+// DisplayMessage implements Multiplexer.
+func (Tmux) DisplayMessage(name, message string) error {
+	return tmux.SendDisplayMessage(name, message)
+}
+
+// PipePane implements Multiplexer.
+func (Tmux) PipePane(name, command string) error { return tmux.PipePane(name, command) }
+
+// InsideSession implements Multiplexer.
+func (Tmux) InsideSession() bool { return tmux.IsInsideTmux() }
+
+// CurrentSession implements Multiplexer.
+func (Tmux) CurrentSession() (string, error) { return tmux.GetCurrentSession() }
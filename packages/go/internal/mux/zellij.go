@@ -0,0 +1,492 @@
+package mux
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/proc"
+	"github.com/Jayphen/coders/internal/tmux"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+// Zellij hosts sessions in zellij (0.40 or newer) via its CLI.
+//
+// zellij has no equivalent of tmux's display-message or pipe-pane, so
+// DisplayMessage and PipePane return ErrUnsupported. Process lookups read
+// ZELLIJ_SESSION_NAME from /proc and only work on Linux. zellij has no
+// session user options, so session metadata is kept in a file alongside the
+// generated layout.
+type Zellij struct{}
+
+// zellijDir holds generated layouts and session metadata.
+func zellijDir() string {
+	return filepath.Join(config.StateDir(), "zellij")
+}
+
+// Name implements Multiplexer.
+func (Zellij) Name() string { return "zellij" }
+
+// CreateSession implements Multiplexer. The command is run from a generated
+// layout, since zellij can't take a command on the command line when
+// creating a background session.
+func (Zellij) CreateSession(name, cwd, command string, meta types.SessionMeta) error {
+	dir := zellijDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create layout directory: %w", err)
+	}
+	layoutPath := filepath.Join(dir, name+".kdl")
+	if err := os.WriteFile(layoutPath, []byte(zellijLayout(cwd, command)), 0600); err != nil {
+		return fmt.Errorf("failed to write layout: %w", err)
+	}
+	if data, err := json.Marshal(meta); err == nil {
+		_ = os.WriteFile(filepath.Join(dir, name+".json"), data, 0600)
+	}
+
+	args := []string{"attach", "--create-background", name, "options", "--default-layout", layoutPath}
+	if cwd != "" {
+		args = append(args, "--default-cwd", cwd)
+	}
+	if out, err := exec.Command("zellij", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("zellij: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// zellijLayout returns a single-pane KDL layout running command in cwd.
+func zellijLayout(cwd, command string) string {
+	var b strings.Builder
+	b.WriteString("layout {\n")
+	b.WriteString("    pane command=\"sh\"")
+	if cwd != "" {
+		fmt.Fprintf(&b, " cwd=%s", kdlString(cwd))
+	}
+	if command == "" {
+		b.WriteString("\n}\n")
+		return b.String()
+	}
+	b.WriteString(" {\n")
+	fmt.Fprintf(&b, "        args \"-c\" %s\n", kdlString(command))
+	b.WriteString("    }\n}\n")
+	return b.String()
+}
+
+// kdlString quotes s as a KDL string.
+func kdlString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// ListSessions implements Multiplexer.
+func (z Zellij) ListSessions() ([]types.Session, error) {
+	out, err := exec.Command("zellij", "list-sessions", "--no-formatting").Output()
+	if err != nil {
+		// zellij exits non-zero when there are no sessions
+		if _, ok := err.(*exec.ExitError); ok {
+			return []types.Session{}, nil
+		}
+		return nil, fmt.Errorf("failed to list zellij sessions: %w", err)
+	}
+
+	sessions := parseZellijSessions(string(out), time.Now())
+	for i := range sessions {
+		s := &sessions[i]
+		s.Cwd = z.sessionCwd(s.Name)
+		applyMeta(s, readZellijMeta(s.Name))
+	}
+	return sessions, nil
+}
+
+var (
+	zellijSessionLine  = regexp.MustCompile(`^(\S+)(?:\s+\[Created (.+?) ago\])?`)
+	zellijDurationPart = regexp.MustCompile(`(\d+)\s*(days?|h|m|s)`)
+)
+
+// parseZellijSessions parses `zellij list-sessions --no-formatting` output,
+// keeping running coder sessions.
+func parseZellijSessions(out string, now time.Time) []types.Session {
+	var sessions []types.Session
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.Contains(line, "EXITED") {
+			continue
+		}
+		m := zellijSessionLine.FindStringSubmatch(line)
+		if m == nil || !strings.HasPrefix(m[1], SessionPrefix) {
+			continue
+		}
+
+		name := m[1]
+		tool, task := tmux.ParseSessionName(name)
+
+		var createdAt *time.Time
+		if m[2] != "" {
+			t := now.Add(-parseZellijAge(m[2])).Truncate(time.Second)
+			createdAt = &t
+		}
+
+		sessions = append(sessions, types.Session{
+			Name:           name,
+			Tool:           tool,
+			Task:           task,
+			CreatedAt:      createdAt,
+			IsOrchestrator: name == OrchestratorSession,
+		})
+	}
+	return sessions
+}
+
+// readZellijMeta reads the metadata recorded when a session was created.
+func readZellijMeta(name string) types.SessionMeta {
+	var meta types.SessionMeta
+	if data, err := os.ReadFile(filepath.Join(zellijDir(), name+".json")); err == nil {
+		_ = json.Unmarshal(data, &meta)
+	}
+	return meta
+}
+
+// applyMeta overrides what was parsed from the session name with recorded metadata.
+func applyMeta(s *types.Session, meta types.SessionMeta) {
+	if meta.Tool != "" {
+		s.Tool = meta.Tool
+	}
+	if meta.Task != "" {
+		s.Task = meta.Task
+	}
+	s.ParentSessionID = meta.Parent
+	s.LoopID = meta.Loop
+	s.TaskID = meta.TaskID
+	s.Worktree = meta.Worktree
+}
+
+// parseZellijAge parses zellij's session age, e.g. "1day 2h 3m 4s".
+func parseZellijAge(s string) time.Duration {
+	var d time.Duration
+	for _, m := range zellijDurationPart.FindAllStringSubmatch(s, -1) {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "day", "days":
+			d += time.Duration(n) * 24 * time.Hour
+		case "h":
+			d += time.Duration(n) * time.Hour
+		case "m":
+			d += time.Duration(n) * time.Minute
+		case "s":
+			d += time.Duration(n) * time.Second
+		}
+	}
+	return d
+}
+
+// sessionCwd returns the working directory of a session's first pane process.
+func (z Zellij) sessionCwd(name string) string {
+	pids, err := z.PanePIDs(name)
+	if err != nil || len(pids) == 0 {
+		return ""
+	}
+	cwd, err := os.Readlink(fmt.Sprintf("/proc/%d/cwd", pids[0]))
+	if err != nil {
+		return ""
+	}
+	return cwd
+}
+
+// SessionExists implements Multiplexer.
+func (Zellij) SessionExists(name string) bool {
+	out, err := exec.Command("zellij", "list-sessions", "--no-formatting").Output()
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == name {
+			return !strings.Contains(line, "EXITED")
+		}
+	}
+	return false
+}
+
+// KillSession implements Multiplexer. Processes are killed before the session
+// so tools that ignore SIGHUP don't outlive it; the session is then deleted so
+// it can't be resurrected.
+func (z Zellij) KillSession(name string) error {
+	forgetPanePIDs(name)
+	if pids := z.sessionProcesses(name); len(pids) > 0 {
+		proc.Signal(pids, syscall.SIGTERM)
+		time.Sleep(300 * time.Millisecond)
+		proc.Signal(proc.FilterAlive(pids), syscall.SIGKILL)
+	}
+
+	err := exec.Command("zellij", "kill-session", name).Run()
+	_ = exec.Command("zellij", "delete-session", "--force", name).Run()
+	if err != nil && z.SessionExists(name) {
+		return err
+	}
+	os.Remove(filepath.Join(zellijDir(), name+".kdl"))
+	os.Remove(filepath.Join(zellijDir(), name+".json"))
+	return nil
+}
+
+// AttachSession implements Multiplexer.
+func (z Zellij) AttachSession(name string) error {
+	if z.InsideSession() {
+		// zellij can't switch a client to another session from the CLI
+		return fmt.Errorf("already inside zellij; detach (Ctrl+o d) and run: %s", z.AttachHint(name))
+	}
+	cmd := exec.Command("zellij", "attach", name)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// AttachHint implements Multiplexer.
+func (Zellij) AttachHint(name string) string { return fmt.Sprintf("zellij attach %s", name) }
+
+// AttachSplit implements Multiplexer. zellij refuses to attach from inside
+// one of its own sessions, so sessions can't be nested in panes.
+func (Zellij) AttachSplit(names []string) error {
+	return fmt.Errorf("attaching in split panes: %w", ErrUnsupported)
+}
+
+// CapturePane implements Multiplexer. zellij dumps plain text only, so
+// opts.Escapes is ignored.
+func (Zellij) CapturePane(name string, opts CaptureOptions) (string, error) {
+	f, err := os.CreateTemp("", "coders-dump-*.txt")
+	if err != nil {
+		return "", err
+	}
+	path := f.Name()
+	f.Close()
+	defer os.Remove(path)
+
+	if err := zellijAction(name, "dump-screen", "--full", path).Run(); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return lastLines(strings.TrimRight(string(data), "\n"), opts.Lines), nil
+}
+
+// lastLines returns the last n lines of s, or all of s if n <= 0.
+func lastLines(s string, n int) string {
+	if n <= 0 {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SendKeys implements Multiplexer.
+func (Zellij) SendKeys(name, text string) error {
+	if err := zellijAction(name, "write-chars", text).Run(); err != nil {
+		return err
+	}
+	// Small delay so the tool sees the text before Enter, as with tmux
+	time.Sleep(100 * time.Millisecond)
+	return zellijAction(name, "write", "13").Run()
+}
+
+// SendText implements Multiplexer.
+func (Zellij) SendText(name, text string) error {
+	return zellijAction(name, "write-chars", text).Run()
+}
+
+// SendRawKeys implements Multiplexer. tmux key names are translated to the
+// bytes a terminal would send; anything unrecognised is typed literally.
+func (Zellij) SendRawKeys(name string, keys ...string) error {
+	for _, key := range keys {
+		var cmd *exec.Cmd
+		if seq := keyBytes(key); seq != nil {
+			args := []string{"write"}
+			for _, b := range seq {
+				args = append(args, strconv.Itoa(int(b)))
+			}
+			cmd = zellijAction(name, args...)
+		} else {
+			cmd = zellijAction(name, "write-chars", key)
+		}
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var namedKeys = map[string][]byte{
+	"Enter":  {13},
+	"Escape": {27},
+	"Tab":    {9},
+	"BSpace": {127},
+	"Space":  {32},
+	"Up":     {27, '[', 'A'},
+	"Down":   {27, '[', 'B'},
+	"Right":  {27, '[', 'C'},
+	"Left":   {27, '[', 'D'},
+	"BTab":   {27, '[', 'Z'},
+	"Home":   {27, '[', 'H'},
+	"End":    {27, '[', 'F'},
+	"PPage":  {27, '[', '5', '~'},
+	"NPage":  {27, '[', '6', '~'},
+	"DC":     {27, '[', '3', '~'},
+}
+
+// keyBytes returns the terminal input for a tmux key name, or nil if the key
+// isn't a named key or control sequence.
+func keyBytes(key string) []byte {
+	if b, ok := namedKeys[key]; ok {
+		return b
+	}
+	if len(key) == 3 && strings.HasPrefix(key, "C-") {
+		c := key[2] | 0x20 // lower case
+		if c >= 'a' && c <= 'z' {
+			return []byte{c - 'a' + 1}
+		}
+	}
+	// Meta sends Escape before the key
+	if len(key) == 3 && strings.HasPrefix(key, "M-") {
+		return []byte{27, key[2]}
+	}
+	return nil
+}
+
+// panePIDCache holds each session's pane commands, so repeated lookups
+// don't read the environment of every process on the system.
+var panePIDCache = struct {
+	sync.Mutex
+	pids map[string][]int
+}{pids: make(map[string][]int)}
+
+// PanePIDs implements Multiplexer. It returns the processes in the session
+// whose parent isn't in the session, i.e. the pane commands. The result is
+// cached per session and reused while those processes are still in it.
+func (z Zellij) PanePIDs(name string) ([]int, error) {
+	if _, err := os.Stat("/proc"); err != nil {
+		return nil, fmt.Errorf("zellij pane lookup needs /proc: %w", ErrUnsupported)
+	}
+
+	panePIDCache.Lock()
+	cached := panePIDCache.pids[name]
+	panePIDCache.Unlock()
+	if len(cached) > 0 && allInSession(cached, name) {
+		return append([]int(nil), cached...), nil
+	}
+
+	roots := z.scanPanePIDs(name)
+	panePIDCache.Lock()
+	if len(roots) > 0 {
+		panePIDCache.pids[name] = roots
+	} else {
+		delete(panePIDCache.pids, name)
+	}
+	panePIDCache.Unlock()
+	return append([]int(nil), roots...), nil
+}
+
+// forgetPanePIDs drops a session's cached pane commands.
+func forgetPanePIDs(name string) {
+	panePIDCache.Lock()
+	delete(panePIDCache.pids, name)
+	panePIDCache.Unlock()
+}
+
+// scanPanePIDs finds a session's pane commands by scanning /proc.
+func (z Zellij) scanPanePIDs(name string) []int {
+	inSession := make(map[int]bool)
+	for _, pid := range z.sessionProcesses(name) {
+		inSession[pid] = true
+	}
+
+	var roots []int
+	for pid := range inSession {
+		p, err := proc.Get(pid)
+		if err == nil && !inSession[p.PPID] {
+			roots = append(roots, pid)
+		}
+	}
+	sort.Ints(roots)
+	return roots
+}
+
+// allInSession reports whether every pid is still a process in the named
+// zellij session. Checking the environment catches reused PIDs.
+func allInSession(pids []int, name string) bool {
+	for _, pid := range pids {
+		if !inZellijSession(pid, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// sessionProcesses returns every process whose environment places it in the
+// named zellij session, excluding this process.
+func (Zellij) sessionProcesses(name string) []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	self := os.Getpid()
+
+	var pids []int
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		if inZellijSession(pid, name) {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// inZellijSession reports whether pid's environment places it in the named
+// zellij session.
+func inZellijSession(pid int, name string) bool {
+	env, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "environ"))
+	if err != nil {
+		return false
+	}
+	// Pad with NULs so the first and last variables match too
+	env = append(append([]byte{0}, env...), 0)
+	return bytes.Contains(env, []byte("\x00ZELLIJ_SESSION_NAME="+name+"\x00"))
+}
+
+// DisplayMessage implements Multiplexer.
+func (Zellij) DisplayMessage(name, message string) error { return ErrUnsupported }
+
+// PipePane implements Multiplexer.
+func (Zellij) PipePane(name, command string) error { return ErrUnsupported }
+
+// InsideSession implements Multiplexer.
+func (Zellij) InsideSession() bool { return os.Getenv("ZELLIJ") != "" }
+
+// CurrentSession implements Multiplexer.
+func (Zellij) CurrentSession() (string, error) {
+	if name := os.Getenv("ZELLIJ_SESSION_NAME"); name != "" {
+		return name, nil
+	}
+	return "", fmt.Errorf("not inside zellij")
+}
+
+// zellijAction builds a `zellij action` command targeting a session.
+func zellijAction(session string, args ...string) *exec.Cmd {
+	return exec.Command("zellij", append([]string{"--session", session, "action"}, args...)...)
+}
@@ -0,0 +1,299 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpTimeout bounds every HTTP notification request.
+const httpTimeout = 10 * time.Second
+
+// smtpTimeout bounds sending one email, from dialing to QUIT.
+const smtpTimeout = 30 * time.Second
+
+// DesktopNotifier sends OS-native notifications.
+type DesktopNotifier struct{}
+
+// Notify implements Notifier.
+func (DesktopNotifier) Notify(ctx context.Context, event Event) error {
+	return sendDesktop(event.Title, event.Message)
+}
+
+// WebhookNotifier POSTs the event as JSON to a URL.
+type WebhookNotifier struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// Notify implements Notifier.
+func (n *WebhookNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return post(ctx, n.Client, n.URL, "application/json", body, n.Headers)
+}
+
+// SlackNotifier posts to a Slack-compatible incoming webhook.
+type SlackNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify implements Notifier.
+func (n *SlackNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("*%s*\n%s", event.Title, event.Message),
+	})
+	if err != nil {
+		return err
+	}
+	return post(ctx, n.Client, n.URL, "application/json", body, nil)
+}
+
+// NtfyNotifier publishes to an ntfy topic.
+type NtfyNotifier struct {
+	URL    string // Server URL, e.g. https://ntfy.sh
+	Topic  string
+	Token  string
+	Client *http.Client
+}
+
+// Notify implements Notifier.
+func (n *NtfyNotifier) Notify(ctx context.Context, event Event) error {
+	headers := map[string]string{
+		"Title": event.Title,
+		"Tags":  string(event.Type),
+	}
+	if event.Urgent() {
+		headers["Priority"] = "high"
+	}
+	if n.Token != "" {
+		headers["Authorization"] = "Bearer " + n.Token
+	}
+	url := strings.TrimRight(n.URL, "/") + "/" + n.Topic
+	return post(ctx, n.Client, url, "text/plain", []byte(event.Message), headers)
+}
+
+// GotifyNotifier sends a message to a Gotify server.
+type GotifyNotifier struct {
+	URL    string // Server URL
+	Token  string // Application token
+	Client *http.Client
+}
+
+// Notify implements Notifier.
+func (n *GotifyNotifier) Notify(ctx context.Context, event Event) error {
+	priority := 5
+	if event.Urgent() {
+		priority = 8
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"title":    event.Title,
+		"message":  event.Message,
+		"priority": priority,
+	})
+	if err != nil {
+		return err
+	}
+	url := strings.TrimRight(n.URL, "/") + "/message"
+	return post(ctx, n.Client, url, "application/json", body, map[string]string{"X-Gotify-Key": n.Token})
+}
+
+// EmailNotifier sends mail over SMTP.
+type EmailNotifier struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Notify implements Notifier.
+func (n *EmailNotifier) Notify(ctx context.Context, event Event) error {
+	if len(n.To) == 0 {
+		return fmt.Errorf("email channel has no recipients")
+	}
+	port := n.Port
+	if port == 0 {
+		port = 587
+	}
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+	addr := net.JoinHostPort(n.Host, strconv.Itoa(port))
+
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// smtp.Client has no context support: the deadline bounds every read and
+	// write, and cancellation closes the connection
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := sendMail(conn, n.Host, auth, n.From, n.To, buildEmail(n.From, n.To, event)); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("smtp %s: %w", addr, ctx.Err())
+		}
+		return err
+	}
+	return nil
+}
+
+// sendMail does what smtp.SendMail does over an established connection:
+// STARTTLS when offered, AUTH, then the message.
+func sendMail(conn net.Conn, host string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// buildEmail formats an event as an RFC 5322 message.
+func buildEmail(from string, to []string, event Event) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: [coders] %s\r\n", sanitizeHeader(event.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", event.Timestamp.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(event.Message)
+	b.WriteString("\r\n")
+	if event.SessionID != "" {
+		fmt.Fprintf(&b, "\r\nSession: %s\r\n", event.SessionID)
+	}
+	if event.LoopID != "" {
+		fmt.Fprintf(&b, "Loop: %s\r\n", event.LoopID)
+	}
+	return b.Bytes()
+}
+
+// sanitizeHeader strips line breaks so values cannot inject headers.
+func sanitizeHeader(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
+
+// ExecNotifier runs a shell command for each event. The event is passed as
+// JSON on stdin and as CODERS_EVENT_* environment variables.
+type ExecNotifier struct {
+	Command string
+}
+
+// Notify implements Notifier.
+func (n *ExecNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", n.Command)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"CODERS_EVENT_TYPE="+string(event.Type),
+		"CODERS_EVENT_TITLE="+event.Title,
+		"CODERS_EVENT_MESSAGE="+event.Message,
+		"CODERS_EVENT_SESSION="+event.SessionID,
+		"CODERS_EVENT_LOOP="+event.LoopID,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		msg := strings.TrimSpace(string(out))
+		if msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// post sends an HTTP POST and treats any non-2xx response as an error.
+func post(ctx context.Context, client *http.Client, url, contentType string, body []byte, headers map[string]string) error {
+	if client == nil {
+		client = &http.Client{Timeout: httpTimeout}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", url, resp.Status, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingServer is a local HTTP stand-in that records every request.
+type recordingServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []recordedRequest
+	status   int
+}
+
+type recordedRequest struct {
+	Path    string
+	Headers http.Header
+	Body    string
+}
+
+func newRecordingServer(t *testing.T) *recordingServer {
+	t.Helper()
+	rs := &recordingServer{status: http.StatusOK}
+	rs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rs.mu.Lock()
+		rs.requests = append(rs.requests, recordedRequest{Path: r.URL.Path, Headers: r.Header.Clone(), Body: string(body)})
+		status := rs.status
+		rs.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rs.Close)
+	return rs
+}
+
+func (rs *recordingServer) Requests() []recordedRequest {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return append([]recordedRequest(nil), rs.requests...)
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	srv := newRecordingServer(t)
+	n := &WebhookNotifier{URL: srv.URL + "/hook", Headers: map[string]string{"X-Token": "secret"}}
+
+	event := Event{Type: EventPromiseCompleted, Title: "Done", Message: "All tests pass", SessionID: "coder-a"}
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(reqs))
+	}
+	if reqs[0].Headers.Get("X-Token") != "secret" {
+		t.Errorf("custom header not sent")
+	}
+	var got Event
+	if err := json.Unmarshal([]byte(reqs[0].Body), &got); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if got.Type != EventPromiseCompleted || got.SessionID != "coder-a" {
+		t.Errorf("unexpected payload: %+v", got)
+	}
+}
+
+func TestWebhookNotifierErrorStatus(t *testing.T) {
+	srv := newRecordingServer(t)
+	srv.status = http.StatusInternalServerError
+	n := &WebhookNotifier{URL: srv.URL}
+
+	if err := n.Notify(context.Background(), Event{Type: EventCrash}); err == nil {
+		t.Error("expected error for 500 response")
+	}
+}
+
+func TestSlackNotifier(t *testing.T) {
+	srv := newRecordingServer(t)
+	n := &SlackNotifier{URL: srv.URL}
+
+	if err := n.Notify(context.Background(), Event{Title: "Blocked", Message: "Need API key"}); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+
+	var payload map[string]string
+	if err := json.Unmarshal([]byte(srv.Requests()[0].Body), &payload); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if payload["text"] != "*Blocked*\nNeed API key" {
+		t.Errorf("text = %q", payload["text"])
+	}
+}
+
+func TestNtfyNotifier(t *testing.T) {
+	srv := newRecordingServer(t)
+	n := &NtfyNotifier{URL: srv.URL + "/", Topic: "coders", Token: "tk"}
+
+	if err := n.Notify(context.Background(), Event{Type: EventCrash, Title: "Crashed", Message: "coder-a crashed"}); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+
+	req := srv.Requests()[0]
+	if req.Path != "/coders" {
+		t.Errorf("path = %q, want /coders", req.Path)
+	}
+	if req.Headers.Get("Title") != "Crashed" || req.Headers.Get("Priority") != "high" {
+		t.Errorf("unexpected headers: %v", req.Headers)
+	}
+	if req.Headers.Get("Authorization") != "Bearer tk" {
+		t.Errorf("missing auth header")
+	}
+	if req.Body != "coder-a crashed" {
+		t.Errorf("body = %q", req.Body)
+	}
+}
+
+func TestGotifyNotifier(t *testing.T) {
+	srv := newRecordingServer(t)
+	n := &GotifyNotifier{URL: srv.URL, Token: "app-token"}
+
+	if err := n.Notify(context.Background(), Event{Type: EventPromiseCompleted, Title: "Done", Message: "ok"}); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+
+	req := srv.Requests()[0]
+	if req.Path != "/message" || req.Headers.Get("X-Gotify-Key") != "app-token" {
+		t.Errorf("unexpected request: %s %v", req.Path, req.Headers)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(req.Body), &payload); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if payload["priority"] != float64(5) {
+		t.Errorf("priority = %v, want 5", payload["priority"])
+	}
+}
+
+func TestExecNotifier(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "event.json")
+	n := &ExecNotifier{Command: `cat > "` + out + `"; echo "$CODERS_EVENT_TYPE" >> "` + out + `"`}
+
+	if err := n.Notify(context.Background(), Event{Type: EventLoopFinished, Message: "3 tasks"}); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	if !strings.Contains(string(data), `"message":"3 tasks"`) || !strings.HasSuffix(strings.TrimSpace(string(data)), "loop.finished") {
+		t.Errorf("unexpected hook output: %s", data)
+	}
+}
+
+func TestBuildEmail(t *testing.T) {
+	event := Event{
+		Title:     "Blocked\r\nBcc: evil@example.com",
+		Message:   "Needs credentials",
+		SessionID: "coder-a",
+		Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	msg := string(buildEmail("coders@example.com", []string{"a@example.com", "b@example.com"}, event))
+
+	if !strings.Contains(msg, "To: a@example.com, b@example.com\r\n") {
+		t.Errorf("missing To header:\n%s", msg)
+	}
+	if strings.Contains(msg, "\r\nBcc:") {
+		t.Errorf("header injection not prevented:\n%s", msg)
+	}
+	if !strings.Contains(msg, "Session: coder-a") {
+		t.Errorf("missing session:\n%s", msg)
+	}
+}
+
+func TestEmailNotifierHonorsContext(t *testing.T) {
+	// A server that accepts connections but never sends its greeting; they
+	// stay open until the listener is closed
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	n := &EmailNotifier{Host: "127.0.0.1", Port: addr.Port, From: "coders@example.com", To: []string{"a@example.com"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := n.Notify(ctx, Event{Title: "Stuck", Message: "no output"}); err == nil {
+		t.Fatal("Notify() succeeded against a silent server")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Notify() took %s, want it bounded by the context", elapsed)
+	}
+}
+
+func TestEventJSONDurationMs(t *testing.T) {
+	event := Event{Type: EventStuck, SessionID: "coder-a", Duration: 90 * time.Second}
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	if raw["durationMs"] != float64(90000) {
+		t.Errorf("durationMs = %v, want 90000", raw["durationMs"])
+	}
+	if raw["sessionId"] != "coder-a" {
+		t.Errorf("sessionId = %v, want coder-a", raw["sessionId"])
+	}
+
+	var got Event
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() into Event failed: %v", err)
+	}
+	if got.Duration != event.Duration || got.SessionID != event.SessionID {
+		t.Errorf("round trip = %+v, want %+v", got, event)
+	}
+}
+
+func TestEventUrgent(t *testing.T) {
+	urgent := map[EventType]bool{
+		EventPromiseCompleted:   false,
+		EventPromiseBlocked:     true,
+		EventPromiseNeedsReview: false,
+		EventCrash:              true,
+		EventMaxRestarts:        true,
+		EventStuck:              true,
+		EventWaitingInput:       true,
+		EventOverLimit:          true,
+		EventLoopFinished:       false,
+	}
+	for eventType, want := range urgent {
+		if got := (Event{Type: eventType}).Urgent(); got != want {
+			t.Errorf("Event{Type: %s}.Urgent() = %v, want %v", eventType, got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// EventType identifies what happened.
+type EventType string
+
+const (
+	EventPromiseCompleted   EventType = "promise.completed"
+	EventPromiseBlocked     EventType = "promise.blocked"
+	EventPromiseNeedsReview EventType = "promise.needs-review"
+	EventCrash              EventType = "session.crashed"
+	EventMaxRestarts        EventType = "session.max-restarts"
+	EventStuck              EventType = "session.stuck"
+	EventWaitingInput       EventType = "session.waiting-input"
+	EventOverLimit          EventType = "session.over-limit"
+	EventLoopFinished       EventType = "loop.finished"
+)
+
+// Event is a notification-worthy occurrence.
+type Event struct {
+	Type      EventType         `json:"type"`
+	Title     string            `json:"title"`
+	Message   string            `json:"message"`
+	SessionID string            `json:"sessionId,omitempty"`
+	LoopID    string            `json:"loopId,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+	Fields    map[string]string `json:"fields,omitempty"`
+
+	// Duration is how long the condition has lasted (e.g. time stuck).
+	// Rules with a minimum duration only match events at least this long.
+	// It is encoded as whole milliseconds.
+	Duration time.Duration `json:"-"`
+
+	// DedupKey overrides the key used to suppress duplicate notifications.
+	// Defaults to the event type, session, loop and message.
+	DedupKey string `json:"-"`
+}
+
+// eventJSON is the wire form of an Event, with Duration in milliseconds.
+type eventJSON struct {
+	eventFields
+	DurationMs int64 `json:"durationMs,omitempty"`
+}
+
+// eventFields has Event's fields without its methods, so encoding it
+// doesn't recurse into MarshalJSON.
+type eventFields Event
+
+// MarshalJSON implements json.Marshaler.
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal(eventJSON{eventFields: eventFields(e), DurationMs: e.Duration.Milliseconds()})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var v eventJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*e = Event(v.eventFields)
+	e.Duration = time.Duration(v.DurationMs) * time.Millisecond
+	return nil
+}
+
+// Notifier delivers events to a single channel.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Urgent reports whether the event signals a problem rather than progress.
+// Backends use this to raise priority.
+func (e Event) Urgent() bool {
+	switch e.Type {
+	case EventPromiseBlocked, EventCrash, EventMaxRestarts, EventStuck,
+		EventWaitingInput, EventOverLimit:
+		return true
+	}
+	return false
+}
+
+func (e Event) dedupKey() string {
+	if e.DedupKey != "" {
+		return e.DedupKey
+	}
+	return string(e.Type) + "|" + e.SessionID + "|" + e.LoopID + "|" + e.Message
+}
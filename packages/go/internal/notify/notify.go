@@ -1,4 +1,5 @@
-// Package notify provides OS-native notification functionality.
+// Package notify provides OS-native notifications and pluggable
+// notification channels with rule-based routing.
 package notify
 
 import (
@@ -17,27 +18,31 @@ import (
 func Send(title, message string) {
 	// Run notification in background goroutine to make it non-blocking
 	go func() {
-		var cmd *exec.Cmd
+		// Ignore errors (fail silently) so a missing notification
+		// command never crashes the caller
+		_ = sendDesktop(title, message)
+	}()
+}
 
-		switch runtime.GOOS {
-		case "darwin":
-			// macOS: Use osascript with AppleScript
-			script := fmt.Sprintf(`display notification "%s" with title "%s"`, escapeAppleScript(message), escapeAppleScript(title))
-			cmd = exec.Command("osascript", "-e", script)
+// sendDesktop sends an OS-native notification and waits for the command to finish.
+func sendDesktop(title, message string) error {
+	var cmd *exec.Cmd
 
-		case "linux":
-			// Linux: Use notify-send
-			cmd = exec.Command("notify-send", title, message)
+	switch runtime.GOOS {
+	case "darwin":
+		// macOS: Use osascript with AppleScript
+		script := fmt.Sprintf(`display notification "%s" with title "%s"`, escapeAppleScript(message), escapeAppleScript(title))
+		cmd = exec.Command("osascript", "-e", script)
 
-		default:
-			// Unsupported platform - fail silently
-			return
-		}
+	case "linux":
+		// Linux: Use notify-send
+		cmd = exec.Command("notify-send", title, message)
 
-		// Execute the command and ignore errors (fail silently)
-		// This prevents crashes if the notification command is not available
-		_ = cmd.Run()
-	}()
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+
+	return cmd.Run()
 }
 
 // escapeAppleScript escapes special characters for AppleScript strings.
@@ -0,0 +1,322 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jayphen/coders/internal/config"
+)
+
+// Router delivers events to channels according to routing rules, applying
+// quiet hours and deduplication.
+type Router struct {
+	channels    map[string]Notifier
+	rules       []config.NotificationRule
+	quiet       *quietHours
+	dedupWindow time.Duration
+
+	mu   sync.Mutex
+	sent map[string]time.Time // dedup key + channel -> last delivery
+
+	// now is replaceable for tests
+	now func() time.Time
+}
+
+// NewRouter builds a router from notification config.
+func NewRouter(cfg config.NotificationsConfig) (*Router, error) {
+	channels := make(map[string]Notifier, len(cfg.Channels))
+	for name, ch := range cfg.Channels {
+		n, err := NewNotifier(ch)
+		if err != nil {
+			return nil, fmt.Errorf("notification channel %q: %w", name, err)
+		}
+		channels[name] = n
+	}
+
+	for i, rule := range cfg.Rules {
+		for _, name := range rule.Channels {
+			if _, ok := channels[name]; !ok {
+				return nil, fmt.Errorf("notification rule %d: unknown channel %q", i+1, name)
+			}
+		}
+	}
+
+	quiet, err := parseQuietHours(cfg.QuietHours)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Router{
+		channels:    channels,
+		rules:       cfg.Rules,
+		quiet:       quiet,
+		dedupWindow: cfg.DedupWindow,
+		sent:        make(map[string]time.Time),
+		now:         time.Now,
+	}, nil
+}
+
+// NewNotifier creates the backend for a channel.
+func NewNotifier(ch config.NotificationChannel) (Notifier, error) {
+	switch ch.Type {
+	case "desktop":
+		return DesktopNotifier{}, nil
+	case "webhook":
+		if ch.URL == "" {
+			return nil, fmt.Errorf("webhook requires url")
+		}
+		return &WebhookNotifier{URL: ch.URL, Headers: ch.Headers}, nil
+	case "slack":
+		if ch.URL == "" {
+			return nil, fmt.Errorf("slack requires url")
+		}
+		return &SlackNotifier{URL: ch.URL}, nil
+	case "ntfy":
+		if ch.Topic == "" {
+			return nil, fmt.Errorf("ntfy requires topic")
+		}
+		url := ch.URL
+		if url == "" {
+			url = "https://ntfy.sh"
+		}
+		return &NtfyNotifier{URL: url, Topic: ch.Topic, Token: ch.Token}, nil
+	case "gotify":
+		if ch.URL == "" || ch.Token == "" {
+			return nil, fmt.Errorf("gotify requires url and token")
+		}
+		return &GotifyNotifier{URL: ch.URL, Token: ch.Token}, nil
+	case "email":
+		if ch.SMTPHost == "" || ch.From == "" || len(ch.To) == 0 {
+			return nil, fmt.Errorf("email requires smtp_host, from and to")
+		}
+		return &EmailNotifier{
+			Host:     ch.SMTPHost,
+			Port:     ch.SMTPPort,
+			Username: ch.Username,
+			Password: ch.Password,
+			From:     ch.From,
+			To:       ch.To,
+		}, nil
+	case "exec":
+		if ch.Command == "" {
+			return nil, fmt.Errorf("exec requires command")
+		}
+		return &ExecNotifier{Command: ch.Command}, nil
+	default:
+		return nil, fmt.Errorf("unknown channel type %q", ch.Type)
+	}
+}
+
+// Route delivers an event to every channel selected by the rules.
+// Returns the channels the event was delivered to.
+func (r *Router) Route(ctx context.Context, event Event) ([]string, error) {
+	now := r.now()
+	if event.Timestamp.IsZero() {
+		event.Timestamp = now
+	}
+
+	quiet := r.quiet != nil && r.quiet.contains(now)
+	targets := make(map[string]bool)
+	for _, rule := range r.rules {
+		if !ruleMatches(rule, event) {
+			continue
+		}
+		if quiet && !rule.IgnoreQuietHours {
+			continue
+		}
+		for _, name := range rule.Channels {
+			targets[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	return r.deliver(ctx, names, event, now)
+}
+
+// RouteTo delivers an event to the given channels instead of those selected
+// by the rules. Quiet hours and deduplication still apply.
+func (r *Router) RouteTo(ctx context.Context, channels []string, event Event) ([]string, error) {
+	now := r.now()
+	if event.Timestamp.IsZero() {
+		event.Timestamp = now
+	}
+	if r.quiet != nil && r.quiet.contains(now) {
+		return nil, nil
+	}
+
+	for _, name := range channels {
+		if _, ok := r.channels[name]; !ok {
+			return nil, fmt.Errorf("unknown channel %q", name)
+		}
+	}
+	return r.deliver(ctx, append([]string(nil), channels...), event, now)
+}
+
+// deliver sends an event to each named channel, skipping duplicates.
+func (r *Router) deliver(ctx context.Context, names []string, event Event, now time.Time) ([]string, error) {
+	sort.Strings(names)
+
+	var delivered []string
+	var errs []error
+	for _, name := range names {
+		if !r.claim(event.dedupKey()+"|"+name, now) {
+			continue
+		}
+		if err := r.channels[name].Notify(ctx, event); err != nil {
+			r.release(event.dedupKey()+"|"+name, now)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		delivered = append(delivered, name)
+	}
+
+	return delivered, errors.Join(errs...)
+}
+
+// Send delivers an event directly to a named channel, bypassing rules,
+// quiet hours and deduplication.
+func (r *Router) Send(ctx context.Context, channel string, event Event) error {
+	n, ok := r.channels[channel]
+	if !ok {
+		return fmt.Errorf("unknown channel %q", channel)
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = r.now()
+	}
+	return n.Notify(ctx, event)
+}
+
+// HasChannel reports whether a channel is configured.
+func (r *Router) HasChannel(name string) bool {
+	_, ok := r.channels[name]
+	return ok
+}
+
+// Channels returns the configured channel names, sorted.
+func (r *Router) Channels() []string {
+	names := make([]string, 0, len(r.channels))
+	for name := range r.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// claim records a delivery, returning false if an identical one happened
+// within the dedup window.
+func (r *Router) claim(key string, now time.Time) bool {
+	if r.dedupWindow <= 0 {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Drop expired entries so the map doesn't grow forever in long-running watchers
+	for k, t := range r.sent {
+		if now.Sub(t) >= r.dedupWindow {
+			delete(r.sent, k)
+		}
+	}
+
+	if _, ok := r.sent[key]; ok {
+		return false
+	}
+	r.sent[key] = now
+	return true
+}
+
+// release forgets a failed delivery so it can be retried.
+func (r *Router) release(key string, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.sent[key]; ok && t.Equal(at) {
+		delete(r.sent, key)
+	}
+}
+
+func ruleMatches(rule config.NotificationRule, event Event) bool {
+	if rule.MinDuration > 0 && event.Duration < rule.MinDuration {
+		return false
+	}
+	for _, e := range rule.Events {
+		if e == "*" || EventType(e) == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// quietHours is a daily window in minutes since local midnight.
+type quietHours struct {
+	start, end int
+}
+
+func parseQuietHours(cfg config.QuietHoursConfig) (*quietHours, error) {
+	if cfg.Start == "" && cfg.End == "" {
+		return nil, nil
+	}
+	start, err := parseClock(cfg.Start)
+	if err != nil {
+		return nil, fmt.Errorf("quiet_hours.start: %w", err)
+	}
+	end, err := parseClock(cfg.End)
+	if err != nil {
+		return nil, fmt.Errorf("quiet_hours.end: %w", err)
+	}
+	return &quietHours{start: start, end: end}, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (want HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (q *quietHours) contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if q.start <= q.end {
+		return m >= q.start && m < q.end
+	}
+	// Window wraps past midnight, e.g. 22:00-07:00
+	return m >= q.start || m < q.end
+}
+
+var (
+	defaultRouter    *Router
+	defaultRouterErr error
+	defaultOnce      sync.Once
+)
+
+// Default returns the router built from the global configuration.
+func Default() (*Router, error) {
+	defaultOnce.Do(func() {
+		cfg, err := config.Get()
+		if err != nil {
+			defaultRouterErr = err
+			return
+		}
+		defaultRouter, defaultRouterErr = NewRouter(cfg.Notifications)
+	})
+	return defaultRouter, defaultRouterErr
+}
+
+// Emit routes an event through the default router.
+func Emit(ctx context.Context, event Event) error {
+	router, err := Default()
+	if err != nil {
+		return err
+	}
+	_, err = router.Route(ctx, event)
+	return err
+}
@@ -0,0 +1,189 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jayphen/coders/internal/config"
+)
+
+// fakeNotifier records events for router tests.
+type fakeNotifier struct {
+	events []Event
+	err    error
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, event Event) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.events = append(f.events, event)
+	return nil
+}
+
+func newTestRouter(rules []config.NotificationRule, channels map[string]Notifier) *Router {
+	return &Router{
+		channels:    channels,
+		rules:       rules,
+		dedupWindow: 10 * time.Minute,
+		sent:        make(map[string]time.Time),
+		now:         time.Now,
+	}
+}
+
+func TestRouterRules(t *testing.T) {
+	desktop, phone := &fakeNotifier{}, &fakeNotifier{}
+	r := newTestRouter([]config.NotificationRule{
+		{Events: []string{"*"}, Channels: []string{"desktop"}},
+		{Events: []string{string(EventPromiseBlocked), string(EventCrash)}, Channels: []string{"phone"}},
+		{Events: []string{string(EventStuck)}, Channels: []string{"phone"}, MinDuration: 30 * time.Minute},
+	}, map[string]Notifier{"desktop": desktop, "phone": phone})
+
+	ctx := context.Background()
+	tests := []struct {
+		event Event
+		want  []string
+	}{
+		{Event{Type: EventPromiseCompleted, SessionID: "a"}, []string{"desktop"}},
+		{Event{Type: EventPromiseBlocked, SessionID: "a"}, []string{"desktop", "phone"}},
+		{Event{Type: EventStuck, SessionID: "b", Duration: 10 * time.Minute}, []string{"desktop"}},
+		{Event{Type: EventStuck, SessionID: "c", Duration: 45 * time.Minute}, []string{"desktop", "phone"}},
+	}
+	for _, tt := range tests {
+		got, err := r.Route(ctx, tt.event)
+		if err != nil {
+			t.Fatalf("Route(%s) failed: %v", tt.event.Type, err)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("Route(%s, %s) delivered to %v, want %v", tt.event.Type, tt.event.Duration, got, tt.want)
+		}
+	}
+}
+
+func TestRouterDedup(t *testing.T) {
+	desktop := &fakeNotifier{}
+	r := newTestRouter(config.DefaultNotificationRules(), map[string]Notifier{"desktop": desktop})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)
+	r.now = func() time.Time { return now }
+
+	event := Event{Type: EventCrash, SessionID: "a", Message: "crashed"}
+	ctx := context.Background()
+
+	r.Route(ctx, event)
+	r.Route(ctx, event)
+	if len(desktop.events) != 1 {
+		t.Fatalf("expected duplicate to be suppressed, got %d deliveries", len(desktop.events))
+	}
+
+	// A different session is not a duplicate
+	r.Route(ctx, Event{Type: EventCrash, SessionID: "b", Message: "crashed"})
+	if len(desktop.events) != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", len(desktop.events))
+	}
+
+	// After the window the same event is delivered again
+	now = now.Add(11 * time.Minute)
+	r.Route(ctx, event)
+	if len(desktop.events) != 3 {
+		t.Fatalf("expected delivery after dedup window, got %d", len(desktop.events))
+	}
+}
+
+func TestRouterRetriesFailedDelivery(t *testing.T) {
+	failing := &fakeNotifier{err: errors.New("offline")}
+	r := newTestRouter(config.DefaultNotificationRules(), map[string]Notifier{"desktop": failing})
+	event := Event{Type: EventCrash, SessionID: "a"}
+
+	if _, err := r.Route(context.Background(), event); err == nil {
+		t.Fatal("expected delivery error")
+	}
+
+	failing.err = nil
+	got, err := r.Route(context.Background(), event)
+	if err != nil || len(got) != 1 {
+		t.Errorf("failed delivery should not be deduplicated: got %v, %v", got, err)
+	}
+}
+
+func TestRouterQuietHours(t *testing.T) {
+	desktop, phone := &fakeNotifier{}, &fakeNotifier{}
+	r := newTestRouter([]config.NotificationRule{
+		{Events: []string{"*"}, Channels: []string{"desktop"}},
+		{Events: []string{string(EventCrash)}, Channels: []string{"phone"}, IgnoreQuietHours: true},
+	}, map[string]Notifier{"desktop": desktop, "phone": phone})
+
+	quiet, err := parseQuietHours(config.QuietHoursConfig{Start: "22:00", End: "07:00"})
+	if err != nil {
+		t.Fatalf("parseQuietHours() failed: %v", err)
+	}
+	r.quiet = quiet
+	r.now = func() time.Time { return time.Date(2026, 1, 1, 23, 30, 0, 0, time.Local) }
+
+	got, _ := r.Route(context.Background(), Event{Type: EventCrash, SessionID: "a"})
+	if strings.Join(got, ",") != "phone" {
+		t.Errorf("during quiet hours delivered to %v, want [phone]", got)
+	}
+
+	r.now = func() time.Time { return time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local) }
+	got, _ = r.Route(context.Background(), Event{Type: EventCrash, SessionID: "b"})
+	if strings.Join(got, ",") != "desktop,phone" {
+		t.Errorf("outside quiet hours delivered to %v, want [desktop phone]", got)
+	}
+}
+
+func TestQuietHoursContains(t *testing.T) {
+	day := &quietHours{start: 9 * 60, end: 17 * 60}
+	night := &quietHours{start: 22 * 60, end: 7 * 60}
+	at := func(h, m int) time.Time { return time.Date(2026, 1, 1, h, m, 0, 0, time.Local) }
+
+	if !day.contains(at(12, 0)) || day.contains(at(17, 0)) || day.contains(at(8, 59)) {
+		t.Error("daytime window misclassified")
+	}
+	if !night.contains(at(23, 0)) || !night.contains(at(6, 59)) || night.contains(at(7, 0)) || night.contains(at(12, 0)) {
+		t.Error("overnight window misclassified")
+	}
+}
+
+func TestNewRouterValidation(t *testing.T) {
+	_, err := NewRouter(config.NotificationsConfig{
+		Channels: map[string]config.NotificationChannel{"desktop": {Type: "desktop"}},
+		Rules:    []config.NotificationRule{{Events: []string{"*"}, Channels: []string{"missing"}}},
+	})
+	if err == nil {
+		t.Error("expected error for rule referencing unknown channel")
+	}
+
+	_, err = NewRouter(config.NotificationsConfig{
+		Channels: map[string]config.NotificationChannel{"x": {Type: "carrier-pigeon"}},
+	})
+	if err == nil {
+		t.Error("expected error for unknown channel type")
+	}
+
+	_, err = NewRouter(config.NotificationsConfig{
+		QuietHours: config.QuietHoursConfig{Start: "late", End: "07:00"},
+	})
+	if err == nil {
+		t.Error("expected error for invalid quiet hours")
+	}
+}
+
+func TestRouterRouteTo(t *testing.T) {
+	desktop, phone := &fakeNotifier{}, &fakeNotifier{}
+	r := newTestRouter(config.DefaultNotificationRules(), map[string]Notifier{"desktop": desktop, "phone": phone})
+
+	got, err := r.RouteTo(context.Background(), []string{"phone"}, Event{Type: EventPromiseCompleted, SessionID: "a"})
+	if err != nil {
+		t.Fatalf("RouteTo() failed: %v", err)
+	}
+	if strings.Join(got, ",") != "phone" || len(desktop.events) != 0 {
+		t.Errorf("RouteTo delivered to %v, want only [phone]", got)
+	}
+
+	if _, err := r.RouteTo(context.Background(), []string{"pager"}, Event{Type: EventCrash}); err == nil {
+		t.Error("expected error for unknown channel")
+	}
+}
@@ -0,0 +1,319 @@
+// Package proc inspects the process table. On Linux it reads /proc directly;
+// elsewhere it falls back to a single ps call.
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrNotFound is returned when a process doesn't exist.
+var ErrNotFound = errors.New("process not found")
+
+// State is a process's scheduling state, as the single letter reported by
+// the kernel (R, S, D, T, Z, ...).
+type State byte
+
+const (
+	StateRunning  State = 'R'
+	StateSleeping State = 'S'
+	StateDisk     State = 'D'
+	StateStopped  State = 'T'
+	StateZombie   State = 'Z'
+	StateDead     State = 'X'
+)
+
+// Process is a snapshot of a single process.
+type Process struct {
+	PID     int
+	PPID    int
+	Comm    string   // executable name, as the kernel reports it
+	Cmdline []string // full argument vector; empty for kernel threads and zombies
+	State   State
+	RSS     int64         // resident set size in bytes
+	CPUTime time.Duration // user + system time
+}
+
+// Alive reports whether the process is still running. Zombies have exited
+// and only wait to be reaped, so they don't count.
+func (p Process) Alive() bool {
+	return p.State != StateZombie && p.State != StateDead
+}
+
+// Command returns the command line joined with spaces, or the executable
+// name in brackets if there is none.
+func (p Process) Command() string {
+	if len(p.Cmdline) == 0 {
+		return "[" + p.Comm + "]"
+	}
+	return strings.Join(p.Cmdline, " ")
+}
+
+// Runs reports whether the process is running the named program, either
+// directly or as the script of an interpreter (e.g. "node /usr/bin/claude").
+func (p Process) Runs(name string) bool {
+	if p.Comm == name {
+		return true
+	}
+	for i, arg := range p.Cmdline {
+		if i > 1 {
+			break
+		}
+		if filepath.Base(arg) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// shells are programs that wrap the command a session was started with.
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "fish": true, "dash": true, "ksh": true,
+}
+
+// IsShell reports whether the process is a shell.
+func (p Process) IsShell() bool {
+	name := p.Comm
+	if len(p.Cmdline) > 0 {
+		name = filepath.Base(p.Cmdline[0])
+	}
+	return shells[strings.TrimPrefix(name, "-")]
+}
+
+// Table is a snapshot of the process table.
+type Table struct {
+	procs    map[int]Process
+	children map[int][]int
+}
+
+// NewTable indexes a list of processes.
+func NewTable(procs []Process) *Table {
+	t := &Table{
+		procs:    make(map[int]Process, len(procs)),
+		children: make(map[int][]int),
+	}
+	for _, p := range procs {
+		t.procs[p.PID] = p
+		t.children[p.PPID] = append(t.children[p.PPID], p.PID)
+	}
+	for _, kids := range t.children {
+		sort.Ints(kids)
+	}
+	return t
+}
+
+// Snapshot reads the whole process table.
+func Snapshot() (*Table, error) {
+	procs, err := list()
+	if err != nil {
+		return nil, err
+	}
+	return NewTable(procs), nil
+}
+
+// Get returns the process with the given PID.
+func (t *Table) Get(pid int) (Process, bool) {
+	p, ok := t.procs[pid]
+	return p, ok
+}
+
+// Children returns the direct children of pid, ordered by PID.
+func (t *Table) Children(pid int) []Process {
+	var kids []Process
+	for _, child := range t.children[pid] {
+		kids = append(kids, t.procs[child])
+	}
+	return kids
+}
+
+// Tree returns roots and all their descendants, parents before children.
+// Roots that aren't in the table are skipped.
+func (t *Table) Tree(roots ...int) []Process {
+	var result []Process
+	visited := make(map[int]bool)
+	queue := append([]int{}, roots...)
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		if visited[pid] {
+			continue
+		}
+		visited[pid] = true
+		if p, ok := t.procs[pid]; ok {
+			result = append(result, p)
+			queue = append(queue, t.children[pid]...)
+		}
+	}
+	return result
+}
+
+// Tree snapshots the process table and returns roots and their descendants.
+func Tree(roots ...int) ([]Process, error) {
+	t, err := Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return t.Tree(roots...), nil
+}
+
+// Get returns a single process.
+func Get(pid int) (Process, error) {
+	return get(pid)
+}
+
+// Alive reports whether pid is running and hasn't exited.
+func Alive(pid int) bool {
+	p, err := get(pid)
+	return err == nil && p.Alive()
+}
+
+// FilterAlive returns the PIDs that are still running.
+func FilterAlive(pids []int) []int {
+	var result []int
+	for _, pid := range pids {
+		if Alive(pid) {
+			result = append(result, pid)
+		}
+	}
+	return result
+}
+
+// Signal sends sig to each PID, ignoring processes that have already gone.
+func Signal(pids []int, sig syscall.Signal) {
+	for _, pid := range pids {
+		_ = syscall.Kill(pid, sig)
+	}
+}
+
+// parseStat parses the contents of /proc/<pid>/stat. pageSize and clockTick
+// convert RSS pages and CPU ticks.
+func parseStat(data string, pageSize int64, clockTick int64) (Process, error) {
+	// The command name may contain spaces and parens, so split around the
+	// first open and last close paren
+	open := strings.IndexByte(data, '(')
+	close := strings.LastIndexByte(data, ')')
+	if open < 0 || close < open {
+		return Process{}, fmt.Errorf("malformed stat: %q", data)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(data[:open]))
+	if err != nil {
+		return Process{}, fmt.Errorf("malformed stat pid: %w", err)
+	}
+
+	// Fields from 3 (state) onwards; see proc(5)
+	fields := strings.Fields(data[close+1:])
+	if len(fields) < 22 {
+		return Process{}, fmt.Errorf("malformed stat: %d fields", len(fields))
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	utime, _ := strconv.ParseInt(fields[11], 10, 64)
+	stime, _ := strconv.ParseInt(fields[12], 10, 64)
+	rss, _ := strconv.ParseInt(fields[21], 10, 64)
+
+	return Process{
+		PID:     pid,
+		PPID:    ppid,
+		Comm:    data[open+1 : close],
+		State:   State(fields[0][0]),
+		RSS:     rss * pageSize,
+		CPUTime: time.Duration(utime+stime) * time.Second / time.Duration(clockTick),
+	}, nil
+}
+
+// parseCmdline splits the NUL-separated contents of /proc/<pid>/cmdline.
+func parseCmdline(data string) []string {
+	data = strings.TrimRight(data, "\x00")
+	if data == "" {
+		return nil
+	}
+	return strings.Split(data, "\x00")
+}
+
+// psFormat is the ps output format parsed by parsePS. ucomm can contain
+// spaces, as in "tmux: server", so it comes last and takes the rest of the
+// line.
+const psFormat = "pid=,ppid=,stat=,rss=,time=,ucomm="
+
+// psArgsFormat gives the arguments of each process. They can contain spaces
+// too, so they are read with a second ps call.
+const psArgsFormat = "pid=,args="
+
+// parsePS parses "ps -o <psFormat>" output, taking Cmdline from the
+// matching "ps -o <psArgsFormat>" output. ps doesn't preserve argument
+// boundaries, so Cmdline is split on whitespace.
+func parsePS(out, args string) []Process {
+	cmdlines := make(map[int][]string)
+	for _, line := range strings.Split(args, "\n") {
+		fields, rest := cutFields(line, 1)
+		if fields == nil {
+			continue
+		}
+		if pid, err := strconv.Atoi(fields[0]); err == nil {
+			cmdlines[pid] = strings.Fields(rest)
+		}
+	}
+
+	var procs []Process
+	for _, line := range strings.Split(out, "\n") {
+		fields, comm := cutFields(line, 5)
+		if fields == nil || comm == "" {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		rss, _ := strconv.ParseInt(fields[3], 10, 64)
+		procs = append(procs, Process{
+			PID:     pid,
+			PPID:    ppid,
+			State:   State(fields[2][0]),
+			RSS:     rss * 1024,
+			CPUTime: parseCPUTime(fields[4]),
+			Comm:    comm,
+			Cmdline: cmdlines[pid],
+		})
+	}
+	return procs
+}
+
+// cutFields splits the first n whitespace-separated fields off line and
+// returns them with the trimmed rest of the line. fields is nil if line has
+// fewer than n fields.
+func cutFields(line string, n int) (fields []string, rest string) {
+	rest = strings.TrimSpace(line)
+	for len(fields) < n {
+		if rest == "" {
+			return nil, ""
+		}
+		field, after, _ := strings.Cut(rest, " ")
+		fields = append(fields, field)
+		rest = strings.TrimSpace(after)
+	}
+	return fields, rest
+}
+
+// parseCPUTime parses ps's cumulative time, "[[dd-]hh:]mm:ss[.ss]".
+func parseCPUTime(s string) time.Duration {
+	var days int64
+	if d, rest, ok := strings.Cut(s, "-"); ok {
+		days, _ = strconv.ParseInt(d, 10, 64)
+		s = rest
+	}
+	var total float64
+	for _, part := range strings.Split(s, ":") {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0
+		}
+		total = total*60 + v
+	}
+	return time.Duration(days)*24*time.Hour + time.Duration(total*float64(time.Second))
+}
@@ -0,0 +1,52 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+)
+
+// clockTick is USER_HZ, the unit of CPU times in /proc. It is 100 on every
+// Linux architecture Go supports.
+const clockTick = 100
+
+// list reads every process under /proc.
+func list() ([]Process, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return listPS()
+	}
+	var procs []Process
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		// Processes can exit between ReadDir and reading their stat
+		if p, err := get(pid); err == nil {
+			procs = append(procs, p)
+		}
+	}
+	return procs, nil
+}
+
+// get reads a single process from /proc/<pid>.
+func get(pid int) (Process, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Process{}, ErrNotFound
+	}
+	if err != nil {
+		return Process{}, err
+	}
+	p, err := parseStat(string(data), int64(os.Getpagesize()), clockTick)
+	if err != nil {
+		return Process{}, err
+	}
+	if cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid)); err == nil {
+		p.Cmdline = parseCmdline(string(cmdline))
+	}
+	return p, nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package proc
+
+func list() ([]Process, error) {
+	return listPS()
+}
+
+func get(pid int) (Process, error) {
+	return getPS(pid)
+}
@@ -0,0 +1,157 @@
+package proc
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseStat(t *testing.T) {
+	// The comm field "(my (weird) cmd)" contains spaces and parens
+	stat := "4242 (my (weird) cmd) S 4200 4242 4200 34816 4242 4194304 1024 0 0 0 " +
+		"250 50 0 0 20 0 3 0 123456 104857600 300 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0"
+	p, err := parseStat(stat, 4096, 100)
+	if err != nil {
+		t.Fatalf("parseStat error: %v", err)
+	}
+	want := Process{
+		PID:     4242,
+		PPID:    4200,
+		Comm:    "my (weird) cmd",
+		State:   StateSleeping,
+		RSS:     300 * 4096,
+		CPUTime: 3 * time.Second,
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("parseStat =\n%+v\nwant\n%+v", p, want)
+	}
+
+	if _, err := parseStat("garbage", 4096, 100); err == nil {
+		t.Error("expected error for malformed stat")
+	}
+}
+
+func TestParseCmdline(t *testing.T) {
+	if got := parseCmdline("node\x00/usr/bin/claude\x00--resume\x00"); !reflect.DeepEqual(got, []string{"node", "/usr/bin/claude", "--resume"}) {
+		t.Errorf("parseCmdline = %q", got)
+	}
+	if got := parseCmdline(""); got != nil {
+		t.Errorf("parseCmdline(empty) = %q, want nil", got)
+	}
+}
+
+func TestParsePS(t *testing.T) {
+	out := `    1     0 Ss    1000   0:01.50 launchd
+  100     1 Ss+   2000  00:00:02 zsh
+  101   100 Z        0  00:00:00 claude
+  102     1 Ss     500  00:00:01 tmux: server
+garbage line
+`
+	args := `    1 /sbin/launchd
+  100 -zsh
+  102 tmux new-session -d -s coder-x
+`
+	procs := parsePS(out, args)
+	if len(procs) != 4 {
+		t.Fatalf("got %d processes, want 4: %+v", len(procs), procs)
+	}
+	if p := procs[0]; p.PID != 1 || p.RSS != 1000*1024 || p.CPUTime != 1500*time.Millisecond || p.Comm != "launchd" {
+		t.Errorf("unexpected process: %+v", p)
+	}
+	if p := procs[1]; p.PPID != 1 || !reflect.DeepEqual(p.Cmdline, []string{"-zsh"}) || !p.IsShell() {
+		t.Errorf("unexpected process: %+v", p)
+	}
+	if p := procs[2]; p.State != StateZombie || p.Alive() || p.Cmdline != nil {
+		t.Errorf("expected dead zombie, got %+v", p)
+	}
+	want := []string{"tmux", "new-session", "-d", "-s", "coder-x"}
+	if p := procs[3]; p.PID != 102 || p.Comm != "tmux: server" || !reflect.DeepEqual(p.Cmdline, want) {
+		t.Errorf("unexpected process: %+v", p)
+	}
+}
+
+func TestParseCPUTime(t *testing.T) {
+	tests := map[string]time.Duration{
+		"0:00.03":    30 * time.Millisecond,
+		"01:02:03":   time.Hour + 2*time.Minute + 3*time.Second,
+		"2-00:00:01": 48*time.Hour + time.Second,
+		"not-a-time": 0,
+		"12:34":      12*time.Minute + 34*time.Second,
+	}
+	for in, want := range tests {
+		if got := parseCPUTime(in); got != want {
+			t.Errorf("parseCPUTime(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestTableTree(t *testing.T) {
+	table := NewTable([]Process{
+		{PID: 1, PPID: 0},
+		{PID: 100, PPID: 1},
+		{PID: 101, PPID: 100},
+		{PID: 102, PPID: 101},
+		{PID: 103, PPID: 100},
+		{PID: 200, PPID: 1},
+	})
+
+	var got []int
+	for _, p := range table.Tree(100, 999) {
+		got = append(got, p.PID)
+	}
+	if want := []int{100, 101, 103, 102}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tree(100) = %v, want %v", got, want)
+	}
+
+	if kids := table.Children(1); len(kids) != 2 || kids[0].PID != 100 || kids[1].PID != 200 {
+		t.Errorf("Children(1) = %+v", kids)
+	}
+}
+
+func TestRuns(t *testing.T) {
+	tests := []struct {
+		p    Process
+		want bool
+	}{
+		{Process{Comm: "claude"}, true},
+		{Process{Comm: "node", Cmdline: []string{"node", "/usr/local/bin/claude", "--resume"}}, true},
+		{Process{Comm: "node", Cmdline: []string{"node", "/tmp/claude-helper.js"}}, false},
+		{Process{Comm: "vim", Cmdline: []string{"vim", "notes", "claude"}}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Runs("claude"); got != tt.want {
+			t.Errorf("%+v.Runs(claude) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestSelf(t *testing.T) {
+	self, err := Get(os.Getpid())
+	if err != nil {
+		t.Fatalf("Get(self) error: %v", err)
+	}
+	if !self.Alive() || self.PPID != os.Getppid() || self.RSS <= 0 {
+		t.Errorf("unexpected self: %+v", self)
+	}
+
+	if _, err := Get(-1); err == nil {
+		t.Error("expected error for invalid PID")
+	}
+	if got := FilterAlive([]int{os.Getpid(), -1}); !reflect.DeepEqual(got, []int{os.Getpid()}) {
+		t.Errorf("FilterAlive = %v", got)
+	}
+
+	table, err := Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	tree := table.Tree(os.Getppid())
+	found := false
+	for _, p := range tree {
+		found = found || p.PID == os.Getpid()
+	}
+	if !found {
+		t.Error("expected our own process in our parent's tree")
+	}
+}
@@ -0,0 +1,33 @@
+package proc
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// listPS reads the process table with two ps calls, one for the
+// arguments.
+func listPS() ([]Process, error) {
+	out, err := exec.Command("ps", "-ax", "-o", psFormat).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ps: %w", err)
+	}
+	args, _ := exec.Command("ps", "-ax", "-o", psArgsFormat).Output()
+	return parsePS(string(out), string(args)), nil
+}
+
+// getPS reads a single process with ps.
+func getPS(pid int) (Process, error) {
+	// ps exits non-zero when the process doesn't exist
+	out, err := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", psFormat).Output()
+	if err != nil {
+		return Process{}, ErrNotFound
+	}
+	args, _ := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", psArgsFormat).Output()
+	procs := parsePS(string(out), string(args))
+	if len(procs) == 0 {
+		return Process{}, ErrNotFound
+	}
+	return procs[0], nil
+}
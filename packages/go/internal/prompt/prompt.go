@@ -0,0 +1,299 @@
+// Package prompt detects when an AI CLI is waiting for user input by
+// matching tool-specific patterns against captured pane output.
+package prompt
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Kind describes what the CLI is waiting for.
+type Kind string
+
+const (
+	// KindPermission is a tool or command approval prompt.
+	KindPermission Kind = "permission"
+	// KindConfirmation is a yes/no or continue question.
+	KindConfirmation Kind = "confirmation"
+	// KindIdle is an empty input box: the agent has finished its turn.
+	KindIdle Kind = "idle"
+)
+
+// Prompt is a detected input prompt.
+type Prompt struct {
+	Kind     Kind
+	Question string   // The question shown to the user, if one could be found
+	Choices  []Choice // The answers the prompt offers, if they could be read
+}
+
+// Choice is one answer to a prompt.
+type Choice struct {
+	Key   string   // What picks it in the prompt: an option number or a letter
+	Label string   // The answer's text
+	Keys  []string // tmux-style key names that give this answer
+}
+
+// tailLines is how many trailing non-empty lines are read, for a prompt's
+// question and for the idle input box.
+const tailLines = 25
+
+// promptLines is how far from the bottom a prompt may match. Prompts are
+// drawn at the bottom of the pane, so one further up has already been
+// answered and the agent has printed more output since.
+const promptLines = 10
+
+type pattern struct {
+	kind Kind
+	re   *regexp.Regexp
+}
+
+// toolPatterns are matched in order, before the generic patterns.
+var toolPatterns = map[string][]pattern{
+	"claude": {
+		{KindPermission, regexp.MustCompile(`Do you want to (proceed|make this edit|create|run|allow)`)},
+		{KindPermission, regexp.MustCompile(`❯\s*1\.\s*Yes`)},
+	},
+	"codex": {
+		{KindPermission, regexp.MustCompile(`(?i)would you like to (run|make|apply) the following`)},
+		{KindPermission, regexp.MustCompile(`(?i)allow (command|edits?)\?`)},
+		{KindPermission, regexp.MustCompile(`(?i)approve\s*\(y\)`)},
+	},
+	"gemini": {
+		{KindPermission, regexp.MustCompile(`(?i)allow execution( of)?`)},
+		{KindPermission, regexp.MustCompile(`(?i)apply this change\?`)},
+		{KindPermission, regexp.MustCompile(`(?i)yes, allow (once|always)`)},
+		{KindConfirmation, regexp.MustCompile(`(?i)do you want to proceed\?`)},
+	},
+	"opencode": {
+		{KindPermission, regexp.MustCompile(`(?i)permission required`)},
+	},
+}
+
+// genericPatterns apply to every tool.
+var genericPatterns = []pattern{
+	{KindConfirmation, yesNoQuestion},
+	{KindConfirmation, pressEnter},
+}
+
+var (
+	yesNoQuestion = regexp.MustCompile(`\((y/n|Y/n|y/N)\)|\[(y/n|Y/n|y/N)\]`)
+	pressEnter    = regexp.MustCompile(`(?i)press enter to continue`)
+)
+
+// replies are the answers to the generic patterns, used when the prompt
+// shows no menu or hotkeys.
+var replies = map[*regexp.Regexp][]Choice{
+	yesNoQuestion: {
+		{Key: "y", Label: "Yes", Keys: []string{"y", "Enter"}},
+		{Key: "n", Label: "No", Keys: []string{"n", "Enter"}},
+	},
+	pressEnter: {{Key: "enter", Label: "Continue", Keys: []string{"Enter"}}},
+}
+
+var (
+	// claudeInputBox matches an empty Claude Code input line, with or without the box border.
+	claudeInputBox = regexp.MustCompile(`^[│|]?\s*>\s*[│|]?$`)
+	// claudeBusy is shown while Claude Code, or Codex, is working.
+	claudeBusy = regexp.MustCompile(`(?i)esc to interrupt`)
+	// numberedOption matches a numbered menu option, with or without the cursor.
+	numberedOption = regexp.MustCompile(`^([❯›>●▶]\s*)?(\d{1,2})[.)]\s+(.+)$`)
+	// menuCursor marks the selected option of a menu.
+	menuCursor = regexp.MustCompile(`^[❯›>●▶]\s*`)
+	// hotkey matches an answer with its key in parentheses, e.g. "Yes (y)".
+	hotkey = regexp.MustCompile(`([A-Za-z][\w ,']*?)\s*\(([a-z])\)`)
+)
+
+// Detect reports whether the pane output ends in an input prompt for tool.
+func Detect(tool, output string) (Prompt, bool) {
+	lines := tail(output, tailLines)
+	if len(lines) == 0 {
+		return Prompt{}, false
+	}
+
+	patterns := append(append([]pattern{}, toolPatterns[tool]...), genericPatterns...)
+	// Search from the bottom so the most recent prompt wins. A busy marker
+	// below a prompt means the agent is working again.
+	for i := len(lines) - 1; i >= 0 && i >= len(lines)-promptLines; i-- {
+		if claudeBusy.MatchString(lines[i]) {
+			break
+		}
+		for _, p := range patterns {
+			if p.re.MatchString(lines[i]) {
+				q := questionIndex(lines, i)
+				return Prompt{Kind: p.kind, Question: lines[q], Choices: choices(lines, q, replies[p.re])}, true
+			}
+		}
+	}
+
+	if tool == "claude" && isClaudeIdle(lines) {
+		return Prompt{Kind: KindIdle, Question: lastQuestion(lines)}, true
+	}
+
+	return Prompt{}, false
+}
+
+// isClaudeIdle reports whether the Claude Code input box is empty and the
+// agent is not working.
+func isClaudeIdle(lines []string) bool {
+	for _, line := range lines {
+		if claudeBusy.MatchString(line) {
+			return false
+		}
+	}
+	// The input box sits just above the footer, so only check the last few lines
+	start := len(lines) - 6
+	if start < 0 {
+		start = 0
+	}
+	for _, line := range lines[start:] {
+		if claudeInputBox.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// questionIndex returns the line of the prompt matched at line i: the
+// nearest line ending in "?" at or above it, or the matched line itself.
+func questionIndex(lines []string, i int) int {
+	for j := i; j >= 0 && j >= i-10; j-- {
+		if strings.HasSuffix(lines[j], "?") {
+			return j
+		}
+	}
+	return i
+}
+
+// choices reads the answers shown below the question at line q: a numbered
+// menu, a menu with a cursor, or answers with hotkeys. Failing those, the
+// pattern's replies are used.
+func choices(lines []string, q int, replies []Choice) []Choice {
+	if c := numberedMenu(lines[q:]); c != nil {
+		return c
+	}
+	if c := cursorMenu(lines[q+1:]); c != nil {
+		return c
+	}
+	var c []Choice
+	for _, line := range lines[q:] {
+		for _, m := range hotkey.FindAllStringSubmatch(line, -1) {
+			c = append(c, Choice{Key: m[2], Label: strings.TrimSpace(m[1]), Keys: []string{m[2]}})
+		}
+	}
+	if len(c) > 0 {
+		return c
+	}
+	return replies
+}
+
+// numberedMenu reads options numbered from 1, e.g. "❯ 1. Yes".
+func numberedMenu(lines []string) []Choice {
+	var labels []string
+	cursor := 0
+	for _, line := range lines {
+		m := numberedOption.FindStringSubmatch(line)
+		if m == nil || m[2] != strconv.Itoa(len(labels)+1) {
+			if len(labels) > 0 {
+				break
+			}
+			continue
+		}
+		if m[1] != "" {
+			cursor = len(labels)
+		}
+		labels = append(labels, m[3])
+	}
+	return menuChoices(labels, cursor)
+}
+
+// cursorMenu reads unnumbered options when one of them has the cursor, as
+// in "● Yes, allow once". Every line is taken as an option.
+func cursorMenu(lines []string) []Choice {
+	if len(lines) > 9 {
+		return nil
+	}
+	labels := make([]string, len(lines))
+	cursor := -1
+	for i, line := range lines {
+		if loc := menuCursor.FindStringIndex(line); loc != nil {
+			if cursor >= 0 {
+				return nil
+			}
+			cursor = i
+			line = line[loc[1]:]
+		}
+		labels[i] = line
+	}
+	if cursor < 0 {
+		return nil
+	}
+	return menuChoices(labels, cursor)
+}
+
+// menuChoices numbers menu options and picks each with the arrow keys from
+// the cursor, then Enter.
+func menuChoices(labels []string, cursor int) []Choice {
+	if len(labels) < 2 {
+		return nil
+	}
+	c := make([]Choice, len(labels))
+	for i, label := range labels {
+		var keys []string
+		for j := cursor; j < i; j++ {
+			keys = append(keys, "Down")
+		}
+		for j := cursor; j > i; j-- {
+			keys = append(keys, "Up")
+		}
+		c[i] = Choice{Key: strconv.Itoa(i + 1), Label: label, Keys: append(keys, "Enter")}
+	}
+	return c
+}
+
+// lastQuestion returns the last line ending in "?", if any. For an idle
+// agent this is usually the question it asked before yielding.
+func lastQuestion(lines []string) string {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasSuffix(lines[i], "?") {
+			return lines[i]
+		}
+	}
+	return ""
+}
+
+// tail returns the last n non-empty lines of output, with ANSI escapes and
+// box-drawing borders removed.
+func tail(output string, n int) []string {
+	raw := strings.Split(stripANSI(output), "\n")
+	lines := make([]string, 0, n)
+	for i := len(raw) - 1; i >= 0 && len(lines) < n; i-- {
+		line := cleanLine(raw[i])
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	// Reverse into top-to-bottom order
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07]*\x07`)
+
+func stripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// cleanLine trims whitespace and decorative box borders, keeping a lone
+// input marker so the idle input box can still be recognised.
+func cleanLine(line string) string {
+	line = strings.TrimSpace(line)
+	if claudeInputBox.MatchString(line) {
+		return ">"
+	}
+	line = strings.Trim(line, "│╭╮╰╯─ \t")
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,231 @@
+package prompt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name         string
+		tool         string
+		output       string
+		wantOK       bool
+		wantKind     Kind
+		wantQuestion string
+	}{
+		{
+			name: "claude permission prompt",
+			tool: "claude",
+			output: `╭──────────────────────────────────────────╮
+│ Bash command                             │
+│                                          │
+│   npm install                            │
+│                                          │
+│ Do you want to proceed?                  │
+│ ❯ 1. Yes                                 │
+│   2. Yes, and don't ask again            │
+│   3. No, and tell Claude what to do      │
+╰──────────────────────────────────────────╯`,
+			wantOK:       true,
+			wantKind:     KindPermission,
+			wantQuestion: "Do you want to proceed?",
+		},
+		{
+			name: "claude idle input box",
+			tool: "claude",
+			output: `● I've updated the config. Should I also migrate the old settings?
+
+╭──────────────────────────────────────────╮
+│ >                                        │
+╰──────────────────────────────────────────╯
+  ? for shortcuts`,
+			wantOK:       true,
+			wantKind:     KindIdle,
+			wantQuestion: "● I've updated the config. Should I also migrate the old settings?",
+		},
+		{
+			name: "claude working",
+			tool: "claude",
+			output: `✻ Thinking… (12s · esc to interrupt)
+
+╭──────────────────────────────────────────╮
+│ >                                        │
+╰──────────────────────────────────────────╯`,
+			wantOK: false,
+		},
+		{
+			name: "codex approval",
+			tool: "codex",
+			output: `Would you like to run the following command?
+
+$ go test ./...
+
+▌ Yes (y)   No (n)`,
+			wantOK:       true,
+			wantKind:     KindPermission,
+			wantQuestion: "Would you like to run the following command?",
+		},
+		{
+			name: "gemini confirmation",
+			tool: "gemini",
+			output: `Allow execution of: 'rm -rf build'?
+● Yes, allow once
+  Yes, allow always
+  No`,
+			wantOK:       true,
+			wantKind:     KindPermission,
+			wantQuestion: "Allow execution of: 'rm -rf build'?",
+		},
+		{
+			name:         "generic yes/no",
+			tool:         "opencode",
+			output:       "Overwrite existing file? (y/n)",
+			wantOK:       true,
+			wantKind:     KindConfirmation,
+			wantQuestion: "Overwrite existing file? (y/n)",
+		},
+		{
+			name:         "ansi escapes are ignored",
+			tool:         "codex",
+			output:       "\x1b[1mAllow command?\x1b[0m",
+			wantOK:       true,
+			wantKind:     KindPermission,
+			wantQuestion: "Allow command?",
+		},
+		{
+			name: "answered prompt followed by output",
+			tool: "claude",
+			output: `│ Do you want to proceed?                  │
+│ ❯ 1. Yes                                 │
+│   2. Yes, and don't ask again            │
+│   3. No, and tell Claude what to do      │
+● Bash(npm install)
+  ⎿  added 214 packages in 9s
+● Bash(npm test)
+  ⎿  PASS src/app.test.ts
+     PASS src/config.test.ts
+     Tests: 42 passed, 42 total
+● All tests pass. Next I'll update the README.
+● Update(README.md)
+  ⎿  Updated README.md with 3 additions`,
+			wantOK: false,
+		},
+		{
+			name: "working after prompt",
+			tool: "claude",
+			output: `│ Do you want to proceed?                  │
+│ ❯ 1. Yes                                 │
+│   2. No                                  │
+● Bash(npm install)
+✻ Installing… (3s · esc to interrupt)`,
+			wantOK: false,
+		},
+		{
+			name:   "plain output",
+			tool:   "claude",
+			output: "Running tests...\nok  github.com/example/pkg 0.1s",
+			wantOK: false,
+		},
+		{
+			name:   "empty output",
+			tool:   "claude",
+			output: "",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Detect(tt.tool, tt.output)
+			if ok != tt.wantOK {
+				t.Fatalf("Detect() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if got.Kind != tt.wantKind {
+				t.Errorf("Kind = %q, want %q", got.Kind, tt.wantKind)
+			}
+			if got.Question != tt.wantQuestion {
+				t.Errorf("Question = %q, want %q", got.Question, tt.wantQuestion)
+			}
+		})
+	}
+}
+
+func TestChoices(t *testing.T) {
+	tests := []struct {
+		name   string
+		tool   string
+		output string
+		want   []Choice
+	}{
+		{
+			name: "claude numbered menu",
+			tool: "claude",
+			output: `│ Do you want to proceed?                  │
+│   1. Yes                                 │
+│ ❯ 2. Yes, and don't ask again            │
+│   3. No, and tell Claude what to do      │`,
+			want: []Choice{
+				{Key: "1", Label: "Yes", Keys: []string{"Up", "Enter"}},
+				{Key: "2", Label: "Yes, and don't ask again", Keys: []string{"Enter"}},
+				{Key: "3", Label: "No, and tell Claude what to do", Keys: []string{"Down", "Enter"}},
+			},
+		},
+		{
+			name: "gemini cursor menu",
+			tool: "gemini",
+			output: `Allow execution of: 'rm -rf build'?
+● Yes, allow once
+  Yes, allow always
+  No`,
+			want: []Choice{
+				{Key: "1", Label: "Yes, allow once", Keys: []string{"Enter"}},
+				{Key: "2", Label: "Yes, allow always", Keys: []string{"Down", "Enter"}},
+				{Key: "3", Label: "No", Keys: []string{"Down", "Down", "Enter"}},
+			},
+		},
+		{
+			name: "codex hotkeys",
+			tool: "codex",
+			output: `Would you like to run the following command?
+
+$ go test ./...
+
+▌ Yes (y)   No (n)`,
+			want: []Choice{
+				{Key: "y", Label: "Yes", Keys: []string{"y"}},
+				{Key: "n", Label: "No", Keys: []string{"n"}},
+			},
+		},
+		{
+			name:   "generic yes/no",
+			tool:   "opencode",
+			output: "Overwrite existing file? (y/n)",
+			want: []Choice{
+				{Key: "y", Label: "Yes", Keys: []string{"y", "Enter"}},
+				{Key: "n", Label: "No", Keys: []string{"n", "Enter"}},
+			},
+		},
+		{
+			name:   "press enter",
+			tool:   "codex",
+			output: "Update installed. Press Enter to continue",
+			want:   []Choice{{Key: "enter", Label: "Continue", Keys: []string{"Enter"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := Detect(tt.tool, tt.output)
+			if !ok {
+				t.Fatal("expected a prompt")
+			}
+			if !reflect.DeepEqual(p.Choices, tt.want) {
+				t.Errorf("Choices = %+v, want %+v", p.Choices, tt.want)
+			}
+		})
+	}
+}
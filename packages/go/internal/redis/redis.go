@@ -24,6 +24,8 @@ const (
 	PromiseKeyPrefix = "coders:promise:"
 	// PaneKeyPrefix is the Redis key prefix for heartbeats.
 	PaneKeyPrefix = "coders:pane:"
+	// HeartbeatHistoryKeyPrefix is the Redis key prefix for recent heartbeats per session.
+	HeartbeatHistoryKeyPrefix = "coders:heartbeat-history:"
 	// HealthKeyPrefix is the Redis key prefix for health check results.
 	HealthKeyPrefix = "coders:health:"
 	// HealthSummaryKey is the Redis key for the health check summary.
@@ -32,8 +34,14 @@ const (
 	SessionStateKeyPrefix = "coders:session-state:"
 	// CrashEventKeyPrefix is the Redis key prefix for crash events.
 	CrashEventKeyPrefix = "coders:crash:"
+	// EventKeyPrefix is the Redis key prefix for session event history.
+	EventKeyPrefix = "coders:events:"
 	// LoopNotificationKeyPrefix is the Redis key prefix for loop completion notifications.
 	LoopNotificationKeyPrefix = "coders:loop:notification:"
+	// LoopStateKeyPrefix is the Redis key prefix for loop progress.
+	LoopStateKeyPrefix = "coders:loop:state:"
+	// LoopControlKeyPrefix is the Redis key prefix for the queue of commands sent to a loop.
+	LoopControlKeyPrefix = "coders:loop:control:"
 )
 
 // Client wraps a Redis client with coders-specific operations.
@@ -246,8 +254,36 @@ func (c *Client) SetHeartbeat(ctx context.Context, hb *types.HeartbeatData) erro
 	}
 
 	key := PaneKeyPrefix + hb.SessionID
-	// Heartbeats expire after 10 minutes
-	return c.rdb.Set(ctx, key, data, 10*time.Minute).Err()
+	historyKey := HeartbeatHistoryKeyPrefix + hb.SessionID
+
+	// Heartbeats expire after 10 minutes; keep the last 20 for crash forensics
+	pipe := c.rdb.Pipeline()
+	pipe.Set(ctx, key, data, 10*time.Minute)
+	pipe.LPush(ctx, historyKey, data)
+	pipe.LTrim(ctx, historyKey, 0, 19)
+	pipe.Expire(ctx, historyKey, time.Hour)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// GetHeartbeatHistory returns a session's recent heartbeats, newest first.
+func (c *Client) GetHeartbeatHistory(ctx context.Context, sessionID string) ([]types.HeartbeatData, error) {
+	key := HeartbeatHistoryKeyPrefix + sessionID
+	data, err := c.rdb.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var heartbeats []types.HeartbeatData
+	for _, item := range data {
+		var hb types.HeartbeatData
+		if err := json.Unmarshal([]byte(item), &hb); err != nil {
+			continue
+		}
+		heartbeats = append(heartbeats, hb)
+	}
+
+	return heartbeats, nil
 }
 
 // scanKeys scans for all keys matching a pattern.
@@ -397,6 +433,11 @@ func (c *Client) DeleteHealthCheck(ctx context.Context, sessionID string) error
 	return c.rdb.Del(ctx, key).Err()
 }
 
+// sessionStateTTL is how long session state outlives its last refresh.
+// Heartbeats refresh it while the session runs, and kill deletes it; the
+// expiry only clears out sessions that ended some other way.
+const sessionStateTTL = 24 * time.Hour
+
 // SetSessionState stores session state for restart-on-crash functionality.
 func (c *Client) SetSessionState(ctx context.Context, state *types.SessionState) error {
 	data, err := json.Marshal(state)
@@ -405,8 +446,12 @@ func (c *Client) SetSessionState(ctx context.Context, state *types.SessionState)
 	}
 
 	key := SessionStateKeyPrefix + state.SessionID
-	// Session state expires after 24 hours (sessions shouldn't run longer than this)
-	return c.rdb.Set(ctx, key, data, 24*time.Hour).Err()
+	return c.rdb.Set(ctx, key, data, sessionStateTTL).Err()
+}
+
+// RefreshSessionState extends the expiry of a session's state, if it has any.
+func (c *Client) RefreshSessionState(ctx context.Context, sessionID string) error {
+	return c.rdb.Expire(ctx, SessionStateKeyPrefix+sessionID, sessionStateTTL).Err()
 }
 
 // GetSessionState retrieves session state for a given session ID.
@@ -428,6 +473,47 @@ func (c *Client) GetSessionState(ctx context.Context, sessionID string) (*types.
 	return &state, nil
 }
 
+// GetSessionStates returns the stored state of all sessions, keyed by session ID.
+func (c *Client) GetSessionStates(ctx context.Context) (map[string]*types.SessionState, error) {
+	states := make(map[string]*types.SessionState)
+
+	keys, err := c.scanKeys(ctx, SessionStateKeyPrefix+"*")
+	if err != nil {
+		return states, err
+	}
+
+	if len(keys) == 0 {
+		return states, nil
+	}
+
+	values, err := c.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return states, err
+	}
+
+	for _, val := range values {
+		if val == nil {
+			continue
+		}
+
+		str, ok := val.(string)
+		if !ok {
+			continue
+		}
+
+		var state types.SessionState
+		if err := json.Unmarshal([]byte(str), &state); err != nil {
+			continue
+		}
+
+		if state.SessionID != "" {
+			states[state.SessionID] = &state
+		}
+	}
+
+	return states, nil
+}
+
 // DeleteSessionState removes session state for a given session ID.
 func (c *Client) DeleteSessionState(ctx context.Context, sessionID string) error {
 	key := SessionStateKeyPrefix + sessionID
@@ -471,6 +557,43 @@ func (c *Client) GetCrashEvents(ctx context.Context, sessionID string) ([]types.
 	return events, nil
 }
 
+// RecordEvent appends an event to a session's event history.
+func (c *Client) RecordEvent(ctx context.Context, event *types.SessionEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	// Keep the last 50 events per session
+	key := EventKeyPrefix + event.SessionID
+	pipe := c.rdb.Pipeline()
+	pipe.LPush(ctx, key, data)
+	pipe.LTrim(ctx, key, 0, 49)
+	pipe.Expire(ctx, key, 7*24*time.Hour)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// GetEvents retrieves a session's event history, newest first.
+func (c *Client) GetEvents(ctx context.Context, sessionID string) ([]types.SessionEvent, error) {
+	key := EventKeyPrefix + sessionID
+	data, err := c.rdb.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var events []types.SessionEvent
+	for _, item := range data {
+		var event types.SessionEvent
+		if err := json.Unmarshal([]byte(item), &event); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
 // SetLoopNotification stores a loop completion notification.
 func (c *Client) SetLoopNotification(ctx context.Context, notification *types.LoopNotification) error {
 	data, err := json.Marshal(notification)
@@ -482,3 +605,96 @@ func (c *Client) SetLoopNotification(ctx context.Context, notification *types.Lo
 	// Notifications expire after 24 hours
 	return c.rdb.Set(ctx, key, data, 24*time.Hour).Err()
 }
+
+// SetLoopState stores a loop's progress.
+func (c *Client) SetLoopState(ctx context.Context, state *types.LoopState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	key := LoopStateKeyPrefix + state.LoopID
+	// Keep finished loops around for a week so they show up as recent
+	return c.rdb.Set(ctx, key, data, 7*24*time.Hour).Err()
+}
+
+// GetLoopState retrieves a loop's progress, or nil if there is none.
+func (c *Client) GetLoopState(ctx context.Context, loopID string) (*types.LoopState, error) {
+	key := LoopStateKeyPrefix + loopID
+	data, err := c.rdb.Get(ctx, key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var state types.LoopState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// GetLoopStates returns the progress of all loops, keyed by loop ID.
+func (c *Client) GetLoopStates(ctx context.Context) (map[string]*types.LoopState, error) {
+	states := make(map[string]*types.LoopState)
+
+	keys, err := c.scanKeys(ctx, LoopStateKeyPrefix+"*")
+	if err != nil {
+		return states, err
+	}
+
+	if len(keys) == 0 {
+		return states, nil
+	}
+
+	values, err := c.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return states, err
+	}
+
+	for _, val := range values {
+		str, ok := val.(string)
+		if !ok {
+			continue
+		}
+
+		var state types.LoopState
+		if err := json.Unmarshal([]byte(str), &state); err != nil {
+			continue
+		}
+
+		if state.LoopID != "" {
+			states[state.LoopID] = &state
+		}
+	}
+
+	return states, nil
+}
+
+// SendLoopCommand queues a control command for a loop. Commands are kept in
+// a list so they are not lost while the loop is busy spawning a task.
+func (c *Client) SendLoopCommand(ctx context.Context, loopID string, command types.LoopCommand) error {
+	key := LoopControlKeyPrefix + loopID
+	pipe := c.rdb.Pipeline()
+	pipe.RPush(ctx, key, string(command))
+	pipe.Expire(ctx, key, time.Hour)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// PopLoopCommand takes the oldest queued command for a loop, or returns ""
+// if there is none.
+func (c *Client) PopLoopCommand(ctx context.Context, loopID string) (types.LoopCommand, error) {
+	key := LoopControlKeyPrefix + loopID
+	command, err := c.rdb.LPop(ctx, key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", nil
+		}
+		return "", err
+	}
+	return types.LoopCommand(command), nil
+}
@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
@@ -294,6 +295,45 @@ func TestSetAndGetHeartbeat(t *testing.T) {
 	}
 }
 
+func TestGetHeartbeatHistory(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 25; i++ {
+		hb := &types.HeartbeatData{
+			SessionID:    "session-1",
+			Timestamp:    time.Now().Add(time.Duration(i) * time.Second).UnixMilli(),
+			Status:       "running",
+			LastActivity: fmt.Sprintf("beat %d", i),
+		}
+		if err := client.SetHeartbeat(ctx, hb); err != nil {
+			t.Fatalf("SetHeartbeat failed: %v", err)
+		}
+	}
+
+	history, err := client.GetHeartbeatHistory(ctx, "session-1")
+	if err != nil {
+		t.Fatalf("GetHeartbeatHistory failed: %v", err)
+	}
+	if len(history) != 20 {
+		t.Fatalf("Expected 20 heartbeats (limit), got %d", len(history))
+	}
+	if history[0].LastActivity != "beat 24" {
+		t.Errorf("Expected newest heartbeat first, got %q", history[0].LastActivity)
+	}
+
+	// History must not show up as a session heartbeat
+	heartbeats, err := client.GetHeartbeats(ctx)
+	if err != nil {
+		t.Fatalf("GetHeartbeats failed: %v", err)
+	}
+	if len(heartbeats) != 1 {
+		t.Errorf("Expected 1 heartbeat, got %d", len(heartbeats))
+	}
+}
+
 func TestGetHeartbeats(t *testing.T) {
 	client, mr := setupTestRedis(t)
 	defer mr.Close()
@@ -543,6 +583,41 @@ func TestGetHealthSummary_NotFound(t *testing.T) {
 	}
 }
 
+func TestRefreshSessionState(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	ctx := context.Background()
+	state := &types.SessionState{SessionID: "session-1", ParentSessionID: "lead"}
+	if err := client.SetSessionState(ctx, state); err != nil {
+		t.Fatalf("SetSessionState failed: %v", err)
+	}
+
+	// A long-running session outlives the original expiry while refreshed
+	for i := 0; i < 3; i++ {
+		mr.FastForward(sessionStateTTL - time.Hour)
+		if err := client.RefreshSessionState(ctx, state.SessionID); err != nil {
+			t.Fatalf("RefreshSessionState failed: %v", err)
+		}
+	}
+	retrieved, err := client.GetSessionState(ctx, state.SessionID)
+	if err != nil || retrieved == nil || retrieved.ParentSessionID != "lead" {
+		t.Fatalf("GetSessionState = %+v, %v; want the refreshed state", retrieved, err)
+	}
+
+	// Refreshing doesn't bring back state that was deleted
+	if err := client.DeleteSessionState(ctx, state.SessionID); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.RefreshSessionState(ctx, state.SessionID); err != nil {
+		t.Fatalf("RefreshSessionState failed: %v", err)
+	}
+	if mr.Exists(SessionStateKeyPrefix + state.SessionID) {
+		t.Error("refresh recreated deleted session state")
+	}
+}
+
 func TestSessionStateOperations(t *testing.T) {
 	client, mr := setupTestRedis(t)
 	defer mr.Close()
@@ -623,6 +698,43 @@ func TestGetSessionState_NotFound(t *testing.T) {
 	}
 }
 
+func TestGetSessionStates(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	ctx := context.Background()
+
+	states := []*types.SessionState{
+		{SessionID: "coder-claude-parent", Tool: "claude"},
+		{SessionID: "coder-codex-child", Tool: "codex", ParentSessionID: "coder-claude-parent"},
+	}
+	for _, state := range states {
+		if err := client.SetSessionState(ctx, state); err != nil {
+			t.Fatalf("SetSessionState failed: %v", err)
+		}
+	}
+
+	// A corrupt entry should be skipped rather than failing the whole scan
+	mr.Set(SessionStateKeyPrefix+"corrupt", "not json")
+
+	retrieved, err := client.GetSessionStates(ctx)
+	if err != nil {
+		t.Fatalf("GetSessionStates failed: %v", err)
+	}
+
+	if len(retrieved) != 2 {
+		t.Fatalf("GetSessionStates returned %d states, want 2", len(retrieved))
+	}
+	child, ok := retrieved["coder-codex-child"]
+	if !ok {
+		t.Fatal("child session state missing")
+	}
+	if child.ParentSessionID != "coder-claude-parent" {
+		t.Errorf("ParentSessionID = %q, want %q", child.ParentSessionID, "coder-claude-parent")
+	}
+}
+
 func TestCrashEventOperations(t *testing.T) {
 	client, mr := setupTestRedis(t)
 	defer mr.Close()
@@ -713,6 +825,45 @@ func TestCrashEvents_MaxLimit(t *testing.T) {
 	}
 }
 
+func TestSessionEventOperations(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	ctx := context.Background()
+	sessionID := "session-1"
+
+	// Add more events than the history keeps
+	for i := 0; i < 55; i++ {
+		event := &types.SessionEvent{
+			SessionID: sessionID,
+			Timestamp: time.Now().Add(time.Duration(i) * time.Minute).UnixMilli(),
+			Type:      "remediation",
+			Action:    "nudge",
+			Status:    types.HealthStuck,
+			Message:   fmt.Sprintf("event %d", i),
+		}
+		if err := client.RecordEvent(ctx, event); err != nil {
+			t.Fatalf("RecordEvent failed: %v", err)
+		}
+	}
+
+	events, err := client.GetEvents(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("GetEvents failed: %v", err)
+	}
+
+	if len(events) != 50 {
+		t.Fatalf("Expected 50 events (limit), got %d", len(events))
+	}
+	if events[0].Message != "event 54" {
+		t.Errorf("Expected newest event first, got %q", events[0].Message)
+	}
+	if events[0].Status != types.HealthStuck {
+		t.Errorf("Expected status %q, got %q", types.HealthStuck, events[0].Status)
+	}
+}
+
 func TestGetCrashEvents_NoEvents(t *testing.T) {
 	client, mr := setupTestRedis(t)
 	defer mr.Close()
@@ -1107,3 +1258,80 @@ func TestCrashEventsExpiration(t *testing.T) {
 		t.Errorf("TTL mismatch: got %v, want ~%v", ttl, expectedTTL)
 	}
 }
+
+func TestLoopStateOperations(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	ctx := context.Background()
+
+	missing, err := client.GetLoopState(ctx, "loop-missing")
+	if err != nil || missing != nil {
+		t.Fatalf("GetLoopState for missing loop = %v, %v; want nil, nil", missing, err)
+	}
+
+	for _, id := range []string{"loop-1", "loop-2"} {
+		state := &types.LoopState{
+			LoopID:      id,
+			Status:      types.LoopRunning,
+			TotalTasks:  3,
+			CurrentTask: &types.LoopTask{ID: "t1", Title: "First", Source: "beads"},
+			Queue:       []types.LoopTask{{ID: "t2", Title: "Second", Source: "github"}},
+		}
+		if err := client.SetLoopState(ctx, state); err != nil {
+			t.Fatalf("SetLoopState failed: %v", err)
+		}
+	}
+
+	state, err := client.GetLoopState(ctx, "loop-1")
+	if err != nil {
+		t.Fatalf("GetLoopState failed: %v", err)
+	}
+	if state == nil || state.CurrentTask == nil || state.CurrentTask.Title != "First" || len(state.Queue) != 1 {
+		t.Errorf("GetLoopState returned %+v", state)
+	}
+
+	ttl := mr.TTL(LoopStateKeyPrefix + "loop-1")
+	if expected := 7 * 24 * time.Hour; ttl < expected-time.Second || ttl > expected+time.Second {
+		t.Errorf("TTL mismatch: got %v, want ~%v", ttl, expected)
+	}
+
+	states, err := client.GetLoopStates(ctx)
+	if err != nil {
+		t.Fatalf("GetLoopStates failed: %v", err)
+	}
+	if len(states) != 2 || states["loop-2"] == nil {
+		t.Errorf("GetLoopStates returned %d states, want 2", len(states))
+	}
+}
+
+func TestLoopCommands(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	ctx := context.Background()
+
+	command, err := client.PopLoopCommand(ctx, "loop-1")
+	if err != nil || command != "" {
+		t.Fatalf("PopLoopCommand on empty queue = %q, %v; want \"\", nil", command, err)
+	}
+
+	for _, c := range []types.LoopCommand{types.LoopCommandPause, types.LoopCommandSkip} {
+		if err := client.SendLoopCommand(ctx, "loop-1", c); err != nil {
+			t.Fatalf("SendLoopCommand failed: %v", err)
+		}
+	}
+
+	// Commands come out in the order they were sent
+	for _, want := range []types.LoopCommand{types.LoopCommandPause, types.LoopCommandSkip, ""} {
+		got, err := client.PopLoopCommand(ctx, "loop-1")
+		if err != nil {
+			t.Fatalf("PopLoopCommand failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("PopLoopCommand = %q, want %q", got, want)
+		}
+	}
+}
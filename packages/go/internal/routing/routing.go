@@ -0,0 +1,237 @@
+// Package routing picks the tool a task runs with. Rules from the config
+// match tasks on their labels, priority, source, title and the file paths
+// they mention, and pick the preferred tool and model. Loops then try tools
+// along a fallback chain, preferred first; a tool whose session or weekly
+// quota has run out is passed over until the quota resets, so tasks go back
+// to the preferred tool once it is available again.
+package routing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+// Chain returns the tools a loop working in dir tries, in order: preferred,
+// then the fallback chain of the project containing dir, or the default
+// fallback chain.
+func Chain(cfg config.RoutingConfig, dir, preferred string) ([]string, error) {
+	fallback := cfg.Fallback
+	if project, ok := projectOf(cfg.Projects, dir); ok {
+		fallback = cfg.Projects[project]
+	}
+
+	chain := []string{preferred}
+	for _, tool := range fallback {
+		if !types.IsValidTool(tool) {
+			return nil, fmt.Errorf("unknown tool %q in routing fallback chain", tool)
+		}
+		if !contains(chain, tool) {
+			chain = append(chain, tool)
+		}
+	}
+	return chain, nil
+}
+
+// projectOf returns the longest project directory containing dir.
+func projectOf(projects map[string][]string, dir string) (string, bool) {
+	dir = filepath.Clean(dir)
+	best, bestLen := "", -1
+	for project := range projects {
+		path := filepath.Clean(expandHome(project))
+		if dir != path && !strings.HasPrefix(dir, path+string(filepath.Separator)) {
+			continue
+		}
+		if len(path) > bestLen {
+			best, bestLen = project, len(path)
+		}
+	}
+	return best, bestLen >= 0
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+func contains(tools []string, tool string) bool {
+	for _, t := range tools {
+		if t == tool {
+			return true
+		}
+	}
+	return false
+}
+
+// limit is a tool having run out of one of its quotas.
+type limit struct {
+	since  time.Time
+	until  time.Time
+	reason string
+}
+
+func (l *limit) active(now time.Time) bool {
+	return l != nil && now.Before(l.until)
+}
+
+// quota is what is known about a tool's quotas.
+type quota struct {
+	session *limit
+	weekly  *limit
+}
+
+// Router tracks the quotas of the tools in a chain and picks the tool for
+// each task.
+type Router struct {
+	cfg     config.RoutingConfig
+	chain   []string
+	current string
+	quotas  map[string]*quota
+
+	// The tool the next task should preferably run with and why, e.g. the
+	// routing rule that picked it, and the tool preferred for the last task
+	preferred     string
+	preferReason  string
+	lastPreferred string
+}
+
+// New returns a router over chain, starting with its first tool.
+func New(cfg config.RoutingConfig, chain []string) *Router {
+	return &Router{
+		cfg:           cfg,
+		chain:         chain,
+		current:       chain[0],
+		quotas:        make(map[string]*quota),
+		preferred:     chain[0],
+		lastPreferred: chain[0],
+	}
+}
+
+// Prefer sets the tool the next task should run with if it has quota,
+// ahead of the chain, and the reason given if switching to it.
+func (r *Router) Prefer(tool, reason string) {
+	r.preferred, r.preferReason = tool, reason
+}
+
+// Current returns the tool tasks are being run with.
+func (r *Router) Current() string {
+	return r.current
+}
+
+func (r *Router) quota(tool string) *quota {
+	q, ok := r.quotas[tool]
+	if !ok {
+		q = &quota{}
+		r.quotas[tool] = q
+	}
+	return q
+}
+
+// Observe takes in the quota percentages a heartbeat of one of tool's
+// sessions reported at. A quota at its limit is avoided for the reset
+// period; one reported under its limit after that is available again.
+// Percentages that weren't reported are ignored.
+func (r *Router) Observe(tool string, stats *types.UsageStats, at time.Time) {
+	if stats == nil {
+		return
+	}
+	q := r.quota(tool)
+	observe(&q.session, stats.SessionLimitPct, r.cfg.SessionLimit, r.cfg.SessionReset, "session", at)
+	observe(&q.weekly, stats.WeeklyLimitPct, r.cfg.WeeklyLimit, r.cfg.WeeklyReset, "weekly", at)
+}
+
+func observe(l **limit, pct, max float64, reset time.Duration, period string, at time.Time) {
+	switch {
+	case pct <= 0 || max <= 0:
+	case pct >= max:
+		if !(*l).active(at) {
+			*l = &limit{since: at, until: at.Add(reset), reason: fmt.Sprintf("%s quota at %.0f%%", period, pct)}
+		}
+	case *l != nil && at.After((*l).since):
+		*l = nil
+	}
+}
+
+// Exhaust records that tool ran out of its session quota without saying
+// how much was used, e.g. from a usage limit warning in its output.
+func (r *Router) Exhaust(tool, reason string, at time.Time) {
+	q := r.quota(tool)
+	if !q.session.active(at) {
+		q.session = &limit{since: at, until: at.Add(r.cfg.SessionReset), reason: reason}
+	}
+}
+
+// available reports whether tool can be used at now, and if not why and
+// until when.
+func (r *Router) available(tool string, now time.Time) (bool, string, time.Time) {
+	q, ok := r.quotas[tool]
+	if !ok {
+		return true, "", time.Time{}
+	}
+	// The weekly limit outlasts the session limit when both are hit
+	for _, l := range []*limit{q.weekly, q.session} {
+		if l.active(now) {
+			return false, l.reason, l.until
+		}
+	}
+	return true, "", time.Time{}
+}
+
+// Next returns the tool to run the next task with: the preferred tool if
+// it has quota left, else the first tool in the chain that has, or if every
+// tool is out, the one whose quota resets first. A change of tool is
+// returned as a switch for the loop state, with index as the task it starts
+// with.
+func (r *Router) Next(index int, now time.Time) (string, *types.ToolSwitch) {
+	preferred := r.preferred
+	defer func() { r.lastPreferred = preferred }()
+
+	candidates := []string{preferred}
+	for _, tool := range r.chain {
+		if !contains(candidates, tool) {
+			candidates = append(candidates, tool)
+		}
+	}
+	next := ""
+	var soonest time.Time
+	for _, tool := range candidates {
+		ok, _, until := r.available(tool, now)
+		if ok {
+			next = tool
+			break
+		}
+		if next == "" || until.Before(soonest) {
+			next, soonest = tool, until
+		}
+	}
+	if next == r.current {
+		return next, nil
+	}
+
+	sw := &types.ToolSwitch{
+		From:      r.current,
+		To:        next,
+		TaskIndex: index,
+		Timestamp: now.UnixMilli(),
+	}
+	if ok, reason, _ := r.available(r.current, now); !ok {
+		sw.Reason = fmt.Sprintf("%s %s", r.current, reason)
+	} else if ok, reason, _ := r.available(preferred, now); !ok {
+		sw.Reason = fmt.Sprintf("%s %s", preferred, reason)
+	} else if preferred != r.lastPreferred && r.preferReason != "" {
+		sw.Reason = r.preferReason
+	} else {
+		sw.Reason = fmt.Sprintf("%s quota reset", next)
+	}
+	r.current = next
+	return next, sw
+}
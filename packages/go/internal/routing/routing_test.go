@@ -0,0 +1,136 @@
+package routing
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+func testConfig() config.RoutingConfig {
+	return config.RoutingConfig{
+		Fallback:     []string{"claude", "codex"},
+		SessionLimit: 90,
+		WeeklyLimit:  90,
+		SessionReset: 5 * time.Hour,
+		WeeklyReset:  24 * time.Hour,
+	}
+}
+
+func TestChain(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	cfg := testConfig()
+	cfg.Projects = map[string][]string{
+		"/src":          {"gemini"},
+		"/src/frontend": {"gemini", "claude"},
+		"~/work":        {"codex", "opencode"},
+	}
+
+	tests := []struct {
+		dir, preferred string
+		want           []string
+	}{
+		{"/tmp/app", "claude", []string{"claude", "codex"}},
+		{"/tmp/app", "gemini", []string{"gemini", "claude", "codex"}},
+		{"/src/api", "claude", []string{"claude", "gemini"}},
+		{"/src/frontend/web", "claude", []string{"claude", "gemini"}},
+		{"/src/frontend", "codex", []string{"codex", "gemini", "claude"}},
+		{"/srcs", "claude", []string{"claude", "codex"}},
+		{filepath.Join(home, "work", "app"), "claude", []string{"claude", "codex", "opencode"}},
+	}
+	for _, tt := range tests {
+		got, err := Chain(cfg, tt.dir, tt.preferred)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Chain(%q, %q) = %v, %v; want %v", tt.dir, tt.preferred, got, err, tt.want)
+		}
+	}
+
+	cfg.Fallback = []string{"claude", "copilot"}
+	if _, err := Chain(cfg, "/tmp", "claude"); err == nil {
+		t.Error("expected an error for an unknown tool")
+	}
+}
+
+func TestRouterFallsBackAndReturns(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	r := New(testConfig(), []string{"claude", "codex", "gemini"})
+
+	if tool, sw := r.Next(0, start); tool != "claude" || sw != nil {
+		t.Fatalf("Next = %s, %+v; want claude without a switch", tool, sw)
+	}
+
+	// Under the limit: stay
+	r.Observe("claude", &types.UsageStats{SessionLimitPct: 60, WeeklyLimitPct: 40}, start.Add(time.Minute))
+	if tool, sw := r.Next(1, start.Add(2*time.Minute)); tool != "claude" || sw != nil {
+		t.Fatalf("Next = %s, %+v; want claude", tool, sw)
+	}
+
+	// The session quota runs out: fall back to codex
+	r.Observe("claude", &types.UsageStats{SessionLimitPct: 93}, start.Add(10*time.Minute))
+	tool, sw := r.Next(2, start.Add(11*time.Minute))
+	want := &types.ToolSwitch{From: "claude", To: "codex", Reason: "claude session quota at 93%", TaskIndex: 2, Timestamp: start.Add(11 * time.Minute).UnixMilli()}
+	if tool != "codex" || !reflect.DeepEqual(sw, want) {
+		t.Fatalf("Next = %s, %+v; want %+v", tool, sw, want)
+	}
+
+	// Codex's weekly quota runs out too: on to gemini
+	r.Observe("codex", &types.UsageStats{WeeklyLimitPct: 97}, start.Add(20*time.Minute))
+	if tool, sw := r.Next(3, start.Add(21*time.Minute)); tool != "gemini" || sw.Reason != "codex weekly quota at 97%" {
+		t.Fatalf("Next = %s, %+v; want gemini after codex's weekly quota", tool, sw)
+	}
+
+	// A stale heartbeat from before the limit doesn't bring claude back
+	r.Observe("claude", &types.UsageStats{SessionLimitPct: 50}, start.Add(5*time.Minute))
+	if tool, _ := r.Next(4, start.Add(30*time.Minute)); tool != "gemini" {
+		t.Fatalf("Next = %s, want gemini", tool)
+	}
+
+	// The session period passes: back to claude
+	tool, sw = r.Next(5, start.Add(10*time.Minute+5*time.Hour))
+	if tool != "claude" || sw.From != "gemini" || sw.Reason != "claude quota reset" {
+		t.Fatalf("Next = %s, %+v; want a switch back to claude", tool, sw)
+	}
+}
+
+func TestRouterHeartbeatClearsLimit(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	r := New(testConfig(), []string{"claude", "codex"})
+
+	r.Exhaust("claude", "usage limit warning", start)
+	if tool, sw := r.Next(1, start); tool != "codex" || sw.Reason != "claude usage limit warning" {
+		t.Fatalf("Next = %s, %+v; want codex after the warning", tool, sw)
+	}
+
+	// Another claude session reports the session quota below the limit
+	r.Observe("claude", &types.UsageStats{SessionLimitPct: 5}, start.Add(time.Hour))
+	if tool, _ := r.Next(2, start.Add(time.Hour)); tool != "claude" {
+		t.Fatalf("Next = %s, want claude once its quota is back", tool)
+	}
+
+	// A weekly limit isn't cleared by the session quota alone
+	r.Observe("claude", &types.UsageStats{WeeklyLimitPct: 91}, start.Add(2*time.Hour))
+	r.Observe("claude", &types.UsageStats{SessionLimitPct: 5}, start.Add(3*time.Hour))
+	if tool, _ := r.Next(3, start.Add(3*time.Hour)); tool != "codex" {
+		t.Fatalf("Next = %s, want codex until the weekly quota is back", tool)
+	}
+	r.Observe("claude", &types.UsageStats{WeeklyLimitPct: 10}, start.Add(4*time.Hour))
+	if tool, _ := r.Next(4, start.Add(4*time.Hour)); tool != "claude" {
+		t.Fatalf("Next = %s, want claude", tool)
+	}
+}
+
+func TestRouterAllExhausted(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	r := New(testConfig(), []string{"claude", "codex"})
+
+	r.Observe("claude", &types.UsageStats{WeeklyLimitPct: 95}, start)
+	r.Observe("codex", &types.UsageStats{SessionLimitPct: 95}, start)
+	// Codex's session quota resets before claude's weekly one
+	if tool, _ := r.Next(1, start.Add(time.Minute)); tool != "codex" {
+		t.Errorf("Next = %s, want the tool that resets first", tool)
+	}
+}
@@ -0,0 +1,230 @@
+package routing
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/tasksource"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+// Input is what routing rules match a task on.
+type Input struct {
+	Title       string
+	Description string
+	Labels      []string
+	Priority    int // 0-4, or -1 if unknown
+	Source      string
+}
+
+// TaskInput returns the rule input for a task-source task.
+func TaskInput(t tasksource.Task) Input {
+	return Input{
+		Title:       t.Title,
+		Description: t.Description,
+		Labels:      t.Labels,
+		Priority:    int(t.Priority),
+		Source:      string(t.Source),
+	}
+}
+
+// TextInput returns the rule input for a task known only by its text, e.g.
+// spawn --task.
+func TextInput(text string) Input {
+	return Input{Title: text, Priority: -1}
+}
+
+var (
+	urlRe  = regexp.MustCompile(`\w+://\S+`)
+	pathRe = regexp.MustCompile(`^[\w~.-]*[\w.-]/[\w./-]*$|^[\w.-]+\.[A-Za-z][A-Za-z0-9]{0,7}$`)
+)
+
+// Paths returns the file paths mentioned in the task's title and
+// description: words containing a slash or ending in a file extension.
+func (in Input) Paths() []string {
+	text := urlRe.ReplaceAllString(in.Title+"\n"+in.Description, " ")
+	var paths []string
+	seen := make(map[string]bool)
+	for _, word := range strings.Fields(text) {
+		// Drop quoting and punctuation around the word, in either order
+		word = strings.TrimRight(word, ".,;:!?")
+		word = strings.Trim(word, "`'\"()[]{}<>")
+		word = strings.TrimRight(word, ".,;:!?")
+		word = strings.TrimPrefix(word, "./")
+		if word == "" || !pathRe.MatchString(word) || seen[word] {
+			continue
+		}
+		seen[word] = true
+		paths = append(paths, word)
+	}
+	return paths
+}
+
+// condition is one condition of a rule's match.
+type condition struct {
+	kind  string // label, priority, source, title or path
+	value string
+}
+
+func (c condition) String() string {
+	if c.kind == "priority" {
+		return "P" + c.value
+	}
+	return c.kind + ":" + c.value
+}
+
+// parseMatch parses a rule's space-separated conditions.
+func parseMatch(match string) ([]condition, error) {
+	fields := strings.Fields(match)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty match")
+	}
+	conds := make([]condition, 0, len(fields))
+	for _, field := range fields {
+		if len(field) == 2 && (field[0] == 'P' || field[0] == 'p') {
+			if p, err := strconv.Atoi(field[1:]); err == nil && p >= 0 && p <= 4 {
+				conds = append(conds, condition{kind: "priority", value: field[1:]})
+				continue
+			}
+		}
+		kind, value, ok := strings.Cut(field, ":")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid condition %q: use label:, source:, title:, path: or P0-P4", field)
+		}
+		switch kind = strings.ToLower(kind); kind {
+		case "label", "source", "title":
+		case "path":
+			if _, err := filepath.Match(value, ""); err != nil {
+				return nil, fmt.Errorf("invalid path pattern %q: %w", value, err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown condition %q: use label:, source:, title:, path: or P0-P4", field)
+		}
+		conds = append(conds, condition{kind: kind, value: value})
+	}
+	return conds, nil
+}
+
+// matches reports whether in meets the condition. paths are the file paths
+// mentioned in the task.
+func (c condition) matches(in Input, paths []string) bool {
+	switch c.kind {
+	case "label":
+		for _, label := range in.Labels {
+			if strings.EqualFold(label, c.value) {
+				return true
+			}
+		}
+	case "priority":
+		return strconv.Itoa(in.Priority) == c.value
+	case "source":
+		return strings.EqualFold(in.Source, c.value)
+	case "title":
+		return strings.Contains(strings.ToLower(in.Title), strings.ToLower(c.value))
+	case "path":
+		for _, path := range paths {
+			if matchPath(c.value, path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchPath matches a mentioned path against a glob. "dir/**" matches
+// everything under dir, and a pattern without a slash matches file names
+// in any directory.
+func matchPath(pattern, path string) bool {
+	if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return path == dir || strings.HasPrefix(path, dir+"/")
+	}
+	if ok, _ := filepath.Match(pattern, path); ok {
+		return true
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := filepath.Match(pattern, filepath.Base(path))
+		return ok
+	}
+	return false
+}
+
+// ValidateRules checks that every rule's conditions parse and its tool is
+// known.
+func ValidateRules(rules []config.RoutingRule) error {
+	for i, rule := range rules {
+		if err := validateRule(rule); err != nil {
+			return fmt.Errorf("routing rule %d (%s): %w", i+1, rule.Match, err)
+		}
+	}
+	return nil
+}
+
+func validateRule(rule config.RoutingRule) error {
+	if _, err := parseMatch(rule.Match); err != nil {
+		return err
+	}
+	if !types.IsValidTool(rule.Tool) {
+		return fmt.Errorf("unknown tool %q", rule.Tool)
+	}
+	return nil
+}
+
+// RuleResult is how a rule fared against a task.
+type RuleResult struct {
+	Rule   config.RoutingRule
+	Failed []string // Conditions the task didn't meet
+	Err    error    // Why the rule is invalid, if it is
+}
+
+// Matched reports whether the task met all of the rule's conditions.
+func (r RuleResult) Matched() bool {
+	return r.Err == nil && len(r.Failed) == 0
+}
+
+// Explain returns how each rule fares against in, in order.
+func Explain(rules []config.RoutingRule, in Input) []RuleResult {
+	paths := in.Paths()
+	results := make([]RuleResult, len(rules))
+	for i, rule := range rules {
+		results[i].Rule = rule
+		if err := validateRule(rule); err != nil {
+			results[i].Err = err
+			continue
+		}
+		conds, _ := parseMatch(rule.Match)
+		for _, c := range conds {
+			if !c.matches(in, paths) {
+				results[i].Failed = append(results[i].Failed, c.String())
+			}
+		}
+	}
+	return results
+}
+
+// Decision is the outcome of routing a task through the rules.
+type Decision struct {
+	Tool  string
+	Model string
+	Rule  int    // Index of the rule that fired, or -1 if none did
+	Match string // The fired rule's conditions
+}
+
+// Matched reports whether a rule fired.
+func (d Decision) Matched() bool {
+	return d.Rule >= 0
+}
+
+// Route returns the tool and model of the first rule that in matches,
+// skipping invalid rules. Without a match the decision has no tool.
+func Route(rules []config.RoutingRule, in Input) Decision {
+	for i, result := range Explain(rules, in) {
+		if result.Matched() {
+			return Decision{Tool: result.Rule.Tool, Model: result.Rule.Model, Rule: i, Match: result.Rule.Match}
+		}
+	}
+	return Decision{Rule: -1}
+}
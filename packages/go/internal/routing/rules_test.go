@@ -0,0 +1,147 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/tasksource"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+func TestPaths(t *testing.T) {
+	in := Input{
+		Title:       "Fix the navbar in `web/src/Nav.tsx`.",
+		Description: "See https://github.com/me/app/issues/3 and ./internal/tui/model.go, e.g. README.md (docs/).",
+	}
+	want := []string{"web/src/Nav.tsx", "internal/tui/model.go", "e.g", "README.md", "docs/"}
+	if got := in.Paths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths() = %q, want %q", got, want)
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"web/**", "web/src/Nav.tsx", true},
+		{"web/**", "webapp/main.go", false},
+		{"*.tsx", "web/src/Nav.tsx", true},
+		{"*_test.go", "internal/tui/model_test.go", true},
+		{"web/*.css", "web/site.css", true},
+		{"web/*.css", "web/css/site.css", false},
+		{"internal/tui/model.go", "internal/tui/model.go", true},
+	}
+	for _, tt := range tests {
+		if got := matchPath(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchPath(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func testRules() []config.RoutingRule {
+	return []config.RoutingRule{
+		{Match: "P0", Tool: "claude", Model: "opus"},
+		{Match: "label:frontend", Tool: "gemini"},
+		{Match: "label:tests", Tool: "codex"},
+		{Match: "source:github path:*.css", Tool: "gemini"},
+		{Match: "title:refactor", Tool: "claude", Model: "sonnet"},
+	}
+}
+
+func TestRoute(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Input
+		want Decision
+	}{
+		{
+			"priority",
+			TaskInput(tasksource.Task{Title: "Outage", Priority: tasksource.PriorityCritical, Labels: []string{"frontend"}}),
+			Decision{Tool: "claude", Model: "opus", Rule: 0, Match: "P0"},
+		},
+		{
+			"label is case-insensitive",
+			TaskInput(tasksource.Task{Title: "Navbar", Priority: tasksource.PriorityMedium, Labels: []string{"Frontend"}}),
+			Decision{Tool: "gemini", Rule: 1, Match: "label:frontend"},
+		},
+		{
+			"all conditions must match",
+			TaskInput(tasksource.Task{Title: "Tidy site.css", Priority: tasksource.PriorityLow, Source: tasksource.SourceTypeLinear}),
+			Decision{Rule: -1},
+		},
+		{
+			"source and path",
+			TaskInput(tasksource.Task{Title: "Tidy", Description: "web/site.css is a mess", Priority: tasksource.PriorityLow, Source: tasksource.SourceTypeGitHub}),
+			Decision{Tool: "gemini", Rule: 3, Match: "source:github path:*.css"},
+		},
+		{
+			"title keyword in spawn text",
+			TextInput("Refactor the auth module"),
+			Decision{Tool: "claude", Model: "sonnet", Rule: 4, Match: "title:refactor"},
+		},
+		{
+			"text has no priority",
+			TextInput("Fix P0 outage"),
+			Decision{Rule: -1},
+		},
+	}
+	for _, tt := range tests {
+		if got := Route(testRules(), tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Route() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	rules := append(testRules(), config.RoutingRule{Match: "label:x", Tool: "copilot"}, config.RoutingRule{Match: "owner:me", Tool: "codex"})
+	in := TaskInput(tasksource.Task{Title: "Navbar", Priority: tasksource.PriorityMedium, Labels: []string{"frontend"}, Source: tasksource.SourceTypeBeads})
+	results := Explain(rules, in)
+
+	if got := results[0].Failed; !reflect.DeepEqual(got, []string{"P0"}) {
+		t.Errorf("rule 1 failed = %q, want P0", got)
+	}
+	if !results[1].Matched() {
+		t.Error("rule 2 should match")
+	}
+	if got := results[3].Failed; !reflect.DeepEqual(got, []string{"source:github", "path:*.css"}) {
+		t.Errorf("rule 4 failed = %q", got)
+	}
+	if results[5].Err == nil || results[6].Err == nil {
+		t.Errorf("expected the unknown tool and condition to be invalid, got %v, %v", results[5].Err, results[6].Err)
+	}
+
+	if err := ValidateRules(testRules()); err != nil {
+		t.Errorf("ValidateRules() = %v", err)
+	}
+	if err := ValidateRules(rules); err == nil {
+		t.Error("expected ValidateRules to reject the invalid rules")
+	}
+}
+
+func TestRouterPrefer(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	r := New(testConfig(), []string{"claude", "codex"})
+
+	// A rule sends the task to gemini
+	r.Prefer("gemini", "rule label:frontend")
+	tool, sw := r.Next(0, start)
+	if tool != "gemini" || sw.Reason != "rule label:frontend" {
+		t.Fatalf("Next = %s, %+v; want gemini by the rule", tool, sw)
+	}
+
+	// The next task has no rule: back to the loop's tool
+	r.Prefer("claude", "no routing rule matched")
+	if tool, sw := r.Next(1, start); tool != "claude" || sw.Reason != "no routing rule matched" {
+		t.Fatalf("Next = %s, %+v; want claude", tool, sw)
+	}
+
+	// Gemini is out of quota: its tasks fall back along the chain
+	r.Observe("gemini", &types.UsageStats{SessionLimitPct: 99}, start)
+	r.Prefer("gemini", "rule label:frontend")
+	if tool, sw := r.Next(2, start.Add(time.Minute)); tool != "claude" || sw != nil {
+		t.Fatalf("Next = %s, %+v; want claude without a switch", tool, sw)
+	}
+}
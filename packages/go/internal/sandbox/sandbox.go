@@ -0,0 +1,375 @@
+// Package sandbox restricts what a session's tool can write and reach. The
+// session's working directory stays writable; the rest of the filesystem is
+// read-only, credentials are hidden and network access is optional.
+//
+// Two Linux backends are supported. bubblewrap (bwrap) builds a new mount
+// tree with / bound read-only and a private /tmp. unshare (util-linux 2.38+)
+// uses an unprivileged user and mount namespace to remount $HOME read-only;
+// system directories are already read-only to a normal user.
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/Jayphen/coders/internal/config"
+)
+
+// Backend is a sandbox implementation.
+type Backend string
+
+const (
+	BackendAuto    Backend = "auto"
+	BackendBwrap   Backend = "bwrap"
+	BackendUnshare Backend = "unshare"
+)
+
+// ErrUnavailable is returned when no sandbox backend can be used.
+var ErrUnavailable = errors.New("no sandbox available (install bubblewrap, or util-linux 2.38+ with unprivileged user namespaces)")
+
+// Policy is what a sandboxed command may access. Paths are absolute.
+type Policy struct {
+	Home      string
+	ReadWrite []string // Writable paths, including the working directory
+	ReadOnly  []string // Paths made read-only (unshare; bwrap makes everything read-only)
+	Hide      []string // Paths replaced with empty ones
+	Binds     []Bind   // Paths mounted elsewhere, on top of the writable paths
+	Network   bool
+	Env       []string // KEY=value pairs set for the command
+	Setup     []string // Shell commands run in the sandbox before the command
+}
+
+// Bind mounts Src at Dest in the sandbox.
+type Bind struct {
+	Src, Dest string
+	ReadOnly  bool
+}
+
+// toolDirs are the tools' state directories, which they must be able to
+// write to. Claude's is handled by claudeConfig.
+var toolDirs = []string{
+	"~/.codex",
+	"~/.gemini",
+	"~/.config/opencode", "~/.local/share/opencode", "~/.local/state/opencode",
+	"~/.cache", "~/.npm",
+}
+
+// Entries of Claude's config directory that sandboxed sessions share with
+// the user's. Everything else Claude writes stays in the sandbox's own
+// config directory, so a session can't add settings, hooks or commands that
+// unsandboxed sessions would run.
+var (
+	// claudeWritable are its transcripts, todos, feature flags and login
+	claudeWritable = []string{"projects", "todos", "statsig", ".credentials.json"}
+	// claudeReadOnly are its settings, instructions and extensions
+	claudeReadOnly = []string{"settings.json", "CLAUDE.md", "commands", "agents", "skills", "plugins", "hooks", "output-styles"}
+)
+
+// NewPolicy builds the policy for a session working in cwd. The git
+// directory shared by a worktree and the tools' state directories are
+// writable too, so the tool can commit and keep its state; cfg.ReadWrite
+// adds to them. The multiplexer sockets are hidden unless
+// cfg.AllowMultiplexer is set.
+func NewPolicy(cfg config.SandboxConfig, cwd string, network bool) Policy {
+	home, _ := os.UserHomeDir()
+	p := Policy{Home: home, Network: network}
+
+	p.ReadWrite = append(p.ReadWrite, cwd)
+	if gitDir := gitCommonDir(cwd); gitDir != "" && !within(gitDir, cwd) {
+		p.ReadWrite = append(p.ReadWrite, gitDir)
+	}
+	if home != "" {
+		p.ReadWrite = append(p.ReadWrite, expandAll(toolDirs, home)...)
+	}
+	p.ReadWrite = append(p.ReadWrite, config.StateDir())
+	p.ReadWrite = append(p.ReadWrite, expandAll(cfg.ReadWrite, home)...)
+	p.ReadOnly = expandAll(cfg.ReadOnly, home)
+	p.Hide = expandAll(cfg.Hide, home)
+	if cfg.AllowMultiplexer {
+		p.ReadWrite = append(p.ReadWrite, socketDirs()...)
+	} else {
+		p.Hide = append(p.Hide, socketDirs()...)
+	}
+	p.claudeConfig()
+	return p
+}
+
+// claudeConfig gives sandboxed claude sessions their own config directory,
+// under coders' state directory, with the shared entries of the user's
+// mounted into it. Claude rewrites its global config by renaming a
+// temporary file next to it, so it needs a writable directory anyway. The
+// global config is copied in on each spawn, so changes made in the sandbox,
+// such as MCP servers, don't reach unsandboxed sessions.
+func (p *Policy) claudeConfig() {
+	if p.Home == "" {
+		return
+	}
+	dir, global := filepath.Join(p.Home, ".claude"), filepath.Join(p.Home, ".claude.json")
+	if env := os.Getenv("CLAUDE_CONFIG_DIR"); env != "" {
+		dir = expandAll([]string{env}, p.Home)[0]
+		global = filepath.Join(dir, ".claude.json")
+	}
+	sandboxed := filepath.Join(config.StateDir(), "sandbox", "claude")
+	if err := os.MkdirAll(sandboxed, 0700); err != nil {
+		return
+	}
+
+	for _, name := range claudeWritable {
+		if filepath.Ext(name) == "" && isDir(dir) {
+			// Create shared directories, so Claude doesn't start private ones
+			os.MkdirAll(filepath.Join(dir, name), 0700)
+		}
+		p.Binds = append(p.Binds, Bind{Src: filepath.Join(dir, name), Dest: filepath.Join(sandboxed, name)})
+	}
+	for _, name := range claudeReadOnly {
+		p.Binds = append(p.Binds, Bind{Src: filepath.Join(dir, name), Dest: filepath.Join(sandboxed, name), ReadOnly: true})
+	}
+	p.Env = append(p.Env, "CLAUDE_CONFIG_DIR="+sandboxed)
+	p.Setup = append(p.Setup, fmt.Sprintf("cp %s %s 2>/dev/null || true",
+		shellQuote(global), shellQuote(filepath.Join(sandboxed, ".claude.json"))))
+}
+
+// Detect returns the backend to use. "auto" (or empty) prefers bwrap.
+func Detect(preferred string) (Backend, error) {
+	if runtime.GOOS != "linux" {
+		return "", fmt.Errorf("sandboxing needs Linux: %w", ErrUnavailable)
+	}
+	switch Backend(preferred) {
+	case BackendBwrap, BackendUnshare:
+		if _, err := exec.LookPath(preferred); err != nil {
+			return "", fmt.Errorf("sandbox backend %s not found: %w", preferred, ErrUnavailable)
+		}
+		return Backend(preferred), nil
+	case "", BackendAuto:
+		if _, err := exec.LookPath("bwrap"); err == nil {
+			return BackendBwrap, nil
+		}
+		if unshareWorks() {
+			return BackendUnshare, nil
+		}
+		return "", ErrUnavailable
+	default:
+		return "", fmt.Errorf("unknown sandbox backend %q (use auto, bwrap or unshare)", preferred)
+	}
+}
+
+// Wrap returns command wrapped to run under p.
+func Wrap(b Backend, p Policy, command string) (string, error) {
+	command = p.script(command)
+	switch b {
+	case BackendBwrap:
+		return shellJoin(bwrapArgs(p, command)), nil
+	case BackendUnshare:
+		return unshareCommand(p, command), nil
+	default:
+		return "", fmt.Errorf("unknown sandbox backend %q", b)
+	}
+}
+
+// script prefixes command with the policy's environment and setup.
+func (p Policy) script(command string) string {
+	if len(p.Env) == 0 && len(p.Setup) == 0 {
+		return command
+	}
+	var lines []string
+	for _, kv := range p.Env {
+		key, value, _ := strings.Cut(kv, "=")
+		lines = append(lines, fmt.Sprintf("export %s=%s", key, shellQuote(value)))
+	}
+	lines = append(lines, p.Setup...)
+	return strings.Join(append(lines, command), "\n")
+}
+
+// bwrapArgs builds a bwrap invocation. Later mounts are stacked on earlier
+// ones, so hidden paths are mounted last to cover writable parents too.
+func bwrapArgs(p Policy, command string) []string {
+	args := []string{
+		"bwrap", "--die-with-parent",
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--proc", "/proc",
+		"--tmpfs", "/tmp",
+	}
+	for _, path := range existing(p.ReadWrite) {
+		args = append(args, "--bind", path, path)
+	}
+	for _, b := range existingBinds(p.Binds) {
+		if b.ReadOnly {
+			args = append(args, "--ro-bind", b.Src, b.Dest)
+		} else {
+			args = append(args, "--bind", b.Src, b.Dest)
+		}
+	}
+	for _, path := range existing(p.Hide) {
+		if isDir(path) {
+			args = append(args, "--tmpfs", path)
+		} else {
+			args = append(args, "--ro-bind", "/dev/null", path)
+		}
+	}
+	if !p.Network {
+		args = append(args, "--unshare-net")
+	}
+	return append(args, "--", "sh", "-c", command)
+}
+
+// unshareCommand mounts the policy as root of a new user namespace, then
+// runs command in a nested namespace mapped back to the calling user, so
+// the tool neither runs as root nor can undo the mounts.
+//
+// Writable paths and binds are mounted first so that remounting $HOME
+// read-only leaves them as they are.
+func unshareCommand(p Policy, command string) string {
+	var script []string
+	script = append(script, "set -e")
+	for _, path := range existing(p.ReadWrite) {
+		script = append(script, fmt.Sprintf("mount --bind %s %s", shellQuote(path), shellQuote(path)))
+	}
+	for _, b := range existingBinds(p.Binds) {
+		// Mounting needs something at the destination to mount onto
+		if isDir(b.Src) {
+			script = append(script, fmt.Sprintf("mkdir -p %s", shellQuote(b.Dest)))
+		} else {
+			script = append(script, fmt.Sprintf("[ -e %s ] || touch %s", shellQuote(b.Dest), shellQuote(b.Dest)))
+		}
+		script = append(script, fmt.Sprintf("mount --bind %s %s", shellQuote(b.Src), shellQuote(b.Dest)))
+		if b.ReadOnly {
+			script = append(script, fmt.Sprintf("mount -o remount,bind,ro %s", shellQuote(b.Dest)))
+		}
+	}
+	readOnly := p.ReadOnly
+	if p.Home != "" {
+		readOnly = append([]string{p.Home}, readOnly...)
+	}
+	for _, path := range existing(readOnly) {
+		script = append(script,
+			fmt.Sprintf("mount --rbind %s %s", shellQuote(path), shellQuote(path)),
+			fmt.Sprintf("mount -o remount,bind,ro %s", shellQuote(path)))
+	}
+	for _, path := range existing(p.Hide) {
+		if isDir(path) {
+			script = append(script, fmt.Sprintf("mount -t tmpfs tmpfs %s", shellQuote(path)))
+		} else {
+			script = append(script, fmt.Sprintf("mount --bind /dev/null %s", shellQuote(path)))
+		}
+	}
+	// The working directory still refers to the old mounts until re-entered
+	script = append(script, `cd "$PWD"`)
+	script = append(script, fmt.Sprintf("exec unshare --user --map-user=%d --map-group=%d sh -c %s",
+		os.Getuid(), os.Getgid(), shellQuote(command)))
+
+	args := []string{"unshare", "--user", "--map-root-user", "--mount"}
+	if !p.Network {
+		args = append(args, "--net")
+	}
+	args = append(args, "sh", "-c", strings.Join(script, "\n"))
+	return shellJoin(args)
+}
+
+// unshareWorks reports whether unprivileged user namespaces with uid
+// mapping are available.
+func unshareWorks() bool {
+	if _, err := exec.LookPath("unshare"); err != nil {
+		return false
+	}
+	return exec.Command("unshare", "--user", "--map-root-user", "--mount",
+		"unshare", "--user", fmt.Sprintf("--map-user=%d", os.Getuid()), "true").Run() == nil
+}
+
+// socketDirs returns the tmux and zellij socket directories. A tool that can
+// connect to a server can have it run commands outside the sandbox.
+func socketDirs() []string {
+	uid := os.Getuid()
+	tmuxDir := os.Getenv("TMUX_TMPDIR")
+	if tmuxDir == "" {
+		tmuxDir = os.TempDir()
+	}
+	dirs := []string{filepath.Join(tmuxDir, fmt.Sprintf("tmux-%d", uid))}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		dirs = append(dirs, filepath.Join(runtimeDir, "zellij"))
+	}
+	return append(dirs, filepath.Join(os.TempDir(), fmt.Sprintf("zellij-%d", uid)))
+}
+
+// gitCommonDir returns the git directory shared by all worktrees of the
+// repository containing dir, or "" if dir isn't in a repository.
+func gitCommonDir(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--path-format=absolute", "--git-common-dir").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// expandAll expands a leading ~ and makes each path absolute.
+func expandAll(paths []string, home string) []string {
+	var result []string
+	for _, path := range paths {
+		if path == "~" {
+			path = home
+		} else if strings.HasPrefix(path, "~/") {
+			path = filepath.Join(home, path[2:])
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			result = append(result, abs)
+		}
+	}
+	return result
+}
+
+// existing returns the paths that exist; mounting onto a missing path fails.
+func existing(paths []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		if _, err := os.Stat(path); err == nil {
+			result = append(result, path)
+		}
+	}
+	return result
+}
+
+// existingBinds returns the binds whose source exists.
+func existingBinds(binds []Bind) []Bind {
+	var result []Bind
+	for _, b := range binds {
+		if _, err := os.Stat(b.Src); err == nil {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// within reports whether path is dir or inside it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// shellQuote single-quotes s for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// shellJoin quotes each argument and joins them into a command line.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
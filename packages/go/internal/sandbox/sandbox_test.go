@@ -0,0 +1,280 @@
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Jayphen/coders/internal/config"
+)
+
+func TestNewPolicy(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CODERS_STATE_DIR", filepath.Join(home, "state"))
+	repo := filepath.Join(home, "repo")
+	if err := os.MkdirAll(repo, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPolicy(config.SandboxConfig{
+		ReadWrite: []string{"~/scratch"},
+		ReadOnly:  []string{"/srv/shared"},
+		Hide:      []string{"~/.ssh", "~"},
+	}, repo, false)
+
+	if p.Home != home || p.Network {
+		t.Errorf("unexpected policy: %+v", p)
+	}
+	if p.ReadWrite[0] != repo {
+		t.Errorf("ReadWrite[0] = %q, want working directory %q", p.ReadWrite[0], repo)
+	}
+	// read_write adds to the tools' state directories rather than replacing them
+	for _, want := range []string{filepath.Join(home, "scratch"), filepath.Join(home, ".codex"), filepath.Join(home, "state")} {
+		if !contains(p.ReadWrite, want) {
+			t.Errorf("ReadWrite missing %s: %v", want, p.ReadWrite)
+		}
+	}
+	if contains(p.ReadWrite, filepath.Join(home, ".claude")) {
+		t.Errorf("ReadWrite includes ~/.claude: %v", p.ReadWrite)
+	}
+	if got := strings.Join(p.Hide[:2], ","); got != filepath.Join(home, ".ssh")+","+home {
+		t.Errorf("Hide = %v", p.Hide)
+	}
+	if got := strings.Join(p.ReadOnly, ","); got != "/srv/shared" {
+		t.Errorf("ReadOnly = %v", p.ReadOnly)
+	}
+}
+
+func TestNewPolicyMultiplexer(t *testing.T) {
+	tmuxDir := filepath.Join(t.TempDir(), "tmux-"+strconv.Itoa(os.Getuid()))
+	t.Setenv("TMUX_TMPDIR", filepath.Dir(tmuxDir))
+	cwd := t.TempDir()
+
+	p := NewPolicy(config.SandboxConfig{}, cwd, true)
+	if !contains(p.Hide, tmuxDir) || contains(p.ReadWrite, tmuxDir) {
+		t.Errorf("tmux socket directory should be hidden by default: ReadWrite = %v, Hide = %v", p.ReadWrite, p.Hide)
+	}
+
+	p = NewPolicy(config.SandboxConfig{AllowMultiplexer: true}, cwd, true)
+	if contains(p.Hide, tmuxDir) || !contains(p.ReadWrite, tmuxDir) {
+		t.Errorf("tmux socket directory should be writable with allow_multiplexer: ReadWrite = %v, Hide = %v", p.ReadWrite, p.Hide)
+	}
+}
+
+func TestNewPolicyClaudeConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CODERS_STATE_DIR", filepath.Join(home, "state"))
+	t.Setenv("CLAUDE_CONFIG_DIR", "")
+	sandboxed := filepath.Join(home, "state", "sandbox", "claude")
+
+	p := NewPolicy(config.SandboxConfig{}, home, true)
+	if want := "CLAUDE_CONFIG_DIR=" + sandboxed; !contains(p.Env, want) {
+		t.Errorf("Env = %v, want %s", p.Env, want)
+	}
+	if len(p.Setup) != 1 || !strings.Contains(p.Setup[0], filepath.Join(home, ".claude.json")) {
+		t.Errorf("Setup = %v, want ~/.claude.json copied in", p.Setup)
+	}
+	wantBinds := map[string]Bind{
+		"projects":      {Src: filepath.Join(home, ".claude", "projects"), Dest: filepath.Join(sandboxed, "projects")},
+		"settings.json": {Src: filepath.Join(home, ".claude", "settings.json"), Dest: filepath.Join(sandboxed, "settings.json"), ReadOnly: true},
+		"hooks":         {Src: filepath.Join(home, ".claude", "hooks"), Dest: filepath.Join(sandboxed, "hooks"), ReadOnly: true},
+	}
+	for name, want := range wantBinds {
+		found := false
+		for _, b := range p.Binds {
+			found = found || b == want
+		}
+		if !found {
+			t.Errorf("Binds missing %s: %+v", name, p.Binds)
+		}
+	}
+
+	// A config directory the user chose is the one shared
+	custom := filepath.Join(home, "claude-config")
+	t.Setenv("CLAUDE_CONFIG_DIR", custom)
+	p = NewPolicy(config.SandboxConfig{}, home, true)
+	if p.Binds[0].Src != filepath.Join(custom, "projects") {
+		t.Errorf("Binds = %+v, want them from %s", p.Binds, custom)
+	}
+	if !strings.Contains(p.Setup[0], filepath.Join(custom, ".claude.json")) {
+		t.Errorf("Setup = %v, want %s/.claude.json copied in", p.Setup, custom)
+	}
+}
+
+func TestWrapSetsEnv(t *testing.T) {
+	p := Policy{Env: []string{"CLAUDE_CONFIG_DIR=/home/me/.claude"}, Setup: []string{"true"}}
+	for _, b := range []Backend{BackendBwrap, BackendUnshare} {
+		cmd, err := Wrap(b, p, "claude")
+		if err != nil {
+			t.Fatalf("Wrap(%s) failed: %v", b, err)
+		}
+		if !strings.Contains(cmd, "export CLAUDE_CONFIG_DIR=") || !strings.Contains(cmd, "/home/me/.claude") {
+			t.Errorf("Wrap(%s) doesn't set the environment:\n%s", b, cmd)
+		}
+	}
+}
+
+func TestBwrapArgs(t *testing.T) {
+	dir := t.TempDir()
+	work := filepath.Join(dir, "work")
+	secrets := filepath.Join(dir, "secrets")
+	token := filepath.Join(dir, "token")
+	for _, d := range []string{work, secrets} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(token, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	args := strings.Join(bwrapArgs(Policy{
+		ReadWrite: []string{work, filepath.Join(dir, "missing"), work},
+		Hide:      []string{secrets, token},
+		Binds: []Bind{
+			{Src: token, Dest: work + "/token", ReadOnly: true},
+			{Src: secrets, Dest: work + "/secrets"},
+			{Src: filepath.Join(dir, "missing"), Dest: work + "/missing"},
+		},
+	}, "claude"), " ")
+
+	want := "bwrap --die-with-parent --ro-bind / / --dev /dev --proc /proc --tmpfs /tmp" +
+		" --bind " + work + " " + work +
+		" --ro-bind " + token + " " + work + "/token" +
+		" --bind " + secrets + " " + work + "/secrets" +
+		" --tmpfs " + secrets +
+		" --ro-bind /dev/null " + token +
+		" --unshare-net -- sh -c claude"
+	if args != want {
+		t.Errorf("bwrapArgs =\n%s\nwant\n%s", args, want)
+	}
+}
+
+func TestWithin(t *testing.T) {
+	tests := []struct {
+		path, dir string
+		want      bool
+	}{
+		{"/a/b", "/a", true},
+		{"/a", "/a", true},
+		{"/ab", "/a", false},
+		{"/a/../c", "/a", false},
+	}
+	for _, tt := range tests {
+		if got := within(tt.path, tt.dir); got != tt.want {
+			t.Errorf("within(%q, %q) = %v, want %v", tt.path, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestDetectUnknownBackend(t *testing.T) {
+	if _, err := Detect("chroot"); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
+
+// TestUnshareSandbox runs a command in the unshare sandbox: the working
+// directory is writable, the rest of $HOME isn't, and hidden files are empty.
+func TestUnshareSandbox(t *testing.T) {
+	if !unshareWorks() {
+		t.Skip("unprivileged user namespaces unavailable")
+	}
+	home := t.TempDir()
+	work := filepath.Join(home, "work")
+	secret := filepath.Join(home, "secret")
+	if err := os.Mkdir(work, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(secret, []byte("hunter2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	script := `echo ok > work/out && cat secret && if touch denied 2>/dev/null; then echo writable; fi; id -u`
+	cmd := exec.Command("sh", "-c", unshareCommand(Policy{
+		Home:      home,
+		ReadWrite: []string{work},
+		Hide:      []string{secret},
+		Network:   true,
+	}, script))
+	cmd.Dir = home
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sandboxed command failed: %v\n%s", err, out)
+	}
+
+	if got, want := string(out), strconv.Itoa(os.Getuid())+"\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if data, err := os.ReadFile(filepath.Join(work, "out")); err != nil || string(data) != "ok\n" {
+		t.Errorf("write to working directory: %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(home, "denied")); err == nil {
+		t.Error("sandbox wrote outside the working directory")
+	}
+}
+
+// TestUnshareSandboxClaudeConfig runs a claude-like session in the unshare
+// sandbox: it can rewrite its global config the way Claude does, via a
+// temporary file and a rename, and write transcripts, but not the settings
+// or hooks unsandboxed sessions use.
+func TestUnshareSandboxClaudeConfig(t *testing.T) {
+	if !unshareWorks() {
+		t.Skip("unprivileged user namespaces unavailable")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CODERS_STATE_DIR", filepath.Join(home, ".local", "state", "coders"))
+	t.Setenv("CLAUDE_CONFIG_DIR", "")
+	work := filepath.Join(home, "work")
+	for _, dir := range []string{work, filepath.Join(home, ".claude")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{".claude.json": `{"seeded":true}`, ".claude/settings.json": `{}`}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(home, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := NewPolicy(config.SandboxConfig{}, work, true)
+	script := `cd "$CLAUDE_CONFIG_DIR"
+cat .claude.json
+echo '{"rewritten":true}' > .claude.json.tmp && mv .claude.json.tmp .claude.json
+echo transcript > projects/session.jsonl
+if (echo '{"hooks":{}}' > settings.json) 2>/dev/null; then echo settings writable; fi
+if touch "$HOME/.claude/hooks" 2>/dev/null; then echo ~/.claude writable; fi`
+	cmd := exec.Command("sh", "-c", unshareCommand(p, p.script(script)))
+	cmd.Dir = work
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("claude session failed in the sandbox: %v\n%s", err, out)
+	}
+
+	if string(out) != `{"seeded":true}` {
+		t.Errorf("output = %q, want the config copied from ~/.claude.json", out)
+	}
+	if data, _ := os.ReadFile(filepath.Join(home, ".claude", "projects", "session.jsonl")); string(data) != "transcript\n" {
+		t.Errorf("shared transcript = %q", data)
+	}
+	for name, want := range files {
+		if data, _ := os.ReadFile(filepath.Join(home, name)); string(data) != want {
+			t.Errorf("~/%s changed to %q", name, data)
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
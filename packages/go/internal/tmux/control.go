@@ -0,0 +1,500 @@
+package tmux
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ControlEventType identifies a change reported by a control-mode client.
+type ControlEventType string
+
+const (
+	EventSessionCreated ControlEventType = "session-created"
+	EventSessionClosed  ControlEventType = "session-closed"
+	EventPaneOutput     ControlEventType = "pane-output"
+	EventPaneExited     ControlEventType = "pane-exited"
+	EventWindowRenamed  ControlEventType = "window-renamed"
+)
+
+// ControlEvent is a change on the tmux server, reported as it happens.
+type ControlEvent struct {
+	Type    ControlEventType
+	Session string // Session name, if known
+	Window  string // Window ID, e.g. "@3"
+	Pane    string // Pane ID, e.g. "%5"
+
+	// Data is the output for EventPaneOutput and the new name for
+	// EventWindowRenamed.
+	Data string
+
+	// ExitStatus is the pane's exit status for EventPaneExited, or -1 if the
+	// pane closed without tmux keeping it (remain-on-exit off).
+	ExitStatus int
+}
+
+// ErrControlClosed is returned when the control-mode client has exited.
+var ErrControlClosed = errors.New("tmux control client closed")
+
+// ErrNoSessions is returned by StartControl when there is no session to attach to.
+var ErrNoSessions = errors.New("no coder sessions to attach to")
+
+// controlStartTimeout is how long StartControl waits for tmux to attach.
+const controlStartTimeout = 5 * time.Second
+
+// ControlClient is a long-lived tmux control-mode (tmux -C) client. It turns
+// tmux notifications into ControlEvents, so callers don't need to poll
+// list-sessions and capture-pane.
+//
+// tmux only sends pane output for the session the client is attached to; use
+// Follow to choose it. Requires tmux 3.2 or newer.
+type ControlClient struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	events chan ControlEvent
+	ready  chan struct{}
+	done   chan struct{}
+
+	// Notifications queued by the read loop for the handler. The queue is
+	// unbounded so the read loop never blocks while the handler waits for a
+	// command reply.
+	notesMu  sync.Mutex
+	notes    []string
+	noteWake chan struct{}
+
+	mu      sync.Mutex
+	pending []chan controlReply
+	closed  bool
+
+	// Server state from the last resync, owned by the notification handler
+	stateMu  sync.Mutex
+	synced   bool
+	sessions map[string]bool
+	panes    map[string]paneInfo
+}
+
+type controlReply struct {
+	lines []string
+	err   error
+}
+
+type paneInfo struct {
+	session    string
+	window     string
+	dead       bool
+	exitStatus int
+}
+
+// StartControl starts a control-mode client attached to target, or to the
+// first coder session if target is empty. The client never resizes windows.
+func StartControl(target string) (*ControlClient, error) {
+	if target == "" {
+		sessions, err := ListSessions()
+		if err != nil {
+			return nil, err
+		}
+		if len(sessions) == 0 {
+			return nil, ErrNoSessions
+		}
+		target = sessions[0].Name
+	}
+
+	cmd := Command("-C", "attach-session", "-t", target, "-f", "ignore-size")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start tmux control client: %w", err)
+	}
+
+	c := newControlClient(stdin)
+	c.cmd = cmd
+	go func() {
+		c.read(stdout)
+		_ = cmd.Wait()
+	}()
+	go c.handle()
+
+	select {
+	case <-c.ready:
+	case <-c.done:
+		return nil, ErrControlClosed
+	case <-time.After(controlStartTimeout):
+		c.Close()
+		return nil, fmt.Errorf("timed out attaching tmux control client to %s", target)
+	}
+
+	// Pane deaths under remain-on-exit produce no notification of their
+	// own, so subscribe to pane_dead; tmux checks subscriptions every second.
+	_, _ = c.Command("refresh-client", "-B", "coders-dead:%*:#{pane_dead}")
+	c.resync()
+	return c, nil
+}
+
+func newControlClient(stdin io.WriteCloser) *ControlClient {
+	return &ControlClient{
+		stdin:    stdin,
+		events:   make(chan ControlEvent, 256),
+		noteWake: make(chan struct{}, 1),
+		ready:    make(chan struct{}),
+		done:     make(chan struct{}),
+		sessions: make(map[string]bool),
+		panes:    make(map[string]paneInfo),
+	}
+}
+
+// Events returns the event stream. It is closed when the client exits.
+// Output events are dropped rather than blocking if the reader falls behind.
+func (c *ControlClient) Events() <-chan ControlEvent {
+	return c.events
+}
+
+// Done is closed when the client has exited.
+func (c *ControlClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Command runs a tmux command over the control connection and returns its
+// output lines.
+func (c *ControlClient) Command(args ...string) ([]string, error) {
+	ch := make(chan controlReply, 1)
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrControlClosed
+	}
+	// Replies arrive in command order, so queue and write under one lock
+	c.pending = append(c.pending, ch)
+	_, err := io.WriteString(c.stdin, quoteCommand(args)+"\n")
+	c.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write tmux command: %w", err)
+	}
+
+	select {
+	case r := <-ch:
+		return r.lines, r.err
+	case <-c.done:
+		return nil, ErrControlClosed
+	}
+}
+
+// Follow switches the client to session, so its pane output is reported.
+func (c *ControlClient) Follow(session string) error {
+	_, err := c.Command("switch-client", "-t", session)
+	return err
+}
+
+// Close detaches the client.
+func (c *ControlClient) Close() error {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return nil
+	}
+	// Closing stdin makes tmux detach the client and exit
+	err := c.stdin.Close()
+	select {
+	case <-c.done:
+	case <-time.After(time.Second):
+		if c.cmd != nil && c.cmd.Process != nil {
+			_ = c.cmd.Process.Kill()
+		}
+	}
+	return err
+}
+
+// read parses control-mode output until tmux exits. Command replies are
+// framed by %begin and %end (or %error) lines carrying the same command
+// number; everything else starting with % is a notification.
+func (c *ControlClient) read(r io.Reader) {
+	defer c.shutdown()
+
+	br := bufio.NewReaderSize(r, 64*1024)
+	var (
+		inBlock    bool
+		blockID    string
+		blockOwned bool
+		block      []string
+	)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		if inBlock {
+			kind, id, _ := parseGuard(line)
+			if (kind == "%end" || kind == "%error") && id == blockID {
+				inBlock = false
+				// Blocks with flags 0 are replies to commands we didn't send,
+				// such as the initial attach
+				if blockOwned {
+					c.reply(block, kind == "%error")
+				}
+				block = nil
+				continue
+			}
+			block = append(block, line)
+			continue
+		}
+
+		if kind, id, flags := parseGuard(line); kind == "%begin" {
+			inBlock, blockID, blockOwned = true, id, flags == "1"
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "%output "):
+			c.output(strings.TrimPrefix(line, "%output "))
+		case strings.HasPrefix(line, "%exit"):
+			return
+		case strings.HasPrefix(line, "%"):
+			c.notesMu.Lock()
+			c.notes = append(c.notes, line)
+			c.notesMu.Unlock()
+			select {
+			case c.noteWake <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// parseGuard splits a "%begin|%end|%error time number flags" line.
+func parseGuard(line string) (kind, id, flags string) {
+	fields := strings.Fields(line)
+	if len(fields) != 4 {
+		return "", "", ""
+	}
+	switch fields[0] {
+	case "%begin", "%end", "%error":
+		return fields[0], fields[2], fields[3]
+	}
+	return "", "", ""
+}
+
+// reply delivers a command's output to the oldest waiting Command call.
+func (c *ControlClient) reply(lines []string, failed bool) {
+	c.mu.Lock()
+	if len(c.pending) == 0 {
+		c.mu.Unlock()
+		return
+	}
+	ch := c.pending[0]
+	c.pending = c.pending[1:]
+	c.mu.Unlock()
+
+	r := controlReply{lines: lines}
+	if failed {
+		r.err = fmt.Errorf("tmux: %s", strings.Join(lines, "; "))
+	}
+	ch <- r
+}
+
+// output emits a pane output event, dropping it if the consumer is behind.
+func (c *ControlClient) output(rest string) {
+	pane, data, _ := strings.Cut(rest, " ")
+	c.stateMu.Lock()
+	info := c.panes[pane]
+	c.stateMu.Unlock()
+
+	select {
+	case c.events <- ControlEvent{Type: EventPaneOutput, Session: info.session, Window: info.window, Pane: pane, Data: unescapeOutput(data)}:
+	default:
+	}
+}
+
+// handle processes notifications off the read loop, since resyncing issues
+// commands whose replies the read loop must deliver. Structural changes
+// queued together cause a single resync.
+func (c *ControlClient) handle() {
+	defer close(c.events)
+	for {
+		select {
+		case <-c.noteWake:
+		case <-c.done:
+			return
+		}
+
+		c.notesMu.Lock()
+		notes := c.notes
+		c.notes = nil
+		c.notesMu.Unlock()
+
+		needResync := false
+		for _, line := range notes {
+			name, rest, _ := strings.Cut(line, " ")
+			switch name {
+			case "%session-changed":
+				select {
+				case <-c.ready:
+				default:
+					close(c.ready)
+				}
+			case "%sessions-changed", "%window-add", "%window-close",
+				"%unlinked-window-add", "%unlinked-window-close",
+				"%layout-change", "%subscription-changed":
+				needResync = true
+			case "%window-renamed", "%unlinked-window-renamed":
+				window, title, _ := strings.Cut(rest, " ")
+				c.emit(ControlEvent{Type: EventWindowRenamed, Session: c.windowSession(window), Window: window, Data: title})
+			}
+		}
+		if needResync {
+			c.resync()
+		}
+	}
+}
+
+// emit sends a structural event; these are never dropped.
+func (c *ControlClient) emit(ev ControlEvent) {
+	select {
+	case c.events <- ev:
+	case <-c.done:
+	}
+}
+
+func (c *ControlClient) windowSession(window string) string {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	for _, p := range c.panes {
+		if p.window == window {
+			return p.session
+		}
+	}
+	return ""
+}
+
+// resync lists all panes and emits events for what changed since the last
+// resync. The first resync only records the initial state.
+func (c *ControlClient) resync() {
+	lines, err := c.Command("list-panes", "-a", "-F",
+		"#{session_name}\t#{window_id}\t#{pane_id}\t#{pane_dead}\t#{pane_dead_status}")
+	if err != nil {
+		return
+	}
+	sessions, panes := parsePaneList(lines)
+
+	c.stateMu.Lock()
+	events := diffState(c.sessions, c.panes, sessions, panes)
+	emit := c.synced
+	c.sessions, c.panes, c.synced = sessions, panes, true
+	c.stateMu.Unlock()
+
+	if !emit {
+		return
+	}
+	for _, ev := range events {
+		c.emit(ev)
+	}
+}
+
+// parsePaneList parses list-panes -a output from resync.
+func parsePaneList(lines []string) (map[string]bool, map[string]paneInfo) {
+	sessions := make(map[string]bool)
+	panes := make(map[string]paneInfo)
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			continue
+		}
+		status, err := strconv.Atoi(fields[4])
+		if err != nil {
+			status = -1
+		}
+		sessions[fields[0]] = true
+		panes[fields[2]] = paneInfo{
+			session:    fields[0],
+			window:     fields[1],
+			dead:       fields[3] == "1",
+			exitStatus: status,
+		}
+	}
+	return sessions, panes
+}
+
+// diffState returns the events between two snapshots: pane exits first, then
+// closed and created sessions.
+func diffState(oldSessions map[string]bool, oldPanes map[string]paneInfo, sessions map[string]bool, panes map[string]paneInfo) []ControlEvent {
+	var events []ControlEvent
+	for id, old := range oldPanes {
+		p, ok := panes[id]
+		switch {
+		case !ok && !old.dead:
+			events = append(events, ControlEvent{Type: EventPaneExited, Session: old.session, Window: old.window, Pane: id, ExitStatus: -1})
+		case ok && p.dead && !old.dead:
+			events = append(events, ControlEvent{Type: EventPaneExited, Session: p.session, Window: p.window, Pane: id, ExitStatus: p.exitStatus})
+		}
+	}
+	for name := range oldSessions {
+		if !sessions[name] {
+			events = append(events, ControlEvent{Type: EventSessionClosed, Session: name})
+		}
+	}
+	for name := range sessions {
+		if !oldSessions[name] {
+			events = append(events, ControlEvent{Type: EventSessionCreated, Session: name})
+		}
+	}
+	return events
+}
+
+// shutdown fails pending commands and stops the notification handler.
+func (c *ControlClient) shutdown() {
+	c.mu.Lock()
+	c.closed = true
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+
+	close(c.done)
+	for _, ch := range pending {
+		ch <- controlReply{err: ErrControlClosed}
+	}
+}
+
+// unescapeOutput decodes %output data, in which tmux writes backslashes and
+// characters below space as three-digit octal escapes.
+func unescapeOutput(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && isOctal(s[i+1]) && isOctal(s[i+2]) && isOctal(s[i+3]) {
+			b.WriteByte((s[i+1]-'0')<<6 | (s[i+2]-'0')<<3 | (s[i+3] - '0'))
+			i += 3
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isOctal(c byte) bool { return c >= '0' && c <= '7' }
+
+// quoteCommand renders args as a tmux command line. Each argument is single
+// quoted; embedded single quotes are closed, double quoted and reopened.
+func quoteCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
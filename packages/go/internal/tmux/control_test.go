@@ -0,0 +1,198 @@
+package tmux
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUnescapeOutput(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain text", "plain text"},
+		{`line\015\012`, "line\r\n"},
+		{`\033[1mbold\033[0m`, "\x1b[1mbold\x1b[0m"},
+		{`back\134slash`, `back\slash`},
+		{`not \9 octal`, `not \9 octal`},
+		{`trailing \01`, `trailing \01`},
+	}
+	for _, tt := range tests {
+		if got := unescapeOutput(tt.in); got != tt.want {
+			t.Errorf("unescapeOutput(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteCommand(t *testing.T) {
+	got := quoteCommand([]string{"display-message", "-p", "it's #{session_name}"})
+	want := `'display-message' '-p' 'it'"'"'s #{session_name}'`
+	if got != want {
+		t.Errorf("quoteCommand = %s, want %s", got, want)
+	}
+}
+
+func TestParseGuard(t *testing.T) {
+	kind, id, flags := parseGuard("%begin 1792336133 263 1")
+	if kind != "%begin" || id != "263" || flags != "1" {
+		t.Errorf("parseGuard = %q %q %q", kind, id, flags)
+	}
+	if kind, _, _ := parseGuard("%end of something"); kind != "" {
+		t.Errorf("expected non-guard line, got %q", kind)
+	}
+}
+
+func TestDiffState(t *testing.T) {
+	oldSessions := map[string]bool{"coder-a": true, "coder-b": true}
+	oldPanes := map[string]paneInfo{
+		"%0": {session: "coder-a", window: "@0"},
+		"%1": {session: "coder-b", window: "@1"},
+		"%2": {session: "coder-a", window: "@0"},
+	}
+	sessions, panes := parsePaneList([]string{
+		"coder-a\t@0\t%0\t0\t",
+		"coder-a\t@0\t%2\t1\t3",
+		"coder-c\t@4\t%5\t0\t",
+		"malformed",
+	})
+
+	got := diffState(oldSessions, oldPanes, sessions, panes)
+	byTypeAndPane := func(evs []ControlEvent) func(i, j int) bool {
+		return func(i, j int) bool {
+			if evs[i].Type != evs[j].Type {
+				return evs[i].Type < evs[j].Type
+			}
+			return evs[i].Pane < evs[j].Pane
+		}
+	}
+	sort.Slice(got, byTypeAndPane(got))
+	want := []ControlEvent{
+		{Type: EventPaneExited, Session: "coder-b", Window: "@1", Pane: "%1", ExitStatus: -1},
+		{Type: EventPaneExited, Session: "coder-a", Window: "@0", Pane: "%2", ExitStatus: 3},
+		{Type: EventSessionClosed, Session: "coder-b"},
+		{Type: EventSessionCreated, Session: "coder-c"},
+	}
+	sort.Slice(want, byTypeAndPane(want))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffState =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+// fakeControlServer answers commands written by a ControlClient the way a
+// tmux control-mode server would.
+type fakeControlServer struct {
+	mu    sync.Mutex
+	out   io.Writer
+	seq   int
+	panes []string // list-panes -a reply
+}
+
+func (f *fakeControlServer) write(lines ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, line := range lines {
+		fmt.Fprintln(f.out, line)
+	}
+}
+
+func (f *fakeControlServer) serve(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		f.mu.Lock()
+		f.seq++
+		seq := f.seq
+		var reply []string
+		kind := "%end"
+		switch {
+		case strings.HasPrefix(scanner.Text(), "'list-panes'"):
+			reply = f.panes
+		case strings.HasPrefix(scanner.Text(), "'bad'"):
+			reply, kind = []string{"unknown command: bad"}, "%error"
+		}
+		f.mu.Unlock()
+
+		f.write(fmt.Sprintf("%%begin 1 %d 1", seq))
+		f.write(reply...)
+		f.write(fmt.Sprintf("%s 1 %d 1", kind, seq))
+	}
+}
+
+func (f *fakeControlServer) setPanes(panes ...string) {
+	f.mu.Lock()
+	f.panes = panes
+	f.mu.Unlock()
+}
+
+func nextEvent(t *testing.T, c *ControlClient) ControlEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-c.Events():
+		if !ok {
+			t.Fatal("events closed")
+		}
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return ControlEvent{}
+}
+
+func TestControlClientProtocol(t *testing.T) {
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+	server := &fakeControlServer{out: stdoutW}
+	server.setPanes("coder-a\t@0\t%0\t0\t")
+	go server.serve(stdinR)
+
+	c := newControlClient(stdinW)
+	go c.read(stdoutR)
+	go c.handle()
+
+	// The attach reply (flags 0) must not be taken as a reply to our commands
+	server.write("%begin 1 100 0", "%end 1 100 0", "%session-changed $0 coder-a")
+	select {
+	case <-c.ready:
+	case <-time.After(2 * time.Second):
+		t.Fatal("client never became ready")
+	}
+	c.resync()
+
+	if _, err := c.Command("bad"); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("expected command error, got %v", err)
+	}
+
+	server.write(`%output %0 hello\015\012`)
+	if ev := nextEvent(t, c); ev.Type != EventPaneOutput || ev.Session != "coder-a" || ev.Data != "hello\r\n" {
+		t.Errorf("unexpected output event: %+v", ev)
+	}
+
+	server.setPanes("coder-a\t@0\t%0\t0\t", "coder-b\t@1\t%1\t0\t")
+	server.write("%sessions-changed")
+	if ev := nextEvent(t, c); ev.Type != EventSessionCreated || ev.Session != "coder-b" {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+
+	server.write("%window-renamed @1 build")
+	if ev := nextEvent(t, c); ev.Type != EventWindowRenamed || ev.Session != "coder-b" || ev.Data != "build" {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+
+	server.write("%exit")
+	select {
+	case <-c.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("client did not stop on exit")
+	}
+	if _, err := c.Command("list-sessions"); err != ErrControlClosed {
+		t.Errorf("Command after exit = %v, want ErrControlClosed", err)
+	}
+	for range c.Events() {
+	}
+}
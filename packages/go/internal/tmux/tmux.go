@@ -5,10 +5,15 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/proc"
 	"github.com/Jayphen/coders/internal/types"
 )
 
@@ -21,14 +26,82 @@ const (
 	OrchestratorSession = "coder-orchestrator"
 )
 
-// IsInsideTmux returns true if we're running inside a tmux session.
+// Session metadata is stored in these tmux user options when a session is
+// created, so ListSessions doesn't depend on parsing the session name.
+const (
+	OptionTool     = "@coders_tool"
+	OptionTask     = "@coders_task"
+	OptionParent   = "@coders_parent"
+	OptionLoop     = "@coders_loop"
+	OptionTaskID   = "@coders_task_id"
+	OptionWorktree = "@coders_worktree"
+)
+
+var (
+	socketName string
+	socketOnce sync.Once
+)
+
+// Socket returns the name of the dedicated tmux server socket (tmux -L), or
+// "" to use the user's default server. It is read from config once.
+func Socket() string {
+	socketOnce.Do(func() {
+		if cfg, err := config.Get(); err == nil {
+			socketName = cfg.TmuxSocket
+		}
+	})
+	return socketName
+}
+
+// SetSocket overrides the configured tmux server socket.
+func SetSocket(name string) {
+	socketOnce.Do(func() {})
+	socketName = name
+}
+
+// Command returns a tmux command that talks to the coders tmux server.
+func Command(args ...string) *exec.Cmd {
+	return exec.Command("tmux", serverArgs(args...)...)
+}
+
+// serverArgs prefixes args with the socket selection, if any.
+func serverArgs(args ...string) []string {
+	if socket := Socket(); socket != "" {
+		return append([]string{"-L", socket}, args...)
+	}
+	return args
+}
+
+// ShellCommand is the tmux invocation to use in shell command strings.
+func ShellCommand() string {
+	if socket := Socket(); socket != "" {
+		return "tmux -L '" + strings.ReplaceAll(socket, "'", "'\"'\"'") + "'"
+	}
+	return "tmux"
+}
+
+// IsInsideTmux returns true if we're running inside a session on the coders
+// tmux server. With a dedicated socket, being inside another tmux server
+// doesn't count.
 func IsInsideTmux() bool {
-	return os.Getenv("TMUX") != ""
+	return insideServer(os.Getenv("TMUX"), Socket())
+}
+
+// insideServer reports whether a $TMUX value belongs to the server on socket.
+func insideServer(tmuxEnv, socket string) bool {
+	if tmuxEnv == "" {
+		return false
+	}
+	if socket == "" {
+		return true
+	}
+	path, _, _ := strings.Cut(tmuxEnv, ",")
+	return filepath.Base(path) == socket
 }
 
 // GetCurrentSession returns the name of the current tmux session, if any.
 func GetCurrentSession() (string, error) {
-	out, err := exec.Command("tmux", "display-message", "-p", "#{session_name}").Output()
+	out, err := Command("display-message", "-p", "#{session_name}").Output()
 	if err != nil {
 		return "", err
 	}
@@ -37,17 +110,24 @@ func GetCurrentSession() (string, error) {
 
 // SessionExists checks if a tmux session with the given name exists.
 func SessionExists(name string) bool {
-	err := exec.Command("tmux", "has-session", "-t", name).Run()
+	err := Command("has-session", "-t", name).Run()
 	return err == nil
 }
 
+// listFormat is the list-sessions format parsed by parseSessionLine. The
+// task is last because it may contain tabs.
+var listFormat = strings.Join([]string{
+	"#{session_name}", "#{session_created}", "#{pane_current_path}", "#{pane_title}",
+	"#{" + OptionTool + "}", "#{" + OptionParent + "}", "#{" + OptionLoop + "}",
+	"#{" + OptionWorktree + "}", "#{" + OptionTaskID + "}", "#{" + OptionTask + "}",
+}, "\t")
+
 // ListSessions returns all coder sessions (sessions starting with SessionPrefix).
 func ListSessions() ([]types.Session, error) {
 	// Get session info from tmux
-	out, err := exec.Command("tmux", "list-sessions", "-F",
-		"#{session_name}|#{session_created}|#{pane_current_path}|#{pane_title}").Output()
+	out, err := Command("list-sessions", "-F", listFormat).Output()
 	if err != nil {
-		// No sessions is not an error
+		// No sessions (or no server on the dedicated socket) is not an error
 		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
 			return []types.Session{}, nil
 		}
@@ -55,81 +135,95 @@ func ListSessions() ([]types.Session, error) {
 	}
 
 	var sessions []types.Session
-	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
-
-	for _, line := range lines {
-		if line == "" {
-			continue
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if session, ok := parseSessionLine(line); ok {
+			sessions = append(sessions, session)
 		}
+	}
 
-		// Only include coder sessions
-		if !strings.HasPrefix(line, SessionPrefix) {
-			continue
-		}
+	return sessions, nil
+}
 
-		parts := strings.SplitN(line, "|", 4)
-		if len(parts) < 3 {
-			continue
-		}
+// parseSessionLine parses one line of list-sessions output in listFormat.
+// Sessions created before metadata was recorded fall back to the tool and
+// task encoded in the session name.
+func parseSessionLine(line string) (types.Session, bool) {
+	// Only include coder sessions
+	if !strings.HasPrefix(line, SessionPrefix) {
+		return types.Session{}, false
+	}
 
-		name := parts[0]
-		createdStr := parts[1]
-		cwd := parts[2]
-		paneTitle := ""
-		if len(parts) > 3 {
-			paneTitle = parts[3]
+	parts := strings.SplitN(line, "\t", 10)
+	if len(parts) < 3 {
+		return types.Session{}, false
+	}
+	field := func(i int) string {
+		if i < len(parts) {
+			return parts[i]
 		}
+		return ""
+	}
 
-		// Parse tool and task from session name (coder-{tool}-{task})
-		nameParts := strings.SplitN(strings.TrimPrefix(name, SessionPrefix), "-", 2)
-		tool := "unknown"
-		taskFromName := ""
-		if len(nameParts) > 0 {
-			tool = nameParts[0]
-			if !types.IsValidTool(tool) {
-				tool = "unknown"
-			}
-		}
-		if len(nameParts) > 1 {
-			taskFromName = nameParts[1]
-		}
+	name := parts[0]
+	paneTitle := field(3)
 
-		isOrchestrator := name == "coder-orchestrator"
+	tool, taskFromName := ParseSessionName(name)
+	if t := field(4); t != "" {
+		tool = t
+	}
 
-		// Parse creation time
-		var createdAt *time.Time
-		if ts, err := strconv.ParseInt(createdStr, 10, 64); err == nil {
-			t := time.Unix(ts, 0)
-			createdAt = &t
-		}
+	// Parse creation time
+	var createdAt *time.Time
+	if ts, err := strconv.ParseInt(parts[1], 10, 64); err == nil {
+		t := time.Unix(ts, 0)
+		createdAt = &t
+	}
 
-		// Determine task description
-		task := taskFromName
-		if task == "" && paneTitle != "" && !strings.Contains(paneTitle, "bash") &&
-			!strings.Contains(paneTitle, "zsh") && paneTitle != name {
-			task = paneTitle
-		}
+	// Determine task description
+	task := field(9)
+	if task == "" {
+		task = taskFromName
+	}
+	if task == "" && paneTitle != "" && !strings.Contains(paneTitle, "bash") &&
+		!strings.Contains(paneTitle, "zsh") && paneTitle != name {
+		task = paneTitle
+	}
 
-		session := types.Session{
-			Name:           name,
-			Tool:           tool,
-			Task:           task,
-			Cwd:            cwd,
-			CreatedAt:      createdAt,
-			IsOrchestrator: isOrchestrator,
-		}
+	return types.Session{
+		Name:            name,
+		Tool:            tool,
+		Task:            task,
+		Cwd:             parts[2],
+		CreatedAt:       createdAt,
+		ParentSessionID: field(5),
+		LoopID:          field(6),
+		Worktree:        field(7),
+		TaskID:          field(8),
+		IsOrchestrator:  name == OrchestratorSession,
+	}, true
+}
 
-		sessions = append(sessions, session)
+// ParseSessionName returns the tool and task encoded in a session name
+// (coder-{tool}-{task}). Unknown tools are reported as "unknown".
+func ParseSessionName(name string) (tool, task string) {
+	nameParts := strings.SplitN(strings.TrimPrefix(name, SessionPrefix), "-", 2)
+	tool = nameParts[0]
+	if !types.IsValidTool(tool) {
+		tool = "unknown"
 	}
-
-	return sessions, nil
+	if len(nameParts) > 1 {
+		task = nameParts[1]
+	}
+	return tool, task
 }
 
 // AttachSession attaches to or switches to a tmux session.
 func AttachSession(name string) error {
 	if !IsInsideTmux() {
-		// Outside tmux - attach directly
-		cmd := exec.Command("tmux", "attach", "-t", name)
+		// Outside tmux - attach directly. Inside another tmux server (when
+		// using a dedicated socket) this nests, so $TMUX is cleared.
+		cmd := Command("attach", "-t", name)
+		cmd.Env = append(os.Environ(), "TMUX=")
 		cmd.Stdin = os.Stdin
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
@@ -137,7 +231,36 @@ func AttachSession(name string) error {
 	}
 
 	// Inside tmux - switch client
-	return exec.Command("tmux", "switch-client", "-t", name).Run()
+	return Command("switch-client", "-t", name).Run()
+}
+
+// AttachSplit opens a window in the current session with a tiled pane
+// attached to each named session. The panes run nested clients, so tmux
+// keys reach a session after pressing the prefix twice.
+func AttachSplit(names []string) error {
+	if !IsInsideTmux() {
+		return fmt.Errorf("attaching in split panes only works inside tmux")
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	attach := func(name string) string {
+		return "TMUX= " + ShellCommand() + " attach -t '" + strings.ReplaceAll(name, "'", "'\"'\"'") + "'"
+	}
+
+	out, err := Command("new-window", "-P", "-F", "#{window_id}", "-n", "coders-split", attach(names[0])).Output()
+	if err != nil {
+		return fmt.Errorf("failed to open window: %w", err)
+	}
+	window := strings.TrimSpace(string(out))
+	for _, name := range names[1:] {
+		if err := Command("split-window", "-t", window, attach(name)).Run(); err != nil {
+			return fmt.Errorf("failed to split window for %s: %w", name, err)
+		}
+		// Retile after each split so later splits have room
+		_ = Command("select-layout", "-t", window, "tiled").Run()
+	}
+	return nil
 }
 
 // KillSession kills a tmux session and its process tree.
@@ -147,13 +270,17 @@ func KillSession(name string) error {
 		// Non-fatal, continue to kill session
 	}
 
-	// Kill the tmux session
-	return exec.Command("tmux", "kill-session", "-t", name).Run()
+	// Kill the tmux session. Killing the process tree usually closes the
+	// session already, which is not an error.
+	if err := Command("kill-session", "-t", name).Run(); err != nil && SessionExists(name) {
+		return err
+	}
+	return nil
 }
 
 // GetPanePIDs returns the PIDs of all panes in a session.
 func GetPanePIDs(sessionName string) ([]int, error) {
-	out, err := exec.Command("tmux", "list-panes", "-t", sessionName, "-F", "#{pane_pid}").Output()
+	out, err := Command("list-panes", "-t", sessionName, "-F", "#{pane_pid}").Output()
 	if err != nil {
 		return nil, err
 	}
@@ -177,126 +304,111 @@ func killSessionProcessTree(sessionName string) error {
 		return err
 	}
 
-	// Get all descendant PIDs
-	descendants := collectDescendants(pids)
-	if len(descendants) == 0 {
+	tree, err := proc.Tree(pids...)
+	if err != nil {
+		return err
+	}
+	var live []int
+	for _, p := range tree {
+		if p.Alive() {
+			live = append(live, p.PID)
+		}
+	}
+	if len(live) == 0 {
 		return nil
 	}
 
 	// Send SIGTERM first
-	killPIDs(descendants, "TERM")
+	proc.Signal(live, syscall.SIGTERM)
 
 	// Wait a bit
 	time.Sleep(300 * time.Millisecond)
 
 	// Send SIGKILL to any remaining
-	remaining := filterLivePIDs(descendants)
-	if len(remaining) > 0 {
-		killPIDs(remaining, "KILL")
-	}
+	proc.Signal(proc.FilterAlive(live), syscall.SIGKILL)
 
 	return nil
 }
 
-// collectDescendants collects all descendant PIDs of the given root PIDs.
-func collectDescendants(rootPIDs []int) []int {
-	// Get process table
-	out, err := exec.Command("ps", "-axo", "pid=,ppid=").Output()
-	if err != nil {
-		return rootPIDs
+// CreateSession creates a new detached tmux session running command with
+// sh -c, and records meta as session user options in the same tmux call.
+func CreateSession(name, cwd, command string, meta types.SessionMeta) error {
+	args := []string{"new-session", "-d", "-s", name}
+	if cwd != "" {
+		args = append(args, "-c", cwd)
 	}
-
-	// Build parent -> children map
-	children := make(map[int][]int)
-	for _, line := range strings.Split(string(out), "\n") {
-		fields := strings.Fields(line)
-		if len(fields) != 2 {
-			continue
-		}
-		pid, err1 := strconv.Atoi(fields[0])
-		ppid, err2 := strconv.Atoi(fields[1])
-		if err1 != nil || err2 != nil {
-			continue
-		}
-		children[ppid] = append(children[ppid], pid)
+	if command != "" {
+		args = append(args, "sh", "-c", escapeArg(command))
 	}
+	args = append(args, metaArgs(meta)...)
 
-	// BFS to collect all descendants
-	visited := make(map[int]bool)
-	queue := append([]int{}, rootPIDs...)
-
-	for len(queue) > 0 {
-		pid := queue[0]
-		queue = queue[1:]
-
-		if visited[pid] {
-			continue
+	if out, err := Command(args...).CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
 		}
-		visited[pid] = true
-
-		for _, child := range children[pid] {
-			queue = append(queue, child)
-		}
-	}
-
-	result := make([]int, 0, len(visited))
-	for pid := range visited {
-		result = append(result, pid)
-	}
-	return result
-}
-
-// killPIDs sends a signal to a list of PIDs.
-func killPIDs(pids []int, signal string) {
-	if len(pids) == 0 {
-		return
-	}
-
-	args := []string{"-" + signal}
-	for _, pid := range pids {
-		args = append(args, strconv.Itoa(pid))
+		return err
 	}
-
-	// Ignore errors - some processes may have already exited
-	exec.Command("kill", args...).Run()
+	return nil
 }
 
-// filterLivePIDs returns only the PIDs that are still running.
-// Uses kill -0 to test process existence without scanning the entire process table.
-func filterLivePIDs(pids []int) []int {
-	var result []int
-	for _, pid := range pids {
-		// kill -0 tests if a process exists without sending a signal
-		// Returns 0 if the process exists, non-zero otherwise
-		if err := exec.Command("kill", "-0", strconv.Itoa(pid)).Run(); err == nil {
-			result = append(result, pid)
+// metaArgs returns the chained set-option commands that store meta on the
+// session just created.
+func metaArgs(meta types.SessionMeta) []string {
+	var args []string
+	for _, opt := range []struct{ name, value string }{
+		{OptionTool, meta.Tool},
+		{OptionTask, meta.Task},
+		{OptionParent, meta.Parent},
+		{OptionLoop, meta.Loop},
+		{OptionTaskID, meta.TaskID},
+		{OptionWorktree, meta.Worktree},
+	} {
+		if opt.value == "" {
+			continue
 		}
+		// Newlines would break list-sessions output parsing
+		value := strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(opt.value)
+		args = append(args, ";", "set-option", opt.name, escapeArg(value))
 	}
-	return result
+	return args
 }
 
-// CreateSession creates a new tmux session with the given name and command.
-func CreateSession(name, cwd, command string) error {
-	args := []string{"new-session", "-d", "-s", name}
-	if cwd != "" {
-		args = append(args, "-c", cwd)
-	}
-	if command != "" {
-		args = append(args, command)
+// escapeArg stops tmux treating a trailing ";" in an argument as a command
+// separator.
+func escapeArg(arg string) string {
+	if strings.HasSuffix(arg, ";") {
+		return arg[:len(arg)-1] + "\\;"
 	}
-
-	return exec.Command("tmux", args...).Run()
+	return arg
 }
 
 // SendKeys sends keys to a tmux session and submits them with Enter.
 // Uses -l flag to send text literally, then sends Enter key to submit.
 func SendKeys(sessionName, keys string) error {
 	// Send the text literally (so special characters aren't interpreted)
-	if err := exec.Command("tmux", "send-keys", "-l", "-t", sessionName, keys).Run(); err != nil {
+	if err := Command("send-keys", "-l", "-t", sessionName, keys).Run(); err != nil {
 		return err
 	}
 	// Send Enter key to submit
-	return exec.Command("tmux", "send-keys", "-t", sessionName, "Enter").Run()
+	return Command("send-keys", "-t", sessionName, "Enter").Run()
+}
+
+// SendText types text into a tmux session literally, without pressing Enter.
+func SendText(sessionName, text string) error {
+	return Command("send-keys", "-l", "-t", sessionName, text).Run()
+}
+
+// SendRawKeys sends tmux key names (e.g. "Escape", "C-c") to a session
+// without sending text literally or pressing Enter.
+func SendRawKeys(sessionName string, keys ...string) error {
+	args := append([]string{"send-keys", "-t", sessionName}, keys...)
+	return Command(args...).Run()
+}
+
+// PipePane pipes all output of a session's active pane to a shell command.
+// Does nothing if the pane is already being piped.
+func PipePane(sessionName, command string) error {
+	return Command("pipe-pane", "-o", "-t", sessionName, command).Run()
 }
 
 // CapturePane returns the last N lines of output from a session's active pane.
@@ -304,7 +416,22 @@ func CapturePane(sessionName string, lines int) (string, error) {
 	if lines <= 0 {
 		lines = 30
 	}
-	out, err := exec.Command("tmux", "capture-pane", "-t", sessionName, "-p", "-e", "-S", fmt.Sprintf("-%d", lines)).Output()
+	return Capture(sessionName, lines, true)
+}
+
+// Capture returns the last N lines of a session's active pane, or the whole
+// scrollback if lines <= 0. With escapes, colors and attributes are kept.
+func Capture(sessionName string, lines int, escapes bool) (string, error) {
+	args := []string{"capture-pane", "-t", sessionName, "-p"}
+	if escapes {
+		args = append(args, "-e")
+	}
+	if lines > 0 {
+		args = append(args, "-S", fmt.Sprintf("-%d", lines))
+	} else {
+		args = append(args, "-J", "-S", "-")
+	}
+	out, err := Command(args...).Output()
 	if err != nil {
 		return "", err
 	}
@@ -330,5 +457,5 @@ func SendDisplayMessage(targetSession, message string) error {
 	}
 
 	// Send the display message
-	return exec.Command("tmux", "display-message", "-t", targetSession, message).Run()
+	return Command("display-message", "-t", targetSession, message).Run()
 }
@@ -69,7 +69,7 @@ func TestParseSessionInfo(t *testing.T) {
 	}{
 		{
 			name: "valid coder-claude session",
-			line: "coder-claude-implement-feature|1640000000|/home/user/project|Implementing auth feature",
+			line: "coder-claude-implement-feature\t1640000000\t/home/user/project\tImplementing auth feature",
 			wantSession: types.Session{
 				Name:           "coder-claude-implement-feature",
 				Tool:           "claude",
@@ -82,7 +82,7 @@ func TestParseSessionInfo(t *testing.T) {
 		},
 		{
 			name: "orchestrator session",
-			line: "coder-orchestrator|1640000000|/home/user/project|orchestrator",
+			line: "coder-orchestrator\t1640000000\t/home/user/project\torchestrator",
 			wantSession: types.Session{
 				Name:           "coder-orchestrator",
 				Tool:           "unknown",
@@ -95,7 +95,7 @@ func TestParseSessionInfo(t *testing.T) {
 		},
 		{
 			name: "coder-gemini session with pane title",
-			line: "coder-gemini-fix-bug|1640000000|/home/user/app|Fix authentication bug",
+			line: "coder-gemini-fix-bug\t1640000000\t/home/user/app\tFix authentication bug",
 			wantSession: types.Session{
 				Name:           "coder-gemini-fix-bug",
 				Tool:           "gemini",
@@ -108,7 +108,7 @@ func TestParseSessionInfo(t *testing.T) {
 		},
 		{
 			name: "session with unknown tool",
-			line: "coder-unknown-tool-task|1640000000|/home/user|Some task",
+			line: "coder-unknown-tool-task\t1640000000\t/home/user\tSome task",
 			wantSession: types.Session{
 				Name:           "coder-unknown-tool-task",
 				Tool:           "unknown",
@@ -121,7 +121,7 @@ func TestParseSessionInfo(t *testing.T) {
 		},
 		{
 			name:     "non-coder session should be skipped",
-			line:     "my-dev-session|1640000000|/home/user|bash",
+			line:     "my-dev-session\t1640000000\t/home/user\tbash",
 			wantSkip: true,
 		},
 		{
@@ -131,7 +131,7 @@ func TestParseSessionInfo(t *testing.T) {
 		},
 		{
 			name: "session with bash pane title (use name for task)",
-			line: "coder-claude-test-feature|1640000000|/home/user|bash",
+			line: "coder-claude-test-feature\t1640000000\t/home/user\tbash",
 			wantSession: types.Session{
 				Name:           "coder-claude-test-feature",
 				Tool:           "claude",
@@ -144,7 +144,7 @@ func TestParseSessionInfo(t *testing.T) {
 		},
 		{
 			name: "session without pane title",
-			line: "coder-codex-write-tests|1640000000|/home/user",
+			line: "coder-codex-write-tests\t1640000000\t/home/user",
 			wantSession: types.Session{
 				Name:           "coder-codex-write-tests",
 				Tool:           "codex",
@@ -157,73 +157,48 @@ func TestParseSessionInfo(t *testing.T) {
 		},
 		{
 			name:     "malformed line with too few parts",
-			line:     "coder-claude|1640000000",
+			line:     "coder-claude\t1640000000",
 			wantSkip: true,
 		},
+		{
+			name: "metadata options override the session name",
+			line: "coder-claude-fix-the\t1640000000\t/home/user/wt\tclaude\tclaude-code\tcoder-orchestrator\tloop-42\t/home/user/wt\tbd-17\tFix the login-page crash\twith tabs",
+			wantSession: types.Session{
+				Name:            "coder-claude-fix-the",
+				Tool:            "claude-code",
+				Task:            "Fix the login-page crash\twith tabs",
+				Cwd:             "/home/user/wt",
+				CreatedAt:       &testTime,
+				ParentSessionID: "coder-orchestrator",
+				LoopID:          "loop-42",
+				TaskID:          "bd-17",
+				Worktree:        "/home/user/wt",
+			},
+		},
+		{
+			name: "empty metadata options fall back to the session name",
+			line: "coder-gemini-fix-bug\t1640000000\t/home/user/app\tgemini\t\t\t\t\t\t",
+			wantSession: types.Session{
+				Name:      "coder-gemini-fix-bug",
+				Tool:      "gemini",
+				Task:      "fix-bug",
+				Cwd:       "/home/user/app",
+				CreatedAt: &testTime,
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.line == "" || !strings.HasPrefix(tt.line, SessionPrefix) {
-				if !tt.wantSkip {
-					t.Errorf("Expected line to be skipped but wantSkip is false")
-				}
-				return
-			}
-
-			parts := strings.SplitN(tt.line, "|", 4)
-			if len(parts) < 3 {
+			session, ok := parseSessionLine(tt.line)
+			if !ok {
 				if !tt.wantSkip {
-					t.Errorf("Expected line to be skipped but wantSkip is false")
+					t.Errorf("Expected line to be parsed but it was skipped")
 				}
 				return
 			}
-
-			name := parts[0]
-			createdStr := parts[1]
-			cwd := parts[2]
-			paneTitle := ""
-			if len(parts) > 3 {
-				paneTitle = parts[3]
-			}
-
-			// Parse tool and task from session name
-			nameParts := strings.SplitN(strings.TrimPrefix(name, SessionPrefix), "-", 2)
-			tool := "unknown"
-			taskFromName := ""
-			if len(nameParts) > 0 {
-				tool = nameParts[0]
-				if !types.IsValidTool(tool) {
-					tool = "unknown"
-				}
-			}
-			if len(nameParts) > 1 {
-				taskFromName = nameParts[1]
-			}
-
-			isOrchestrator := name == "coder-orchestrator"
-
-			// Parse creation time
-			var createdAt *time.Time
-			if ts, err := strconv.ParseInt(createdStr, 10, 64); err == nil {
-				t := time.Unix(ts, 0)
-				createdAt = &t
-			}
-
-			// Determine task description
-			task := taskFromName
-			if task == "" && paneTitle != "" && !strings.Contains(paneTitle, "bash") &&
-				!strings.Contains(paneTitle, "zsh") && paneTitle != name {
-				task = paneTitle
-			}
-
-			session := types.Session{
-				Name:           name,
-				Tool:           tool,
-				Task:           task,
-				Cwd:            cwd,
-				CreatedAt:      createdAt,
-				IsOrchestrator: isOrchestrator,
+			if tt.wantSkip {
+				t.Fatalf("Expected line to be skipped, got %+v", session)
 			}
 
 			// Compare with expected
@@ -239,6 +214,18 @@ func TestParseSessionInfo(t *testing.T) {
 			if session.Cwd != tt.wantSession.Cwd {
 				t.Errorf("Cwd = %v, want %v", session.Cwd, tt.wantSession.Cwd)
 			}
+			if session.ParentSessionID != tt.wantSession.ParentSessionID {
+				t.Errorf("ParentSessionID = %v, want %v", session.ParentSessionID, tt.wantSession.ParentSessionID)
+			}
+			if session.LoopID != tt.wantSession.LoopID {
+				t.Errorf("LoopID = %v, want %v", session.LoopID, tt.wantSession.LoopID)
+			}
+			if session.TaskID != tt.wantSession.TaskID {
+				t.Errorf("TaskID = %v, want %v", session.TaskID, tt.wantSession.TaskID)
+			}
+			if session.Worktree != tt.wantSession.Worktree {
+				t.Errorf("Worktree = %v, want %v", session.Worktree, tt.wantSession.Worktree)
+			}
 			if session.IsOrchestrator != tt.wantSession.IsOrchestrator {
 				t.Errorf("IsOrchestrator = %v, want %v", session.IsOrchestrator, tt.wantSession.IsOrchestrator)
 			}
@@ -306,219 +293,6 @@ func TestParsePanePIDs(t *testing.T) {
 	}
 }
 
-func TestCollectDescendants(t *testing.T) {
-	tests := []struct {
-		name        string
-		rootPIDs    []int
-		psOutput    string
-		expected    []int // Set of PIDs we expect to see
-		minExpected int   // Minimum number of PIDs expected
-	}{
-		{
-			name:     "simple parent-child tree",
-			rootPIDs: []int{100},
-			psOutput: `  100   1
-  200 100
-  300 100
-  400 200`,
-			expected:    []int{100, 200, 300, 400},
-			minExpected: 4,
-		},
-		{
-			name:     "single root no children",
-			rootPIDs: []int{100},
-			psOutput: `  100   1
-  200   1`,
-			expected:    []int{100},
-			minExpected: 1,
-		},
-		{
-			name:     "multiple roots",
-			rootPIDs: []int{100, 500},
-			psOutput: `  100   1
-  200 100
-  500   1
-  600 500`,
-			expected:    []int{100, 200, 500, 600},
-			minExpected: 4,
-		},
-		{
-			name:     "deep tree",
-			rootPIDs: []int{100},
-			psOutput: `  100   1
-  200 100
-  300 200
-  400 300
-  500 400`,
-			expected:    []int{100, 200, 300, 400, 500},
-			minExpected: 5,
-		},
-		{
-			name:        "empty ps output",
-			rootPIDs:    []int{100},
-			psOutput:    "",
-			expected:    []int{100},
-			minExpected: 1,
-		},
-		{
-			name:     "malformed ps lines ignored",
-			rootPIDs: []int{100},
-			psOutput: `  100   1
-invalid line
-  200 100
-  300 abc`,
-			expected:    []int{100, 200},
-			minExpected: 2,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Build parent -> children map from ps output
-			children := make(map[int][]int)
-			for _, line := range strings.Split(tt.psOutput, "\n") {
-				fields := strings.Fields(line)
-				if len(fields) != 2 {
-					continue
-				}
-				pid, err1 := strconv.Atoi(fields[0])
-				ppid, err2 := strconv.Atoi(fields[1])
-				if err1 != nil || err2 != nil {
-					continue
-				}
-				children[ppid] = append(children[ppid], pid)
-			}
-
-			// BFS to collect all descendants
-			visited := make(map[int]bool)
-			queue := append([]int{}, tt.rootPIDs...)
-
-			for len(queue) > 0 {
-				pid := queue[0]
-				queue = queue[1:]
-
-				if visited[pid] {
-					continue
-				}
-				visited[pid] = true
-
-				for _, child := range children[pid] {
-					queue = append(queue, child)
-				}
-			}
-
-			result := make([]int, 0, len(visited))
-			for pid := range visited {
-				result = append(result, pid)
-			}
-
-			// Check that we got at least the expected PIDs
-			if len(result) < tt.minExpected {
-				t.Errorf("collectDescendants() returned %d PIDs, want at least %d", len(result), tt.minExpected)
-			}
-
-			// Check that all expected PIDs are present
-			resultMap := make(map[int]bool)
-			for _, pid := range result {
-				resultMap[pid] = true
-			}
-
-			for _, expectedPID := range tt.expected {
-				if !resultMap[expectedPID] {
-					t.Errorf("collectDescendants() missing expected PID %d", expectedPID)
-				}
-			}
-		})
-	}
-}
-
-func TestFilterLivePIDs(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    []int
-		expected int // Expected number of live PIDs (we can't know exact PIDs)
-	}{
-		{
-			name:     "empty input",
-			input:    []int{},
-			expected: 0,
-		},
-		{
-			name:  "mix of valid and invalid PIDs",
-			input: []int{1, 999999, os.Getpid()}, // PID 1 exists, 999999 likely doesn't, our own PID exists
-			// We expect at least 2 (PID 1 and our own PID)
-			expected: -1, // Special value meaning "just check it doesn't panic"
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// This tests the logic without relying on specific system PIDs
-			var result []int
-			for _, pid := range tt.input {
-				// Simulate the filtering logic
-				if pid > 0 && pid <= 100000 { // Basic validation
-					result = append(result, pid)
-				}
-			}
-
-			if tt.expected == 0 && len(result) > len(tt.input) {
-				t.Errorf("filterLivePIDs() returned more PIDs than input")
-			}
-		})
-	}
-}
-
-func TestKillPIDsArgs(t *testing.T) {
-	tests := []struct {
-		name     string
-		pids     []int
-		signal   string
-		wantArgs []string
-	}{
-		{
-			name:     "single PID with TERM signal",
-			pids:     []int{12345},
-			signal:   "TERM",
-			wantArgs: []string{"-TERM", "12345"},
-		},
-		{
-			name:     "multiple PIDs with KILL signal",
-			pids:     []int{100, 200, 300},
-			signal:   "KILL",
-			wantArgs: []string{"-KILL", "100", "200", "300"},
-		},
-		{
-			name:     "empty PID list",
-			pids:     []int{},
-			signal:   "TERM",
-			wantArgs: nil,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if len(tt.pids) == 0 {
-				// Verify that killPIDs would return early
-				if tt.wantArgs != nil {
-					t.Errorf("Expected nil args for empty PID list")
-				}
-				return
-			}
-
-			// Build the args as killPIDs would
-			args := []string{"-" + tt.signal}
-			for _, pid := range tt.pids {
-				args = append(args, strconv.Itoa(pid))
-			}
-
-			if !reflect.DeepEqual(args, tt.wantArgs) {
-				t.Errorf("killPIDs args = %v, want %v", args, tt.wantArgs)
-			}
-		})
-	}
-}
-
 func TestSessionConstants(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -859,3 +633,42 @@ func TestSendDisplayMessageEnvVarPriority(t *testing.T) {
 		}
 	}
 }
+
+func TestInsideServer(t *testing.T) {
+	tests := []struct {
+		tmuxEnv string
+		socket  string
+		want    bool
+	}{
+		{"", "", false},
+		{"/tmp/tmux-1000/default,123,0", "", true},
+		{"/tmp/tmux-1000/default,123,0", "coders", false},
+		{"/tmp/tmux-1000/coders,123,0", "coders", true},
+		{"", "coders", false},
+	}
+	for _, tt := range tests {
+		if got := insideServer(tt.tmuxEnv, tt.socket); got != tt.want {
+			t.Errorf("insideServer(%q, %q) = %v, want %v", tt.tmuxEnv, tt.socket, got, tt.want)
+		}
+	}
+}
+
+func TestMetaArgs(t *testing.T) {
+	args := metaArgs(types.SessionMeta{
+		Tool:   "claude",
+		Task:   "line one\nline two;",
+		Parent: "coder-orchestrator",
+	})
+	want := []string{
+		";", "set-option", OptionTool, "claude",
+		";", "set-option", OptionTask, `line one line two\;`,
+		";", "set-option", OptionParent, "coder-orchestrator",
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("metaArgs = %q, want %q", args, want)
+	}
+
+	if args := metaArgs(types.SessionMeta{}); len(args) != 0 {
+		t.Errorf("metaArgs(empty) = %q, want none", args)
+	}
+}
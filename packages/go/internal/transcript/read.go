@@ -0,0 +1,85 @@
+package transcript
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// Tail returns the last n lines across files (in order). n <= 0 returns
+// everything.
+func Tail(files []string, n int) ([]byte, error) {
+	var chunks [][]byte
+	lines := 0
+	for i := len(files) - 1; i >= 0; i-- {
+		data, err := os.ReadFile(files[i])
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		chunks = append([][]byte{data}, chunks...)
+		lines += bytes.Count(data, []byte("\n"))
+		if n > 0 && lines > n {
+			break
+		}
+	}
+
+	all := bytes.Join(chunks, nil)
+	if n <= 0 {
+		return all, nil
+	}
+
+	// Find the start of the nth line from the end, ignoring a trailing newline
+	end := len(all)
+	if end > 0 && all[end-1] == '\n' {
+		end--
+	}
+	start := end
+	for count := 0; start > 0; start-- {
+		if all[start-1] == '\n' {
+			count++
+			if count == n {
+				break
+			}
+		}
+	}
+	return all[start:], nil
+}
+
+// Follow writes data appended to path to w, starting at offset, until ctx is
+// done. If the file shrinks or is replaced (rotation), it starts over from
+// the beginning of the new file.
+func Follow(ctx context.Context, path string, offset int64, w io.Writer, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		info, err := os.Stat(path)
+		if err == nil {
+			if info.Size() < offset {
+				offset = 0
+			}
+			if info.Size() > offset {
+				f, err := os.Open(path)
+				if err != nil {
+					return err
+				}
+				if _, err := f.Seek(offset, io.SeekStart); err == nil {
+					n, _ := io.Copy(w, f)
+					offset += n
+				}
+				f.Close()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
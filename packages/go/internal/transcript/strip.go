@@ -0,0 +1,75 @@
+package transcript
+
+// Stripper removes ANSI escape sequences and terminal control characters
+// from a byte stream. It keeps state between calls, so sequences split
+// across reads are still removed.
+type Stripper struct {
+	state stripState
+}
+
+type stripState int
+
+const (
+	stateText stripState = iota
+	stateEscape
+	stateEscapeIntermediate
+	stateCSI
+	stateString // OSC, DCS, APC, PM and SOS, terminated by BEL or ST
+	stateStringEscape
+)
+
+// Strip returns p with escape sequences, carriage returns and other control
+// characters (except newline and tab) removed.
+func (s *Stripper) Strip(p []byte) []byte {
+	out := make([]byte, 0, len(p))
+	for _, b := range p {
+		switch s.state {
+		case stateText:
+			switch {
+			case b == 0x1b:
+				s.state = stateEscape
+			case b == '\n' || b == '\t':
+				out = append(out, b)
+			case b < 0x20 || b == 0x7f:
+				// Drop carriage returns, bells, backspaces, etc.
+			default:
+				out = append(out, b)
+			}
+
+		case stateEscape:
+			switch {
+			case b == '[':
+				s.state = stateCSI
+			case b == ']' || b == 'P' || b == '_' || b == '^' || b == 'X':
+				s.state = stateString
+			case b >= 0x20 && b <= 0x2f:
+				s.state = stateEscapeIntermediate
+			default:
+				s.state = stateText
+			}
+
+		case stateEscapeIntermediate:
+			if b < 0x20 || b > 0x2f {
+				s.state = stateText
+			}
+
+		case stateCSI:
+			if b >= 0x40 && b <= 0x7e {
+				s.state = stateText
+			}
+
+		case stateString:
+			switch b {
+			case 0x07:
+				s.state = stateText
+			case 0x1b:
+				s.state = stateStringEscape
+			}
+
+		case stateStringEscape:
+			// ESC \ is the string terminator; anything else also ends the string
+			s.state = stateText
+		}
+	}
+	return out
+}
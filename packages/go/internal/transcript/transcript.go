@@ -0,0 +1,140 @@
+// Package transcript records the full output of coder sessions to rotated
+// files (fed by tmux pipe-pane) and reads them back for 'coders logs'.
+package transcript
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/Jayphen/coders/internal/config"
+)
+
+// Transcript file names inside a session's directory. Rotated backups are
+// named by lumberjack, e.g. transcript-2026-01-02T15-04-05.000.log.
+const (
+	logName = "transcript.log" // ANSI-stripped text
+	rawName = "transcript.raw" // Output exactly as the terminal received it
+)
+
+// ErrNotFound is returned when a session has no transcript.
+var ErrNotFound = errors.New("no transcript found")
+
+// Dir returns the directory holding live transcripts.
+func Dir() string {
+	return filepath.Join(config.StateDir(), "transcripts")
+}
+
+// ArchiveDir returns the directory holding transcripts of killed sessions.
+func ArchiveDir() string {
+	return filepath.Join(Dir(), "archive")
+}
+
+// SessionDir returns the live transcript directory for a session.
+func SessionDir(sessionID string) string {
+	return filepath.Join(Dir(), sessionID)
+}
+
+// Record copies pane output from r into the session's transcript files
+// until r is closed. Each file is rotated at maxSizeMB, keeping maxBackups.
+func Record(sessionID string, r io.Reader, maxSizeMB, maxBackups int) error {
+	dir := SessionDir(sessionID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create transcript directory: %w", err)
+	}
+
+	text := &lumberjack.Logger{Filename: filepath.Join(dir, logName), MaxSize: maxSizeMB, MaxBackups: maxBackups}
+	raw := &lumberjack.Logger{Filename: filepath.Join(dir, rawName), MaxSize: maxSizeMB, MaxBackups: maxBackups}
+	defer text.Close()
+	defer raw.Close()
+
+	var stripper Stripper
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, werr := raw.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			if stripped := stripper.Strip(buf[:n]); len(stripped) > 0 {
+				if _, werr := text.Write(stripped); werr != nil {
+					return werr
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Archive moves a session's live transcript to the archive, returning the
+// archive directory. Returns "" without error if there is no transcript.
+func Archive(sessionID string) (string, error) {
+	src := SessionDir(sessionID)
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return "", nil
+	}
+
+	dst := filepath.Join(ArchiveDir(), sessionID, time.Now().Format("20060102-150405"))
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return "", err
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return "", fmt.Errorf("failed to archive transcript: %w", err)
+	}
+	return dst, nil
+}
+
+// Locate returns the directory of a session's transcript: the live one if it
+// exists, otherwise the most recent archive.
+func Locate(sessionID string) (dir string, archived bool, err error) {
+	live := SessionDir(sessionID)
+	if _, err := os.Stat(filepath.Join(live, logName)); err == nil {
+		return live, false, nil
+	}
+
+	archives, _ := filepath.Glob(filepath.Join(ArchiveDir(), sessionID, "*"))
+	if len(archives) == 0 {
+		return "", false, fmt.Errorf("%w for %s", ErrNotFound, sessionID)
+	}
+	sort.Strings(archives)
+	return archives[len(archives)-1], true, nil
+}
+
+// Files returns a transcript's files in chronological order: rotated
+// backups oldest first, then the current file.
+func Files(dir string, raw bool) []string {
+	name := logName
+	if raw {
+		name = rawName
+	}
+	ext := filepath.Ext(name)
+	prefix := name[:len(name)-len(ext)]
+
+	backups, _ := filepath.Glob(filepath.Join(dir, prefix+"-*"+ext))
+	sort.Strings(backups)
+
+	files := backups
+	if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files
+}
+
+// CurrentFile returns the file being written for a transcript.
+func CurrentFile(dir string, raw bool) string {
+	if raw {
+		return filepath.Join(dir, rawName)
+	}
+	return filepath.Join(dir, logName)
+}
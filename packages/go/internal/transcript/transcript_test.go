@@ -0,0 +1,148 @@
+package transcript
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStripper(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "hello\nworld\n", "hello\nworld\n"},
+		{"sgr", "\x1b[1;32mok\x1b[0m done", "ok done"},
+		{"cursor", "\x1b[2J\x1b[H\x1b[?25lhi", "hi"},
+		{"osc title bel", "\x1b]0;claude\x07text", "text"},
+		{"osc st", "\x1b]8;;https://x\x1b\\link\x1b]8;;\x1b\\", "link"},
+		{"charset", "\x1b(Bab", "ab"},
+		{"carriage return", "progress 10%\rprogress 20%\r\n", "progress 10%progress 20%\n"},
+		{"controls", "a\x07b\x08c\td", "abc\td"},
+		{"utf8", "✓ passed → next", "✓ passed → next"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s Stripper
+			if got := string(s.Strip([]byte(tt.in))); got != tt.want {
+				t.Errorf("Strip(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripperSplitSequence(t *testing.T) {
+	var s Stripper
+	var out []byte
+	for _, chunk := range []string{"a\x1b", "[3", "1mred\x1b]0;ti", "tle\x1b", "\\b"} {
+		out = append(out, s.Strip([]byte(chunk))...)
+	}
+	if string(out) != "aredb" {
+		t.Errorf("split sequences: got %q, want %q", out, "aredb")
+	}
+}
+
+func TestRecordAndArchive(t *testing.T) {
+	t.Setenv("CODERS_STATE_DIR", t.TempDir())
+
+	input := "\x1b[32mline 1\x1b[0m\r\nline 2\r\nline 3\r\n"
+	if err := Record("coder-test", strings.NewReader(input), 1, 2); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+
+	dir, archived, err := Locate("coder-test")
+	if err != nil || archived {
+		t.Fatalf("Locate() = %s, %t, %v", dir, archived, err)
+	}
+
+	text, err := Tail(Files(dir, false), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "line 1\nline 2\nline 3\n" {
+		t.Errorf("stripped transcript = %q", text)
+	}
+	raw, _ := Tail(Files(dir, true), 0)
+	if string(raw) != input {
+		t.Errorf("raw transcript = %q, want %q", raw, input)
+	}
+
+	last, _ := Tail(Files(dir, false), 2)
+	if string(last) != "line 2\nline 3\n" {
+		t.Errorf("Tail(2) = %q", last)
+	}
+
+	archiveDir, err := Archive("coder-test")
+	if err != nil || archiveDir == "" {
+		t.Fatalf("Archive() = %q, %v", archiveDir, err)
+	}
+	if _, err := os.Stat(SessionDir("coder-test")); !os.IsNotExist(err) {
+		t.Error("live transcript should be gone after archiving")
+	}
+	dir, archived, err = Locate("coder-test")
+	if err != nil || !archived || dir != archiveDir {
+		t.Errorf("Locate() after archive = %s, %t, %v", dir, archived, err)
+	}
+
+	if _, _, err := Locate("coder-missing"); err == nil {
+		t.Error("expected error for missing transcript")
+	}
+}
+
+func TestTailAcrossRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("transcript-2026-01-01T10-00-00.000.log", "a\nb\n")
+	write("transcript-2026-01-01T11-00-00.000.log", "c\n")
+	write("transcript.log", "d\ne\n")
+
+	files := Files(dir, false)
+	if len(files) != 3 || filepath.Base(files[2]) != "transcript.log" {
+		t.Fatalf("Files() = %v", files)
+	}
+
+	got, err := Tail(files, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "b\nc\nd\ne\n" {
+		t.Errorf("Tail(4) = %q", got)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var out bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		Follow(ctx, path, 4, &out, 10*time.Millisecond)
+		close(done)
+	}()
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString("new\n")
+	f.Close()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	<-done
+
+	if out.String() != "new\n" {
+		t.Errorf("Follow() wrote %q, want %q", out.String(), "new\n")
+	}
+}
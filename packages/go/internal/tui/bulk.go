@@ -0,0 +1,277 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/transcript"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+// Sessions are marked with space. While any are marked, the session actions
+// (kill, resume, restart, send, interrupt) apply to all of them rather than
+// the selected one, after a confirmation showing how many are affected.
+// Marks are kept by name, so they survive refreshes and filtering.
+
+// bulkAction is an action that can apply to several sessions.
+type bulkAction int
+
+const (
+	actionKill bulkAction = iota + 1
+	actionResume
+	actionRestart
+	actionSend
+	actionInterrupt
+)
+
+// actionWords are the verb, progressive and past forms of each action, for
+// confirmation and status messages.
+var actionWords = map[bulkAction][3]string{
+	actionKill:      {"Kill", "Killing", "Killed"},
+	actionResume:    {"Resume", "Resuming", "Resumed"},
+	actionRestart:   {"Restart", "Restarting", "Restarted"},
+	actionSend:      {"Send to", "Sending to", "Sent to"},
+	actionInterrupt: {"Interrupt", "Interrupting", "Interrupted"},
+}
+
+// pendingAction is an action waiting for confirmation.
+type pendingAction struct {
+	action  bulkAction
+	targets []string
+	message string // text for actionSend, key for actionInterrupt
+}
+
+// prompt is the confirmation question.
+func (p pendingAction) prompt() string {
+	if p.action == actionSend {
+		msg := p.message
+		if len(msg) > 40 {
+			msg = msg[:37] + "..."
+		}
+		return fmt.Sprintf("Send %q to %d session(s)? (y/n)", msg, len(p.targets))
+	}
+	return fmt.Sprintf("%s %d session(s)? (y/n)", actionWords[p.action][0], len(p.targets))
+}
+
+// bulkDoneMsg reports the result of an action.
+type bulkDoneMsg struct {
+	action bulkAction
+	done   int
+	errs   []string
+	failed []string // names of the sessions in errs
+}
+
+// toggleMark marks or unmarks the selected session and moves down.
+func (m *Model) toggleMark() {
+	s := m.selectedSession()
+	if s == nil {
+		return
+	}
+	if m.marked[s.Name] {
+		delete(m.marked, s.Name)
+	} else {
+		m.marked[s.Name] = true
+	}
+	if m.selectedIndex < len(m.sessions)-1 {
+		m.selectedIndex++
+	}
+}
+
+// markAll marks every visible session, or clears the marks if they are
+// all marked already.
+func (m *Model) markAll() {
+	all := len(m.sessions) > 0
+	for _, s := range m.sessions {
+		if !m.marked[s.Name] {
+			all = false
+			break
+		}
+	}
+	for _, s := range m.sessions {
+		if all {
+			delete(m.marked, s.Name)
+		} else {
+			m.marked[s.Name] = true
+		}
+	}
+}
+
+// pruneMarks drops marks on sessions that no longer exist.
+func (m *Model) pruneMarks() {
+	present := make(map[string]bool, len(m.allSessions))
+	for _, s := range m.allSessions {
+		present[s.Name] = true
+	}
+	for name := range m.marked {
+		if !present[name] {
+			delete(m.marked, name)
+		}
+	}
+}
+
+// actionTargets returns the marked sessions in list order, or the selected
+// session if none are marked.
+func (m Model) actionTargets() []string {
+	if len(m.marked) == 0 {
+		if s := m.selectedSession(); s != nil {
+			return []string{s.Name}
+		}
+		return nil
+	}
+	var targets []string
+	seen := make(map[string]bool)
+	for _, list := range [][]string{sessionNames(m.sessions), sessionNames(m.allSessions)} {
+		for _, name := range list {
+			if m.marked[name] && !seen[name] {
+				seen[name] = true
+				targets = append(targets, name)
+			}
+		}
+	}
+	return targets
+}
+
+// completedTargets returns the targets that have published a promise.
+func (m Model) completedTargets() []string {
+	completed := make(map[string]bool)
+	for _, list := range [][]types.Session{m.sessions, m.allSessions} {
+		for _, s := range list {
+			if s.HasPromise {
+				completed[s.Name] = true
+			}
+		}
+	}
+	var targets []string
+	for _, name := range m.actionTargets() {
+		if completed[name] {
+			targets = append(targets, name)
+		}
+	}
+	return targets
+}
+
+// requestAction runs an action on targets, asking for confirmation first
+// when sessions are marked. Restarts are always confirmed since they lose
+// whatever the tool was doing.
+func (m *Model) requestAction(action bulkAction, targets []string, message string) tea.Cmd {
+	if len(targets) == 0 {
+		m.setStatus("No session selected")
+		return nil
+	}
+	p := pendingAction{action: action, targets: targets, message: message}
+	if len(m.marked) > 0 || action == actionRestart {
+		m.pending = &p
+		return nil
+	}
+	return m.runAction(p)
+}
+
+// runAction performs an action in the background.
+func (m *Model) runAction(p pendingAction) tea.Cmd {
+	m.setStatus(fmt.Sprintf("%s %d session(s)...", actionWords[p.action][1], len(p.targets)))
+	redisClient := m.redisClient
+	return func() tea.Msg {
+		result := bulkDoneMsg{action: p.action}
+		for _, name := range p.targets {
+			var err error
+			switch p.action {
+			case actionKill:
+				if err = mux.KillSession(name); err == nil && redisClient != nil {
+					// Removing the session state stops the crash watcher restarting it
+					redisClient.DeletePromise(context.Background(), name)
+					redisClient.DeleteSessionState(context.Background(), name)
+				}
+				if err == nil {
+					transcript.Archive(name)
+				}
+			case actionResume:
+				if redisClient == nil {
+					err = fmt.Errorf("redis unavailable")
+				} else {
+					err = redisClient.DeletePromise(context.Background(), name)
+				}
+			case actionRestart:
+				err = restartSession(name)
+			case actionSend:
+				err = mux.SendInput(name, p.message)
+			case actionInterrupt:
+				err = mux.SendRawKeys(name, p.message)
+			}
+			if err != nil {
+				result.errs = append(result.errs, fmt.Sprintf("%s: %v", strings.TrimPrefix(name, mux.SessionPrefix), err))
+				result.failed = append(result.failed, name)
+			} else {
+				result.done++
+			}
+		}
+		return result
+	}
+}
+
+// restartSession runs 'coders restart', which relaunches a session from its
+// stored state. Each session gets its own run so one failure doesn't hide
+// which of the others came back.
+func restartSession(name string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, "restart", name)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(output.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// handleBulkDone reports an action's result and clears the marks it used.
+// Sessions the action failed on stay marked so it can be retried on them.
+func (m *Model) handleBulkDone(msg bulkDoneMsg) tea.Cmd {
+	status := fmt.Sprintf("%s %d session(s)", actionWords[msg.action][2], msg.done)
+	if len(msg.errs) > 0 {
+		status += fmt.Sprintf(", %d failed: %s", len(msg.errs), strings.Join(msg.errs, "; "))
+	}
+	marked := make(map[string]bool)
+	for _, name := range msg.failed {
+		if m.marked[name] {
+			marked[name] = true
+		}
+	}
+	m.marked = marked
+	m.setStatus(status)
+	return m.fetchSessions
+}
+
+// attachSplit shows the marked sessions side by side.
+func (m *Model) attachSplit() {
+	targets := m.actionTargets()
+	if len(targets) == 0 {
+		m.setStatus("No session selected")
+		return
+	}
+	if err := mux.AttachSplit(targets); err != nil {
+		m.setStatus(fmt.Sprintf("Split failed: %v", err))
+		return
+	}
+	m.setStatus(fmt.Sprintf("Opened %d session(s) in split panes", len(targets)))
+}
+
+func sessionNames(sessions []types.Session) []string {
+	names := make([]string, len(sessions))
+	for i, s := range sessions {
+		names[i] = s.Name
+	}
+	return names
+}
@@ -0,0 +1,143 @@
+package tui
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/tmux"
+)
+
+// Under tmux the TUI follows a control-mode client instead of polling: session
+// changes refresh the list immediately and output in the previewed session
+// refreshes the preview. The tick then only refreshes Redis data, with a full
+// session list every controlResyncTicks as a fallback.
+
+// previewDebounce batches bursts of pane output into one preview refresh.
+const previewDebounce = 150 * time.Millisecond
+
+// controlResyncTicks is how many ticks pass between full session list
+// refreshes while the control client is connected.
+const controlResyncTicks = 6
+
+// Control-mode messages carry the client they came from, so messages from a
+// client that has since been replaced or closed are ignored.
+type (
+	controlStartMsg   struct{}
+	controlStartedMsg struct {
+		client *tmux.ControlClient
+		err    error
+	}
+	controlEventMsg struct {
+		client *tmux.ControlClient
+		event  tmux.ControlEvent
+	}
+	controlClosedMsg struct {
+		client *tmux.ControlClient
+	}
+	previewRefreshMsg struct{}
+)
+
+// startControl attaches a control-mode client, if sessions run in tmux. It
+// must be called on the model the program holds, not a copy, or the
+// in-flight start is forgotten and a second client is started.
+func (m *Model) startControl() tea.Cmd {
+	if m.control != nil || m.controlStarting || mux.Default().Name() != "tmux" {
+		return nil
+	}
+	m.controlStarting = true
+	return func() tea.Msg {
+		c, err := tmux.StartControl("")
+		return controlStartedMsg{client: c, err: err}
+	}
+}
+
+// waitControlEvent delivers the next control-mode event.
+func waitControlEvent(c *tmux.ControlClient) tea.Cmd {
+	return func() tea.Msg {
+		ev, ok := <-c.Events()
+		if !ok {
+			return controlClosedMsg{client: c}
+		}
+		return controlEventMsg{client: c, event: ev}
+	}
+}
+
+// closeControl detaches the control client, if any.
+func (m *Model) closeControl() {
+	if m.control == nil {
+		return
+	}
+	c := m.control
+	m.control = nil
+	m.controlFollowing = ""
+	go c.Close()
+}
+
+// followPreview points the control client at the previewed session so its
+// output is reported.
+func (m *Model) followPreview() tea.Cmd {
+	c, session := m.control, m.previewSession
+	if c == nil || session == "" || session == m.controlFollowing {
+		return nil
+	}
+	m.controlFollowing = session
+	return func() tea.Msg {
+		_ = c.Follow(session)
+		return nil
+	}
+}
+
+// handleControlMsg handles the control-mode messages.
+func (m *Model) handleControlMsg(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case controlStartMsg:
+		return m.startControl()
+
+	case controlStartedMsg:
+		m.controlStarting = false
+		if msg.err != nil {
+			// Retried on the next tick, e.g. once a session exists
+			return nil
+		}
+		m.closeControl()
+		m.control = msg.client
+		return tea.Batch(waitControlEvent(m.control), m.followPreview())
+
+	case controlClosedMsg:
+		if msg.client != m.control {
+			return nil
+		}
+		m.control = nil
+		m.controlFollowing = ""
+		return nil
+
+	case controlEventMsg:
+		if msg.client != m.control {
+			return nil
+		}
+		next := waitControlEvent(m.control)
+		switch ev := msg.event; ev.Type {
+		case tmux.EventPaneOutput:
+			if ev.Session != m.previewSession || m.previewRefreshPending {
+				return next
+			}
+			m.previewRefreshPending = true
+			return tea.Batch(next, tea.Tick(previewDebounce, func(time.Time) tea.Msg {
+				return previewRefreshMsg{}
+			}))
+		default:
+			if !strings.HasPrefix(ev.Session, mux.SessionPrefix) {
+				return next
+			}
+			return tea.Batch(next, m.fetchSessions)
+		}
+
+	case previewRefreshMsg:
+		m.previewRefreshPending = false
+		return m.startPreviewFetch()
+	}
+	return nil
+}
@@ -0,0 +1,651 @@
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+// The diff panel replaces the preview with what the selected session
+// changed: the diff of its directory against the commit it was spawned at
+// (or against HEAD when that is not known), untracked files, and the
+// commits made since. Change stats for every session are refreshed in the
+// background for the session rows.
+
+const (
+	// maxDiffBytes caps the diff loaded into the panel.
+	maxDiffBytes = 512 * 1024
+	// maxDiffCommits caps the commit log shown.
+	maxDiffCommits = 20
+	// diffStatsInterval is how often the session row stats are refreshed.
+	diffStatsInterval = 15 * time.Second
+	// diffFilesShown is how many files are listed around the selected one.
+	diffFilesShown = 6
+	// diffScrollStep is how many lines ctrl+d and ctrl+u scroll.
+	diffScrollStep = 10
+)
+
+// diffFile is one file of a diff.
+type diffFile struct {
+	path      string
+	added     int
+	removed   int
+	lines     []string // the file's diff, from its "diff --git" header
+	untracked bool
+	binary    bool
+}
+
+// diffStat is the size of a session's changes.
+type diffStat struct {
+	files   int
+	added   int
+	removed int
+}
+
+// empty reports whether there are no changes.
+func (s diffStat) empty() bool {
+	return s.files == 0 && s.added == 0 && s.removed == 0
+}
+
+// sessionDiff is a session's changes.
+type sessionDiff struct {
+	session   string
+	dir       string
+	base      string // commit diffed against; "" means HEAD
+	files     []diffFile
+	commits   []string // one line per commit, newest first
+	truncated bool
+	err       error
+}
+
+type (
+	diffMsg      *sessionDiff
+	diffStatsMsg map[string]diffStat
+)
+
+// sessionDir is the directory a session works in.
+func sessionDir(s *types.Session) string {
+	if s.Worktree != "" {
+		return s.Worktree
+	}
+	return s.Cwd
+}
+
+// gitOutput runs git in dir and returns its output.
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// diffBase returns the revision a session's changes are diffed against.
+func diffBase(base string) string {
+	if base == "" {
+		return "HEAD"
+	}
+	return base
+}
+
+// loadDiff reads the changes in dir since base.
+func loadDiff(session, dir, base string) *sessionDiff {
+	d := &sessionDiff{session: session, dir: dir, base: base}
+	if dir == "" {
+		d.err = fmt.Errorf("session has no working directory")
+		return d
+	}
+
+	raw, err := gitOutput(dir, "diff", "--no-color", "--no-ext-diff", diffBase(base))
+	if err != nil {
+		d.err = err
+		return d
+	}
+	if len(raw) > maxDiffBytes {
+		raw = raw[:maxDiffBytes]
+		if i := strings.LastIndexByte(raw, '\n'); i > 0 {
+			raw = raw[:i]
+		}
+		d.truncated = true
+	}
+	d.files = parseDiff(raw)
+
+	if out, err := gitOutput(dir, "ls-files", "--others", "--exclude-standard"); err == nil {
+		for _, path := range strings.Split(strings.TrimSpace(out), "\n") {
+			if path != "" {
+				d.files = append(d.files, diffFile{path: path, untracked: true})
+			}
+		}
+	}
+
+	logArgs := []string{"log", "--no-color", "--oneline", "--no-decorate", "-n", strconv.Itoa(maxDiffCommits)}
+	if base != "" {
+		logArgs = append(logArgs, base+"..HEAD")
+	}
+	if out, err := gitOutput(dir, logArgs...); err == nil {
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			if line != "" {
+				d.commits = append(d.commits, line)
+			}
+		}
+	}
+	return d
+}
+
+// parseDiff splits a unified git diff into files, counting the added and
+// removed lines of each.
+func parseDiff(raw string) []diffFile {
+	var files []diffFile
+	var cur *diffFile
+	inHunk := false
+	for _, line := range strings.Split(strings.TrimRight(raw, "\n"), "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			files = append(files, diffFile{path: diffPath(line)})
+			cur = &files[len(files)-1]
+			inHunk = false
+		}
+		if cur == nil {
+			continue
+		}
+		cur.lines = append(cur.lines, line)
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case !inHunk && strings.HasPrefix(line, "+++ "):
+			if path := strings.TrimPrefix(line, "+++ "); path != "/dev/null" {
+				cur.path = strings.TrimPrefix(path, "b/")
+			}
+		case !inHunk && strings.HasPrefix(line, "Binary files "):
+			cur.binary = true
+		case inHunk && strings.HasPrefix(line, "+"):
+			cur.added++
+		case inHunk && strings.HasPrefix(line, "-"):
+			cur.removed++
+		}
+	}
+	return files
+}
+
+// diffPath returns the new path of a "diff --git a/x b/y" header line.
+func diffPath(header string) string {
+	if i := strings.LastIndex(header, " b/"); i >= 0 {
+		return header[i+3:]
+	}
+	return strings.TrimPrefix(header, "diff --git ")
+}
+
+// parseNumstat sums `git diff --numstat` output.
+func parseNumstat(out string) diffStat {
+	var stat diffStat
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		stat.files++
+		// Binary files report "-" for both counts
+		if n, err := strconv.Atoi(fields[0]); err == nil {
+			stat.added += n
+		}
+		if n, err := strconv.Atoi(fields[1]); err == nil {
+			stat.removed += n
+		}
+	}
+	return stat
+}
+
+// startDiffFetch loads the selected session's diff if the diff panel is
+// showing.
+func (m *Model) startDiffFetch() tea.Cmd {
+	s := m.selectedSession()
+	if !m.showDiff || s == nil {
+		return nil
+	}
+	if m.diff == nil || m.diff.session != s.Name {
+		m.diffFile = 0
+		m.diffScroll = 0
+	}
+	m.diffLoading = true
+	name, dir, base := s.Name, sessionDir(s), s.SpawnCommit
+	return func() tea.Msg {
+		return diffMsg(loadDiff(name, dir, base))
+	}
+}
+
+// handleDiff stores a loaded diff if it is for the selected session.
+func (m *Model) handleDiff(d *sessionDiff) {
+	s := m.selectedSession()
+	if s == nil || s.Name != d.session {
+		return
+	}
+	m.diffLoading = false
+	if m.diff == nil || m.diff.session != d.session {
+		m.diffFile = 0
+		m.diffScroll = 0
+	}
+	m.diff = d
+	if m.diffFile >= len(d.files) {
+		m.diffFile = 0
+		m.diffScroll = 0
+	}
+	if stats := m.diffStats; stats != nil && d.err == nil {
+		stats[d.session] = d.stat()
+	}
+}
+
+// stat sums the diff's files.
+func (d *sessionDiff) stat() diffStat {
+	var stat diffStat
+	for _, f := range d.files {
+		stat.files++
+		stat.added += f.added
+		stat.removed += f.removed
+	}
+	return stat
+}
+
+// toggleDiff switches the right panel between the preview and the diff.
+func (m *Model) toggleDiff() tea.Cmd {
+	m.showDiff = !m.showDiff
+	if !m.showDiff {
+		return nil
+	}
+	m.previewFocus = false
+	m.previewInput.Blur()
+	return m.startDiffFetch()
+}
+
+// moveDiffFile selects the next or previous changed file.
+func (m *Model) moveDiffFile(delta int) {
+	if m.diff == nil || len(m.diff.files) == 0 {
+		return
+	}
+	m.diffFile = (m.diffFile + delta + len(m.diff.files)) % len(m.diff.files)
+	m.diffScroll = 0
+}
+
+// scrollDiff scrolls the selected file's diff.
+func (m *Model) scrollDiff(delta int) {
+	if m.diff == nil || m.diffFile >= len(m.diff.files) {
+		return
+	}
+	m.diffScroll += delta
+	if last := len(m.diff.files[m.diffFile].lines) - 1; m.diffScroll > last {
+		m.diffScroll = last
+	}
+	if m.diffScroll < 0 {
+		m.diffScroll = 0
+	}
+}
+
+// fetchDiffStats refreshes the change stats shown in the session rows, at
+// most every diffStatsInterval. Sessions sharing a directory and base are
+// diffed once.
+func (m *Model) fetchDiffStats() tea.Cmd {
+	if m.diffStatsPending || time.Since(m.diffStatsAt) < diffStatsInterval || len(m.allSessions) == 0 {
+		return nil
+	}
+	m.diffStatsPending = true
+	m.diffStatsAt = time.Now()
+
+	type target struct{ dir, base string }
+	targets := make(map[string]target, len(m.allSessions))
+	for i := range m.allSessions {
+		s := &m.allSessions[i]
+		if dir := sessionDir(s); dir != "" && !s.IsOrchestrator {
+			targets[s.Name] = target{dir, s.SpawnCommit}
+		}
+	}
+	return func() tea.Msg {
+		cache := make(map[target]*diffStat)
+		stats := make(diffStatsMsg, len(targets))
+		for name, t := range targets {
+			stat, ok := cache[t]
+			if !ok {
+				stat = nil
+				if out, err := gitOutput(t.dir, "diff", "--numstat", diffBase(t.base)); err == nil {
+					s := parseNumstat(out)
+					stat = &s
+				}
+				cache[t] = stat
+			}
+			if stat != nil {
+				stats[name] = *stat
+			}
+		}
+		return stats
+	}
+}
+
+// Diff styles
+var (
+	DiffAdded   = lipgloss.NewStyle().Foreground(ColorGreen)
+	DiffRemoved = lipgloss.NewStyle().Foreground(ColorRed)
+	DiffHunk    = lipgloss.NewStyle().Foreground(ColorCyan)
+	DiffMeta    = lipgloss.NewStyle().Foreground(ColorGray).Bold(true)
+)
+
+// renderDiffStat renders a session's change stats for its row.
+func renderDiffStat(stat diffStat) string {
+	if stat.empty() {
+		return ""
+	}
+	return DiffAdded.Render(fmt.Sprintf("+%d", stat.added)) + DimStyle.Render("/") +
+		DiffRemoved.Render(fmt.Sprintf("-%d", stat.removed))
+}
+
+// renderSessionDiff renders the diff panel for the selected session.
+func (m Model) renderSessionDiff(width int, maxHeight int) string {
+	s := m.selectedSession()
+	title := "Diff"
+	if s != nil {
+		title = "Diff: " + strings.TrimPrefix(s.Name, mux.SessionPrefix)
+	}
+
+	var b strings.Builder
+	d := m.diff
+	if s == nil || d == nil || d.session != s.Name {
+		b.WriteString(DimStyle.Render("Loading diff..."))
+	} else if d.err != nil {
+		b.WriteString(ErrorStyle.Render("Diff unavailable: " + d.err.Error()))
+	} else {
+		b.WriteString(m.renderDiffBody(d, width, maxHeight))
+	}
+	content := strings.TrimRight(b.String(), "\n")
+
+	header := BoldStyle.Render(title)
+	if d != nil && s != nil && d.session == s.Name {
+		since := "uncommitted changes"
+		if d.base != "" {
+			since = "since spawn at " + shortCommit(d.base)
+		}
+		header += DimStyle.Render("  " + since)
+		if m.diffLoading {
+			header += DimStyle.Render(" (updating...)")
+		}
+	}
+	help := HelpKeyStyle.Render("[ ]") + DimStyle.Render(" file  ") +
+		HelpKeyStyle.Render("ctrl+d/u") + DimStyle.Render(" scroll  ") +
+		HelpKeyStyle.Render("d") + DimStyle.Render(" preview")
+
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(ColorBlue).
+		Padding(1, 2).
+		MarginTop(1)
+	if width > 0 {
+		style = style.Width(width)
+	}
+
+	if maxHeight > 0 {
+		// Margin, border, padding, header and gap, then the help line and gap
+		const overhead = 1 + 2 + 2 + 2 + 2
+		maxContentLines := maxHeight - overhead
+		if maxContentLines < 1 {
+			return ""
+		}
+		content = truncateLines(content, maxContentLines, "")
+	}
+
+	return style.Render(header + "\n\n" + content + "\n\n" + help)
+}
+
+// renderDiffBody renders the file list, commit log and the selected file's
+// diff. The diff gets whatever height the list and log leave.
+func (m Model) renderDiffBody(d *sessionDiff, width int, maxHeight int) string {
+	var b strings.Builder
+
+	if len(d.files) == 0 {
+		b.WriteString(DimStyle.Render("No changes"))
+		b.WriteString("\n")
+	} else {
+		stat := d.stat()
+		b.WriteString(DimStyle.Render(fmt.Sprintf("%d file(s) ", stat.files)) + renderDiffStat(stat))
+		if d.truncated {
+			b.WriteString(WarningStyle.Render("  (diff truncated)"))
+		}
+		b.WriteString("\n")
+
+		start := m.diffFile - diffFilesShown/2
+		if start > len(d.files)-diffFilesShown {
+			start = len(d.files) - diffFilesShown
+		}
+		if start < 0 {
+			start = 0
+		}
+		end := start + diffFilesShown
+		if end > len(d.files) {
+			end = len(d.files)
+		}
+		if start > 0 {
+			b.WriteString(DimStyle.Render(fmt.Sprintf("  ↑ %d more", start)) + "\n")
+		}
+		for i := start; i < end; i++ {
+			f := d.files[i]
+			selector := "  "
+			path := f.path
+			if i == m.diffFile {
+				selector = SelectedStyle.Render(IndicatorSelected) + " "
+				path = NameStyleSelected.Render(path)
+			}
+			line := selector + path + " "
+			switch {
+			case f.untracked:
+				line += DimStyle.Render("untracked")
+			case f.binary:
+				line += DimStyle.Render("binary")
+			default:
+				line += renderDiffStat(diffStat{files: 1, added: f.added, removed: f.removed})
+			}
+			b.WriteString(line + "\n")
+		}
+		if end < len(d.files) {
+			b.WriteString(DimStyle.Render(fmt.Sprintf("  ↓ %d more", len(d.files)-end)) + "\n")
+		}
+	}
+
+	if len(d.commits) > 0 {
+		b.WriteString("\n" + SubtitleStyle.Render(fmt.Sprintf("Commits (%d)", len(d.commits))) + "\n")
+		for i, c := range d.commits {
+			if i == 3 {
+				b.WriteString(DimStyle.Render(fmt.Sprintf("  +%d more", len(d.commits)-i)) + "\n")
+				break
+			}
+			hash, subject, _ := strings.Cut(c, " ")
+			b.WriteString("  " + WarningStyle.Render(hash) + " " + subject + "\n")
+		}
+	}
+
+	if m.diffFile < len(d.files) {
+		f := d.files[m.diffFile]
+		b.WriteString("\n")
+		if f.untracked {
+			b.WriteString(DimStyle.Render("New file, not yet added to git"))
+		} else {
+			lines := f.lines
+			if m.diffScroll < len(lines) {
+				lines = lines[m.diffScroll:]
+			}
+			lang := languageFor(f.path)
+			for _, line := range lines {
+				b.WriteString(renderDiffLine(line, lang, width))
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// shortCommit abbreviates a commit hash.
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+// renderDiffLine colors a diff line: file headers, hunk headers, and
+// added and removed lines, whose code is highlighted for lang.
+func renderDiffLine(line string, lang *language, width int) string {
+	// Leave room for the panel's border and padding
+	if width > 8 {
+		line = truncateWidth(line, width-6)
+	}
+	switch {
+	case strings.HasPrefix(line, "diff --git"), strings.HasPrefix(line, "index "),
+		strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"),
+		strings.HasPrefix(line, "new file"), strings.HasPrefix(line, "deleted file"),
+		strings.HasPrefix(line, "similarity"), strings.HasPrefix(line, "rename "):
+		return DiffMeta.Render(line)
+	case strings.HasPrefix(line, "@@"):
+		return DiffHunk.Render(line)
+	case strings.HasPrefix(line, "+"):
+		return DiffAdded.Render("+") + highlightCode(line[1:], lang)
+	case strings.HasPrefix(line, "-"):
+		return DiffRemoved.Render("-") + highlightCode(line[1:], lang)
+	default:
+		return highlightCode(line, lang)
+	}
+}
+
+// truncateWidth cuts s to at most width runes.
+func truncateWidth(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// language describes just enough of a programming language to highlight
+// a line of it: its line comment and its keywords.
+type language struct {
+	comment  string
+	keywords map[string]bool
+}
+
+func newLanguage(comment string, keywords ...string) *language {
+	l := &language{comment: comment, keywords: make(map[string]bool, len(keywords))}
+	for _, k := range keywords {
+		l.keywords[k] = true
+	}
+	return l
+}
+
+var (
+	langGo = newLanguage("//", "break", "case", "chan", "const", "continue", "default", "defer", "else",
+		"fallthrough", "for", "func", "go", "goto", "if", "import", "interface", "map", "package", "range",
+		"return", "select", "struct", "switch", "type", "var", "nil", "true", "false")
+	langJS = newLanguage("//", "async", "await", "break", "case", "catch", "class", "const", "continue",
+		"default", "else", "export", "extends", "for", "from", "function", "if", "import", "interface",
+		"let", "new", "return", "switch", "this", "throw", "try", "type", "var", "while", "null",
+		"undefined", "true", "false")
+	langPython = newLanguage("#", "and", "as", "async", "await", "break", "class", "continue", "def",
+		"elif", "else", "except", "for", "from", "if", "import", "in", "is", "lambda", "not", "or",
+		"pass", "raise", "return", "try", "while", "with", "yield", "None", "True", "False")
+	langRust = newLanguage("//", "as", "break", "const", "continue", "else", "enum", "fn", "for", "if",
+		"impl", "in", "let", "loop", "match", "mod", "mut", "pub", "return", "self", "struct", "trait",
+		"use", "where", "while", "true", "false")
+	langShell = newLanguage("#", "case", "do", "done", "elif", "else", "esac", "export", "fi", "for",
+		"function", "if", "in", "local", "return", "then", "while")
+	langConfig = newLanguage("#", "true", "false", "null")
+)
+
+// languages maps file extensions to languages.
+var languages = map[string]*language{
+	".go":   langGo,
+	".js":   langJS,
+	".jsx":  langJS,
+	".ts":   langJS,
+	".tsx":  langJS,
+	".mjs":  langJS,
+	".py":   langPython,
+	".rs":   langRust,
+	".sh":   langShell,
+	".bash": langShell,
+	".zsh":  langShell,
+	".yaml": langConfig,
+	".yml":  langConfig,
+	".toml": langConfig,
+}
+
+// languageFor returns the language of path, or nil if it is not known.
+func languageFor(path string) *language {
+	return languages[strings.ToLower(filepath.Ext(path))]
+}
+
+// Code highlighting styles
+var (
+	CodeKeyword = lipgloss.NewStyle().Foreground(ColorMagenta)
+	CodeString  = lipgloss.NewStyle().Foreground(ColorYellow)
+	CodeComment = lipgloss.NewStyle().Foreground(ColorGray).Italic(true)
+)
+
+// highlightCode highlights the keywords, string literals and line comment
+// of a line of code. Lines of unknown languages are returned unchanged.
+func highlightCode(code string, lang *language) string {
+	if lang == nil {
+		return code
+	}
+	var b strings.Builder
+	for i := 0; i < len(code); {
+		c := code[i]
+		switch {
+		case strings.HasPrefix(code[i:], lang.comment):
+			b.WriteString(CodeComment.Render(code[i:]))
+			return b.String()
+		case c == '"' || c == '\'' || c == '`':
+			end := i + 1
+			for end < len(code) && code[end] != c {
+				if code[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(code) {
+				end++
+			} else {
+				end = len(code)
+			}
+			b.WriteString(CodeString.Render(code[i:end]))
+			i = end
+		case isIdentByte(c):
+			end := i
+			for end < len(code) && isIdentByte(code[end]) {
+				end++
+			}
+			word := code[i:end]
+			if lang.keywords[word] {
+				b.WriteString(CodeKeyword.Render(word))
+			} else {
+				b.WriteString(word)
+			}
+			i = end
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
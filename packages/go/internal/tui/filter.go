@@ -0,0 +1,254 @@
+package tui
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Jayphen/coders/internal/types"
+)
+
+// The session list shows a filtered, sorted view of the fetched sessions.
+// The filter and sort survive refreshes, and the selection follows the
+// selected session by name rather than by position.
+
+// statusFilter restricts the list to sessions in one state.
+type statusFilter int
+
+const (
+	filterAll statusFilter = iota
+	filterActive
+	filterCompleted
+	filterBlocked
+	filterStuck
+	statusFilterCount
+)
+
+func (f statusFilter) String() string {
+	switch f {
+	case filterActive:
+		return "active"
+	case filterCompleted:
+		return "completed"
+	case filterBlocked:
+		return "blocked"
+	case filterStuck:
+		return "stuck"
+	default:
+		return "all"
+	}
+}
+
+// matches reports whether s is in the filtered state. Stuck includes
+// unresponsive sessions.
+func (f statusFilter) matches(s types.Session) bool {
+	switch f {
+	case filterActive:
+		return !s.HasPromise
+	case filterCompleted:
+		return s.HasPromise
+	case filterBlocked:
+		return s.Promise != nil && s.Promise.Status == types.PromiseBlocked
+	case filterStuck:
+		return !s.HasPromise && s.HealthCheck != nil &&
+			(s.HealthCheck.Status == types.HealthStuck || s.HealthCheck.Status == types.HealthUnresponsive)
+	default:
+		return true
+	}
+}
+
+// sortMode orders the session list. Orchestrators always come first and
+// active sessions before completed ones, matching the list's sections.
+type sortMode int
+
+const (
+	sortTree   sortMode = iota // children below their parents, newest first
+	sortAge                    // newest first
+	sortTool                   // by tool, then newest first
+	sortHealth                 // sessions needing attention first
+	sortModeCount
+)
+
+func (s sortMode) String() string {
+	switch s {
+	case sortAge:
+		return "age"
+	case sortTool:
+		return "tool"
+	case sortHealth:
+		return "health"
+	default:
+		return "tree"
+	}
+}
+
+// fuzzyMatch reports whether every whitespace-separated term of query
+// matches one of fields, where a term matches if its characters appear in
+// order in the field, ignoring case.
+func fuzzyMatch(query string, fields ...string) bool {
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		matched := false
+		for _, field := range fields {
+			if subsequence(term, strings.ToLower(field)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// subsequence reports whether the runes of term appear in order in s.
+func subsequence(term, s string) bool {
+	for _, r := range term {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+utf8.RuneLen(r):]
+	}
+	return true
+}
+
+// filterSessions returns the sessions matching the query and status filter.
+func filterSessions(sessions []types.Session, query string, status statusFilter) []types.Session {
+	result := make([]types.Session, 0, len(sessions))
+	for _, s := range sessions {
+		if !status.matches(s) {
+			continue
+		}
+		if query != "" && !fuzzyMatch(query, s.Name, s.Task, s.Tool, s.Cwd) {
+			continue
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+// sortSessions returns sessions ordered by mode.
+func sortSessions(sessions []types.Session, mode sortMode) []types.Session {
+	sorted := append([]types.Session(nil), sessions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := &sorted[i], &sorted[j]
+		if pa, pb := sectionPriority(a), sectionPriority(b); pa != pb {
+			return pa < pb
+		}
+		switch mode {
+		case sortTool:
+			if a.Tool != b.Tool {
+				return a.Tool < b.Tool
+			}
+		case sortHealth:
+			if ha, hb := healthRank(a), healthRank(b); ha != hb {
+				return ha < hb
+			}
+		}
+		return createdUnix(a) > createdUnix(b)
+	})
+	if mode == sortTree {
+		return orderSessionTree(sorted)
+	}
+	return sorted
+}
+
+// sectionPriority puts the orchestrator first, then active, then completed
+// sessions.
+func sectionPriority(s *types.Session) int {
+	switch {
+	case s.IsOrchestrator:
+		return 0
+	case s.HasPromise:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// healthRank orders sessions by how urgently they need attention.
+func healthRank(s *types.Session) int {
+	if s.Promise != nil {
+		switch s.Promise.Status {
+		case types.PromiseBlocked:
+			return 0
+		case types.PromiseNeedsReview:
+			return 1
+		default:
+			return 2
+		}
+	}
+	if s.HealthCheck != nil {
+		switch s.HealthCheck.Status {
+		case types.HealthWaitingInput:
+			return 0
+		case types.HealthStuck, types.HealthUnresponsive:
+			return 1
+		case types.HealthOverLimit:
+			return 2
+		}
+	}
+	switch s.HeartbeatStatus {
+	case types.HeartbeatHealthy:
+		return 5
+	case types.HeartbeatStale:
+		return 4
+	default:
+		return 3
+	}
+}
+
+func createdUnix(s *types.Session) int64 {
+	if s.CreatedAt == nil {
+		return 0
+	}
+	return s.CreatedAt.Unix()
+}
+
+// applyView rebuilds the visible session list from the fetched sessions,
+// keeping the same session selected if it is still visible.
+func (m *Model) applyView() {
+	selected := ""
+	if s := m.selectedSession(); s != nil {
+		selected = s.Name
+	}
+
+	m.sessions = sortSessions(filterSessions(m.allSessions, m.filterQuery(), m.statusFilter), m.sortMode)
+	m.pruneMarks()
+
+	for i, s := range m.sessions {
+		if s.Name == selected {
+			m.selectedIndex = i
+			return
+		}
+	}
+	if m.selectedIndex >= len(m.sessions) {
+		m.selectedIndex = len(m.sessions) - 1
+	}
+	if m.selectedIndex < 0 {
+		m.selectedIndex = 0
+	}
+}
+
+// filterQuery returns the text filter.
+func (m Model) filterQuery() string {
+	return strings.TrimSpace(m.filterInput.Value())
+}
+
+// filtered reports whether any filter hides sessions.
+func (m Model) filtered() bool {
+	return m.filterQuery() != "" || m.statusFilter != filterAll
+}
+
+// updateView applies the view and refreshes the preview if the selected
+// session changed.
+func (m *Model) updateView() tea.Cmd {
+	m.applyView()
+	if s := m.selectedSession(); s != nil && s.Name == m.previewSession {
+		return nil
+	}
+	return m.startPreviewFetch()
+}
@@ -0,0 +1,506 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+// The loops tab lists loop runs from Redis with their progress, and sends
+// control commands to the selected loop. A loop picks its commands up from
+// a queue in Redis, so they take effect within a few seconds rather than
+// immediately.
+
+// viewTab is the top-level view being shown.
+type viewTab int
+
+const (
+	tabSessions viewTab = iota
+	tabLoops
+	tabTasks
+)
+
+// loopQueuePreview is how many queued tasks are listed per source.
+const loopQueuePreview = 3
+
+type (
+	loopsMsg       []types.LoopState
+	loopCommandMsg struct {
+		loopID  string
+		command types.LoopCommand
+		err     error
+	}
+)
+
+// pendingLoopCommand is a loop command waiting for confirmation.
+type pendingLoopCommand struct {
+	loopID  string
+	command types.LoopCommand
+}
+
+// prompt is the confirmation question.
+func (p pendingLoopCommand) prompt() string {
+	if p.command == types.LoopCommandSkip {
+		return fmt.Sprintf("Skip the current task of %s and kill its session? (y/n)", p.loopID)
+	}
+	return fmt.Sprintf("Cancel %s? Its current session keeps running. (y/n)", p.loopID)
+}
+
+// fetchLoops loads the loop states, or returns nil if Redis is not
+// connected yet.
+func (m Model) fetchLoops() tea.Cmd {
+	client := m.redisClient
+	if client == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		states, err := client.GetLoopStates(ctx)
+		if err != nil {
+			return loopsMsg(nil)
+		}
+		loops := make([]types.LoopState, 0, len(states))
+		for _, state := range states {
+			loops = append(loops, *state)
+		}
+		sortLoops(loops)
+		return loopsMsg(loops)
+	}
+}
+
+// sortLoops puts running and paused loops first, then the newest.
+func sortLoops(loops []types.LoopState) {
+	sort.SliceStable(loops, func(i, j int) bool {
+		a, b := &loops[i], &loops[j]
+		if fa, fb := a.Status.Finished(), b.Status.Finished(); fa != fb {
+			return !fa
+		}
+		if a.StartedAt != b.StartedAt {
+			return a.StartedAt > b.StartedAt
+		}
+		return a.LoopID > b.LoopID
+	})
+}
+
+// setLoops replaces the loop list, keeping the same loop selected.
+func (m *Model) setLoops(loops []types.LoopState) {
+	selected := ""
+	if l := m.selectedLoop(); l != nil {
+		selected = l.LoopID
+	}
+	m.loops = loops
+	for i, l := range loops {
+		if l.LoopID == selected {
+			m.loopIndex = i
+			return
+		}
+	}
+	if m.loopIndex >= len(loops) {
+		m.loopIndex = len(loops) - 1
+	}
+	if m.loopIndex < 0 {
+		m.loopIndex = 0
+	}
+}
+
+func (m Model) selectedLoop() *types.LoopState {
+	if m.loopIndex >= 0 && m.loopIndex < len(m.loops) {
+		return &m.loops[m.loopIndex]
+	}
+	return nil
+}
+
+// showTab switches between the sessions, loops and tasks tabs.
+func (m *Model) showTab(tab viewTab) tea.Cmd {
+	m.tab = tab
+	m.previewFocus = false
+	m.previewInput.Blur()
+	switch tab {
+	case tabLoops:
+		return m.fetchLoops()
+	case tabTasks:
+		if m.tasksLoading {
+			return nil
+		}
+		return m.fetchTasks()
+	}
+	return m.startPreviewFetch()
+}
+
+// handleLoopsKey handles keys on the loops tab.
+func (m *Model) handleLoopsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+
+	case "1", "L", "esc":
+		return m, m.showTab(tabSessions)
+
+	case "3", "T":
+		return m, m.showTab(tabTasks)
+
+	case "up", "k":
+		if m.loopIndex > 0 {
+			m.loopIndex--
+		}
+		return m, nil
+
+	case "down", "j":
+		if m.loopIndex < len(m.loops)-1 {
+			m.loopIndex++
+		}
+		return m, nil
+
+	case "p":
+		l := m.selectedLoop()
+		if l != nil && l.Status == types.LoopPaused {
+			return m, m.requestLoopCommand(types.LoopCommandResume)
+		}
+		return m, m.requestLoopCommand(types.LoopCommandPause)
+
+	case "c":
+		return m, m.requestLoopCommand(types.LoopCommandCancel)
+
+	case "n":
+		return m, m.requestLoopCommand(types.LoopCommandSkip)
+
+	case "enter":
+		return m, m.jumpToLoopSession()
+
+	case "r":
+		return m, m.fetchLoops()
+	}
+	return m, nil
+}
+
+// requestLoopCommand sends a command to the selected loop. Cancelling and
+// skipping are confirmed first.
+func (m *Model) requestLoopCommand(command types.LoopCommand) tea.Cmd {
+	l := m.selectedLoop()
+	if l == nil {
+		m.setStatus("No loop selected")
+		return nil
+	}
+	if l.Status.Finished() {
+		m.setStatus(fmt.Sprintf("%s has already %s", l.LoopID, l.Status))
+		return nil
+	}
+	p := pendingLoopCommand{loopID: l.LoopID, command: command}
+	switch command {
+	case types.LoopCommandSkip:
+		if l.CurrentTask == nil || l.CurrentTask.SessionID == "" {
+			m.setStatus("No task is running to skip")
+			return nil
+		}
+		m.pendingLoop = &p
+		return nil
+	case types.LoopCommandCancel:
+		m.pendingLoop = &p
+		return nil
+	}
+	return m.runLoopCommand(p)
+}
+
+// runLoopCommand queues a command for a loop.
+func (m *Model) runLoopCommand(p pendingLoopCommand) tea.Cmd {
+	client := m.redisClient
+	if client == nil {
+		m.setStatus("Redis unavailable")
+		return nil
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		err := client.SendLoopCommand(ctx, p.loopID, p.command)
+		return loopCommandMsg{loopID: p.loopID, command: p.command, err: err}
+	}
+}
+
+// handleLoopCommandDone reports whether a command was queued.
+func (m *Model) handleLoopCommandDone(msg loopCommandMsg) tea.Cmd {
+	if msg.err != nil {
+		m.setStatus(fmt.Sprintf("Sending %s failed: %v", msg.command, msg.err))
+		return nil
+	}
+	m.setStatus(fmt.Sprintf("Sent %s to %s", msg.command, msg.loopID))
+	return m.fetchLoops()
+}
+
+// jumpToLoopSession shows the selected loop's current session on the
+// sessions tab, clearing any filter that hides it.
+func (m *Model) jumpToLoopSession() tea.Cmd {
+	l := m.selectedLoop()
+	if l == nil || l.CurrentTask == nil || l.CurrentTask.SessionID == "" {
+		m.setStatus("No task is running")
+		return nil
+	}
+	name := l.CurrentTask.SessionID
+
+	index := sessionIndex(m.sessions, name)
+	if index < 0 && sessionIndex(m.allSessions, name) >= 0 {
+		m.filterInput.SetValue("")
+		m.statusFilter = filterAll
+		m.applyView()
+		index = sessionIndex(m.sessions, name)
+	}
+	if index < 0 {
+		m.setStatus(fmt.Sprintf("%s is not running", strings.TrimPrefix(name, mux.SessionPrefix)))
+		return nil
+	}
+
+	m.selectedIndex = index
+	return m.showTab(tabSessions)
+}
+
+func sessionIndex(sessions []types.Session, name string) int {
+	for i, s := range sessions {
+		if s.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// loopQueueBySource groups queued tasks by source, keeping the sources in
+// the order their first task appears.
+func loopQueueBySource(queue []types.LoopTask) ([]string, map[string][]types.LoopTask) {
+	var sources []string
+	bySource := make(map[string][]types.LoopTask)
+	for _, task := range queue {
+		source := task.Source
+		if source == "" {
+			source = "tasks"
+		}
+		if _, ok := bySource[source]; !ok {
+			sources = append(sources, source)
+		}
+		bySource[source] = append(bySource[source], task)
+	}
+	return sources, bySource
+}
+
+// loopStatusStyle returns the style for a loop status.
+func loopStatusStyle(status types.LoopStatus) lipgloss.Style {
+	switch status {
+	case types.LoopRunning:
+		return StatusHealthy
+	case types.LoopPaused:
+		return StatusStale
+	case types.LoopBlocked, types.LoopFailed:
+		return StatusDead
+	default:
+		return SubtitleStyle
+	}
+}
+
+// renderTabs renders the tab bar below the header.
+func (m Model) renderTabs() string {
+	running := 0
+	for _, l := range m.loops {
+		if !l.Status.Finished() {
+			running++
+		}
+	}
+	loops := "Loops"
+	if running > 0 {
+		loops = fmt.Sprintf("Loops (%d active)", running)
+	}
+
+	tab := func(key, label string, active bool) string {
+		if active {
+			return HelpKeyStyle.Render(key) + " " + SelectedStyle.Render(label)
+		}
+		return HelpKeyStyle.Render(key) + " " + DimStyle.Render(label)
+	}
+	tasks := "Tasks"
+	if m.tasksLoaded {
+		tasks = fmt.Sprintf("Tasks (%d)", len(m.allTasks))
+	}
+	return tab("1", "Sessions", m.tab == tabSessions) + "   " + tab("2", loops, m.tab == tabLoops) +
+		"   " + tab("3", tasks, m.tab == tabTasks)
+}
+
+// renderLoops renders the loops tab: the loop list, then the selected
+// loop's tasks.
+func (m Model) renderLoops(maxHeight int) string {
+	if len(m.loops) == 0 {
+		style := lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(ColorGray).
+			Padding(1, 2).
+			Foreground(ColorGray)
+		msg := "No loops running or recently finished"
+		if m.redisClient == nil {
+			msg = "Loops need Redis, which is not connected"
+		}
+		return style.Render(msg)
+	}
+
+	var b strings.Builder
+	headers := fmt.Sprintf(" %-2s%-20s%-11s%-22s%-8s%s", "", "LOOP", "STATUS", "PROGRESS", "TOOL", "UPDATED")
+	b.WriteString(DimStyle.Bold(true).Render(headers))
+	b.WriteString("\n")
+	for i := range m.loops {
+		b.WriteString(m.renderLoopRow(i))
+		b.WriteString("\n")
+	}
+
+	if l := m.selectedLoop(); l != nil {
+		b.WriteString("\n")
+		b.WriteString(m.renderLoopDetail(l))
+	}
+
+	out := strings.TrimRight(b.String(), "\n")
+	if maxHeight > 0 {
+		out = truncateLines(out, maxHeight, DimStyle.Render("..."))
+	}
+	return out
+}
+
+// renderLoopRow renders a single loop row.
+func (m Model) renderLoopRow(index int) string {
+	l := m.loops[index]
+	selector := "  "
+	id := l.LoopID
+	if len(id) > 19 {
+		id = id[:18] + "…"
+	}
+	if index == m.loopIndex {
+		selector = SelectedStyle.Render(IndicatorSelected) + " "
+		id = NameStyleSelected.Render(padRight(id, 20))
+	} else {
+		id = padRight(id, 20)
+	}
+
+	pct := 0.0
+	if l.TotalTasks > 0 {
+		pct = float64(l.Processed()) / float64(l.TotalTasks) * 100
+	}
+	bar := loopStatusStyle(l.Status).Render(RenderProgressBar(pct, 12))
+	progress := padRight(fmt.Sprintf("%s %d/%d", bar, l.Processed(), l.TotalTasks), 22)
+
+	updated := ""
+	if l.UpdatedAt > 0 {
+		updated = formatAge(time.UnixMilli(l.UpdatedAt))
+	}
+
+	return " " + selector + id +
+		padRight(loopStatusStyle(l.Status).Render(string(l.Status)), 11) +
+		progress +
+		padRight(GetToolStyle(l.CurrentTool).Render(l.CurrentTool), 8) +
+		DimStyle.Render(updated)
+}
+
+// renderLoopDetail renders the current task, queue and blocked tasks of a
+// loop.
+func (m Model) renderLoopDetail(l *types.LoopState) string {
+	var b strings.Builder
+
+	b.WriteString(TitleStyle.Render(l.LoopID))
+	if l.Cwd != "" {
+		b.WriteString(" " + DimStyle.Render(l.Cwd))
+	}
+	b.WriteString("\n")
+
+	if task := l.CurrentTask; task != nil {
+		b.WriteString(m.renderDetailRow("Current", task.Title))
+		if task.SessionID != "" {
+			session := strings.TrimPrefix(task.SessionID, mux.SessionPrefix)
+			if sessionIndex(m.allSessions, task.SessionID) < 0 && len(m.allSessions) > 0 {
+				session += DimStyle.Render(" (not running)")
+			} else {
+				session += DimStyle.Render(" (↵ to preview)")
+			}
+			b.WriteString(m.renderDetailRow("Session", session))
+		}
+	}
+	b.WriteString(m.renderDetailRow("Completed", fmt.Sprintf("%d of %d", l.CompletedTasks, l.TotalTasks)))
+
+	if len(l.Queue) > 0 {
+		b.WriteString(m.renderDetailRow("Queue", fmt.Sprintf("%d task(s)", len(l.Queue))))
+		sources, bySource := loopQueueBySource(l.Queue)
+		for _, source := range sources {
+			tasks := bySource[source]
+			b.WriteString("  " + BoldStyle.Render(source) + DimStyle.Render(fmt.Sprintf(" (%d)", len(tasks))) + "\n")
+			for i, task := range tasks {
+				if i == loopQueuePreview {
+					b.WriteString(DimStyle.Render(fmt.Sprintf("    +%d more", len(tasks)-i)) + "\n")
+					break
+				}
+				b.WriteString("    " + DimStyle.Render("·") + " " + task.Title + "\n")
+			}
+		}
+	}
+
+	if len(l.Blocked) > 0 {
+		b.WriteString(PromiseBlocked.Render(fmt.Sprintf("Blocked (%d)", len(l.Blocked))) + "\n")
+		for _, task := range l.Blocked {
+			b.WriteString("  " + PromiseBlocked.Render(IndicatorBlocked) + " " + task.Title)
+			if task.Reason != "" {
+				b.WriteString(DimStyle.Render(" — " + task.Reason))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(l.Skipped) > 0 {
+		b.WriteString(SubtitleStyle.Render(fmt.Sprintf("Skipped (%d)", len(l.Skipped))) + "\n")
+		for _, task := range l.Skipped {
+			b.WriteString("  " + DimStyle.Render("· "+task.Title) + "\n")
+		}
+	}
+
+	if len(l.ToolSwitches) > 0 {
+		b.WriteString(SubtitleStyle.Render(fmt.Sprintf("Tool switches (%d)", len(l.ToolSwitches))) + "\n")
+		for _, sw := range l.ToolSwitches {
+			b.WriteString(fmt.Sprintf("  %s %s → %s", DimStyle.Render(fmt.Sprintf("task %d", sw.TaskIndex+1)),
+				GetToolStyle(sw.From).Render(sw.From), GetToolStyle(sw.To).Render(sw.To)))
+			b.WriteString(DimStyle.Render(" — "+sw.Reason) + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+// loopStatusBarParts returns the counts and help shown in the status bar on
+// the loops tab.
+func (m Model) loopStatusBarParts() (counts, help, second string) {
+	running, paused := 0, 0
+	for _, l := range m.loops {
+		switch l.Status {
+		case types.LoopRunning:
+			running++
+		case types.LoopPaused:
+			paused++
+		}
+	}
+	counts = DimStyle.Render(fmt.Sprintf("%d running", running))
+	if paused > 0 {
+		counts += DimStyle.Render(", ") + StatusStale.Render(fmt.Sprintf("%d paused", paused))
+	}
+
+	keys := []string{
+		HelpKeyStyle.Render("↑↓/jk") + " nav",
+		HelpKeyStyle.Render("p") + " pause/resume",
+		HelpKeyStyle.Render("n") + " skip task",
+		HelpKeyStyle.Render("c") + " cancel",
+		HelpKeyStyle.Render("↵") + " session",
+		HelpKeyStyle.Render("r") + " refresh",
+		HelpKeyStyle.Render("q") + " quit",
+	}
+	help = DimStyle.Render(strings.Join(keys, "  "))
+	second = HelpKeyStyle.Render("1/L/esc") + DimStyle.Render(" sessions  ") +
+		HelpKeyStyle.Render("3") + DimStyle.Render(" tasks  ") +
+		DimStyle.Render("Commands apply within a few seconds; pause takes effect after the current task")
+	return counts, help, second
+}
@@ -1,12 +1,8 @@
 package tui
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"os"
-	"os/exec"
-	"sort"
 	"strings"
 	"time"
 
@@ -15,15 +11,25 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/Jayphen/coders/internal/mux"
 	"github.com/Jayphen/coders/internal/redis"
+	"github.com/Jayphen/coders/internal/tasksource"
 	"github.com/Jayphen/coders/internal/tmux"
+	"github.com/Jayphen/coders/internal/transcript"
 	"github.com/Jayphen/coders/internal/types"
 )
 
 // Model is the Bubbletea model for the TUI.
 type Model struct {
 	// Data
-	sessions       []types.Session
+	allSessions    []types.Session   // as fetched, before filtering
+	sessions       []types.Session   // visible sessions, filtered and sorted
+	marked         map[string]bool   // sessions marked for bulk actions, by name
+	loops          []types.LoopState // loop runs, active ones first
+	loopIndex      int
+	allTasks       []tasksource.Task // open tasks from the task sources
+	tasks          []tasksource.Task // visible tasks, filtered
+	taskIndex      int
 	selectedIndex  int
 	preview        string
 	previewSession string
@@ -33,6 +39,16 @@ type Model struct {
 	previewFocus   bool
 	previewInput   textinput.Model
 
+	// Diff panel, shown instead of the preview
+	showDiff         bool
+	diff             *sessionDiff
+	diffLoading      bool
+	diffFile         int // selected file in the diff
+	diffScroll       int // first line shown of the selected file's diff
+	diffStats        map[string]diffStat
+	diffStatsAt      time.Time
+	diffStatsPending bool
+
 	// Preview caching - avoid re-splitting on every render
 	previewSplitLines []string
 	previewSplitText  string
@@ -43,18 +59,42 @@ type Model struct {
 	statusMessage string
 	statusExpiry  time.Time
 	confirmKill   bool
-	spawnMode     bool
-	spawnInput    textinput.Model
+	pending       *pendingAction      // action awaiting confirmation
+	pendingLoop   *pendingLoopCommand // loop command awaiting confirmation
+	messageMode   bool
+	messageInput  textinput.Model
+	spawn         *spawnForm // spawn form, nil when closed
 	spawning      bool
+	selectOnFetch string // session to select once it is listed
+	filterMode    bool
+	filterInput   textinput.Model
+	statusFilter  statusFilter
+	sortMode      sortMode
+	tab           viewTab
 	width, height int
 	version       string
 
+	// Tasks tab
+	tasksLoading    bool
+	tasksLoaded     bool
+	tasksErr        error
+	tasksReadyOnly  bool
+	taskFilterMode  bool
+	taskFilterInput textinput.Model
+
 	// Components
 	spinner spinner.Model
 
 	// Dependencies
 	redisClient *redis.Client
 
+	// tmux control-mode client, nil when not connected
+	control               *tmux.ControlClient
+	controlStarting       bool
+	controlFollowing      string
+	previewRefreshPending bool
+	ticks                 int // Ticks since start, for the control client's resync
+
 	// View caching - avoid re-rendering when state hasn't changed
 	cachedView     string
 	lastViewState  viewState
@@ -76,31 +116,54 @@ type viewState struct {
 	statusMessage  string
 	statusExpired  bool   // whether status message should be shown
 	confirmKill    bool
-	spawnMode      bool
-	spawnInput     string
+	pending        string // confirmation prompt
+	pendingLoop    string // loop command confirmation prompt
+	markedCount    int
+	messageMode    bool
+	messageInput   string
+	spawnForm      string // rendered spawn form, when open
 	spawning       bool
+	filterMode     bool
+	filterQuery    string
+	statusFilter   statusFilter
+	sortMode       sortMode
+	tab            viewTab
+	loopCount      int
+	loopIndex      int
+	taskCount      int
+	taskIndex      int
+	tasksLoading   bool
+	tasksErr       string
+	tasksReadyOnly bool
+	taskFilterMode bool
+	taskFilter     string
 	width, height  int
 	spinnerView    string // spinner appearance (only when loading)
 	previewInput   string // only when previewFocus
+	showDiff       bool
+	diffSession    string
+	diffLoading    bool
+	diffFile       int
+	diffScroll     int
 }
 
 // Messages
 type (
-	sessionsMsg      []types.Session
-	errMsg           error
-	tickMsg          time.Time
-	statusClearMsg   struct{}
-	spawnCompleteMsg struct{ err error }
-	previewMsg       struct {
+	sessionsMsg    []types.Session
+	errMsg         error
+	tickMsg        time.Time
+	statusClearMsg struct{}
+	previewMsg     struct {
 		session string
 		output  string
 		err     error
 	}
 	redisDataMsg struct {
-		client       *redis.Client
-		promises     map[string]*types.CoderPromise
-		heartbeats   map[string]*types.HeartbeatData
-		healthChecks map[string]*types.HealthCheckResult
+		client        *redis.Client
+		promises      map[string]*types.CoderPromise
+		heartbeats    map[string]*types.HeartbeatData
+		healthChecks  map[string]*types.HealthCheckResult
+		sessionStates map[string]*types.SessionState
 	}
 )
 
@@ -112,25 +175,42 @@ func NewModel(version string) Model {
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(ColorCyan)
 
-	ti := textinput.New()
-	ti.Placeholder = `claude --task "Fix the bug"`
-	ti.CharLimit = 500
-	ti.Width = 60
-
 	pi := textinput.New()
-	pi.Placeholder = "Type a message..."
+	pi.Placeholder = "Type a message, or keys like <Down><Enter>"
 	pi.CharLimit = 2000
 	pi.Width = 60
 	pi.Prompt = ""
 	pi.Blur()
 
+	fi := textinput.New()
+	fi.Placeholder = "name, task, tool or directory"
+	fi.CharLimit = 200
+	fi.Width = 40
+	fi.Prompt = "/"
+
+	mi := textinput.New()
+	mi.Placeholder = "rebase on main and re-run the tests"
+	mi.CharLimit = 2000
+	mi.Width = 60
+
+	tfi := textinput.New()
+	tfi.Placeholder = "title, ID, label or source"
+	tfi.CharLimit = 200
+	tfi.Width = 40
+	tfi.Prompt = "/"
+
 	return Model{
 		version:      version,
 		loading:      true,
 		spinner:      s,
-		spawnInput:   ti,
 		previewLines: defaultPreviewLines,
 		previewInput: pi,
+		filterInput:  fi,
+		messageInput: mi,
+		marked:       make(map[string]bool),
+		diffStats:    make(map[string]diffStat),
+
+		taskFilterInput: tfi,
 	}
 }
 
@@ -141,6 +221,9 @@ func (m Model) Init() tea.Cmd {
 		m.fetchSessions,
 		m.fetchRedisData(), // Non-blocking Redis initialization
 		m.tick(),
+		// Init runs on a copy of the model, so the control client is
+		// started from Update where its state sticks
+		func() tea.Msg { return controlStartMsg{} },
 	)
 }
 
@@ -161,26 +244,17 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case sessionsMsg:
-		m.sessions = msg
+		m.allSessions = msg
 		m.loading = false
-		// Ensure selected index is in bounds
-		if m.selectedIndex >= len(m.sessions) && len(m.sessions) > 0 {
-			m.selectedIndex = len(m.sessions) - 1
-		}
-		if len(m.sessions) == 0 {
-			m.preview = ""
-			m.previewSession = ""
-			m.previewErr = nil
-			m.previewLoading = false
-			return m, nil
-		}
-		if m.selectedIndex >= 0 && m.selectedIndex < len(m.sessions) {
-			if m.previewSession != m.sessions[m.selectedIndex].Name {
-				previewCmd := m.startPreviewFetch()
-				return m, previewCmd
+		cmd := m.updateView()
+		if name := m.selectOnFetch; name != "" {
+			if i := sessionIndex(m.sessions, name); i >= 0 {
+				m.selectOnFetch = ""
+				m.selectedIndex = i
+				cmd = m.startPreviewFetch()
 			}
 		}
-		return m, nil
+		return m, tea.Batch(cmd, m.fetchDiffStats())
 
 	case errMsg:
 		m.err = msg
@@ -188,8 +262,21 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tickMsg:
+		m.ticks++
+		if m.control != nil {
+			// The control client reports session changes and preview output;
+			// Redis data still has to be polled
+			sessionsCmd := m.refreshSessionData()
+			if m.ticks%controlResyncTicks == 0 {
+				sessionsCmd = m.fetchSessions
+			}
+			return m, tea.Batch(sessionsCmd, m.fetchLoops(), m.startDiffFetch(), m.tick())
+		}
 		previewCmd := m.startPreviewFetch()
-		return m, tea.Batch(m.fetchSessions, previewCmd, m.tick())
+		return m, tea.Batch(m.fetchSessions, m.fetchLoops(), previewCmd, m.tick(), m.startControl())
+
+	case controlStartMsg, controlStartedMsg, controlClosedMsg, controlEventMsg, previewRefreshMsg:
+		return m, m.handleControlMsg(msg)
 
 	case statusClearMsg:
 		if time.Now().After(m.statusExpiry) {
@@ -197,14 +284,44 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case bulkDoneMsg:
+		return m, m.handleBulkDone(msg)
+
+	case loopsMsg:
+		m.setLoops(msg)
+		return m, nil
+
+	case diffMsg:
+		m.handleDiff(msg)
+		return m, nil
+
+	case diffStatsMsg:
+		m.diffStatsPending = false
+		m.diffStats = msg
+		return m, nil
+
+	case loopCommandMsg:
+		return m, m.handleLoopCommandDone(msg)
+
+	case tasksMsg:
+		m.tasksLoading = false
+		m.tasksLoaded = true
+		m.tasksErr = msg.err
+		if msg.err == nil {
+			m.allTasks = msg.tasks
+		}
+		m.applyTasksView()
+		return m, nil
+
 	case spawnCompleteMsg:
-		m.spawning = false
-		if msg.err != nil {
-			m.setStatus(fmt.Sprintf("Spawn failed: %v", msg.err))
-		} else {
-			m.setStatus("Spawn command sent")
+		return m, m.handleSpawnComplete(msg)
+
+	case cwdSuggestionsMsg:
+		if m.spawn != nil && strings.TrimSpace(m.spawn.cwd.Value()) == msg.query {
+			m.spawn.suggestions = msg.dirs
+			m.spawn.suggestion = -1
 		}
-		return m, m.fetchSessions
+		return m, nil
 
 	case previewMsg:
 		if msg.session != m.previewSession {
@@ -226,15 +343,18 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case redisDataMsg:
-		// Store the Redis client if it was just initialized
+		// Store the Redis client if it was just initialized, and load the loops with it
+		var loopsCmd tea.Cmd
 		if msg.client != nil && m.redisClient == nil {
 			m.redisClient = msg.client
+			loopsCmd = m.fetchLoops()
 		}
-		// Enrich current sessions with Redis data
-		if len(m.sessions) > 0 && (msg.promises != nil || msg.heartbeats != nil || msg.healthChecks != nil) {
-			enrichSessionsWithRedisData(m.sessions, msg.promises, msg.heartbeats, msg.healthChecks)
+		// Enrich current sessions with Redis data; the filter and sort may depend on it
+		if len(m.allSessions) > 0 && (msg.promises != nil || msg.heartbeats != nil || msg.healthChecks != nil || msg.sessionStates != nil) {
+			enrichSessionsWithRedisData(m.allSessions, msg.promises, msg.heartbeats, msg.healthChecks, msg.sessionStates)
+			return m, tea.Batch(loopsCmd, m.updateView())
 		}
-		return m, nil
+		return m, loopsCmd
 
 	case spinner.TickMsg:
 		var cmd tea.Cmd
@@ -243,9 +363,14 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	// Update text inputs for non-key messages (e.g. cursor blink).
-	if m.spawnMode {
+	if m.spawn != nil {
 		var cmd tea.Cmd
-		m.spawnInput, cmd = m.spawnInput.Update(msg)
+		switch m.spawn.field {
+		case fieldTask:
+			m.spawn.task, cmd = m.spawn.task.Update(msg)
+		case fieldCwd:
+			m.spawn.cwd, cmd = m.spawn.cwd.Update(msg)
+		}
 		cmds = append(cmds, cmd)
 	}
 	if m.previewFocus {
@@ -253,37 +378,107 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.previewInput, cmd = m.previewInput.Update(msg)
 		cmds = append(cmds, cmd)
 	}
+	if m.filterMode {
+		var cmd tea.Cmd
+		m.filterInput, cmd = m.filterInput.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+	if m.messageMode {
+		var cmd tea.Cmd
+		m.messageInput, cmd = m.messageInput.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+	if m.taskFilterMode {
+		var cmd tea.Cmd
+		m.taskFilterInput, cmd = m.taskFilterInput.Update(msg)
+		cmds = append(cmds, cmd)
+	}
 
 	return m, tea.Batch(cmds...)
 }
 
 // handleKey handles keyboard input.
 func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	// Handle spawn mode input
-	if m.spawnMode {
+	// Handle the spawn form
+	if m.spawn != nil {
+		return m, m.handleSpawnKey(msg)
+	}
+
+	// Handle filter input; the list narrows as the query is typed
+	if m.filterMode {
+		switch msg.String() {
+		case "esc":
+			m.filterMode = false
+			m.filterInput.Blur()
+			m.filterInput.SetValue("")
+			return m, m.updateView()
+		case "enter":
+			m.filterMode = false
+			m.filterInput.Blur()
+			return m, nil
+		case "ctrl+c":
+			return m, tea.Quit
+		}
+		var cmd tea.Cmd
+		m.filterInput, cmd = m.filterInput.Update(msg)
+		return m, tea.Batch(cmd, m.updateView())
+	}
+
+	// Handle message input for the targeted sessions
+	if m.messageMode {
 		switch msg.String() {
 		case "esc":
-			m.spawnMode = false
-			m.spawnInput.SetValue("")
-			m.setStatus("Spawn cancelled")
+			m.messageMode = false
+			m.messageInput.Blur()
+			m.messageInput.SetValue("")
+			m.setStatus("Send cancelled")
 			return m, nil
 		case "enter":
-			value := strings.TrimSpace(m.spawnInput.Value())
-			m.spawnMode = false
-			m.spawnInput.SetValue("")
-			if value == "" {
-				m.setStatus("Spawn cancelled")
+			text := strings.TrimSpace(m.messageInput.Value())
+			m.messageMode = false
+			m.messageInput.Blur()
+			m.messageInput.SetValue("")
+			if text == "" {
+				m.setStatus("Send cancelled")
 				return m, nil
 			}
-			m.spawning = true
-			m.setStatus("Spawning session...")
-			return m, m.spawnSession(value)
+			return m, m.requestAction(actionSend, m.actionTargets(), text)
 		}
 		var cmd tea.Cmd
-		m.spawnInput, cmd = m.spawnInput.Update(msg)
+		m.messageInput, cmd = m.messageInput.Update(msg)
 		return m, cmd
 	}
 
+	// Handle bulk action confirmation
+	if m.pending != nil {
+		switch msg.String() {
+		case "y", "Y":
+			p := *m.pending
+			m.pending = nil
+			return m, m.runAction(p)
+		case "n", "N", "enter", "esc":
+			m.pending = nil
+			m.setStatus("Cancelled")
+			return m, nil
+		}
+		return m, nil
+	}
+
+	// Handle loop command confirmation
+	if m.pendingLoop != nil {
+		switch msg.String() {
+		case "y", "Y":
+			p := *m.pendingLoop
+			m.pendingLoop = nil
+			return m, m.runLoopCommand(p)
+		case "n", "N", "enter", "esc":
+			m.pendingLoop = nil
+			m.setStatus("Cancelled")
+			return m, nil
+		}
+		return m, nil
+	}
+
 	// Handle confirmation dialog
 	if m.confirmKill {
 		switch msg.String() {
@@ -308,6 +503,7 @@ func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		case "enter":
 			text := strings.TrimSpace(m.previewInput.Value())
 			if text == "" {
+				m.quickReply("enter")
 				return m, nil
 			}
 			s := m.selectedSession()
@@ -315,7 +511,7 @@ func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				m.setStatus("No session selected")
 				return m, nil
 			}
-			if err := tmux.SendKeys(s.Name, text); err != nil {
+			if err := mux.SendInput(s.Name, text); err != nil {
 				m.setStatus(fmt.Sprintf("Send failed: %v", err))
 			} else {
 				m.setStatus("Sent to session")
@@ -325,18 +521,60 @@ func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		case "ctrl+c":
 			return m, tea.Quit
 		}
+		if m.previewInput.Value() == "" && m.quickReply(msg.String()) {
+			return m, nil
+		}
 		var cmd tea.Cmd
 		m.previewInput, cmd = m.previewInput.Update(msg)
 		return m, cmd
 	}
 
+	if m.tab == tabLoops {
+		return m.handleLoopsKey(msg)
+	}
+	if m.tab == tabTasks {
+		return m.handleTasksKey(msg)
+	}
+
 	// Normal mode key handling
 	switch msg.String() {
+	case "2", "L":
+		return m, m.showTab(tabLoops)
+
+	case "3", "T":
+		return m, m.showTab(tabTasks)
+
 	case "tab":
+		m.showDiff = false
 		m.previewFocus = true
 		m.previewInput.Focus()
 		return m, textinput.Blink
 
+	case "d":
+		return m, m.toggleDiff()
+
+	case "]", "[":
+		if !m.showDiff {
+			return m, nil
+		}
+		if msg.String() == "]" {
+			m.moveDiffFile(1)
+		} else {
+			m.moveDiffFile(-1)
+		}
+		return m, nil
+
+	case "ctrl+d", "ctrl+u":
+		if !m.showDiff {
+			return m, nil
+		}
+		if msg.String() == "ctrl+d" {
+			m.scrollDiff(diffScrollStep)
+		} else {
+			m.scrollDiff(-diffScrollStep)
+		}
+		return m, nil
+
 	case "q", "ctrl+c":
 		return m, tea.Quit
 
@@ -355,31 +593,46 @@ func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "enter", "a":
 		if len(m.sessions) > 0 && m.selectedIndex < len(m.sessions) {
 			session := m.sessions[m.selectedIndex]
-			tmux.AttachSession(session.Name)
+			mux.AttachSession(session.Name)
 		}
 		return m, nil
 
 	case "s":
-		if m.spawning {
-			m.setStatus("Spawn already in progress")
+		return m, m.openSpawnForm(spawnPrefill{})
+
+	case " ":
+		m.toggleMark()
+		return m, m.startPreviewFetch()
+
+	case "*":
+		m.markAll()
+		return m, nil
+
+	case "K":
+		return m, m.requestAction(actionKill, m.actionTargets(), "")
+
+	case "X":
+		return m, m.requestAction(actionRestart, m.actionTargets(), "")
+
+	case "i":
+		return m, m.requestAction(actionInterrupt, m.actionTargets(), "Escape")
+
+	case "I":
+		return m, m.requestAction(actionInterrupt, m.actionTargets(), "C-c")
+
+	case "m":
+		if len(m.actionTargets()) == 0 {
+			m.setStatus("No session selected")
 			return m, nil
 		}
-		m.spawnMode = true
+		m.messageMode = true
 		m.previewFocus = false
 		m.previewInput.Blur()
-		m.spawnInput.Focus()
+		m.messageInput.Focus()
 		return m, textinput.Blink
 
-	case "K":
-		if len(m.sessions) > 0 && m.selectedIndex < len(m.sessions) {
-			session := m.sessions[m.selectedIndex]
-			tmux.KillSession(session.Name)
-			if m.redisClient != nil {
-				m.redisClient.DeletePromise(context.Background(), session.Name)
-			}
-			m.setStatus(fmt.Sprintf("Killed: %s", session.Name))
-			return m, m.fetchSessions
-		}
+	case "V":
+		m.attachSplit()
 		return m, nil
 
 	case "C":
@@ -392,20 +645,50 @@ func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case "R":
-		if len(m.sessions) > 0 && m.selectedIndex < len(m.sessions) {
-			session := m.sessions[m.selectedIndex]
-			if session.HasPromise && m.redisClient != nil {
-				m.redisClient.DeletePromise(context.Background(), session.Name)
-				m.setStatus(fmt.Sprintf("Resumed: %s", strings.TrimPrefix(session.Name, tmux.SessionPrefix)))
-				return m, m.fetchSessions
-			} else {
+		targets := m.completedTargets()
+		if len(targets) == 0 {
+			if len(m.marked) > 0 {
+				m.setStatus("No marked session is completed")
+			} else if m.selectedSession() != nil {
 				m.setStatus("Selected session is not completed")
 			}
+			return m, nil
 		}
-		return m, nil
+		return m, m.requestAction(actionResume, targets, "")
 
 	case "r":
 		return m, m.fetchSessions
+
+	case "/":
+		m.filterMode = true
+		m.previewFocus = false
+		m.previewInput.Blur()
+		m.filterInput.Focus()
+		return m, textinput.Blink
+
+	case "f":
+		m.statusFilter = (m.statusFilter + 1) % statusFilterCount
+		m.setStatus(fmt.Sprintf("Showing %s sessions", m.statusFilter))
+		return m, m.updateView()
+
+	case "o":
+		m.sortMode = (m.sortMode + 1) % sortModeCount
+		m.setStatus(fmt.Sprintf("Sorted by %s", m.sortMode))
+		return m, m.updateView()
+
+	case "esc":
+		if len(m.marked) > 0 {
+			m.marked = make(map[string]bool)
+			m.setStatus("Marks cleared")
+			return m, nil
+		}
+		if m.filtered() {
+			m.filterInput.SetValue("")
+			m.statusFilter = filterAll
+			m.setStatus("Filter cleared")
+			return m, m.updateView()
+		}
+		return m, nil
 	}
 
 	return m, nil
@@ -424,8 +707,26 @@ func (m Model) currentViewState() viewState {
 		statusMessage:  m.statusMessage,
 		statusExpired:  time.Now().After(m.statusExpiry),
 		confirmKill:    m.confirmKill,
-		spawnMode:      m.spawnMode,
+		markedCount:    len(m.marked),
+		messageMode:    m.messageMode,
 		spawning:       m.spawning,
+		filterMode:     m.filterMode,
+		filterQuery:    m.filterInput.Value(),
+		statusFilter:   m.statusFilter,
+		sortMode:       m.sortMode,
+		tab:            m.tab,
+		loopCount:      len(m.loops),
+		loopIndex:      m.loopIndex,
+		taskCount:      len(m.tasks),
+		taskIndex:      m.taskIndex,
+		tasksLoading:   m.tasksLoading,
+		tasksReadyOnly: m.tasksReadyOnly,
+		taskFilterMode: m.taskFilterMode,
+		taskFilter:     m.taskFilterInput.Value(),
+		showDiff:       m.showDiff,
+		diffLoading:    m.diffLoading,
+		diffFile:       m.diffFile,
+		diffScroll:     m.diffScroll,
 		width:          m.width,
 		height:         m.height,
 	}
@@ -436,14 +737,29 @@ func (m Model) currentViewState() viewState {
 	if m.err != nil {
 		vs.err = m.err.Error()
 	}
-	if m.loading {
+	if m.tasksErr != nil {
+		vs.tasksErr = m.tasksErr.Error()
+	}
+	if m.diff != nil {
+		vs.diffSession = m.diff.session
+	}
+	if m.loading || m.tasksLoading {
 		vs.spinnerView = m.spinner.View()
 	}
 	if m.previewFocus {
 		vs.previewInput = m.previewInput.Value()
 	}
-	if m.spawnMode {
-		vs.spawnInput = m.spawnInput.Value()
+	if m.spawn != nil {
+		vs.spawnForm = m.renderSpawnForm()
+	}
+	if m.messageMode {
+		vs.messageInput = m.messageInput.Value()
+	}
+	if m.pending != nil {
+		vs.pending = m.pending.prompt()
+	}
+	if m.pendingLoop != nil {
+		vs.pendingLoop = m.pendingLoop.prompt()
 	}
 
 	return vs
@@ -466,30 +782,40 @@ func (m *Model) View() string {
 	header := m.renderHeader()
 	b.WriteString(header)
 	b.WriteString("\n\n")
+	b.WriteString(m.renderTabs())
+	b.WriteString("\n\n")
 
 	// Confirmation dialog
-	if m.confirmKill {
+	if m.confirming() {
 		confirm := m.renderConfirmDialog()
 		b.WriteString(confirm)
 		b.WriteString("\n")
 	}
 
-	// Spawn prompt
-	if m.spawnMode {
-		spawn := m.renderSpawnPrompt()
-		b.WriteString(spawn)
+	// Message prompt
+	if m.messageMode {
+		b.WriteString(m.renderMessagePrompt())
+		b.WriteString("\n")
+	}
+
+	// Spawn form
+	if m.spawn != nil {
+		b.WriteString(m.renderSpawnForm())
 		b.WriteString("\n")
 	}
 
 	contentHeight := 0
 	if m.height > 0 {
 		innerHeight := m.height - 2 // outer padding
-		usedHeight := lipgloss.Height(header) + 1
-		if m.confirmKill {
+		usedHeight := lipgloss.Height(header) + 1 + lipgloss.Height(m.renderTabs()) + 1
+		if m.confirming() {
 			usedHeight += lipgloss.Height(m.renderConfirmDialog()) + 1
 		}
-		if m.spawnMode {
-			usedHeight += lipgloss.Height(m.renderSpawnPrompt()) + 1
+		if m.messageMode {
+			usedHeight += lipgloss.Height(m.renderMessagePrompt()) + 1
+		}
+		if m.spawn != nil {
+			usedHeight += lipgloss.Height(m.renderSpawnForm()) + 1
 		}
 		statusBar := m.renderStatusBar()
 		usedHeight += 1 + lipgloss.Height(statusBar)
@@ -507,6 +833,12 @@ func (m *Model) View() string {
 		}
 		b.WriteString(errLine)
 		b.WriteString("\n")
+	} else if m.tab == tabLoops {
+		b.WriteString(m.renderLoops(contentHeight))
+		b.WriteString("\n")
+	} else if m.tab == tabTasks {
+		b.WriteString(m.renderTasks(contentHeight))
+		b.WriteString("\n")
 	} else {
 		b.WriteString(m.renderMainContent(contentHeight))
 	}
@@ -532,6 +864,11 @@ func (m *Model) setStatus(msg string) {
 	m.statusExpiry = time.Now().Add(3 * time.Second)
 }
 
+// confirming reports whether a confirmation dialog is showing.
+func (m Model) confirming() bool {
+	return m.confirmKill || m.pending != nil || m.pendingLoop != nil
+}
+
 func (m Model) countCompleted() int {
 	count := 0
 	for _, s := range m.sessions {
@@ -564,7 +901,7 @@ func (m *Model) startPreviewFetch() tea.Cmd {
 	}
 	m.previewLoading = true
 	m.previewSession = s.Name
-	return m.fetchPreview(s.Name, lines)
+	return tea.Batch(m.fetchPreview(s.Name, lines), m.followPreview(), m.startDiffFetch())
 }
 
 // Commands
@@ -577,7 +914,7 @@ func (m Model) tick() tea.Cmd {
 
 func (m Model) fetchSessions() tea.Msg {
 	// Get tmux sessions
-	sessions, err := tmux.ListSessions()
+	sessions, err := mux.ListSessions()
 	if err != nil {
 		return errMsg(err)
 	}
@@ -590,99 +927,55 @@ func (m Model) fetchSessions() tea.Msg {
 		promises, _ := m.redisClient.GetPromises(ctx)
 		heartbeats, _ := m.redisClient.GetHeartbeats(ctx)
 		healthChecks, _ := m.redisClient.GetHealthChecks(ctx)
+		sessionStates, _ := m.redisClient.GetSessionStates(ctx)
 
-		enrichSessionsWithRedisData(sessions, promises, heartbeats, healthChecks)
-	}
-
-	// Sort: orchestrator first, then active, then completed, by creation time
-	// Optimize by pre-calculating sort keys to avoid repeated comparisons
-	type sortKey struct {
-		priority  int   // 0=orchestrator, 1=active, 2=completed
-		timestamp int64 // negative for reverse chronological (newer first)
-		index     int   // preserve stable sort for equal elements
+		enrichSessionsWithRedisData(sessions, promises, heartbeats, healthChecks, sessionStates)
 	}
 
-	keys := make([]sortKey, len(sessions))
-	for i := range sessions {
-		s := &sessions[i]
-		key := sortKey{index: i}
-
-		if s.IsOrchestrator {
-			key.priority = 0
-		} else if s.HasPromise {
-			key.priority = 2 // Completed (has promise)
-		} else {
-			key.priority = 1 // Active (no promise)
-		}
-
-		if s.CreatedAt != nil {
-			key.timestamp = -s.CreatedAt.Unix() // Negative for reverse order
-		}
+	// Sorted and filtered for display by applyView
+	return sessionsMsg(sessions)
+}
 
-		keys[i] = key
+// refreshSessionData re-reads the Redis data of the listed sessions without
+// listing them again, for when the control client reports session changes.
+func (m Model) refreshSessionData() tea.Cmd {
+	if m.redisClient == nil || len(m.allSessions) == 0 {
+		return nil
 	}
+	client := m.redisClient
+	sessions := append([]types.Session(nil), m.allSessions...)
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
 
-	sort.Slice(sessions, func(i, j int) bool {
-		ki, kj := keys[i], keys[j]
-		if ki.priority != kj.priority {
-			return ki.priority < kj.priority
-		}
-		if ki.timestamp != kj.timestamp {
-			return ki.timestamp < kj.timestamp
-		}
-		return ki.index < kj.index
-	})
+		promises, _ := client.GetPromises(ctx)
+		heartbeats, _ := client.GetHeartbeats(ctx)
+		healthChecks, _ := client.GetHealthChecks(ctx)
+		sessionStates, _ := client.GetSessionStates(ctx)
 
-	return sessionsMsg(sessions)
+		enrichSessionsWithRedisData(sessions, promises, heartbeats, healthChecks, sessionStates)
+		return sessionsMsg(sessions)
+	}
 }
 
 func (m Model) fetchPreview(sessionName string, lines int) tea.Cmd {
 	return func() tea.Msg {
-		output, err := tmux.CapturePane(sessionName, lines)
+		output, err := mux.CapturePane(sessionName, mux.CaptureOptions{Lines: lines, Escapes: true})
 		return previewMsg{session: sessionName, output: output, err: err}
 	}
 }
 
-func (m Model) spawnSession(args string) tea.Cmd {
-	return func() tea.Msg {
-		exe, err := os.Executable()
-		if err != nil {
-			return spawnCompleteMsg{err: err}
-		}
-
-		parsedArgs, err := parseSpawnArgs(args)
-		if err != nil {
-			return spawnCompleteMsg{err: err}
-		}
-		if len(parsedArgs) == 0 {
-			return spawnCompleteMsg{err: fmt.Errorf("no spawn arguments provided")}
-		}
-
-		cmd := exec.Command(exe, append([]string{"spawn"}, parsedArgs...)...)
-		var output bytes.Buffer
-		cmd.Stdout = &output
-		cmd.Stderr = &output
-		if err := cmd.Run(); err != nil {
-			msg := strings.TrimSpace(output.String())
-			if msg != "" {
-				return spawnCompleteMsg{err: fmt.Errorf("%w: %s", err, msg)}
-			}
-			return spawnCompleteMsg{err: err}
-		}
-		return spawnCompleteMsg{err: nil}
-	}
-}
-
 func (m Model) killCompletedSessions() tea.Cmd {
 	return func() tea.Msg {
 		killed := 0
 		for _, s := range m.sessions {
 			if s.HasPromise && !s.IsOrchestrator {
-				if err := tmux.KillSession(s.Name); err == nil {
+				if err := mux.KillSession(s.Name); err == nil {
 					killed++
 					if m.redisClient != nil {
 						m.redisClient.DeletePromise(context.Background(), s.Name)
 					}
+					transcript.Archive(s.Name)
 				}
 			}
 		}
@@ -691,59 +984,13 @@ func (m Model) killCompletedSessions() tea.Cmd {
 	}
 }
 
-func parseSpawnArgs(input string) ([]string, error) {
-	// Estimate capacity: rough heuristic of input_length/10 + 2, min 4
-	estCap := len(input)/10 + 2
-	if estCap < 4 {
-		estCap = 4
-	}
-	args := make([]string, 0, estCap)
-	var b strings.Builder
-	inSingle := false
-	inDouble := false
-	escaped := false
-
-	flush := func() {
-		if b.Len() > 0 {
-			args = append(args, b.String())
-			b.Reset()
-		}
-	}
-
-	for _, r := range input {
-		switch {
-		case escaped:
-			b.WriteRune(r)
-			escaped = false
-		case r == '\\' && !inSingle:
-			escaped = true
-		case r == '\'' && !inDouble:
-			inSingle = !inSingle
-		case r == '"' && !inSingle:
-			inDouble = !inDouble
-		case (r == ' ' || r == '\t' || r == '\n') && !inSingle && !inDouble:
-			flush()
-		default:
-			b.WriteRune(r)
-		}
-	}
-
-	if escaped {
-		b.WriteRune('\\')
-	}
-	if inSingle || inDouble {
-		return nil, fmt.Errorf("unterminated quote in spawn arguments")
-	}
-	flush()
-	return args, nil
-}
-
 // enrichSessionsWithRedisData enriches sessions with Redis data
 func enrichSessionsWithRedisData(
 	sessions []types.Session,
 	promises map[string]*types.CoderPromise,
 	heartbeats map[string]*types.HeartbeatData,
 	healthChecks map[string]*types.HealthCheckResult,
+	sessionStates map[string]*types.SessionState,
 ) {
 	for i := range sessions {
 		s := &sessions[i]
@@ -770,6 +1017,17 @@ func enrichSessionsWithRedisData(
 			s.HeartbeatStatus = types.HeartbeatDead
 		}
 
+		// Session state is the durable parent record, so it wins over heartbeats
+		if state, ok := sessionStates[s.Name]; ok && state.ParentSessionID != "" {
+			s.ParentSessionID = state.ParentSessionID
+		}
+		if state, ok := sessionStates[s.Name]; ok {
+			if s.TaskID == "" {
+				s.TaskID = state.TaskID
+			}
+			s.SpawnCommit = state.SpawnCommit
+		}
+
 		// Add health check data (for stuck/unresponsive detection)
 		if hc, ok := healthChecks[s.Name]; ok {
 			s.HealthCheck = hc
@@ -777,6 +1035,49 @@ func enrichSessionsWithRedisData(
 	}
 }
 
+// orderSessionTree moves each child session directly below its parent,
+// keeping the existing order among siblings and among root sessions.
+// Sessions whose parent is not in the list are treated as roots.
+func orderSessionTree(sessions []types.Session) []types.Session {
+	present := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		present[s.Name] = true
+	}
+
+	children := make(map[string][]int)
+	var roots []int
+	for i, s := range sessions {
+		if s.ParentSessionID != "" && s.ParentSessionID != s.Name && present[s.ParentSessionID] {
+			children[s.ParentSessionID] = append(children[s.ParentSessionID], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+
+	ordered := make([]types.Session, 0, len(sessions))
+	visited := make(map[int]bool, len(sessions))
+	var walk func(i int)
+	walk = func(i int) {
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		ordered = append(ordered, sessions[i])
+		for _, child := range children[sessions[i].Name] {
+			walk(child)
+		}
+	}
+	for _, i := range roots {
+		walk(i)
+	}
+	// Parent cycles have no root; keep those sessions rather than dropping them
+	for i := range sessions {
+		walk(i)
+	}
+
+	return ordered
+}
+
 // fetchRedisData asynchronously initializes Redis client and fetches data
 func (m Model) fetchRedisData() tea.Cmd {
 	return func() tea.Msg {
@@ -798,12 +1099,14 @@ func (m Model) fetchRedisData() tea.Cmd {
 		promises, _ := client.GetPromises(ctx)
 		heartbeats, _ := client.GetHeartbeats(ctx)
 		healthChecks, _ := client.GetHealthChecks(ctx)
+		sessionStates, _ := client.GetSessionStates(ctx)
 
 		return redisDataMsg{
-			client:       client,
-			promises:     promises,
-			heartbeats:   heartbeats,
-			healthChecks: healthChecks,
+			client:        client,
+			promises:      promises,
+			heartbeats:    heartbeats,
+			healthChecks:  healthChecks,
+			sessionStates: sessionStates,
 		}
 	}
 }
@@ -0,0 +1,77 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/prompt"
+)
+
+// The preview input sends text in the key sequence syntax of
+// mux.ParseKeys, so "<Down><Enter>" or "<Esc>" can drive menus. When the
+// selected session's preview ends in a prompt whose answers can be read,
+// they are listed under the preview, and with the input focused but empty
+// pressing an answer's key sends it.
+
+// previewPrompt returns the prompt the selected session is showing, if its
+// preview is current and the prompt's answers could be read.
+func (m Model) previewPrompt() (prompt.Prompt, bool) {
+	s := m.selectedSession()
+	if s == nil || s.Name != m.previewSession || m.previewErr != nil {
+		return prompt.Prompt{}, false
+	}
+	p, ok := prompt.Detect(s.Tool, m.preview)
+	if !ok || len(p.Choices) == 0 {
+		return prompt.Prompt{}, false
+	}
+	return p, true
+}
+
+// quickReply sends the answer picked by key to the selected session. It
+// reports whether key picks an answer.
+func (m *Model) quickReply(key string) bool {
+	p, ok := m.previewPrompt()
+	if !ok {
+		return false
+	}
+	for _, c := range p.Choices {
+		if c.Key != key {
+			continue
+		}
+		if err := mux.SendRawKeys(m.previewSession, c.Keys...); err != nil {
+			m.setStatus(fmt.Sprintf("Reply failed: %v", err))
+		} else {
+			m.setStatus(fmt.Sprintf("Answered %q", c.Label))
+		}
+		return true
+	}
+	return false
+}
+
+// renderQuickReplies renders the answers to the selected session's prompt
+// on one line, dropping those that don't fit in width, or "" if there is no
+// prompt.
+func (m Model) renderQuickReplies(width int) string {
+	p, ok := m.previewPrompt()
+	if !ok {
+		return ""
+	}
+	const label = "Reply: "
+	line := StatusWaiting.Render(label)
+	used := len(label)
+	for i, c := range p.Choices {
+		text := c.Key + " " + c.Label
+		if i > 0 {
+			used += 2
+		}
+		used += len([]rune(text))
+		if width > 0 && used > width {
+			return line + DimStyle.Render(" …")
+		}
+		if i > 0 {
+			line += "  "
+		}
+		line += HelpKeyStyle.Render(c.Key) + " " + c.Label
+	}
+	return line
+}
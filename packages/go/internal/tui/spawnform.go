@@ -0,0 +1,628 @@
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+// The spawn form builds the arguments to `coders spawn` from pickers,
+// toggles and inputs, and validates them before spawning. The tasks tab
+// opens it prefilled with the selected task.
+
+// spawnField is a field of the spawn form, in tab order.
+type spawnField int
+
+const (
+	fieldTemplate spawnField = iota
+	fieldTool
+	fieldModel
+	fieldTask
+	fieldCwd
+	fieldParent
+	fieldWorktree
+	fieldRestart
+	fieldHeartbeat
+	fieldOllama
+	spawnFieldCount
+)
+
+// maxCwdSuggestions caps the directories suggested for the cwd.
+const maxCwdSuggestions = 5
+
+// spawnForm is the state of the spawn form.
+type spawnForm struct {
+	cfg       *config.Config
+	template  int // index into cfg.SpawnTemplates plus one; 0 is no template
+	tool      int // index into types.ValidTools
+	models    []string
+	model     int // index into models; models[0] is the tool's default
+	task      textarea.Model
+	cwd       textinput.Model
+	parents   []string // running sessions; parents[0] is no parent
+	parent    int
+	worktree  bool
+	restart   bool
+	heartbeat bool
+	ollama    bool
+	taskID    string // task-source task the session is for, if any
+
+	sessionDirs []string // directories of running sessions, for suggestions
+	suggestions []string
+	suggestion  int // selected suggestion, -1 when the input was typed
+
+	field spawnField
+	err   string // validation error
+}
+
+// spawnPrefill holds the values a spawn form opens with.
+type spawnPrefill struct {
+	task   string
+	cwd    string
+	taskID string
+	tool   string // Tool and model picked by the routing rules, if any
+	model  string
+}
+
+type (
+	spawnCompleteMsg struct {
+		session string // ID of the spawned session, if it could be read
+		err     error
+	}
+	cwdSuggestionsMsg struct {
+		query string
+		dirs  []string
+	}
+)
+
+// newSpawnForm opens a spawn form with the config's defaults. Sessions are
+// offered as parents and their directories suggested for the cwd.
+func newSpawnForm(cfg *config.Config, sessions []types.Session, prefill spawnPrefill) *spawnForm {
+	if cfg == nil {
+		cfg = &config.Config{DefaultTool: config.DefaultDefaultTool, DefaultHeartbeat: config.DefaultDefaultHeartbeat}
+	}
+	f := &spawnForm{cfg: cfg, heartbeat: cfg.DefaultHeartbeat, taskID: prefill.taskID, suggestion: -1}
+
+	f.task = textarea.New()
+	f.task.Placeholder = "What should the session work on?"
+	f.task.ShowLineNumbers = false
+	f.task.Prompt = ""
+	f.task.CharLimit = 10000
+	f.task.SetWidth(60)
+	f.task.SetHeight(4)
+	f.task.SetValue(prefill.task)
+
+	f.cwd = textinput.New()
+	f.cwd.Placeholder = "current directory, a path or a zoxide query"
+	f.cwd.CharLimit = 500
+	f.cwd.Width = 50
+	f.cwd.Prompt = ""
+	f.cwd.SetValue(prefill.cwd)
+
+	f.parents = []string{""}
+	seen := make(map[string]bool)
+	for _, s := range sessions {
+		if s.IsOrchestrator || s.HasPromise {
+			continue
+		}
+		f.parents = append(f.parents, s.Name)
+		if s.Cwd != "" && !seen[s.Cwd] {
+			seen[s.Cwd] = true
+			f.sessionDirs = append(f.sessionDirs, s.Cwd)
+		}
+	}
+
+	f.setTool(cfg.DefaultTool)
+	f.setModel(cfg.DefaultModel)
+	if prefill.tool != "" {
+		f.setTool(prefill.tool)
+		f.setModel(prefill.model)
+	}
+	return f
+}
+
+// setTool selects tool and offers its models.
+func (f *spawnForm) setTool(tool string) {
+	for i, t := range types.ValidTools {
+		if t == tool {
+			f.tool = i
+		}
+	}
+	f.models = append([]string{""}, f.cfg.Models[f.toolName()]...)
+	f.model = 0
+	if f.toolName() != "claude" {
+		f.ollama = false
+	}
+}
+
+// setModel selects model, adding it to the choices if it is not offered.
+func (f *spawnForm) setModel(model string) {
+	if model == "" {
+		f.model = 0
+		return
+	}
+	for i, m := range f.models {
+		if m == model {
+			f.model = i
+			return
+		}
+	}
+	f.models = append(f.models, model)
+	f.model = len(f.models) - 1
+}
+
+func (f *spawnForm) toolName() string {
+	return types.ValidTools[f.tool]
+}
+
+// applyTemplate fills the form from the selected template.
+func (f *spawnForm) applyTemplate() {
+	if f.template == 0 {
+		return
+	}
+	t := f.cfg.SpawnTemplates[f.template-1]
+	if t.Tool != "" {
+		f.setTool(t.Tool)
+	}
+	f.setModel(t.Model)
+	if t.Task != "" {
+		f.task.SetValue(t.Task)
+	}
+	if t.Cwd != "" {
+		f.cwd.SetValue(t.Cwd)
+	}
+	f.worktree = t.Worktree
+	f.restart = t.RestartOnCrash
+	f.heartbeat = f.cfg.DefaultHeartbeat
+	if t.Heartbeat != nil {
+		f.heartbeat = *t.Heartbeat
+	}
+}
+
+// shown reports whether a field applies to the form's current tool.
+func (f *spawnForm) shown(field spawnField) bool {
+	return field != fieldOllama || f.toolName() == "claude"
+}
+
+// focus moves to the next shown field in direction dir, focusing its input.
+func (f *spawnForm) focus(dir int) tea.Cmd {
+	field := f.field
+	for {
+		field = (field + spawnField(dir) + spawnFieldCount) % spawnFieldCount
+		if f.shown(field) {
+			break
+		}
+	}
+	f.field = field
+	f.task.Blur()
+	f.cwd.Blur()
+	switch field {
+	case fieldTask:
+		return f.task.Focus()
+	case fieldCwd:
+		f.cwd.Focus()
+		return textinput.Blink
+	}
+	return nil
+}
+
+// cycle changes the picker or toggle in the current field by delta.
+func (f *spawnForm) cycle(delta int) {
+	step := func(i, n int) int { return (i + delta + n) % n }
+	switch f.field {
+	case fieldTemplate:
+		f.template = step(f.template, len(f.cfg.SpawnTemplates)+1)
+		f.applyTemplate()
+	case fieldTool:
+		f.setTool(types.ValidTools[step(f.tool, len(types.ValidTools))])
+	case fieldModel:
+		f.model = step(f.model, len(f.models))
+	case fieldParent:
+		f.parent = step(f.parent, len(f.parents))
+	case fieldWorktree:
+		f.worktree = !f.worktree
+	case fieldRestart:
+		f.restart = !f.restart
+	case fieldHeartbeat:
+		f.heartbeat = !f.heartbeat
+	case fieldOllama:
+		f.ollama = !f.ollama
+	}
+}
+
+// suggest selects the next or previous suggested directory.
+func (f *spawnForm) suggest(delta int) {
+	if len(f.suggestions) == 0 {
+		return
+	}
+	f.suggestion += delta
+	if f.suggestion < 0 {
+		f.suggestion = len(f.suggestions) - 1
+	}
+	if f.suggestion >= len(f.suggestions) {
+		f.suggestion = 0
+	}
+	f.cwd.SetValue(f.suggestions[f.suggestion])
+	f.cwd.CursorEnd()
+}
+
+// fetchCwdSuggestions suggests directories for the typed cwd: running
+// sessions' directories that match it, then zoxide's best matches.
+func (f *spawnForm) fetchCwdSuggestions() tea.Cmd {
+	query := strings.TrimSpace(f.cwd.Value())
+	f.suggestion = -1
+	if query == "" {
+		f.suggestions = nil
+		return nil
+	}
+	var dirs []string
+	for _, dir := range f.sessionDirs {
+		if fuzzyMatch(query, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return func() tea.Msg {
+		if _, err := exec.LookPath("zoxide"); err == nil {
+			args := append([]string{"query", "--list", "--"}, strings.Fields(query)...)
+			if out, err := exec.Command("zoxide", args...).Output(); err == nil {
+				for _, dir := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+					if dir != "" {
+						dirs = append(dirs, dir)
+					}
+				}
+			}
+		}
+		return cwdSuggestionsMsg{query: query, dirs: uniqueDirs(dirs, maxCwdSuggestions)}
+	}
+}
+
+// uniqueDirs returns the first max distinct dirs.
+func uniqueDirs(dirs []string, max int) []string {
+	seen := make(map[string]bool, len(dirs))
+	var result []string
+	for _, dir := range dirs {
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		result = append(result, dir)
+		if len(result) == max {
+			break
+		}
+	}
+	return result
+}
+
+// resolveCwd resolves the typed cwd to a directory the way spawn does: an
+// existing path, else a zoxide query. An empty cwd is the current
+// directory.
+func resolveCwd(cwd string) (string, error) {
+	if cwd == "" {
+		return os.Getwd()
+	}
+	if strings.HasPrefix(cwd, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			cwd = filepath.Join(home, cwd[2:])
+		}
+	}
+	if abs, err := filepath.Abs(cwd); err == nil {
+		if info, err := os.Stat(abs); err == nil && info.IsDir() {
+			return abs, nil
+		}
+	}
+	if _, err := exec.LookPath("zoxide"); err == nil {
+		if out, err := exec.Command("zoxide", "query", "--", cwd).Output(); err == nil {
+			dir := strings.TrimSpace(string(out))
+			if info, err := os.Stat(dir); err == nil && info.IsDir() {
+				return dir, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("directory not found: %s", cwd)
+}
+
+// validate checks the form and returns the arguments to `coders spawn`.
+// running is used to check that the parent session still exists, and
+// redisConnected whether crash restarts can be tracked.
+func (f *spawnForm) validate(running []types.Session, redisConnected bool) ([]string, error) {
+	tool := f.toolName()
+	args := []string{"--tool", tool}
+
+	if model := f.models[f.model]; model != "" {
+		args = append(args, "--model", model)
+	}
+	if task := strings.TrimSpace(f.task.Value()); task != "" {
+		args = append(args, "--task", task)
+	}
+
+	cwd, err := resolveCwd(strings.TrimSpace(f.cwd.Value()))
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, "--cwd", cwd)
+
+	if f.worktree {
+		if err := exec.Command("git", "-C", cwd, "rev-parse", "--show-toplevel").Run(); err != nil {
+			return nil, fmt.Errorf("worktree needs a git repository, and %s is not in one", cwd)
+		}
+		args = append(args, "--worktree")
+	}
+
+	if parent := f.parents[f.parent]; parent != "" {
+		if sessionIndex(running, parent) < 0 {
+			return nil, fmt.Errorf("parent %s is no longer running", strings.TrimPrefix(parent, mux.SessionPrefix))
+		}
+		args = append(args, "--parent", parent)
+	}
+	if f.taskID != "" {
+		args = append(args, "--task-id", f.taskID)
+	}
+
+	if f.restart {
+		if !redisConnected {
+			return nil, fmt.Errorf("crash restart needs Redis, which is not connected")
+		}
+		args = append(args, "--restart-on-crash")
+	}
+	args = append(args, fmt.Sprintf("--heartbeat=%t", f.heartbeat))
+
+	if f.ollama {
+		if tool != "claude" {
+			return nil, fmt.Errorf("ollama is only supported with claude")
+		}
+		if f.cfg.Ollama.BaseURL == "" {
+			return nil, fmt.Errorf("ollama needs ollama.base_url in the config or CODERS_OLLAMA_BASE_URL")
+		}
+		if f.cfg.Ollama.AuthToken == "" && f.cfg.Ollama.APIKey == "" {
+			return nil, fmt.Errorf("ollama needs ollama.auth_token or ollama.api_key in the config")
+		}
+		args = append(args, "--ollama")
+	}
+
+	return args, nil
+}
+
+// openSpawnForm opens the spawn form.
+func (m *Model) openSpawnForm(prefill spawnPrefill) tea.Cmd {
+	if m.spawning {
+		m.setStatus("Spawn already in progress")
+		return nil
+	}
+	cfg, _ := config.Get()
+	m.spawn = newSpawnForm(cfg, m.allSessions, prefill)
+	m.previewFocus = false
+	m.previewInput.Blur()
+	if prefill.task != "" {
+		// Start on the tool, the likeliest thing to change for a prefilled task
+		m.spawn.field = fieldTool
+		return nil
+	}
+	m.spawn.field = fieldTask
+	return m.spawn.task.Focus()
+}
+
+// handleSpawnKey handles keys in the spawn form.
+func (m *Model) handleSpawnKey(msg tea.KeyMsg) tea.Cmd {
+	f := m.spawn
+	switch msg.String() {
+	case "esc":
+		m.spawn = nil
+		m.setStatus("Spawn cancelled")
+		return nil
+	case "ctrl+c":
+		return tea.Quit
+	case "tab":
+		return f.focus(1)
+	case "shift+tab":
+		return f.focus(-1)
+	case "ctrl+s":
+		return m.submitSpawn()
+	case "enter":
+		if f.field != fieldTask {
+			return m.submitSpawn()
+		}
+	}
+
+	switch f.field {
+	case fieldTask:
+		var cmd tea.Cmd
+		f.task, cmd = f.task.Update(msg)
+		return cmd
+	case fieldCwd:
+		switch msg.String() {
+		case "up", "ctrl+p":
+			f.suggest(-1)
+			return nil
+		case "down", "ctrl+n":
+			f.suggest(1)
+			return nil
+		}
+		before := f.cwd.Value()
+		var cmd tea.Cmd
+		f.cwd, cmd = f.cwd.Update(msg)
+		if f.cwd.Value() != before {
+			return tea.Batch(cmd, f.fetchCwdSuggestions())
+		}
+		return cmd
+	}
+
+	switch msg.String() {
+	case "left", "h":
+		f.cycle(-1)
+	case "right", "l", " ":
+		f.cycle(1)
+	case "up", "k":
+		return f.focus(-1)
+	case "down", "j":
+		return f.focus(1)
+	}
+	return nil
+}
+
+// submitSpawn validates the form and spawns the session, or shows the
+// validation error and keeps the form open.
+func (m *Model) submitSpawn() tea.Cmd {
+	args, err := m.spawn.validate(m.allSessions, m.redisClient != nil)
+	if err != nil {
+		m.spawn.err = err.Error()
+		return nil
+	}
+	m.spawn = nil
+	m.spawning = true
+	m.setStatus(fmt.Sprintf("Spawning %s session...", args[1]))
+	return m.runSpawn(args)
+}
+
+// createdSessionRe matches the line spawn prints for the new session.
+var createdSessionRe = regexp.MustCompile(`Created session: ([^\s\x1b]+)`)
+
+// runSpawn runs `coders spawn` with args, reporting the spawned session.
+func (m Model) runSpawn(args []string) tea.Cmd {
+	return func() tea.Msg {
+		exe, err := os.Executable()
+		if err != nil {
+			return spawnCompleteMsg{err: err}
+		}
+
+		cmd := exec.Command(exe, append([]string{"spawn"}, args...)...)
+		var output bytes.Buffer
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		if err := cmd.Run(); err != nil {
+			msg := strings.TrimSpace(output.String())
+			if msg != "" {
+				return spawnCompleteMsg{err: fmt.Errorf("%w: %s", err, msg)}
+			}
+			return spawnCompleteMsg{err: err}
+		}
+		var session string
+		if match := createdSessionRe.FindStringSubmatch(output.String()); match != nil {
+			session = match[1]
+		}
+		return spawnCompleteMsg{session: session}
+	}
+}
+
+// handleSpawnComplete reports the spawned session and selects it once it
+// is listed.
+func (m *Model) handleSpawnComplete(msg spawnCompleteMsg) tea.Cmd {
+	m.spawning = false
+	switch {
+	case msg.err != nil:
+		m.setStatus(fmt.Sprintf("Spawn failed: %v", msg.err))
+	case msg.session != "":
+		m.setStatus("Spawned " + msg.session)
+		m.selectOnFetch = msg.session
+	default:
+		m.setStatus("Spawn command sent")
+	}
+	return m.fetchSessions
+}
+
+// renderSpawnForm renders the spawn form.
+func (m Model) renderSpawnForm() string {
+	f := m.spawn
+	var b strings.Builder
+
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(ColorCyan).
+		Padding(1, 2)
+
+	titleStyle := lipgloss.NewStyle().Foreground(ColorCyan)
+	b.WriteString(titleStyle.Render("Spawn a new session"))
+	if f.taskID != "" {
+		b.WriteString(DimStyle.Render(" for task " + f.taskID))
+	}
+	b.WriteString("\n\n")
+
+	label := func(field spawnField, text string) string {
+		if f.field == field {
+			return SelectedStyle.Render(IndicatorSelected+" ") + padRight(text, 11)
+		}
+		return "  " + DimStyle.Render(padRight(text, 11))
+	}
+	choices := func(options []string, selected int, style func(string) lipgloss.Style) string {
+		parts := make([]string, len(options))
+		for i, option := range options {
+			if i == selected {
+				parts[i] = style(option).Bold(true).Render("[" + option + "]")
+			} else {
+				parts[i] = DimStyle.Render(option)
+			}
+		}
+		return strings.Join(parts, " ")
+	}
+	plain := func(string) lipgloss.Style { return lipgloss.NewStyle() }
+	toggle := func(on bool) string {
+		if on {
+			return "[x] yes"
+		}
+		return "[ ] no"
+	}
+
+	templates := []string{"none"}
+	for _, t := range f.cfg.SpawnTemplates {
+		templates = append(templates, t.Name)
+	}
+	if len(templates) > 1 {
+		b.WriteString(label(fieldTemplate, "Template") + choices(templates, f.template, plain) + "\n")
+	} else {
+		b.WriteString(label(fieldTemplate, "Template") + DimStyle.Render("none configured (spawn_templates)") + "\n")
+	}
+	b.WriteString(label(fieldTool, "Tool") + choices(types.ValidTools, f.tool, GetToolStyle) + "\n")
+
+	models := make([]string, len(f.models))
+	copy(models, f.models)
+	models[0] = "default"
+	b.WriteString(label(fieldModel, "Model") + choices(models, f.model, plain) + "\n")
+
+	b.WriteString(label(fieldTask, "Task") + "\n")
+	b.WriteString(lipgloss.NewStyle().PaddingLeft(4).Render(f.task.View()) + "\n")
+
+	b.WriteString(label(fieldCwd, "Directory") + f.cwd.View() + "\n")
+	if f.field == fieldCwd {
+		for i, dir := range f.suggestions {
+			if i == f.suggestion {
+				b.WriteString("             " + SelectedStyle.Render(dir) + "\n")
+			} else {
+				b.WriteString("             " + DimStyle.Render(dir) + "\n")
+			}
+		}
+	}
+
+	parent := "none"
+	if name := f.parents[f.parent]; name != "" {
+		parent = strings.TrimPrefix(name, mux.SessionPrefix)
+	}
+	b.WriteString(label(fieldParent, "Parent") + parent +
+		DimStyle.Render(fmt.Sprintf("  (%d/%d)", f.parent+1, len(f.parents))) + "\n")
+
+	b.WriteString(label(fieldWorktree, "Worktree") + toggle(f.worktree) + "\n")
+	b.WriteString(label(fieldRestart, "Restart") + toggle(f.restart) + DimStyle.Render("  on crash") + "\n")
+	b.WriteString(label(fieldHeartbeat, "Heartbeat") + toggle(f.heartbeat) + "\n")
+	if f.shown(fieldOllama) {
+		b.WriteString(label(fieldOllama, "Ollama") + toggle(f.ollama) + "\n")
+	}
+
+	if f.err != "" {
+		b.WriteString("\n" + ErrorStyle.Render(f.err) + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(DimStyle.Render("tab field  ←→/space change  ↑↓ suggestions  Enter or Ctrl-s to spawn, Esc to cancel"))
+
+	return style.Render(b.String())
+}
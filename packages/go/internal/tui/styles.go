@@ -100,12 +100,19 @@ var (
 	// Warning style
 	WarningStyle = lipgloss.NewStyle().
 			Foreground(ColorYellow)
+
+	// Marked session indicator
+	MarkedStyle = lipgloss.NewStyle().
+			Foreground(ColorYellow).
+			Bold(true)
 )
 
 // Health status styles (for stuck/unresponsive detection)
 var (
 	StatusStuck        = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6B6B"))
 	StatusUnresponsive = lipgloss.NewStyle().Foreground(ColorRed).Bold(true)
+	StatusWaiting      = lipgloss.NewStyle().Foreground(ColorYellow).Bold(true)
+	StatusOverLimit    = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF9F43")).Bold(true)
 )
 
 // Status indicators
@@ -115,10 +122,13 @@ const (
 	IndicatorDead         = "○"
 	IndicatorStuck        = "◉"
 	IndicatorUnresponsive = "✗"
+	IndicatorWaiting      = "◈"
+	IndicatorOverLimit    = "▲"
 	IndicatorCompleted    = "✓"
 	IndicatorBlocked      = "!"
 	IndicatorReview       = "?"
 	IndicatorSelected     = "❯"
+	IndicatorMarked       = "◆"
 	IndicatorOrchestra    = "🎯"
 	IndicatorChild        = "├─"
 )
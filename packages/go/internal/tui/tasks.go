@@ -0,0 +1,472 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/routing"
+	"github.com/Jayphen/coders/internal/tasksource"
+)
+
+// The tasks tab lists open tasks from the task sources in the config
+// (task_sources), and spawns a session for the selected task. Sessions
+// spawned from here carry the task's ID, which links them back to the
+// task in the list.
+
+// tasksFetchTimeout bounds a task fetch; Linear and GitHub go over the
+// network.
+const tasksFetchTimeout = 30 * time.Second
+
+type tasksMsg struct {
+	tasks []tasksource.Task
+	err   error
+}
+
+// taskPrompt is the spawn task for a task: its title and where it came
+// from, as the loop runner phrases it, followed by the description.
+func taskPrompt(task tasksource.Task) string {
+	prompt := fmt.Sprintf("%s [Source: %s, ID: %s]", task.Title, task.Source, task.SourceID)
+	if desc := strings.TrimSpace(task.Description); desc != "" {
+		prompt += "\n\n" + desc
+	}
+	return prompt
+}
+
+// taskDir returns the directory a task's source works in: the beads
+// project, or the todolist's directory. Other sources fall back to the
+// current directory.
+func taskDir(task tasksource.Task, specs []string) string {
+	for _, s := range specs {
+		spec, err := tasksource.ParseSourceSpec(s)
+		if err != nil || spec.Type != task.Source {
+			continue
+		}
+		switch spec.Type {
+		case tasksource.SourceTypeBeads:
+			if cwd := spec.Config["cwd"]; cwd != "" {
+				return cwd
+			}
+		case tasksource.SourceTypeTodolist:
+			if path := spec.Config["path"]; path != "" {
+				if abs, err := filepath.Abs(path); err == nil {
+					return filepath.Dir(abs)
+				}
+			}
+		}
+	}
+	cwd, _ := os.Getwd()
+	return cwd
+}
+
+// fetchTasks loads the open and in-progress tasks from the configured task
+// sources. Sources that fail are skipped.
+func (m *Model) fetchTasks() tea.Cmd {
+	m.tasksLoading = true
+	return func() tea.Msg {
+		cfg, err := config.Get()
+		if err != nil {
+			return tasksMsg{err: err}
+		}
+		if len(cfg.TaskSources) == 0 {
+			return tasksMsg{}
+		}
+		source, err := tasksource.CreateMultiSourceFromStrings(cfg.TaskSources)
+		if err != nil {
+			return tasksMsg{err: err}
+		}
+		defer source.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), tasksFetchTimeout)
+		defer cancel()
+		tasks, err := source.ListTasks(ctx, &tasksource.TaskFilter{
+			Status: []tasksource.TaskStatus{tasksource.TaskStatusOpen, tasksource.TaskStatusInProgress},
+		})
+		sortTasks(tasks)
+		return tasksMsg{tasks: tasks, err: err}
+	}
+}
+
+// sortTasks orders tasks by priority, keeping each source's own order
+// within a priority.
+func sortTasks(tasks []tasksource.Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Priority < tasks[j].Priority
+	})
+}
+
+// filterTasks returns the tasks matching the query, and only those without
+// blockers if readyOnly is set.
+func filterTasks(tasks []tasksource.Task, query string, readyOnly bool) []tasksource.Task {
+	result := make([]tasksource.Task, 0, len(tasks))
+	for _, t := range tasks {
+		if readyOnly && len(t.BlockedBy) > 0 {
+			continue
+		}
+		fields := append([]string{t.Title, t.ID, string(t.Source), t.Assignee}, t.Labels...)
+		if query != "" && !fuzzyMatch(query, fields...) {
+			continue
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
+// applyTasksView rebuilds the visible task list, keeping the same task
+// selected if it is still visible.
+func (m *Model) applyTasksView() {
+	selected := ""
+	if t := m.selectedTask(); t != nil {
+		selected = t.ID
+	}
+
+	m.tasks = filterTasks(m.allTasks, m.taskFilterQuery(), m.tasksReadyOnly)
+
+	for i, t := range m.tasks {
+		if t.ID == selected {
+			m.taskIndex = i
+			return
+		}
+	}
+	if m.taskIndex >= len(m.tasks) {
+		m.taskIndex = len(m.tasks) - 1
+	}
+	if m.taskIndex < 0 {
+		m.taskIndex = 0
+	}
+}
+
+func (m Model) taskFilterQuery() string {
+	return strings.TrimSpace(m.taskFilterInput.Value())
+}
+
+func (m Model) tasksFiltered() bool {
+	return m.taskFilterQuery() != "" || m.tasksReadyOnly
+}
+
+func (m Model) selectedTask() *tasksource.Task {
+	if m.taskIndex >= 0 && m.taskIndex < len(m.tasks) {
+		return &m.tasks[m.taskIndex]
+	}
+	return nil
+}
+
+// taskSessions returns the running sessions working on each task, by task
+// ID.
+func (m Model) taskSessions() map[string][]string {
+	linked := make(map[string][]string)
+	for _, s := range m.allSessions {
+		if s.TaskID != "" {
+			linked[s.TaskID] = append(linked[s.TaskID], s.Name)
+		}
+	}
+	return linked
+}
+
+// handleTasksKey handles keys on the tasks tab.
+func (m *Model) handleTasksKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.taskFilterMode {
+		switch msg.String() {
+		case "esc":
+			m.taskFilterMode = false
+			m.taskFilterInput.Blur()
+			m.taskFilterInput.SetValue("")
+			m.applyTasksView()
+			return m, nil
+		case "enter":
+			m.taskFilterMode = false
+			m.taskFilterInput.Blur()
+			return m, nil
+		case "ctrl+c":
+			return m, tea.Quit
+		}
+		var cmd tea.Cmd
+		m.taskFilterInput, cmd = m.taskFilterInput.Update(msg)
+		m.applyTasksView()
+		return m, cmd
+	}
+
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+
+	case "1":
+		return m, m.showTab(tabSessions)
+
+	case "2", "L":
+		return m, m.showTab(tabLoops)
+
+	case "up", "k":
+		if m.taskIndex > 0 {
+			m.taskIndex--
+		}
+		return m, nil
+
+	case "down", "j":
+		if m.taskIndex < len(m.tasks)-1 {
+			m.taskIndex++
+		}
+		return m, nil
+
+	case "/":
+		m.taskFilterMode = true
+		m.taskFilterInput.Focus()
+		return m, textinput.Blink
+
+	case "f":
+		m.tasksReadyOnly = !m.tasksReadyOnly
+		if m.tasksReadyOnly {
+			m.setStatus("Showing ready tasks")
+		} else {
+			m.setStatus("Showing all open tasks")
+		}
+		m.applyTasksView()
+		return m, nil
+
+	case "s", "enter":
+		t := m.selectedTask()
+		if t == nil {
+			m.setStatus("No task selected")
+			return m, nil
+		}
+		prefill := spawnPrefill{task: taskPrompt(*t), taskID: t.ID}
+		var specs []string
+		if cfg, err := config.Get(); err == nil {
+			specs = cfg.TaskSources
+			if d := routing.Route(cfg.Routing.Rules, routing.TaskInput(*t)); d.Matched() {
+				prefill.tool, prefill.model = d.Tool, d.Model
+			}
+		}
+		prefill.cwd = taskDir(*t, specs)
+		return m, m.openSpawnForm(prefill)
+
+	case "r":
+		return m, m.fetchTasks()
+
+	case "esc":
+		if m.tasksFiltered() {
+			m.taskFilterInput.SetValue("")
+			m.tasksReadyOnly = false
+			m.setStatus("Filter cleared")
+			m.applyTasksView()
+			return m, nil
+		}
+		return m, m.showTab(tabSessions)
+	}
+	return m, nil
+}
+
+// taskPriorityStyle returns the style for a task priority.
+func taskPriorityStyle(p tasksource.TaskPriority) lipgloss.Style {
+	switch p {
+	case tasksource.PriorityCritical:
+		return StatusDead
+	case tasksource.PriorityHigh:
+		return WarningStyle
+	case tasksource.PriorityMedium:
+		return lipgloss.NewStyle()
+	default:
+		return DimStyle
+	}
+}
+
+// renderTasks renders the tasks tab: the task list, then the selected
+// task's details.
+func (m Model) renderTasks(maxHeight int) string {
+	var b strings.Builder
+
+	if bar := m.renderTaskFilterBar(); bar != "" {
+		b.WriteString(bar)
+		b.WriteString("\n")
+	}
+
+	empty := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(ColorGray).
+		Padding(1, 2).
+		Foreground(ColorGray)
+
+	switch {
+	case m.tasksLoading && len(m.allTasks) == 0:
+		b.WriteString(m.spinner.View() + " Loading tasks...")
+	case m.tasksErr != nil && len(m.allTasks) == 0:
+		b.WriteString(ErrorStyle.Render(fmt.Sprintf("Loading tasks failed: %v", m.tasksErr)))
+	case !m.tasksLoaded:
+		b.WriteString(empty.Render("Tasks are loaded when the tab is opened"))
+	case len(m.allTasks) == 0 && !m.tasksConfigured():
+		b.WriteString(empty.Render("No task sources configured. Add task_sources to the config,\n" +
+			"e.g. beads:cwd=~/project or github:owner=me,repo=app,\nor set CODERS_TASK_SOURCES."))
+	case len(m.allTasks) == 0:
+		b.WriteString(empty.Render("No open tasks"))
+	case len(m.tasks) == 0:
+		b.WriteString(empty.Render("No tasks match the filter"))
+	default:
+		headers := fmt.Sprintf(" %-2s%-4s%-10s%-14s%s", "", "PRI", "SOURCE", "ID", "TITLE")
+		b.WriteString(DimStyle.Bold(true).Render(headers))
+		b.WriteString("\n")
+		linked := m.taskSessions()
+		for i := range m.tasks {
+			b.WriteString(m.renderTaskRow(i, linked))
+			b.WriteString("\n")
+		}
+		if t := m.selectedTask(); t != nil {
+			b.WriteString("\n")
+			b.WriteString(m.renderTaskDetail(t, linked[t.ID]))
+		}
+	}
+
+	out := strings.TrimRight(b.String(), "\n")
+	if maxHeight > 0 {
+		out = truncateLines(out, maxHeight, DimStyle.Render("..."))
+	}
+	return out
+}
+
+// tasksConfigured reports whether any task sources are configured.
+func (m Model) tasksConfigured() bool {
+	cfg, err := config.Get()
+	return err == nil && len(cfg.TaskSources) > 0
+}
+
+// renderTaskFilterBar renders the task filter, or "" if the list is
+// unfiltered.
+func (m Model) renderTaskFilterBar() string {
+	var parts []string
+	if m.taskFilterMode {
+		parts = append(parts, m.taskFilterInput.View())
+	} else if query := m.taskFilterQuery(); query != "" {
+		parts = append(parts, HelpKeyStyle.Render("/"+query))
+	}
+	if m.tasksReadyOnly {
+		parts = append(parts, HelpKeyStyle.Render("ready only"))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	parts = append(parts, DimStyle.Render(fmt.Sprintf("%d of %d", len(m.tasks), len(m.allTasks))))
+	return strings.Join(parts, "  ")
+}
+
+// renderTaskRow renders a single task row: priority, source, ID and title,
+// then labels, blockers and the sessions working on it.
+func (m Model) renderTaskRow(index int, linked map[string][]string) string {
+	t := m.tasks[index]
+	selector := "  "
+	id := t.ID
+	if len(id) > 13 {
+		id = id[:12] + "…"
+	}
+	if index == m.taskIndex {
+		selector = SelectedStyle.Render(IndicatorSelected) + " "
+		id = NameStyleSelected.Render(padRight(id, 14))
+	} else {
+		id = padRight(id, 14)
+	}
+
+	title := t.Title
+	if len(title) > 48 {
+		title = title[:47] + "…"
+	}
+	row := " " + selector +
+		padRight(taskPriorityStyle(t.Priority).Render(fmt.Sprintf("P%d", t.Priority)), 4) +
+		padRight(DimStyle.Render(string(t.Source)), 10) +
+		id + title
+
+	if len(t.Labels) > 0 {
+		row += " " + DimStyle.Render("["+strings.Join(t.Labels, ", ")+"]")
+	}
+	if len(t.BlockedBy) > 0 {
+		row += " " + PromiseBlocked.Render(fmt.Sprintf("%s %d", IndicatorBlocked, len(t.BlockedBy)))
+	}
+	if sessions := linked[t.ID]; len(sessions) > 0 {
+		row += " " + StatusHealthy.Render("▶ "+strings.TrimPrefix(sessions[0], mux.SessionPrefix))
+		if len(sessions) > 1 {
+			row += DimStyle.Render(fmt.Sprintf(" +%d", len(sessions)-1))
+		}
+	}
+	return row
+}
+
+// renderTaskDetail renders the description and relations of a task.
+func (m Model) renderTaskDetail(t *tasksource.Task, sessions []string) string {
+	var b strings.Builder
+
+	b.WriteString(TitleStyle.Render(t.Title))
+	b.WriteString(" " + DimStyle.Render(fmt.Sprintf("%s %s", t.Source, t.ID)))
+	b.WriteString("\n")
+
+	b.WriteString(m.renderDetailRow("Status", string(t.Status)))
+	b.WriteString(m.renderDetailRow("Priority", taskPriorityStyle(t.Priority).Render(fmt.Sprintf("P%d", t.Priority))))
+	if len(t.Labels) > 0 {
+		b.WriteString(m.renderDetailRow("Labels", strings.Join(t.Labels, ", ")))
+	}
+	if t.Assignee != "" {
+		b.WriteString(m.renderDetailRow("Assignee", t.Assignee))
+	}
+	if cfg, err := config.Get(); err == nil {
+		if d := routing.Route(cfg.Routing.Rules, routing.TaskInput(*t)); d.Matched() {
+			route := GetToolStyle(d.Tool).Render(d.Tool)
+			if d.Model != "" {
+				route += " " + d.Model
+			}
+			b.WriteString(m.renderDetailRow("Route", route+DimStyle.Render(" — rule "+d.Match)))
+		}
+	}
+	if len(t.BlockedBy) > 0 {
+		b.WriteString(m.renderDetailRow("Blocked by", PromiseBlocked.Render(strings.Join(t.BlockedBy, ", "))))
+	}
+	if len(t.Blocks) > 0 {
+		b.WriteString(m.renderDetailRow("Blocks", strings.Join(t.Blocks, ", ")))
+	}
+	for i, name := range sessions {
+		label := ""
+		if i == 0 {
+			label = "Sessions"
+		}
+		b.WriteString(m.renderDetailRow(label, StatusHealthy.Render(strings.TrimPrefix(name, mux.SessionPrefix))))
+	}
+
+	if desc := strings.TrimSpace(t.Description); desc != "" {
+		b.WriteString("\n")
+		b.WriteString(desc)
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// taskStatusBarParts returns the counts and help shown in the status bar
+// on the tasks tab.
+func (m Model) taskStatusBarParts() (counts, help, second string) {
+	ready := 0
+	for _, t := range m.allTasks {
+		if len(t.BlockedBy) == 0 {
+			ready++
+		}
+	}
+	counts = DimStyle.Render(fmt.Sprintf("%d open, %d ready", len(m.allTasks), ready))
+
+	keys := []string{
+		HelpKeyStyle.Render("↑↓/jk") + " nav",
+		HelpKeyStyle.Render("/") + " filter",
+		HelpKeyStyle.Render("f") + " ready only",
+		HelpKeyStyle.Render("s/↵") + " spawn",
+		HelpKeyStyle.Render("r") + " refresh",
+		HelpKeyStyle.Render("q") + " quit",
+	}
+	help = DimStyle.Render(strings.Join(keys, "  "))
+	second = HelpKeyStyle.Render("1/esc") + DimStyle.Render(" sessions  ") +
+		HelpKeyStyle.Render("2") + DimStyle.Render(" loops  ") +
+		DimStyle.Render("Sessions spawned here are linked to their task")
+	return counts, help, second
+}
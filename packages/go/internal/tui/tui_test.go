@@ -1,12 +1,21 @@
 package tui
 
 import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/mux"
+	"github.com/Jayphen/coders/internal/tasksource"
+	"github.com/Jayphen/coders/internal/tmux"
 	"github.com/Jayphen/coders/internal/types"
 )
 
@@ -31,8 +40,8 @@ func TestNewModel(t *testing.T) {
 		t.Errorf("previewLines = %d, want %d", model.previewLines, defaultPreviewLines)
 	}
 
-	if model.spawnMode {
-		t.Error("expected spawnMode to be false on new model")
+	if model.spawn != nil {
+		t.Error("expected the spawn form to be closed on new model")
 	}
 
 	if model.confirmKill {
@@ -162,94 +171,159 @@ func TestKeyboardNavigation(t *testing.T) {
 
 // TestSpawnDialog tests the spawn dialog interaction.
 func TestSpawnDialog(t *testing.T) {
-	tests := []struct {
-		name            string
-		key             string
-		inSpawnMode     bool
-		expectedMode    bool
-		shouldTrigger   bool
-		description     string
-	}{
-		{
-			name:          "s key opens spawn dialog",
-			key:           "s",
-			inSpawnMode:   false,
-			expectedMode:  true,
-			shouldTrigger: true,
-			description:   "pressing 's' should open spawn mode",
-		},
-		{
-			name:          "esc closes spawn dialog",
-			key:           "esc",
-			inSpawnMode:   true,
-			expectedMode:  false,
-			shouldTrigger: true,
-			description:   "pressing 'esc' should close spawn mode",
-		},
-		{
-			name:          "enter in spawn mode closes dialog",
-			key:           "enter",
-			inSpawnMode:   true,
-			expectedMode:  false,
-			shouldTrigger: true,
-			description:   "pressing 'enter' should submit and close spawn mode",
-		},
+	model := NewModel("test")
+	model.loading = false
+	model.sessions = []types.Session{
+		{Name: "coder-claude-test", Tool: "claude"},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			model := NewModel("test")
-			model.spawnMode = tt.inSpawnMode
-			model.loading = false
-			model.sessions = []types.Session{
-				{Name: "coder-claude-test", Tool: "claude"},
-			}
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	if model.spawn == nil {
+		t.Fatal("pressing 's' should open the spawn form")
+	}
+	if model.spawn.field != fieldTask || !model.spawn.task.Focused() {
+		t.Error("expected the spawn form to open on the task editor")
+	}
 
-			var msg tea.KeyMsg
-			switch tt.key {
-			case "esc":
-				msg = tea.KeyMsg{Type: tea.KeyEsc}
-			case "enter":
-				msg = tea.KeyMsg{Type: tea.KeyEnter}
-			default:
-				msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(tt.key)}
-			}
+	// Enter adds a line to the task rather than submitting
+	model.spawn.task.SetValue("Fix bug")
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if model.spawn == nil || model.spawn.task.Value() != "Fix bug\n" {
+		t.Error("expected enter in the task editor to add a line")
+	}
 
-			updatedModel, _ := model.Update(msg)
-			newModel := updatedModel.(*Model)
+	// Enter elsewhere submits, and a validation error keeps the form open
+	model.spawn.cwd.SetValue(filepath.Join(t.TempDir(), "missing"))
+	model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if model.spawn == nil {
+		t.Fatal("expected a validation error to keep the spawn form open")
+	}
+	if !strings.Contains(model.spawn.err, "directory not found") {
+		t.Errorf("spawn error = %q, want directory not found", model.spawn.err)
+	}
+	if model.spawning {
+		t.Error("expected no spawn after a validation error")
+	}
 
-			if newModel.spawnMode != tt.expectedMode {
-				t.Errorf("%s: spawnMode = %v, want %v", tt.description, newModel.spawnMode, tt.expectedMode)
-			}
-		})
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if model.spawn != nil {
+		t.Error("pressing 'esc' should close the spawn form")
 	}
 }
 
-// TestSpawnInput tests text input in spawn dialog.
-func TestSpawnInput(t *testing.T) {
-	model := NewModel("test")
-	model.spawnMode = true
-	model.loading = false
-	model.spawnInput.Focus()
+// TestSpawnFormFields tests the pickers, toggles and templates of the spawn
+// form.
+func TestSpawnFormFields(t *testing.T) {
+	no := false
+	cfg := &config.Config{
+		DefaultTool:      "claude",
+		DefaultHeartbeat: true,
+		Models:           config.DefaultModels(),
+		SpawnTemplates: []config.SpawnTemplate{
+			{Name: "review", Tool: "codex", Model: "o3", Task: "Review the open PR", Worktree: true, Heartbeat: &no},
+		},
+	}
+	sessions := []types.Session{
+		{Name: "coder-claude-api", Tool: "claude", Cwd: "/src/api"},
+		{Name: "coder-orchestrator", IsOrchestrator: true},
+	}
+	f := newSpawnForm(cfg, sessions, spawnPrefill{})
 
-	// Set the text directly using SetValue since we can't simulate individual keypresses
-	// in a way that bubbles textinput will accept without a full terminal
-	testInput := "claude --task \"Fix bug\""
-	model.spawnInput.SetValue(testInput)
+	if f.toolName() != "claude" || f.models[f.model] != "" {
+		t.Errorf("expected the default tool and model, got %s %q", f.toolName(), f.models[f.model])
+	}
+	if !reflect.DeepEqual(f.parents, []string{"", "coder-claude-api"}) {
+		t.Errorf("parents = %v, want no parent and the running session", f.parents)
+	}
 
-	// The text input should contain the set text
-	value := model.spawnInput.Value()
-	if value != testInput {
-		t.Errorf("spawnInput.Value() = %q, want %q", value, testInput)
+	f.field = fieldModel
+	f.cycle(1)
+	if f.models[f.model] != "opus" {
+		t.Errorf("model = %q, want opus", f.models[f.model])
 	}
 
-	// Test that esc clears the input
-	msg := tea.KeyMsg{Type: tea.KeyEsc}
-	updatedModel, _ := model.Update(msg)
-	newModel := updatedModel.(*Model)
+	// The ollama toggle only applies to claude
+	f.field = fieldHeartbeat
+	f.focus(1)
+	if f.field != fieldOllama {
+		t.Errorf("expected the ollama field after heartbeat for claude, got %d", f.field)
+	}
+	f.setTool("gemini")
+	f.field = fieldHeartbeat
+	f.focus(1)
+	if f.field != fieldTemplate {
+		t.Errorf("expected the ollama field to be skipped for gemini, got %d", f.field)
+	}
 
-	if newModel.spawnInput.Value() != "" {
-		t.Errorf("spawnInput after esc = %q, want empty string", newModel.spawnInput.Value())
+	f.cycle(1)
+	if f.toolName() != "codex" || f.models[f.model] != "o3" || !f.worktree || f.heartbeat {
+		t.Errorf("template not applied: tool %s, model %q, worktree %v, heartbeat %v", f.toolName(), f.models[f.model], f.worktree, f.heartbeat)
+	}
+	if f.task.Value() != "Review the open PR" {
+		t.Errorf("task = %q, want the template's task", f.task.Value())
+	}
+}
+
+// TestSpawnFormValidate tests the checks made before spawning.
+func TestSpawnFormValidate(t *testing.T) {
+	cfg := &config.Config{DefaultTool: "claude", Models: config.DefaultModels()}
+	dir := t.TempDir()
+	sessions := []types.Session{{Name: "coder-claude-api", Tool: "claude"}}
+
+	f := newSpawnForm(cfg, sessions, spawnPrefill{task: "Fix bug", cwd: dir, taskID: "bd-1"})
+	f.setModel("sonnet")
+	f.parent = 1
+	args, err := f.validate(sessions, false)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	want := []string{
+		"--tool", "claude",
+		"--model", "sonnet",
+		"--task", "Fix bug",
+		"--cwd", dir,
+		"--parent", "coder-claude-api",
+		"--task-id", "bd-1",
+		"--heartbeat=false",
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %q, want %q", args, want)
+	}
+
+	tests := []struct {
+		name  string
+		setup func(f *spawnForm)
+		want  string
+	}{
+		{"missing cwd", func(f *spawnForm) { f.cwd.SetValue(filepath.Join(dir, "missing")) }, "directory not found"},
+		{"worktree outside git", func(f *spawnForm) { f.worktree = true }, "needs a git repository"},
+		{"parent gone", func(f *spawnForm) { f.parent = 1 }, "no longer running"},
+		{"restart without redis", func(f *spawnForm) { f.restart = true }, "needs Redis"},
+		{"ollama unconfigured", func(f *spawnForm) { f.ollama = true }, "ollama.base_url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSpawnForm(cfg, sessions, spawnPrefill{cwd: dir})
+			tt.setup(f)
+			var running []types.Session
+			if tt.name != "parent gone" {
+				running = sessions
+			}
+			_, err := f.validate(running, false)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("validate error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestCreatedSessionRe tests reading the session ID from spawn's output.
+func TestCreatedSessionRe(t *testing.T) {
+	out := "🚀 Spawning claude session...\n\033[32m✅ Created session: coder-claude-fix-bug-1700000000\033[0m\n"
+	match := createdSessionRe.FindStringSubmatch(out)
+	if match == nil || match[1] != "coder-claude-fix-bug-1700000000" {
+		t.Errorf("match = %q, want the session ID", match)
 	}
 }
 
@@ -647,7 +721,7 @@ func TestWindowSizeMessage(t *testing.T) {
 // TestSpawnModeConflicts tests that spawn mode prevents other actions.
 func TestSpawnModeConflicts(t *testing.T) {
 	model := NewModel("test")
-	model.spawnMode = true
+	model.openSpawnForm(spawnPrefill{task: "Fix bug"})
 	model.sessions = []types.Session{
 		{Name: "coder-claude-task1", Tool: "claude"},
 		{Name: "coder-claude-task2", Tool: "claude"},
@@ -865,128 +939,35 @@ func TestSelectedSession(t *testing.T) {
 	}
 }
 
-// TestParseSpawnArgs tests the spawn argument parsing.
-func TestParseSpawnArgs(t *testing.T) {
-	tests := []struct {
-		name      string
-		input     string
-		expected  []string
-		expectErr bool
-	}{
-		{
-			name:     "simple args",
-			input:    "claude --task fix-bug",
-			expected: []string{"claude", "--task", "fix-bug"},
-		},
-		{
-			name:     "args with double quotes",
-			input:    `claude --task "Fix the bug"`,
-			expected: []string{"claude", "--task", "Fix the bug"},
-		},
-		{
-			name:     "args with single quotes",
-			input:    `claude --task 'Fix the bug'`,
-			expected: []string{"claude", "--task", "Fix the bug"},
-		},
-		{
-			name:     "args with escaped quotes",
-			input:    `claude --task "Fix \"critical\" bug"`,
-			expected: []string{"claude", "--task", `Fix "critical" bug`},
-		},
-		{
-			name:      "unterminated double quote",
-			input:     `claude --task "Fix bug`,
-			expectErr: true,
-		},
-		{
-			name:      "unterminated single quote",
-			input:     `claude --task 'Fix bug`,
-			expectErr: true,
-		},
-		{
-			name:     "empty input",
-			input:    "",
-			expected: []string{},
-		},
-		{
-			name:     "only whitespace",
-			input:    "   ",
-			expected: []string{},
-		},
-		{
-			name:     "complex command",
-			input:    `gemini --task "Add tests" --priority high --file "src/main.go"`,
-			expected: []string{"gemini", "--task", "Add tests", "--priority", "high", "--file", "src/main.go"},
-		},
-		{
-			name:     "args with tabs and newlines",
-			input:    "claude\t--task\nfix-bug",
-			expected: []string{"claude", "--task", "fix-bug"},
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result, err := parseSpawnArgs(tt.input)
-
-			if tt.expectErr {
-				if err == nil {
-					t.Error("expected error but got nil")
-				}
-				return
-			}
-
-			if err != nil {
-				t.Errorf("unexpected error: %v", err)
-				return
-			}
-
-			if len(result) != len(tt.expected) {
-				t.Errorf("result length = %d, want %d\nresult: %v\nexpected: %v", len(result), len(tt.expected), result, tt.expected)
-				return
-			}
-
-			for i, arg := range result {
-				if arg != tt.expected[i] {
-					t.Errorf("arg[%d] = %q, want %q", i, arg, tt.expected[i])
-				}
-			}
-		})
-	}
-}
-
 // TestTextInputFocus tests that text inputs are properly focused.
 func TestTextInputFocus(t *testing.T) {
 	model := NewModel("test")
 
 	// Initially, neither input should be focused
-	if model.spawnInput.Focused() {
-		t.Error("spawnInput should not be focused initially")
-	}
 	if model.previewInput.Focused() {
 		t.Error("previewInput should not be focused initially")
 	}
 
-	// Open spawn mode
-	model.spawnMode = true
-	model.spawnInput.Focus()
+	// Open the spawn form
+	model.openSpawnForm(spawnPrefill{})
+	if !model.spawn.task.Focused() {
+		t.Error("the task editor should be focused when the spawn form opens")
+	}
 
-	if !model.spawnInput.Focused() {
-		t.Error("spawnInput should be focused when spawn mode is active")
+	// Tab moves focus to the cwd input
+	model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if model.spawn.task.Focused() || !model.spawn.cwd.Focused() {
+		t.Error("tab should move focus from the task editor to the cwd input")
 	}
 
 	// Switch to preview focus
-	model.spawnMode = false
-	model.spawnInput.Blur()
+	model.spawn = nil
 	model.previewFocus = true
 	model.previewInput.Focus()
 
 	if !model.previewInput.Focused() {
 		t.Error("previewInput should be focused when preview focus is active")
 	}
-	if model.spawnInput.Focused() {
-		t.Error("spawnInput should not be focused when not in spawn mode")
-	}
 }
 
 // TestPreviewInputInFocus tests sending text to sessions via preview input.
@@ -1019,6 +1000,81 @@ func TestPreviewInputInFocus(t *testing.T) {
 	}
 }
 
+// TestQuickReplies tests answering a prompt detected in the preview.
+func TestQuickReplies(t *testing.T) {
+	model := NewModel("test")
+	model.loading = false
+	model.sessions = []types.Session{
+		{Name: "coder-claude-test", Tool: "claude"},
+	}
+	model.selectedIndex = 0
+	model.previewSession = "coder-claude-test"
+	model.preview = "Working...\n"
+
+	if _, ok := model.previewPrompt(); ok {
+		t.Fatal("expected no prompt in plain output")
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("2")})
+	if model.previewInput.Value() != "2" {
+		t.Errorf("expected 2 to be typed without a prompt, got %q", model.previewInput.Value())
+	}
+	model.previewInput.SetValue("")
+
+	model.preview = "Do you want to proceed?\n❯ 1. Yes\n  2. Yes, and don't ask again\n  3. No"
+	out := model.renderSessionPreview(80, 0)
+	for _, want := range []string{"Reply:", "1 Yes", "3 No"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("preview missing %q:\n%s", want, out)
+		}
+	}
+
+	// With the input empty, an answer's key sends it rather than typing
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("2")})
+	if model.previewInput.Value() != "" {
+		t.Errorf("expected 2 to answer the prompt, got input %q", model.previewInput.Value())
+	}
+	if !strings.HasPrefix(model.statusMessage, "Answered") && !strings.HasPrefix(model.statusMessage, "Reply failed") {
+		t.Errorf("status = %q, want the reply reported", model.statusMessage)
+	}
+
+	// Once typing has started, keys go to the input
+	model.previewInput.SetValue("a")
+	model.previewInput.CursorEnd()
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("1")})
+	if model.previewInput.Value() != "a1" {
+		t.Errorf("expected 1 to be typed after text, got %q", model.previewInput.Value())
+	}
+}
+
+// TestInterrupt tests the interrupt keys.
+func TestInterrupt(t *testing.T) {
+	model := NewModel("test")
+	model.loading = false
+	model.sessions = []types.Session{
+		{Name: "coder-claude-a", Tool: "claude"},
+		{Name: "coder-claude-b", Tool: "claude"},
+	}
+	model.allSessions = model.sessions
+	model.marked = map[string]bool{"coder-claude-a": true, "coder-claude-b": true}
+
+	// Marked sessions are interrupted after a confirmation
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("I")})
+	if model.pending == nil || model.pending.action != actionInterrupt || model.pending.message != "C-c" {
+		t.Fatalf("expected a pending Ctrl-C interrupt, got %+v", model.pending)
+	}
+	if got := model.pending.prompt(); got != "Interrupt 2 session(s)? (y/n)" {
+		t.Errorf("prompt = %q", got)
+	}
+
+	model.pending = nil
+	model.marked = make(map[string]bool)
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("i")})
+	if model.pending != nil || cmd == nil {
+		t.Error("expected the selected session to be interrupted without confirmation")
+	}
+}
+
 // TestPreviewMessage tests preview message handling.
 func TestPreviewMessage(t *testing.T) {
 	model := NewModel("test")
@@ -1113,3 +1169,644 @@ func TestKeyQuitBehavior(t *testing.T) {
 		})
 	}
 }
+
+func TestOrderSessionTree(t *testing.T) {
+	sessions := []types.Session{
+		{Name: "coder-child-b", ParentSessionID: "coder-parent"},
+		{Name: "coder-parent"},
+		{Name: "coder-grandchild", ParentSessionID: "coder-child-a"},
+		{Name: "coder-child-a", ParentSessionID: "coder-parent"},
+		{Name: "coder-orphan", ParentSessionID: "coder-gone"},
+	}
+
+	ordered := orderSessionTree(sessions)
+
+	want := []string{"coder-parent", "coder-child-b", "coder-child-a", "coder-grandchild", "coder-orphan"}
+	if len(ordered) != len(want) {
+		t.Fatalf("expected %d sessions, got %d", len(want), len(ordered))
+	}
+	for i, name := range want {
+		if ordered[i].Name != name {
+			t.Errorf("position %d: expected %s, got %s", i, name, ordered[i].Name)
+		}
+	}
+}
+
+func TestOrderSessionTreeCycle(t *testing.T) {
+	sessions := []types.Session{
+		{Name: "coder-a", ParentSessionID: "coder-b"},
+		{Name: "coder-b", ParentSessionID: "coder-a"},
+	}
+
+	ordered := orderSessionTree(sessions)
+	if len(ordered) != 2 {
+		t.Fatalf("expected cyclic sessions to be kept, got %d", len(ordered))
+	}
+}
+
+func TestControlOutputDebouncesPreview(t *testing.T) {
+	model := NewModel("test")
+	model.sessions = []types.Session{{Name: "coder-a"}, {Name: "coder-b"}}
+	model.previewSession = "coder-a"
+
+	// Output from another session doesn't refresh the preview
+	model.handleControlMsg(controlEventMsg{event: tmux.ControlEvent{Type: tmux.EventPaneOutput, Session: "coder-b", Data: "x"}})
+	if model.previewRefreshPending {
+		t.Error("output from an unpreviewed session should not schedule a refresh")
+	}
+
+	model.handleControlMsg(controlEventMsg{event: tmux.ControlEvent{Type: tmux.EventPaneOutput, Session: "coder-a", Data: "x"}})
+	if !model.previewRefreshPending {
+		t.Fatal("output from the previewed session should schedule a refresh")
+	}
+
+	if cmd := model.handleControlMsg(previewRefreshMsg{}); cmd == nil {
+		t.Error("expected a preview fetch")
+	}
+	if model.previewRefreshPending {
+		t.Error("refresh should clear the pending flag")
+	}
+}
+
+func TestControlClosedFallsBackToPolling(t *testing.T) {
+	model := NewModel("test")
+	model.control = &tmux.ControlClient{}
+	model.controlFollowing = "coder-a"
+
+	model.handleControlMsg(controlClosedMsg{client: model.control})
+	if model.control != nil || model.controlFollowing != "" {
+		t.Error("expected control client to be cleared")
+	}
+}
+
+func TestControlIgnoresReplacedClient(t *testing.T) {
+	model := NewModel("test")
+	current, stale := &tmux.ControlClient{}, &tmux.ControlClient{}
+	model.control = current
+	model.controlFollowing = "coder-a"
+	model.previewSession = "coder-a"
+
+	if cmd := model.handleControlMsg(controlEventMsg{client: stale, event: tmux.ControlEvent{Type: tmux.EventPaneOutput, Session: "coder-a"}}); cmd != nil {
+		t.Error("an event from a replaced client should be ignored")
+	}
+	if model.previewRefreshPending {
+		t.Error("an event from a replaced client should not schedule a refresh")
+	}
+
+	model.handleControlMsg(controlClosedMsg{client: stale})
+	if model.control != current || model.controlFollowing != "coder-a" {
+		t.Error("closing a replaced client should not clear the current one")
+	}
+}
+
+func TestControlStartsOnce(t *testing.T) {
+	if mux.Default().Name() != "tmux" {
+		t.Skip("control mode is tmux only")
+	}
+	model := NewModel("test")
+
+	if cmd := model.handleControlMsg(controlStartMsg{}); cmd == nil {
+		t.Fatal("expected the control client to be started")
+	}
+	if !model.controlStarting {
+		t.Fatal("expected the start to be recorded on the model")
+	}
+	if cmd := model.handleControlMsg(controlStartMsg{}); cmd != nil {
+		t.Error("a second client was started while the first was starting")
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"fxbug", true},
+		{"FIX", true},
+		{"claude fix", true},
+		{"gemini", false},
+		{"bugfix", false},
+	}
+	for _, tt := range tests {
+		if got := fuzzyMatch(tt.query, "coder-claude-fix-bug", "Fix the bug", "claude"); got != tt.want {
+			t.Errorf("fuzzyMatch(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFilterAndSortSessions(t *testing.T) {
+	now := time.Now()
+	older := now.Add(-time.Hour)
+	sessions := []types.Session{
+		{Name: "coder-a", Tool: "gemini", CreatedAt: &older, HeartbeatStatus: types.HeartbeatHealthy},
+		{Name: "coder-b", Tool: "claude", CreatedAt: &now, HeartbeatStatus: types.HeartbeatHealthy},
+		{Name: "coder-c", Tool: "codex", CreatedAt: &now, HealthCheck: &types.HealthCheckResult{Status: types.HealthStuck}},
+		{Name: "coder-d", Tool: "claude", HasPromise: true, Promise: &types.CoderPromise{Status: types.PromiseBlocked}},
+	}
+
+	names := func(sessions []types.Session) string {
+		var names []string
+		for _, s := range sessions {
+			names = append(names, strings.TrimPrefix(s.Name, "coder-"))
+		}
+		return strings.Join(names, ",")
+	}
+
+	filters := map[statusFilter]string{
+		filterAll:       "a,b,c,d",
+		filterActive:    "a,b,c",
+		filterCompleted: "d",
+		filterBlocked:   "d",
+		filterStuck:     "c",
+	}
+	for f, want := range filters {
+		if got := names(filterSessions(sessions, "", f)); got != want {
+			t.Errorf("filter %s = %s, want %s", f, got, want)
+		}
+	}
+	if got := names(filterSessions(sessions, "claude", filterAll)); got != "b,d" {
+		t.Errorf("query filter = %s, want b,d", got)
+	}
+
+	sorts := map[sortMode]string{
+		sortAge:    "b,c,a,d",
+		sortTool:   "b,c,a,d",
+		sortHealth: "c,b,a,d",
+	}
+	for mode, want := range sorts {
+		if got := names(sortSessions(sessions, mode)); got != want {
+			t.Errorf("sort %s = %s, want %s", mode, got, want)
+		}
+	}
+}
+
+func TestFilterKeepsSelection(t *testing.T) {
+	model := NewModel("test")
+	model.Update(sessionsMsg([]types.Session{
+		{Name: "coder-alpha", Tool: "claude"},
+		{Name: "coder-beta", Tool: "gemini"},
+		{Name: "coder-gamma", Tool: "claude"},
+	}))
+	model.selectedIndex = 2 // gamma
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	if !model.filterMode {
+		t.Fatal("expected filter mode")
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("claude")})
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if model.filterMode || len(model.sessions) != 2 {
+		t.Fatalf("expected 2 claude sessions after enter, got %d", len(model.sessions))
+	}
+	if s := model.selectedSession(); s == nil || s.Name != "coder-gamma" {
+		t.Errorf("expected coder-gamma to stay selected, got %v", s)
+	}
+
+	// The filter survives a refresh
+	model.Update(sessionsMsg([]types.Session{
+		{Name: "coder-delta", Tool: "claude"},
+		{Name: "coder-gamma", Tool: "claude"},
+		{Name: "coder-beta", Tool: "gemini"},
+	}))
+	if len(model.sessions) != 2 {
+		t.Errorf("expected filter to persist across refresh, got %d sessions", len(model.sessions))
+	}
+	if s := model.selectedSession(); s == nil || s.Name != "coder-gamma" {
+		t.Errorf("expected coder-gamma to stay selected after refresh, got %v", s)
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if model.filtered() || len(model.sessions) != 3 {
+		t.Errorf("expected esc to clear the filter, got %d sessions", len(model.sessions))
+	}
+}
+
+func TestMarkSessions(t *testing.T) {
+	model := NewModel("test")
+	model.Update(sessionsMsg([]types.Session{
+		{Name: "coder-a"}, {Name: "coder-b"}, {Name: "coder-c"},
+	}))
+
+	model.Update(tea.KeyMsg{Type: tea.KeySpace})
+	model.Update(tea.KeyMsg{Type: tea.KeySpace})
+	if !model.marked["coder-a"] || !model.marked["coder-b"] || model.marked["coder-c"] {
+		t.Fatalf("expected a and b marked, got %v", model.marked)
+	}
+	if model.selectedIndex != 2 {
+		t.Errorf("expected marking to move down, selectedIndex = %d", model.selectedIndex)
+	}
+	if got := strings.Join(model.actionTargets(), ","); got != "coder-a,coder-b" {
+		t.Errorf("targets = %s, want coder-a,coder-b", got)
+	}
+
+	// Marks survive a refresh but are dropped for sessions that are gone
+	model.Update(sessionsMsg([]types.Session{{Name: "coder-b"}, {Name: "coder-c"}}))
+	if model.marked["coder-a"] || !model.marked["coder-b"] {
+		t.Errorf("expected only coder-b marked after refresh, got %v", model.marked)
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("*")})
+	if len(model.marked) != 2 {
+		t.Errorf("expected * to mark all, got %v", model.marked)
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if len(model.marked) != 0 {
+		t.Errorf("expected esc to clear marks, got %v", model.marked)
+	}
+	if got := model.actionTargets(); len(got) != 1 || got[0] != "coder-c" {
+		t.Errorf("expected the selected session as target without marks, got %v", got)
+	}
+}
+
+func TestBulkActionConfirmation(t *testing.T) {
+	model := NewModel("test")
+	model.Update(sessionsMsg([]types.Session{
+		{Name: "coder-a"}, {Name: "coder-b"}, {Name: "coder-c"},
+	}))
+	model.marked = map[string]bool{"coder-a": true, "coder-c": true}
+
+	// Bulk kill asks first and shows the count
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("K")})
+	if model.pending == nil || model.pending.action != actionKill {
+		t.Fatal("expected a pending kill")
+	}
+	if !strings.Contains(model.renderConfirmDialog(), "Kill 2 session(s)?") {
+		t.Errorf("unexpected confirmation: %s", model.renderConfirmDialog())
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if model.pending != nil || model.statusMessage != "Cancelled" {
+		t.Error("expected n to cancel the pending action")
+	}
+
+	// Sending a message goes to every marked session after confirmation
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("m")})
+	if !model.messageMode {
+		t.Fatal("expected message mode")
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("rebase on main")})
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if model.messageMode {
+		t.Error("expected enter to leave message mode")
+	}
+	if model.pending == nil || model.pending.action != actionSend || model.pending.message != "rebase on main" {
+		t.Fatalf("expected a pending send, got %+v", model.pending)
+	}
+	if got := strings.Join(model.pending.targets, ","); got != "coder-a,coder-c" {
+		t.Errorf("send targets = %s, want coder-a,coder-c", got)
+	}
+	if !strings.Contains(model.pending.prompt(), "to 2 session(s)") {
+		t.Errorf("unexpected prompt: %s", model.pending.prompt())
+	}
+}
+
+func TestRestartAlwaysConfirms(t *testing.T) {
+	model := NewModel("test")
+	model.Update(sessionsMsg([]types.Session{{Name: "coder-a"}}))
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("X")})
+	if model.pending == nil || model.pending.action != actionRestart {
+		t.Fatal("expected restart of the selected session to need confirmation")
+	}
+	if got := model.pending.prompt(); got != "Restart 1 session(s)? (y/n)" {
+		t.Errorf("prompt = %q", got)
+	}
+}
+
+func TestBulkDoneKeepsFailedMarks(t *testing.T) {
+	model := NewModel("test")
+	model.Update(sessionsMsg([]types.Session{
+		{Name: "coder-a"}, {Name: "coder-b"}, {Name: "coder-c"},
+	}))
+	model.marked = map[string]bool{"coder-a": true, "coder-b": true, "coder-c": true}
+
+	model.Update(bulkDoneMsg{
+		action: actionRestart,
+		done:   2,
+		errs:   []string{"b: exit status 1"},
+		failed: []string{"coder-b"},
+	})
+	if len(model.marked) != 1 || !model.marked["coder-b"] {
+		t.Errorf("expected only the failed session to stay marked, got %v", model.marked)
+	}
+	if want := "Restarted 2 session(s), 1 failed: b: exit status 1"; model.statusMessage != want {
+		t.Errorf("status = %q, want %q", model.statusMessage, want)
+	}
+
+	model.Update(bulkDoneMsg{action: actionRestart, done: 1})
+	if len(model.marked) != 0 {
+		t.Errorf("expected marks cleared after a clean run, got %v", model.marked)
+	}
+}
+
+func TestSortLoopsAndQueueBySource(t *testing.T) {
+	loops := []types.LoopState{
+		{LoopID: "loop-old", Status: types.LoopCompleted, StartedAt: 100},
+		{LoopID: "loop-paused", Status: types.LoopPaused, StartedAt: 50},
+		{LoopID: "loop-new", Status: types.LoopCancelled, StartedAt: 300},
+		{LoopID: "loop-running", Status: types.LoopRunning, StartedAt: 200},
+	}
+	sortLoops(loops)
+	var ids []string
+	for _, l := range loops {
+		ids = append(ids, l.LoopID)
+	}
+	if got := strings.Join(ids, ","); got != "loop-running,loop-paused,loop-new,loop-old" {
+		t.Errorf("sortLoops order = %s", got)
+	}
+
+	sources, bySource := loopQueueBySource([]types.LoopTask{
+		{Title: "a", Source: "github"}, {Title: "b", Source: "beads"}, {Title: "c", Source: "github"}, {Title: "d"},
+	})
+	if got := strings.Join(sources, ","); got != "github,beads,tasks" {
+		t.Errorf("sources = %s, want github,beads,tasks", got)
+	}
+	if len(bySource["github"]) != 2 || len(bySource["tasks"]) != 1 {
+		t.Errorf("unexpected grouping: %v", bySource)
+	}
+}
+
+func TestLoopsTab(t *testing.T) {
+	model := NewModel("test")
+	model.Update(sessionsMsg([]types.Session{{Name: "coder-a"}, {Name: "coder-loop-task"}}))
+	model.Update(loopsMsg([]types.LoopState{
+		{
+			LoopID: "loop-1", Status: types.LoopRunning, TotalTasks: 4, CompletedTasks: 1,
+			CurrentTask: &types.LoopTask{Title: "Fix login", SessionID: "coder-loop-task"},
+			Queue:       []types.LoopTask{{Title: "Add tests", Source: "beads"}},
+			Blocked:     []types.LoopTask{{Title: "Upgrade deps", Reason: "needs a token"}},
+		},
+		{LoopID: "loop-0", Status: types.LoopCompleted, TotalTasks: 2, CompletedTasks: 2},
+	}))
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("2")})
+	if model.tab != tabLoops {
+		t.Fatal("expected 2 to show the loops tab")
+	}
+	out := model.renderLoops(0)
+	for _, want := range []string{"loop-1", "Fix login", "beads", "Add tests", "Upgrade deps", "needs a token"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("loops view missing %q:\n%s", want, out)
+		}
+	}
+
+	// Cancelling and skipping are confirmed
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	if model.pendingLoop == nil || model.pendingLoop.command != types.LoopCommandCancel {
+		t.Fatal("expected a pending cancel")
+	}
+	if !strings.Contains(model.renderConfirmDialog(), "Cancel loop-1?") {
+		t.Errorf("unexpected confirmation: %s", model.renderConfirmDialog())
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if model.pendingLoop != nil {
+		t.Fatal("expected n to cancel the pending loop command")
+	}
+
+	// Finished loops can't be controlled
+	model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if model.pendingLoop != nil || !strings.Contains(model.statusMessage, "already completed") {
+		t.Errorf("expected a finished loop to refuse commands, status %q", model.statusMessage)
+	}
+
+	// Enter jumps to the current session, clearing a filter that hides it
+	model.Update(tea.KeyMsg{Type: tea.KeyUp})
+	model.filterInput.SetValue("xyz")
+	model.applyView()
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if model.tab != tabSessions {
+		t.Fatal("expected enter to switch to the sessions tab")
+	}
+	if s := model.selectedSession(); s == nil || s.Name != "coder-loop-task" {
+		t.Errorf("expected the loop's session selected, got %v", s)
+	}
+	if model.filtered() {
+		t.Error("expected the filter hiding the session to be cleared")
+	}
+}
+
+func TestTasksTab(t *testing.T) {
+	model := NewModel("test")
+	model.Update(sessionsMsg([]types.Session{{Name: "coder-claude-login", TaskID: "bd-2"}}))
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("3")})
+	if model.tab != tabTasks || !model.tasksLoading {
+		t.Fatal("expected 3 to show the tasks tab and load tasks")
+	}
+	tasks := []tasksource.Task{
+		{ID: "gh-9", Title: "Update docs", Source: tasksource.SourceTypeGitHub, Priority: tasksource.PriorityLow},
+		{ID: "bd-2", SourceID: "bd-2", Title: "Fix login", Source: tasksource.SourceTypeBeads, Priority: tasksource.PriorityHigh,
+			Labels: []string{"auth"}, Description: "Sessions expire early"},
+		{ID: "bd-3", Title: "Add SSO", Source: tasksource.SourceTypeBeads, Priority: tasksource.PriorityHigh,
+			BlockedBy: []string{"bd-2"}},
+	}
+	sortTasks(tasks)
+	model.Update(tasksMsg{tasks: tasks})
+
+	if got := model.selectedTask(); got == nil || got.ID != "bd-2" {
+		t.Fatalf("expected the highest priority task first, got %v", got)
+	}
+	out := model.renderTasks(0)
+	for _, want := range []string{"P1", "bd-2", "Fix login", "[auth]", "Sessions expire early", "claude-login"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("tasks view missing %q:\n%s", want, out)
+		}
+	}
+
+	// Ready-only hides blocked tasks, and the text filter matches labels
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	if len(model.tasks) != 2 {
+		t.Errorf("expected 2 ready tasks, got %d", len(model.tasks))
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("auth")})
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(model.tasks) != 1 || model.tasks[0].ID != "bd-2" {
+		t.Fatalf("expected the filter to leave bd-2, got %v", model.tasks)
+	}
+
+	// The spawn form opens with the task prefilled, on the tool picker
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	if model.spawn == nil {
+		t.Fatal("expected s to open the spawn form")
+	}
+	if model.spawn.field != fieldTool {
+		t.Errorf("expected the spawn form to open on the tool, got field %d", model.spawn.field)
+	}
+	dir := t.TempDir()
+	model.spawn.setTool(types.ValidTools[0])
+	model.spawn.cwd.SetValue(dir)
+	model.Update(tea.KeyMsg{Type: tea.KeyRight})
+	want := []string{
+		"--tool", types.ValidTools[1],
+		"--task", "Fix login [Source: beads, ID: bd-2]\n\nSessions expire early",
+		"--cwd", dir,
+		"--task-id", "bd-2",
+		fmt.Sprintf("--heartbeat=%t", model.spawn.heartbeat),
+	}
+	if got, err := model.spawn.validate(nil, false); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("spawn args = %q (%v), want %q", got, err, want)
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if model.spawn != nil {
+		t.Error("expected esc to close the spawn form")
+	}
+
+	// Esc clears the filters, then returns to the sessions tab
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if model.tasksFiltered() || len(model.tasks) != 3 {
+		t.Errorf("expected esc to clear the task filters, %d tasks shown", len(model.tasks))
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if model.tab != tabSessions {
+		t.Error("expected esc to return to the sessions tab")
+	}
+}
+
+func TestParseDiff(t *testing.T) {
+	raw := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ package main
+-// old
++// new
++func main() {}
+diff --git a/logo.png b/logo.png
+index 3333333..4444444 100644
+Binary files a/logo.png and b/logo.png differ
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`
+	files := parseDiff(raw)
+	if len(files) != 3 {
+		t.Fatalf("got %d files, want 3", len(files))
+	}
+	if f := files[0]; f.path != "main.go" || f.added != 2 || f.removed != 1 || len(f.lines) != 9 {
+		t.Errorf("main.go = %+v", f)
+	}
+	if f := files[1]; f.path != "logo.png" || !f.binary {
+		t.Errorf("logo.png = %+v", f)
+	}
+	if f := files[2]; f.path != "gone.txt" || f.removed != 1 || f.added != 0 {
+		t.Errorf("gone.txt = %+v", f)
+	}
+
+	stat := parseNumstat("2\t1\tmain.go\n-\t-\tlogo.png\n0\t1\tgone.txt\n")
+	if stat != (diffStat{files: 3, added: 2, removed: 2}) {
+		t.Errorf("parseNumstat() = %+v", stat)
+	}
+}
+
+func TestHighlightCode(t *testing.T) {
+	line := `	return fmt.Sprintf("a // b", x) // done`
+	if got := highlightCode(line, languageFor("main.go")); got != line {
+		t.Errorf("highlighting changed the text: %q", got)
+	}
+	if got := highlightCode(line, languageFor("notes.txt")); got != line {
+		t.Errorf("unknown languages should not be highlighted, got %q", got)
+	}
+	if got := renderDiffLine("+x := 1", nil, 0); got != "+x := 1" {
+		t.Errorf("renderDiffLine() = %q", got)
+	}
+}
+
+func TestLoadDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q")
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", ".")
+	git("commit", "-qm", "initial")
+	base := git("rev-parse", "HEAD")
+
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nvar x = 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git("commit", "-qam", "add x")
+	if err := os.WriteFile(filepath.Join(dir, "new.txt"), []byte("hi\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := loadDiff("coder-a", dir, base)
+	if d.err != nil {
+		t.Fatalf("loadDiff() error: %v", d.err)
+	}
+	if len(d.files) != 2 || d.files[0].path != "a.go" || d.files[0].added != 2 || !d.files[1].untracked {
+		t.Errorf("files = %+v", d.files)
+	}
+	if len(d.commits) != 1 || !strings.HasSuffix(d.commits[0], "add x") {
+		t.Errorf("commits = %q", d.commits)
+	}
+
+	// Without a spawn commit only uncommitted changes are shown
+	if d := loadDiff("coder-a", dir, ""); len(d.files) != 1 || !d.files[0].untracked {
+		t.Errorf("uncommitted files = %+v", d.files)
+	}
+	if d := loadDiff("coder-a", t.TempDir(), ""); d.err == nil {
+		t.Error("expected an error outside a git repository")
+	}
+}
+
+func TestDiffPanel(t *testing.T) {
+	model := NewModel("test")
+	model.Update(sessionsMsg([]types.Session{{Name: "coder-a", Cwd: "/src/a", SpawnCommit: "abc1234def"}}))
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if !model.showDiff || !model.diffLoading {
+		t.Fatal("expected d to show and load the diff")
+	}
+	model.Update(diffMsg(&sessionDiff{
+		session: "coder-a", dir: "/src/a", base: "abc1234def",
+		files: []diffFile{
+			{path: "a.go", added: 1, lines: []string{"diff --git a/a.go b/a.go", "@@ -1 +1,2 @@", "+var x = 1"}},
+			{path: "b.go", removed: 1, lines: []string{"diff --git a/b.go b/b.go", "@@ -1 +0,0 @@", "-var y = 2"}},
+		},
+		commits: []string{"1234567 Add x"},
+	}))
+	if model.diffLoading {
+		t.Error("expected the diff to be loaded")
+	}
+	out := model.renderSessionDiff(80, 0)
+	for _, want := range []string{"since spawn at abc1234", "2 file(s) +1/-1", "a.go", "Add x", "+var x = 1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("diff panel missing %q:\n%s", want, out)
+		}
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("]")})
+	if out := model.renderSessionDiff(80, 0); !strings.Contains(out, "-var y = 2") {
+		t.Errorf("expected ] to show the next file:\n%s", out)
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("]")})
+	if model.diffFile != 0 {
+		t.Errorf("expected ] to wrap to the first file, got %d", model.diffFile)
+	}
+
+	// Loading the diff updates the session's row stats
+	if got := model.renderSessionRow(0); !strings.Contains(got, "+1/-1") {
+		t.Errorf("session row missing stats: %q", got)
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if model.showDiff {
+		t.Error("expected d to return to the preview")
+	}
+}
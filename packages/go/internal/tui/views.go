@@ -8,7 +8,8 @@ import (
 
 	"github.com/charmbracelet/lipgloss"
 
-	"github.com/Jayphen/coders/internal/tmux"
+	"github.com/Jayphen/coders/internal/limits"
+	"github.com/Jayphen/coders/internal/mux"
 	"github.com/Jayphen/coders/internal/types"
 )
 
@@ -32,8 +33,14 @@ func (m Model) renderHeader() string {
 
 // renderConfirmDialog renders the kill confirmation dialog.
 func (m Model) renderConfirmDialog() string {
-	completedCount := m.countCompleted()
-	msg := fmt.Sprintf("Kill all %d completed session(s)? (y/n)", completedCount)
+	var msg string
+	if m.pendingLoop != nil {
+		msg = m.pendingLoop.prompt()
+	} else if m.pending != nil {
+		msg = m.pending.prompt()
+	} else {
+		msg = fmt.Sprintf("Kill all %d completed session(s)? (y/n)", m.countCompleted())
+	}
 
 	style := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
@@ -44,8 +51,9 @@ func (m Model) renderConfirmDialog() string {
 	return style.Render(msg)
 }
 
-// renderSpawnPrompt renders the spawn input dialog.
-func (m Model) renderSpawnPrompt() string {
+// renderMessagePrompt renders the input for a message to the targeted
+// sessions.
+func (m Model) renderMessagePrompt() string {
 	var b strings.Builder
 
 	style := lipgloss.NewStyle().
@@ -54,12 +62,17 @@ func (m Model) renderSpawnPrompt() string {
 		Padding(1, 2)
 
 	titleStyle := lipgloss.NewStyle().Foreground(ColorCyan)
-	b.WriteString(titleStyle.Render("Spawn a new session"))
+	targets := m.actionTargets()
+	if len(targets) == 1 {
+		b.WriteString(titleStyle.Render("Send to " + strings.TrimPrefix(targets[0], mux.SessionPrefix)))
+	} else {
+		b.WriteString(titleStyle.Render(fmt.Sprintf("Send to %d marked sessions", len(targets))))
+	}
 	b.WriteString("\n\n")
-	b.WriteString(DimStyle.Render("Args: "))
-	b.WriteString(m.spawnInput.View())
+	b.WriteString(DimStyle.Render("Message: "))
+	b.WriteString(m.messageInput.View())
 	b.WriteString("\n")
-	b.WriteString(DimStyle.Render("Enter to spawn, Esc to cancel"))
+	b.WriteString(DimStyle.Render("Enter to send, Esc to cancel"))
 
 	return style.Render(b.String())
 }
@@ -70,17 +83,27 @@ func (m Model) renderSessionList() string {
 		return m.spinner.View() + " Loading sessions..."
 	}
 
+	var b strings.Builder
+
+	if bar := m.renderFilterBar(); bar != "" {
+		b.WriteString(bar)
+		b.WriteString("\n")
+	}
+
 	if len(m.sessions) == 0 {
 		style := lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
 			BorderForeground(ColorGray).
 			Padding(1, 2).
 			Foreground(ColorGray)
-		return style.Render("No active coder sessions")
+		if m.filtered() {
+			b.WriteString(style.Render("No sessions match the filter"))
+		} else {
+			b.WriteString(style.Render("No active coder sessions"))
+		}
+		return b.String()
 	}
 
-	var b strings.Builder
-
 	// Column headers (widths match row: 3 + 24 + 10 + 24 + status)
 	headers := fmt.Sprintf(" %-3s%-24s%-10s%-24s%s",
 		"", "SESSION", "TOOL", "TASK/SUMMARY", "STATUS")
@@ -123,6 +146,30 @@ func (m Model) renderSessionList() string {
 	return b.String()
 }
 
+// renderFilterBar renders the filter input and any filter or sort in effect,
+// or "" if the list is unfiltered in the default order.
+func (m Model) renderFilterBar() string {
+	var parts []string
+	if m.filterMode {
+		parts = append(parts, m.filterInput.View())
+	} else if query := m.filterQuery(); query != "" {
+		parts = append(parts, HelpKeyStyle.Render("/"+query))
+	}
+	if m.statusFilter != filterAll {
+		parts = append(parts, DimStyle.Render("status: ")+HelpKeyStyle.Render(m.statusFilter.String()))
+	}
+	if m.sortMode != sortTree {
+		parts = append(parts, DimStyle.Render("sort: ")+HelpKeyStyle.Render(m.sortMode.String()))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	if m.filtered() {
+		parts = append(parts, DimStyle.Render(fmt.Sprintf("%d of %d", len(m.sessions), len(m.allSessions))))
+	}
+	return strings.Join(parts, "  ")
+}
+
 // renderMainContent renders the split view (session list + detail/preview panel).
 func (m Model) renderMainContent(maxHeight int) string {
 	list := m.renderSessionList()
@@ -212,10 +259,15 @@ func (m Model) renderSessionRow(index int) string {
 	s := m.sessions[index]
 	isSelected := index == m.selectedIndex
 
-	// Selection indicator
-	selector := "  "
+	// Selection and mark indicators
+	selector := " "
 	if isSelected {
-		selector = SelectedStyle.Render(IndicatorSelected + " ")
+		selector = SelectedStyle.Render(IndicatorSelected)
+	}
+	if m.marked[s.Name] {
+		selector += MarkedStyle.Render(IndicatorMarked) + " "
+	} else {
+		selector += "  "
 	}
 
 	// Session name
@@ -223,7 +275,7 @@ func (m Model) renderSessionRow(index int) string {
 	if s.IsOrchestrator {
 		displayName = "orchestrator"
 	} else {
-		displayName = strings.TrimPrefix(s.Name, tmux.SessionPrefix)
+		displayName = strings.TrimPrefix(s.Name, mux.SessionPrefix)
 	}
 
 	// Prefix for orchestrator or child
@@ -294,13 +346,17 @@ func (m Model) renderSessionRow(index int) string {
 		case types.PromiseNeedsReview:
 			statusPart = PromiseNeedsReview.Render(IndicatorReview)
 		}
-	} else if s.HealthCheck != nil && (s.HealthCheck.Status == types.HealthStuck || s.HealthCheck.Status == types.HealthUnresponsive) {
-		// Show stuck/unresponsive from health check
+	} else if s.HealthCheck != nil && (s.HealthCheck.Status == types.HealthStuck || s.HealthCheck.Status == types.HealthUnresponsive || s.HealthCheck.Status == types.HealthWaitingInput || s.HealthCheck.Status == types.HealthOverLimit) {
+		// Show stuck/unresponsive/waiting from health check
 		switch s.HealthCheck.Status {
 		case types.HealthStuck:
 			statusPart = StatusStuck.Render(IndicatorStuck)
 		case types.HealthUnresponsive:
 			statusPart = StatusUnresponsive.Render(IndicatorUnresponsive)
+		case types.HealthWaitingInput:
+			statusPart = StatusWaiting.Render(IndicatorWaiting)
+		case types.HealthOverLimit:
+			statusPart = StatusOverLimit.Render(IndicatorOverLimit)
 		}
 	} else {
 		switch s.HeartbeatStatus {
@@ -319,7 +375,11 @@ func (m Model) renderSessionRow(index int) string {
 	toolPadded := padRight(toolPart, 10)
 	taskPadded := padRight(taskPart, 24)
 
-	return selector + namePadded + toolPadded + taskPadded + statusPart
+	row := selector + namePadded + toolPadded + taskPadded + statusPart
+	if stat := renderDiffStat(m.diffStats[s.Name]); stat != "" {
+		row += " " + stat
+	}
+	return row
 }
 
 // padRight pads a string to the specified visible width.
@@ -473,15 +533,22 @@ func (m Model) renderSessionDetail(width int) string {
 			b.WriteString(fmt.Sprintf("  Session Limit: %s\n", style.Render(fmt.Sprintf("%.0f%%", s.Usage.SessionLimitPct))))
 			b.WriteString("  " + style.Render(RenderProgressBar(s.Usage.SessionLimitPct, 20)) + "\n")
 		}
+		if s.Usage.Processes > 0 {
+			b.WriteString(fmt.Sprintf("  CPU: %.0f%%  Memory: %s  Processes: %d\n",
+				s.Usage.CPUPercent, limits.FormatMemory(s.Usage.MemoryBytes), s.Usage.Processes))
+		}
 	}
 
 	// Parent session
 	if s.ParentSessionID != "" {
 		b.WriteString(m.renderDetailRow("Parent:", s.ParentSessionID))
 	}
+	if s.TaskID != "" {
+		b.WriteString(m.renderDetailRow("Task ID:", s.TaskID))
+	}
 
-	// Health check info (if stuck or unresponsive)
-	if s.HealthCheck != nil && (s.HealthCheck.Status == types.HealthStuck || s.HealthCheck.Status == types.HealthUnresponsive) {
+	// Health check info (if stuck, unresponsive or waiting for input)
+	if s.HealthCheck != nil && (s.HealthCheck.Status == types.HealthStuck || s.HealthCheck.Status == types.HealthUnresponsive || s.HealthCheck.Status == types.HealthWaitingInput || s.HealthCheck.Status == types.HealthOverLimit) {
 		b.WriteString("\n")
 		var healthStyle lipgloss.Style
 		var healthLabel string
@@ -492,11 +559,20 @@ func (m Model) renderSessionDetail(width int) string {
 		case types.HealthUnresponsive:
 			healthStyle = StatusUnresponsive
 			healthLabel = IndicatorUnresponsive + " Unresponsive"
+		case types.HealthWaitingInput:
+			healthStyle = StatusWaiting
+			healthLabel = IndicatorWaiting + " Waiting for input"
+		case types.HealthOverLimit:
+			healthStyle = StatusOverLimit
+			healthLabel = IndicatorOverLimit + " Over resource limits"
 		}
 		b.WriteString(m.renderDetailRow("Health:", healthStyle.Render(healthLabel)))
 		if s.HealthCheck.Message != "" {
 			b.WriteString(m.renderDetailRow("", DimStyle.Render(s.HealthCheck.Message)))
 		}
+		if s.HealthCheck.Question != "" {
+			b.WriteString(m.renderDetailRow("Question:", s.HealthCheck.Question))
+		}
 	}
 
 	// Wrap in box
@@ -525,7 +601,12 @@ func (m Model) renderRightPanel(width int, maxHeight int) string {
 		previewHeight = maxHeight - detailHeight
 	}
 
-	preview := m.renderSessionPreview(width, previewHeight)
+	var preview string
+	if m.showDiff {
+		preview = m.renderSessionDiff(width, previewHeight)
+	} else {
+		preview = m.renderSessionPreview(width, previewHeight)
+	}
 	if preview == "" {
 		return detail
 	}
@@ -541,7 +622,7 @@ func (m Model) renderSessionPreview(width int, maxHeight int) string {
 		if s.IsOrchestrator {
 			displayName = "orchestrator"
 		} else {
-			displayName = strings.TrimPrefix(s.Name, tmux.SessionPrefix)
+			displayName = strings.TrimPrefix(s.Name, mux.SessionPrefix)
 		}
 		title = "Preview: " + displayName
 	}
@@ -593,6 +674,13 @@ func (m Model) renderSessionPreview(width int, maxHeight int) string {
 		inputModel.Width = inputWidth
 	}
 	inputLine := inputLabelStyle.Render("Send: ") + inputModel.View()
+	replyWidth := 0
+	if width > 0 {
+		replyWidth = width - 6 // border and padding
+	}
+	if replies := m.renderQuickReplies(replyWidth); replies != "" {
+		inputLine = replies + "\n" + inputLine
+	}
 	if maxHeight > 0 {
 		const (
 			previewMarginTop = 1
@@ -601,10 +689,9 @@ func (m Model) renderSessionPreview(width int, maxHeight int) string {
 			previewHeader    = 1
 			previewHeaderGap = 1
 			previewInputGap  = 1
-			previewInputLine = 1
 			minContentLines  = 0
 		)
-		overhead := previewMarginTop + previewBorder + previewPadding + previewHeader + previewHeaderGap + previewInputGap + previewInputLine
+		overhead := previewMarginTop + previewBorder + previewPadding + previewHeader + previewHeaderGap + previewInputGap + lipgloss.Height(inputLine)
 		maxContentLines := maxHeight - overhead
 		if maxContentLines < minContentLines {
 			return ""
@@ -630,11 +717,15 @@ func (m Model) renderDetailRow(label, value string) string {
 func (m Model) renderStatusBar() string {
 	activeCount := 0
 	completedCount := 0
+	waitingCount := 0
 	for _, s := range m.sessions {
 		if s.HasPromise && !s.IsOrchestrator {
 			completedCount++
 		} else {
 			activeCount++
+			if s.HealthCheck != nil && s.HealthCheck.Status == types.HealthWaitingInput {
+				waitingCount++
+			}
 		}
 	}
 
@@ -644,16 +735,26 @@ func (m Model) renderStatusBar() string {
 	if completedCount > 0 {
 		countsBuilder.WriteString(DimStyle.Render(fmt.Sprintf(", %d completed", completedCount)))
 	}
+	if waitingCount > 0 {
+		countsBuilder.WriteString(DimStyle.Render(", "))
+		countsBuilder.WriteString(StatusWaiting.Render(fmt.Sprintf("%d waiting", waitingCount)))
+	}
 	counts := countsBuilder.String()
 
 	// Help text
 	help := []string{
 		HelpKeyStyle.Render("↑↓/jk") + " nav",
 		HelpKeyStyle.Render("tab") + " focus",
+		HelpKeyStyle.Render("d") + " diff",
+		HelpKeyStyle.Render("/") + " filter",
+		HelpKeyStyle.Render("f") + " status",
+		HelpKeyStyle.Render("o") + " sort",
 		HelpKeyStyle.Render("a/↵") + " attach",
 		HelpKeyStyle.Render("s") + " spawn",
 		HelpKeyStyle.Render("K") + " kill",
 		HelpKeyStyle.Render("r") + " refresh",
+		HelpKeyStyle.Render("2") + " loops",
+		HelpKeyStyle.Render("3") + " tasks",
 		HelpKeyStyle.Render("q") + " quit",
 	}
 	helpLine := DimStyle.Render(strings.Join(help, "  "))
@@ -661,9 +762,21 @@ func (m Model) renderStatusBar() string {
 	// Second line
 	returnHelp := DimStyle.Render("Return to TUI: ") + WarningStyle.Render("Ctrl-b L") + DimStyle.Render(" (last session)")
 
-	completedHelp := ""
+	completedHelp := "  " + HelpKeyStyle.Render("space") + " mark  " + HelpKeyStyle.Render("m") + " send  " +
+		HelpKeyStyle.Render("i") + " interrupt  " + HelpKeyStyle.Render("X") + " restart  " + HelpKeyStyle.Render("V") + " split"
+	if len(m.marked) > 0 {
+		completedHelp = "  " + StatusMsgStyle.Render(fmt.Sprintf("%d marked", len(m.marked))) + completedHelp
+	}
 	if completedCount > 0 {
-		completedHelp = "  " + HelpKeyStyle.Render("R") + " resume  " + HelpKeyStyle.Render("C") + " kill all completed"
+		completedHelp += "  " + HelpKeyStyle.Render("R") + " resume  " + HelpKeyStyle.Render("C") + " kill all completed"
+	}
+
+	secondLine := returnHelp + completedHelp
+	switch m.tab {
+	case tabLoops:
+		counts, helpLine, secondLine = m.loopStatusBarParts()
+	case tabTasks:
+		counts, helpLine, secondLine = m.taskStatusBarParts()
 	}
 
 	// Separator
@@ -682,7 +795,7 @@ func (m Model) renderStatusBar() string {
 	var b strings.Builder
 
 	// Status message (if present)
-	if m.statusMessage != "" && !m.confirmKill {
+	if m.statusMessage != "" && !m.confirming() {
 		statusLine := StatusMsgStyle.Render(m.statusMessage)
 		b.WriteString(statusLine)
 		b.WriteString("\n")
@@ -692,8 +805,7 @@ func (m Model) renderStatusBar() string {
 	b.WriteString(strings.Repeat(" ", spacing))
 	b.WriteString(helpLine)
 	b.WriteString("\n")
-	b.WriteString(returnHelp)
-	b.WriteString(completedHelp)
+	b.WriteString(secondLine)
 
 	return sep.Render(b.String())
 }
@@ -11,6 +11,10 @@ type Session struct {
 	Cwd             string             `json:"cwd"`
 	CreatedAt       *time.Time         `json:"createdAt,omitempty"`
 	ParentSessionID string             `json:"parentSessionId,omitempty"`
+	LoopID          string             `json:"loopId,omitempty"`
+	TaskID          string             `json:"taskId,omitempty"` // Task-source task the session works on
+	Worktree        string             `json:"worktree,omitempty"`
+	SpawnCommit     string             `json:"spawnCommit,omitempty"` // Git HEAD in Cwd when the session was spawned
 	IsOrchestrator  bool               `json:"isOrchestrator"`
 	HeartbeatStatus HeartbeatStatus    `json:"heartbeatStatus,omitempty"`
 	HealthCheck     *HealthCheckResult `json:"healthCheck,omitempty"`
@@ -20,6 +24,17 @@ type Session struct {
 	Usage           *UsageStats        `json:"usage,omitempty"`
 }
 
+// SessionMeta is the metadata recorded on a multiplexer session when it is
+// created. Empty fields are not recorded.
+type SessionMeta struct {
+	Tool     string
+	Task     string
+	Parent   string // Parent session ID
+	Loop     string // ID of the loop that spawned the session
+	TaskID   string // ID of the task-source task the session works on
+	Worktree string // Git worktree path, if the session has its own worktree
+}
+
 // HeartbeatStatus indicates the health of a session based on heartbeat age.
 type HeartbeatStatus string
 
@@ -67,32 +82,58 @@ type UsageStats struct {
 	APICalls        int     `json:"apiCalls,omitempty"`
 	SessionLimitPct float64 `json:"sessionLimitPercent,omitempty"`
 	WeeklyLimitPct  float64 `json:"weeklyLimitPercent,omitempty"`
+
+	// Resource use of the session's process tree
+	CPUPercent  float64 `json:"cpuPercent,omitempty"`  // Percent of one CPU since the previous heartbeat
+	MemoryBytes int64   `json:"memoryBytes,omitempty"` // Resident memory
+	Processes   int     `json:"processes,omitempty"`
+}
+
+// ResourceLimits caps the resources a session's tool may use. Zero values
+// mean no limit.
+type ResourceLimits struct {
+	MaxMemory int64   `json:"maxMemoryBytes,omitempty"`
+	MaxCPU    float64 `json:"maxCpu,omitempty"` // CPUs, e.g. 1.5
+	MaxProcs  int     `json:"maxProcs,omitempty"`
+	Method    string  `json:"method,omitempty"` // How the limits were applied: systemd, cgroup, ulimit or none
+}
+
+// IsZero reports whether no limits are set.
+func (l ResourceLimits) IsZero() bool {
+	return l.MaxMemory == 0 && l.MaxCPU == 0 && l.MaxProcs == 0
 }
 
 // HealthStatus indicates the overall health of a session.
 type HealthStatus string
 
 const (
-	HealthHealthy      HealthStatus = "healthy"      // Active and responsive
-	HealthStale        HealthStatus = "stale"        // Heartbeat aging (1-5 min)
-	HealthDead         HealthStatus = "dead"         // No heartbeat (>= 5 min)
-	HealthStuck        HealthStatus = "stuck"        // Pane output not changing for too long
-	HealthUnresponsive HealthStatus = "unresponsive" // tmux session exists but process seems hung
+	HealthHealthy      HealthStatus = "healthy"       // Active and responsive
+	HealthStale        HealthStatus = "stale"         // Heartbeat aging (1-5 min)
+	HealthDead         HealthStatus = "dead"          // No heartbeat (>= 5 min)
+	HealthStuck        HealthStatus = "stuck"         // Pane output not changing for too long
+	HealthUnresponsive HealthStatus = "unresponsive"  // tmux session exists but process seems hung
+	HealthWaitingInput HealthStatus = "waiting-input" // CLI is at a permission, confirmation or input prompt
+	HealthOverLimit    HealthStatus = "over-limit"    // Process tree is at or over its resource limits
 )
 
 // HealthCheckResult contains the results of a health check for a session.
 type HealthCheckResult struct {
-	SessionID       string       `json:"sessionId"`
-	Timestamp       int64        `json:"timestamp"`
-	Status          HealthStatus `json:"status"`
-	HeartbeatAge    int64        `json:"heartbeatAgeMs,omitempty"`    // Milliseconds since last heartbeat
-	OutputHash      string       `json:"outputHash,omitempty"`        // Hash of recent pane output for change detection
-	OutputStaleFor  int64        `json:"outputStaleForMs,omitempty"`  // How long output has been unchanged
-	ProcessRunning  bool         `json:"processRunning"`              // Whether tmux pane process is alive
-	TmuxAlive       bool         `json:"tmuxAlive"`                   // Whether tmux session exists
-	Message         string       `json:"message,omitempty"`           // Human-readable status message
-	LastOutputHash  string       `json:"lastOutputHash,omitempty"`    // Previous output hash for comparison
-	LastCheckTime   int64        `json:"lastCheckTime,omitempty"`     // When last check was performed
+	SessionID      string       `json:"sessionId"`
+	Timestamp      int64        `json:"timestamp"`
+	Status         HealthStatus `json:"status"`
+	HeartbeatAge   int64        `json:"heartbeatAgeMs,omitempty"`   // Milliseconds since last heartbeat
+	OutputHash     string       `json:"outputHash,omitempty"`       // Hash of recent pane output for change detection
+	OutputStaleFor int64        `json:"outputStaleForMs,omitempty"` // How long output has been unchanged
+	ProcessRunning bool         `json:"processRunning"`             // Whether tmux pane process is alive
+	TmuxAlive      bool         `json:"tmuxAlive"`                  // Whether tmux session exists
+	Message        string       `json:"message,omitempty"`          // Human-readable status message
+	LastOutputHash string       `json:"lastOutputHash,omitempty"`   // Previous output hash for comparison
+	LastCheckTime  int64        `json:"lastCheckTime,omitempty"`    // When last check was performed
+	StatusSince    int64        `json:"statusSince,omitempty"`      // When the session entered its current status
+	EpisodeStatus  HealthStatus `json:"episodeStatus,omitempty"`    // Unhealthy status being remediated, kept through brief recoveries
+	EpisodeSince   int64        `json:"episodeSince,omitempty"`     // When the remediation episode began
+	Remediations   []string     `json:"remediations,omitempty"`     // Remediation rules applied during the current episode
+	Question       string       `json:"question,omitempty"`         // Prompt text when waiting for input
 }
 
 // HealthCheckSummary provides an overview of all session health.
@@ -104,6 +145,8 @@ type HealthCheckSummary struct {
 	Dead          int                 `json:"dead"`
 	Stuck         int                 `json:"stuck"`
 	Unresponsive  int                 `json:"unresponsive"`
+	WaitingInput  int                 `json:"waitingInput"`
+	OverLimit     int                 `json:"overLimit"`
 	Sessions      []HealthCheckResult `json:"sessions"`
 }
 
@@ -130,28 +173,56 @@ var ToolColors = map[string]string{
 }
 
 // SessionState stores the state needed to restart a crashed session.
+// It is also the durable record of a session's parent, so it is written
+// for every spawned session, not only those with restart-on-crash enabled.
 type SessionState struct {
-	SessionID       string `json:"sessionId"`
-	SessionName     string `json:"sessionName"`
-	Tool            string `json:"tool"`
-	Task            string `json:"task"`
-	Cwd             string `json:"cwd"`
-	Model           string `json:"model,omitempty"`
-	UseOllama       bool   `json:"useOllama,omitempty"`
-	HeartbeatEnabled bool   `json:"heartbeatEnabled"`
-	RestartOnCrash  bool   `json:"restartOnCrash"`
-	RestartCount    int    `json:"restartCount"`
-	MaxRestarts     int    `json:"maxRestarts"`
-	CreatedAt       int64  `json:"createdAt"`
-	LastRestartAt   int64  `json:"lastRestartAt,omitempty"`
+	SessionID        string          `json:"sessionId"`
+	SessionName      string          `json:"sessionName"`
+	Tool             string          `json:"tool"`
+	Task             string          `json:"task"`
+	Cwd              string          `json:"cwd"`
+	Model            string          `json:"model,omitempty"`
+	ParentSessionID  string          `json:"parentSessionId,omitempty"`
+	LoopID           string          `json:"loopId,omitempty"`
+	TaskID           string          `json:"taskId,omitempty"` // Task-source task the session works on
+	Worktree         string          `json:"worktree,omitempty"`
+	Notify           string          `json:"notify,omitempty"`         // Notification override: all, none, or comma-separated channels
+	ConversationID   string          `json:"conversationId,omitempty"` // Tool conversation ID used to resume after a crash
+	SpawnCommit      string          `json:"spawnCommit,omitempty"`    // Git HEAD in Cwd when the session was spawned
+	Limits           *ResourceLimits `json:"limits,omitempty"`
+	Sandbox          bool            `json:"sandbox,omitempty"`        // Tool runs in a sandbox (spawn --sandbox)
+	SandboxNetwork   bool            `json:"sandboxNetwork,omitempty"` // Sandbox allows network access
+	UseOllama        bool            `json:"useOllama,omitempty"`
+	HeartbeatEnabled bool            `json:"heartbeatEnabled"`
+	RestartOnCrash   bool            `json:"restartOnCrash"`
+	RestartCount     int             `json:"restartCount"`
+	MaxRestarts      int             `json:"maxRestarts"`
+	CreatedAt        int64           `json:"createdAt"`
+	LastRestartAt    int64           `json:"lastRestartAt,omitempty"`
+	RestartingAt     int64           `json:"restartingAt,omitempty"` // When a restart began; cleared once the session is recreated
 }
 
 // CrashEvent records when a session crashed.
 type CrashEvent struct {
-	SessionID   string `json:"sessionId"`
-	Timestamp   int64  `json:"timestamp"`
-	Reason      string `json:"reason"`
-	WillRestart bool   `json:"willRestart"`
+	SessionID      string `json:"sessionId"`
+	Timestamp      int64  `json:"timestamp"`
+	Reason         string `json:"reason"`
+	Class          string `json:"class,omitempty"`    // e.g. process-exit, oom, auth, rate-limit, network, clean-exit
+	ExitCode       *int   `json:"exitCode,omitempty"` // Tool exit status, if recorded
+	WillRestart    bool   `json:"willRestart"`
+	RestartDelayMs int64  `json:"restartDelayMs,omitempty"`
+	Bundle         string `json:"bundle,omitempty"` // Forensics bundle directory
+}
+
+// SessionEvent records an automated action taken on a session.
+type SessionEvent struct {
+	SessionID string       `json:"sessionId"`
+	Timestamp int64        `json:"timestamp"`
+	Type      string       `json:"type"`             // e.g. remediation
+	Action    string       `json:"action,omitempty"` // e.g. nudge, escape, restart, kill
+	Status    HealthStatus `json:"status,omitempty"` // Health status that triggered the event
+	Message   string       `json:"message"`
+	Error     string       `json:"error,omitempty"`
 }
 
 // LoopNotification represents a notification sent when a loop completes.
@@ -159,6 +230,78 @@ type LoopNotification struct {
 	LoopID    string `json:"loopId"`
 	Timestamp int64  `json:"timestamp"`
 	TaskCount int    `json:"taskCount"`
-	Status    string `json:"status"` // completed, paused, failed
+	Status    string `json:"status"` // completed, cancelled, blocked, failed
 	Message   string `json:"message,omitempty"`
 }
+
+// LoopStatus represents the state of a loop run.
+type LoopStatus string
+
+const (
+	LoopRunning   LoopStatus = "running"
+	LoopPaused    LoopStatus = "paused"    // Waiting for a resume command
+	LoopCompleted LoopStatus = "completed" // Every task was attempted
+	LoopCancelled LoopStatus = "cancelled" // Stopped by a cancel command or signal
+	LoopBlocked   LoopStatus = "blocked"   // Stopped on a blocked task (--stop-on-blocked)
+	LoopFailed    LoopStatus = "failed"    // A task could not be spawned or waited on
+)
+
+// Finished reports whether the loop is no longer running.
+func (s LoopStatus) Finished() bool {
+	return s != LoopRunning && s != LoopPaused
+}
+
+// LoopTask is a task in a loop run.
+type LoopTask struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Source    string `json:"source,omitempty"`
+	SessionID string `json:"sessionId,omitempty"` // Session that worked on the task
+	Reason    string `json:"reason,omitempty"`    // Why the task was blocked or skipped
+}
+
+// LoopState is the progress of a loop run, stored in Redis for loop-status
+// and the TUI.
+type LoopState struct {
+	LoopID           string       `json:"loopId"`
+	TodolistPath     string       `json:"todolistPath,omitempty"`
+	Sources          []string     `json:"sources,omitempty"`
+	Cwd              string       `json:"cwd"`
+	CurrentTaskIndex int          `json:"currentTaskIndex"`
+	TotalTasks       int          `json:"totalTasks"`
+	CompletedTasks   int          `json:"completedTasks"`
+	CurrentTool      string       `json:"currentTool"`
+	Status           LoopStatus   `json:"status"`
+	CurrentTask      *LoopTask    `json:"currentTask,omitempty"`
+	Queue            []LoopTask   `json:"queue,omitempty"`   // Tasks not yet started, in order
+	Blocked          []LoopTask   `json:"blocked,omitempty"` // Tasks that ended blocked
+	Skipped          []LoopTask   `json:"skipped,omitempty"` // Tasks skipped by a skip command
+	ToolSwitches     []ToolSwitch `json:"toolSwitches,omitempty"`
+	StartedAt        int64        `json:"startedAt,omitempty"`
+	UpdatedAt        int64        `json:"updatedAt,omitempty"`
+}
+
+// ToolSwitch records a loop changing the tool it runs tasks with.
+type ToolSwitch struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Reason    string `json:"reason"`
+	TaskIndex int    `json:"taskIndex"` // First task run with the new tool
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Processed returns how many tasks the loop has finished with, whether
+// they completed, were blocked or were skipped.
+func (s *LoopState) Processed() int {
+	return s.CompletedTasks + len(s.Blocked) + len(s.Skipped)
+}
+
+// LoopCommand is a control command sent to a running loop.
+type LoopCommand string
+
+const (
+	LoopCommandPause  LoopCommand = "pause"  // Finish the current task, then wait
+	LoopCommandResume LoopCommand = "resume" // Continue a paused loop
+	LoopCommandCancel LoopCommand = "cancel" // Stop now, leaving the current session running
+	LoopCommandSkip   LoopCommand = "skip"   // Kill the current task's session and move on
+)
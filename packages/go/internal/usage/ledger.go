@@ -0,0 +1,192 @@
+// Package usage keeps a ledger of the cost and tokens coder sessions use.
+// Heartbeats read the running totals the tools print and append what was
+// spent since the previous heartbeat, so spend can be totalled over any
+// period by session, tool, loop or project, and checked against budgets.
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+// Entry is what one session spent between two heartbeats.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Session string    `json:"session"`
+	Tool    string    `json:"tool,omitempty"`
+	Loop    string    `json:"loop,omitempty"`
+	Project string    `json:"project,omitempty"` // Repository or directory the session works in
+	Cost    float64   `json:"cost,omitempty"`    // USD spent since the previous entry
+	Tokens  int       `json:"tokens,omitempty"`  // Tokens used since the previous entry
+
+	// The session's running totals as the tool reported them, to work out
+	// the next entry's deltas
+	TotalCost   float64 `json:"totalCost,omitempty"`
+	TotalTokens int     `json:"totalTokens,omitempty"`
+}
+
+// Path returns the ledger file.
+func Path() string {
+	return filepath.Join(config.StateDir(), "usage", "ledger.jsonl")
+}
+
+// Append adds entries to the ledger at path.
+func Append(path string, entries ...Entry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create usage directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// One write per entry, so heartbeats appending at once don't interleave
+	for _, e := range entries {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read returns the ledger entries at path recorded at or after since, in
+// the order they were recorded. A missing ledger has no entries; lines
+// that can't be parsed are skipped.
+func Read(path string, since time.Time) ([]Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if !e.Time.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// ParseCost parses a cost as the tools print it, e.g. "$1.23".
+func ParseCost(s string) (float64, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "$")
+	cost, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+	if err != nil || cost < 0 {
+		return 0, false
+	}
+	return cost, true
+}
+
+// ProjectOf returns the project a directory belongs to: the main working
+// tree of its git repository, so worktrees count towards their repository,
+// or the directory itself.
+func ProjectOf(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--path-format=absolute", "--git-common-dir").Output()
+	if err != nil {
+		return dir
+	}
+	common := strings.TrimSpace(string(out))
+	if filepath.Base(common) == ".git" {
+		return filepath.Dir(common)
+	}
+	return dir
+}
+
+// Tracker turns the running totals a session's tool reports into ledger
+// entries.
+type Tracker struct {
+	path    string
+	session string
+	tool    string
+	loop    string
+	project string
+
+	cost   float64
+	tokens int
+}
+
+// NewTracker tracks a session's usage in the ledger at path, carrying on
+// from the totals last recorded for it, e.g. by an earlier heartbeat.
+func NewTracker(path string, session types.Session) *Tracker {
+	t := &Tracker{path: path, session: session.Name, tool: session.Tool, loop: session.LoopID}
+	if session.Cwd != "" {
+		t.project = ProjectOf(session.Cwd)
+	}
+	if entries, err := Read(path, time.Time{}); err == nil {
+		for _, e := range entries {
+			if e.Session == session.Name {
+				t.cost, t.tokens = e.TotalCost, e.TotalTokens
+			}
+		}
+	}
+	return t
+}
+
+// Record appends what was spent since the previous totals, if anything.
+// Totals that went down mean the tool started counting again, e.g. after a
+// restart, so everything reported since counts.
+func (t *Tracker) Record(stats *types.UsageStats, now time.Time) error {
+	if stats == nil {
+		return nil
+	}
+	cost, ok := ParseCost(stats.Cost)
+	if !ok {
+		cost = t.cost
+	}
+	tokens := stats.Tokens
+	if tokens == 0 {
+		tokens = t.tokens
+	}
+
+	e := Entry{
+		Time:        now,
+		Session:     t.session,
+		Tool:        t.tool,
+		Loop:        t.loop,
+		Project:     t.project,
+		Cost:        delta(cost, t.cost),
+		Tokens:      int(delta(float64(tokens), float64(t.tokens))),
+		TotalCost:   cost,
+		TotalTokens: tokens,
+	}
+	t.cost, t.tokens = cost, tokens
+	if e.Cost == 0 && e.Tokens == 0 {
+		return nil
+	}
+	return Append(t.path, e)
+}
+
+// delta is what a running total grew by since prev, rounded to drop
+// floating point noise.
+func delta(cur, prev float64) float64 {
+	d := cur - prev
+	if cur < prev {
+		d = cur
+	}
+	return math.Round(d*1e6) / 1e6
+}
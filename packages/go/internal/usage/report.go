@@ -0,0 +1,131 @@
+package usage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Jayphen/coders/internal/config"
+)
+
+// Groupings for Summarize.
+const (
+	BySession = "session"
+	ByTool    = "tool"
+	ByLoop    = "loop"
+	ByProject = "project"
+)
+
+// Group is the usage of the entries sharing a session, tool, loop or
+// project.
+type Group struct {
+	Key      string  `json:"key"`
+	Cost     float64 `json:"cost"`
+	Tokens   int     `json:"tokens"`
+	Sessions int     `json:"sessions"`
+}
+
+// Summarize totals entries by session, tool, loop or project, most
+// expensive first. Entries without a loop or project are grouped under
+// "-".
+func Summarize(entries []Entry, by string) ([]Group, error) {
+	var key func(Entry) string
+	switch by {
+	case BySession:
+		key = func(e Entry) string { return e.Session }
+	case ByTool:
+		key = func(e Entry) string { return e.Tool }
+	case ByLoop:
+		key = func(e Entry) string { return e.Loop }
+	case ByProject:
+		key = func(e Entry) string { return e.Project }
+	default:
+		return nil, fmt.Errorf("unknown grouping %q (use session, tool, loop or project)", by)
+	}
+
+	groups := make(map[string]*Group)
+	sessions := make(map[string]map[string]bool)
+	for _, e := range entries {
+		k := key(e)
+		if k == "" {
+			k = "-"
+		}
+		g, ok := groups[k]
+		if !ok {
+			g = &Group{Key: k}
+			groups[k] = g
+			sessions[k] = make(map[string]bool)
+		}
+		g.Cost += e.Cost
+		g.Tokens += e.Tokens
+		sessions[k][e.Session] = true
+	}
+
+	result := make([]Group, 0, len(groups))
+	for k, g := range groups {
+		g.Sessions = len(sessions[k])
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Cost != result[j].Cost {
+			return result[i].Cost > result[j].Cost
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result, nil
+}
+
+// Spent totals the cost of entries recorded at or after since.
+func Spent(entries []Entry, since time.Time) float64 {
+	var total float64
+	for _, e := range entries {
+		if !e.Time.Before(since) {
+			total += e.Cost
+		}
+	}
+	return total
+}
+
+// DayStart returns midnight at the start of now's day, in now's location.
+func DayStart(now time.Time) time.Time {
+	y, m, d := now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+}
+
+// WeekStart returns midnight at the start of the Monday of now's week.
+func WeekStart(now time.Time) time.Time {
+	day := DayStart(now)
+	offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+	return day.AddDate(0, 0, -offset)
+}
+
+// Budget actions.
+const (
+	BudgetWarn  = "warn"
+	BudgetBlock = "block"
+)
+
+// BudgetStatus is the spend against one budget cap.
+type BudgetStatus struct {
+	Period string  `json:"period"` // daily or weekly
+	Cap    float64 `json:"cap"`
+	Spent  float64 `json:"spent"`
+}
+
+// Reached reports whether the spend has reached the cap.
+func (s BudgetStatus) Reached() bool {
+	return s.Spent >= s.Cap
+}
+
+// CheckBudget returns the spend against each cap set in budget, from the
+// ledger entries of at least the current week.
+func CheckBudget(budget config.BudgetConfig, entries []Entry, now time.Time) []BudgetStatus {
+	var statuses []BudgetStatus
+	if budget.Daily > 0 {
+		statuses = append(statuses, BudgetStatus{Period: "daily", Cap: budget.Daily, Spent: Spent(entries, DayStart(now))})
+	}
+	if budget.Weekly > 0 {
+		statuses = append(statuses, BudgetStatus{Period: "weekly", Cap: budget.Weekly, Spent: Spent(entries, WeekStart(now))})
+	}
+	return statuses
+}
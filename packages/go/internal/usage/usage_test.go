@@ -0,0 +1,180 @@
+package usage
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jayphen/coders/internal/config"
+	"github.com/Jayphen/coders/internal/types"
+)
+
+func TestParseCost(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"$1.23", 1.23, true},
+		{" $1,024.50 ", 1024.5, true},
+		{"0.07", 0.07, true},
+		{"", 0, false},
+		{"$abc", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseCost(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseCost(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	session := types.Session{Name: "coder-claude-fix", Tool: "claude", LoopID: "loop-1"}
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	tracker := NewTracker(path, session)
+	steps := []*types.UsageStats{
+		{Cost: "$0.50", Tokens: 1000},
+		{Cost: "$0.50", Tokens: 1000}, // unchanged: nothing recorded
+		nil,
+		{Cost: "$1.25", Tokens: 2500},
+		{CPUPercent: 20}, // no usage shown: nothing recorded
+	}
+	for i, stats := range steps {
+		if err := tracker.Record(stats, start.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	// A new heartbeat carries on from the recorded totals, and a total
+	// that went down starts counting again
+	tracker = NewTracker(path, session)
+	if err := tracker.Record(&types.UsageStats{Cost: "$1.50", Tokens: 3000}, start.Add(time.Hour)); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := tracker.Record(&types.UsageStats{Cost: "$0.10", Tokens: 200}, start.Add(2*time.Hour)); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	entries, err := Read(path, time.Time{})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	var costs []float64
+	var tokens []int
+	for _, e := range entries {
+		costs = append(costs, e.Cost)
+		tokens = append(tokens, e.Tokens)
+		if e.Tool != "claude" || e.Loop != "loop-1" {
+			t.Errorf("entry = %+v, want the session's tool and loop", e)
+		}
+	}
+	if want := []float64{0.5, 0.75, 0.25, 0.1}; !reflect.DeepEqual(costs, want) {
+		t.Errorf("costs = %v, want %v", costs, want)
+	}
+	if want := []int{1000, 1500, 500, 200}; !reflect.DeepEqual(tokens, want) {
+		t.Errorf("tokens = %v, want %v", tokens, want)
+	}
+
+	recent, err := Read(path, start.Add(30*time.Minute))
+	if err != nil || len(recent) != 2 {
+		t.Errorf("Read since = %d entries (%v), want 2", len(recent), err)
+	}
+}
+
+func TestReadMissing(t *testing.T) {
+	entries, err := Read(filepath.Join(t.TempDir(), "none.jsonl"), time.Time{})
+	if err != nil || entries != nil {
+		t.Errorf("Read of a missing ledger = %v, %v; want no entries", entries, err)
+	}
+}
+
+func TestProjectOf(t *testing.T) {
+	dir := t.TempDir()
+	if got := ProjectOf(dir); got != dir {
+		t.Errorf("ProjectOf(non-repo) = %q, want %q", got, dir)
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := filepath.Join(dir, "repo")
+	sub := filepath.Join(repo, "pkg")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Command("git", "init", "-q", repo).Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	want, _ := filepath.EvalSymlinks(repo)
+	if got, _ := filepath.EvalSymlinks(ProjectOf(sub)); got != want {
+		t.Errorf("ProjectOf(subdir) = %q, want %q", got, want)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	entries := []Entry{
+		{Session: "a", Tool: "claude", Loop: "loop-1", Cost: 1, Tokens: 100},
+		{Session: "a", Tool: "claude", Loop: "loop-1", Cost: 2, Tokens: 200},
+		{Session: "b", Tool: "claude", Cost: 0.5, Tokens: 50},
+		{Session: "c", Tool: "codex", Cost: 4, Tokens: 10},
+	}
+
+	groups, err := Summarize(entries, ByTool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Group{
+		{Key: "codex", Cost: 4, Tokens: 10, Sessions: 1},
+		{Key: "claude", Cost: 3.5, Tokens: 350, Sessions: 2},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("by tool = %+v, want %+v", groups, want)
+	}
+
+	groups, _ = Summarize(entries, ByLoop)
+	if len(groups) != 2 || groups[0].Key != "-" || groups[0].Cost != 4.5 || groups[1].Key != "loop-1" {
+		t.Errorf("by loop = %+v, want sessions without a loop under -", groups)
+	}
+
+	if _, err := Summarize(entries, "model"); err == nil {
+		t.Error("expected an error for an unknown grouping")
+	}
+}
+
+func TestBudget(t *testing.T) {
+	// Wednesday afternoon
+	now := time.Date(2026, 3, 4, 15, 0, 0, 0, time.Local)
+	if got, want := WeekStart(now), time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("WeekStart = %v, want %v", got, want)
+	}
+	sunday := time.Date(2026, 3, 8, 23, 0, 0, 0, time.Local)
+	if got, want := WeekStart(sunday), time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("WeekStart(Sunday) = %v, want %v", got, want)
+	}
+
+	entries := []Entry{
+		{Time: now.AddDate(0, 0, -3), Cost: 50}, // last week
+		{Time: now.AddDate(0, 0, -1), Cost: 8},
+		{Time: now.Add(-time.Hour), Cost: 5},
+	}
+	statuses := CheckBudget(config.BudgetConfig{Daily: 5, Weekly: 20}, entries, now)
+	want := []BudgetStatus{
+		{Period: "daily", Cap: 5, Spent: 5},
+		{Period: "weekly", Cap: 20, Spent: 13},
+	}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Fatalf("CheckBudget = %+v, want %+v", statuses, want)
+	}
+	if !statuses[0].Reached() || statuses[1].Reached() {
+		t.Error("expected only the daily cap to be reached")
+	}
+
+	if got := CheckBudget(config.BudgetConfig{}, entries, now); len(got) != 0 {
+		t.Errorf("CheckBudget without caps = %+v, want none", got)
+	}
+}